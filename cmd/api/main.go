@@ -31,22 +31,28 @@ func main() {
 
 	admin, err := infrakafka.NewAdminClient(appConfig.Kafka.Brokers)
 	if err != nil {
-		log.Fatal("kafka admin: %v", err)
+		log.Fatalf("kafka admin: %v", err)
 	}
 
 	if err := admin.EnsureTopics(context.Background(), infrakafka.DefaultTopics()); err != nil {
-		log.Fatal("kafka admin: ensure topics: %v", err)
+		log.Fatalf("kafka admin: ensure topics: %v", err)
 	}
 
 	producer, err := infrakafka.NewKafkaProducer(*appConfig.Kafka)
 	if err != nil {
-		log.Fatal("kafka producer: %v", err)
+		log.Fatalf("kafka producer: %v", err)
 	}
 
 	defer producer.Close()
 	publisher := messaging.NewKafkaPublisher(producer)
 
-	router := routes.SetupRoutes(db, redis, appConfig, publisher)
+	webhookConsumer, err := infrakafka.NewConsumer(*appConfig.Kafka, "webhook-worker")
+	if err != nil {
+		log.Fatalf("kafka consumer: %v", err)
+	}
+	defer webhookConsumer.Close()
+
+	router := routes.SetupRoutes(db, redis, appConfig, publisher, messaging.NewKafkaConsumer(webhookConsumer))
 
 	srv := &http.Server{
 		Addr:    ":" + appConfig.ServerPort,
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/omerbeden/paymentgateway/internal/adapter/handler/messaging"
+	"github.com/omerbeden/paymentgateway/internal/adapter/handler/messaging/consumer"
+	"github.com/omerbeden/paymentgateway/internal/adapter/repository/postgres"
+	"github.com/omerbeden/paymentgateway/internal/adapter/webhooksender"
+	"github.com/omerbeden/paymentgateway/internal/domain/event"
+	"github.com/omerbeden/paymentgateway/internal/infrastructure/config"
+	"github.com/omerbeden/paymentgateway/internal/infrastructure/database"
+	"github.com/omerbeden/paymentgateway/internal/infrastructure/logger"
+	"github.com/omerbeden/paymentgateway/internal/infrastructure/metrics"
+	infrakafka "github.com/omerbeden/paymentgateway/internal/infrastructure/queue/kafka"
+	"github.com/omerbeden/paymentgateway/internal/usecase/merchantwebhook"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// The merchant webhook worker turns payment events into deliveries for the
+// registered endpoints and sends them, retrying failures with backoff.
+func main() {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	appConfig := config.Load()
+	var log logger.Logger
+
+	if appConfig.Environment == "development" {
+		log = logger.NewDevelopment()
+	} else {
+		log = logger.New(appConfig.LogLevel)
+	}
+
+	log.Info("Starting merchant webhook worker...")
+
+	db, err := database.NewPostgres(appConfig.DatabaseDSN)
+	if err != nil {
+		log.Fatal("Failed to connect to database", "error", err)
+	}
+	defer db.Close()
+
+	infraConsumer, err := infrakafka.NewConsumer(*appConfig.Kafka, "merchant-webhooks")
+	if err != nil {
+		log.Fatal("kafka consumer", "error", err)
+	}
+	defer infraConsumer.Close()
+	kafkaConsumer := messaging.NewKafkaConsumer(infraConsumer)
+
+	m := metrics.New()
+	endpointRepository := postgres.NewWebhookEndpointRepository(db)
+	deliveryRepository := postgres.NewWebhookDeliveryRepository(db)
+	sender := webhooksender.NewSender(appConfig.MerchantWebhooks.Timeout)
+
+	dispatchEventUC := merchantwebhook.NewDispatchEventUseCase(endpointRepository, deliveryRepository, log)
+	deliverWebhooksUC := merchantwebhook.NewDeliverWebhooksUseCase(endpointRepository, deliveryRepository, sender, *appConfig.MerchantWebhooks, log, m)
+	merchantWebhookConsumer := consumer.NewMerchantWebhookConsumer(dispatchEventUC, log)
+
+	healthMux := http.NewServeMux()
+	healthMux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"status":"healthy","service":"merchant-webhook-worker"}`))
+	})
+	healthMux.Handle("/metrics", promhttp.Handler())
+
+	healthServer := &http.Server{Addr: ":8082", Handler: healthMux}
+	go func() {
+		log.Info("Health check server listening on :8082")
+		if err := healthServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Error("health server error", "error", err)
+		}
+	}()
+
+	go func() {
+		if err := kafkaConsumer.Subscribe(ctx, event.PaymentEventTopics, merchantWebhookConsumer.Handle); err != nil {
+			log.Error("kafka consumer error", "error", err)
+		}
+	}()
+
+	go deliverWebhooksUC.Run(ctx)
+
+	log.Info("Merchant webhook worker started", "topics", event.PaymentEventTopics)
+
+	<-ctx.Done()
+	log.Info("Shutting down merchant webhook worker...")
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer shutdownCancel()
+	healthServer.Shutdown(shutdownCtx)
+
+	log.Info("Merchant webhook worker stopped")
+}
@@ -33,7 +33,7 @@ func main() {
 
 	log.Info("Starting Notification Consumer Service...")
 
-	infraConsumer, err := infrakafka.NewConsumer(*appConfig.Kafka)
+	infraConsumer, err := infrakafka.NewConsumer(*appConfig.Kafka, "notification-consumer")
 	if err != nil {
 		log.Fatal("kafka consumer: %v", err)
 	}
@@ -0,0 +1,172 @@
+// Command webhookctl lists and replays stored provider webhooks through the
+// gateway's admin API.
+//
+//	webhookctl list [-provider paypal] [-payment ID] [-state failed] [-limit 20]
+//	webhookctl replay -id EVENT_ID [-skip-verification]
+//	webhookctl replay [-provider paypal] [-payment ID] [-state failed] [-limit 20] [-skip-verification]
+//
+// The API address and key are read from ADMIN_API_URL (default
+// http://localhost:8080) and ADMIN_API_KEY.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+)
+
+type filterFlags struct {
+	provider string
+	payment  string
+	state    string
+	limit    int
+}
+
+func (f *filterFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.provider, "provider", "", "provider ID, e.g. paypal")
+	fs.StringVar(&f.payment, "payment", "", "provider payment ID")
+	fs.StringVar(&f.state, "state", "", "pending, processed, failed or unverified")
+	fs.IntVar(&f.limit, "limit", 0, "maximum number of events (1-100, default 20)")
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	client := &adminClient{
+		baseURL: getEnv("ADMIN_API_URL", "http://localhost:8080"),
+		apiKey:  os.Getenv("ADMIN_API_KEY"),
+		http:    &http.Client{Timeout: 5 * time.Minute},
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "list":
+		err = list(client, os.Args[2:])
+	case "replay":
+		err = replay(client, os.Args[2:])
+	default:
+		usage()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "webhookctl:", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: webhookctl list|replay [flags]")
+	os.Exit(2)
+}
+
+func list(client *adminClient, args []string) error {
+	var filter filterFlags
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	filter.register(fs)
+	fs.Parse(args)
+
+	query := url.Values{}
+	setIfNotEmpty(query, "provider_id", filter.provider)
+	setIfNotEmpty(query, "provider_payment_id", filter.payment)
+	setIfNotEmpty(query, "state", filter.state)
+	if filter.limit > 0 {
+		query.Set("limit", strconv.Itoa(filter.limit))
+	}
+
+	return client.do(http.MethodGet, "/api/v1/admin/webhooks/events?"+query.Encode(), nil)
+}
+
+func replay(client *adminClient, args []string) error {
+	var (
+		filter           filterFlags
+		id               string
+		skipVerification bool
+	)
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	filter.register(fs)
+	fs.StringVar(&id, "id", "", "replay a single webhook event by ID")
+	fs.BoolVar(&skipVerification, "skip-verification", false, "do not verify the stored signature again")
+	fs.Parse(args)
+
+	if id != "" {
+		return client.do(http.MethodPost, "/api/v1/admin/webhooks/events/"+url.PathEscape(id)+"/replay",
+			map[string]any{"skip_verification": skipVerification})
+	}
+	return client.do(http.MethodPost, "/api/v1/admin/webhooks/events/replay", map[string]any{
+		"provider_id":         filter.provider,
+		"provider_payment_id": filter.payment,
+		"state":               filter.state,
+		"limit":               filter.limit,
+		"skip_verification":   skipVerification,
+	})
+}
+
+type adminClient struct {
+	baseURL string
+	apiKey  string
+	http    *http.Client
+}
+
+// do sends the request and copies the JSON response to stdout.
+func (c *adminClient) do(method, path string, body any) error {
+	var reqBody io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequest(method, c.baseURL+path, reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s: %s", resp.Status, respBody)
+	}
+
+	var out bytes.Buffer
+	if err := json.Indent(&out, respBody, "", "  "); err != nil {
+		out.Write(respBody)
+	}
+	out.WriteString("\n")
+	_, err = out.WriteTo(os.Stdout)
+	return err
+}
+
+func setIfNotEmpty(values url.Values, key, value string) {
+	if value != "" {
+		values.Set(key, value)
+	}
+}
+
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
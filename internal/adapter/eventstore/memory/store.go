@@ -0,0 +1,81 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/omerbeden/paymentgateway/internal/domain/event"
+)
+
+// Store is an event.Store kept in memory, for unit tests. Positions have no
+// gaps: the event at position p is the p-th event appended.
+type Store struct {
+	mu       sync.Mutex
+	events   []event.RecordedEvent
+	versions map[string]int
+}
+
+func NewStore() *Store {
+	return &Store{versions: make(map[string]int)}
+}
+
+func (s *Store) Append(ctx context.Context, aggregateID string, expectedVersion int, events ...event.DomainEvent) error {
+	for _, evt := range events {
+		if evt.AggregateID() != aggregateID {
+			return fmt.Errorf("memory event store: append: %s event of %s appended to %s",
+				evt.EventType(), evt.AggregateID(), aggregateID)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current := s.versions[aggregateID]
+	if expectedVersion != event.AnyVersion && current != expectedVersion {
+		return &event.ConcurrencyError{AggregateID: aggregateID, Expected: expectedVersion, Actual: current}
+	}
+
+	for _, evt := range events {
+		current++
+		s.events = append(s.events, event.RecordedEvent{
+			Event:    evt,
+			Version:  current,
+			Position: int64(len(s.events) + 1),
+		})
+	}
+	s.versions[aggregateID] = current
+	return nil
+}
+
+func (s *Store) Load(ctx context.Context, aggregateID string) ([]event.RecordedEvent, error) {
+	return s.LoadSince(ctx, aggregateID, 0)
+}
+
+func (s *Store) LoadSince(ctx context.Context, aggregateID string, version int) ([]event.RecordedEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var events []event.RecordedEvent
+	for _, recorded := range s.events {
+		if recorded.Event.AggregateID() == aggregateID && recorded.Version > version {
+			events = append(events, recorded)
+		}
+	}
+	return events, nil
+}
+
+func (s *Store) ReadAll(ctx context.Context, fromPosition int64, limit int) ([]event.RecordedEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	start := max(fromPosition, 1) - 1
+	if start >= int64(len(s.events)) {
+		return nil, nil
+	}
+	end := int64(len(s.events))
+	if limit > 0 {
+		end = min(start+int64(limit), end)
+	}
+	return append([]event.RecordedEvent(nil), s.events[start:end]...), nil
+}
@@ -0,0 +1,109 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/omerbeden/paymentgateway/internal/domain/event"
+	"github.com/omerbeden/paymentgateway/internal/domain/money"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAppend_NumbersEventsPerAggregate(t *testing.T) {
+	// Arrange
+	store := NewStore()
+	ctx := context.Background()
+	amount := money.New(1000, "USD")
+
+	// Act
+	err := store.Append(ctx, "pay_1", 0,
+		event.NewPaymentCreatedEvent("pay_1", "stripe", "capture", "pending", amount),
+		event.NewPaymentCapturedEvent("pay_1", "stripe", amount))
+	assert.NoError(t, err)
+	err = store.Append(ctx, "pay_2", 0, event.NewPaymentCreatedEvent("pay_2", "stripe", "capture", "pending", amount))
+	assert.NoError(t, err)
+	err = store.Append(ctx, "pay_1", 2, event.NewPaymentRefundedEvent("pay_1", "stripe", "re_1", "refunded", "", amount, amount))
+	assert.NoError(t, err)
+
+	// Assert
+	events, err := store.Load(ctx, "pay_1")
+	assert.NoError(t, err)
+	assert.Len(t, events, 3)
+	assert.Equal(t, []int{1, 2, 3}, []int{events[0].Version, events[1].Version, events[2].Version})
+	assert.Equal(t, []int64{1, 2, 4}, []int64{events[0].Position, events[1].Position, events[2].Position})
+	assert.Equal(t, event.PaymentRefunded, events[2].Event.EventType())
+}
+
+func TestAppend_RejectsUnexpectedVersion(t *testing.T) {
+	// Arrange
+	store := NewStore()
+	ctx := context.Background()
+	amount := money.New(1000, "USD")
+	assert.NoError(t, store.Append(ctx, "pay_1", 0, event.NewPaymentCreatedEvent("pay_1", "stripe", "capture", "pending", amount)))
+
+	// Act
+	err := store.Append(ctx, "pay_1", 0, event.NewPaymentFailedEvent("pay_1", "stripe", "declined", amount))
+
+	// Assert
+	var conflict *event.ConcurrencyError
+	assert.True(t, errors.As(err, &conflict))
+	assert.True(t, errors.Is(err, event.ErrConcurrencyConflict))
+	assert.Equal(t, 0, conflict.Expected)
+	assert.Equal(t, 1, conflict.Actual)
+	events, _ := store.Load(ctx, "pay_1")
+	assert.Len(t, events, 1)
+}
+
+func TestAppend_AnyVersionSkipsCheck(t *testing.T) {
+	// Arrange
+	store := NewStore()
+	ctx := context.Background()
+	amount := money.New(1000, "USD")
+	assert.NoError(t, store.Append(ctx, "pay_1", 0, event.NewPaymentCreatedEvent("pay_1", "stripe", "capture", "pending", amount)))
+
+	// Act
+	err := store.Append(ctx, "pay_1", event.AnyVersion, event.NewPaymentCapturedEvent("pay_1", "stripe", amount))
+
+	// Assert
+	assert.NoError(t, err)
+	events, _ := store.LoadSince(ctx, "pay_1", 1)
+	assert.Len(t, events, 1)
+	assert.Equal(t, 2, events[0].Version)
+}
+
+func TestAppend_RejectsEventOfAnotherAggregate(t *testing.T) {
+	// Arrange
+	store := NewStore()
+
+	// Act
+	err := store.Append(context.Background(), "pay_1", 0,
+		event.NewPaymentCreatedEvent("pay_2", "stripe", "capture", "pending", money.New(1000, "USD")))
+
+	// Assert
+	assert.Error(t, err)
+	events, _ := store.ReadAll(context.Background(), 1, 10)
+	assert.Empty(t, events)
+}
+
+func TestReadAll_PagesByPosition(t *testing.T) {
+	// Arrange
+	store := NewStore()
+	ctx := context.Background()
+	amount := money.New(1000, "USD")
+	for _, id := range []string{"pay_1", "pay_2", "pay_3"} {
+		assert.NoError(t, store.Append(ctx, id, 0, event.NewPaymentCreatedEvent(id, "stripe", "capture", "pending", amount)))
+	}
+
+	// Act
+	first, err := store.ReadAll(ctx, 1, 2)
+	assert.NoError(t, err)
+	rest, err := store.ReadAll(ctx, first[len(first)-1].Position+1, 2)
+	assert.NoError(t, err)
+
+	// Assert
+	assert.Len(t, first, 2)
+	assert.Len(t, rest, 1)
+	assert.Equal(t, "pay_3", rest[0].Event.AggregateID())
+	assert.Equal(t, int64(3), rest[0].Position)
+}
@@ -2,79 +2,230 @@ package mongodb
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/omerbeden/paymentgateway/internal/domain/event"
+	"github.com/omerbeden/paymentgateway/internal/infrastructure/logger"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+const (
+	// checkpointName identifies this publisher's checkpoint.
+	checkpointName = "event_publisher"
+
+	minRestartDelay = time.Second
+	maxRestartDelay = time.Minute
+
+	// Server error codes for a resume token that is no longer usable.
+	errCodeChangeStreamHistoryLost = 286
+	errCodeChangeStreamFatal       = 280
+)
+
+// ChangeStreamPublisher publishes events appended to the event store. Each
+// event is its own document, so every insert is published exactly once per
+// pass. It saves the stream's resume token after each published event and
+// resumes from it, so events appended while it was down are published when
+// it restarts.
 type ChangeStreamPublisher struct {
-	store     *MongoEventStore
-	publisher event.Publisher
+	store       *MongoEventStore
+	checkpoints *checkpointStore
+	publisher   event.Publisher
+	log         logger.Logger
 }
 
-func NewChangeStreamPublisher(store *MongoEventStore, publisher event.Publisher) *ChangeStreamPublisher {
+func NewChangeStreamPublisher(store *MongoEventStore, publisher event.Publisher, log logger.Logger) *ChangeStreamPublisher {
 	return &ChangeStreamPublisher{
-		store:     store,
-		publisher: publisher,
+		store:       store,
+		checkpoints: newCheckpointStore(store.collection.Database()),
+		publisher:   publisher,
+		log:         log.With("component", "change_stream_publisher"),
+	}
+}
+
+// Run keeps the publisher running until ctx is cancelled, restarting it with
+// a growing delay whenever it stops. It must run on a single replica; see
+// database.Leader.
+func (c *ChangeStreamPublisher) Run(ctx context.Context) {
+	delay := minRestartDelay
+	for {
+		started := time.Now()
+		err := c.Start(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(started) > maxRestartDelay {
+			delay = minRestartDelay
+		}
+		c.log.Error("Change stream publisher stopped, restarting", "error", err, "delay", delay)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxRestartDelay)
 	}
 }
 
-// Start begins watching the event_store collection and publishing events to Kafka.
+// Start watches the event store collection and publishes its events until ctx
+// is cancelled or publishing fails. It resumes after the last saved
+// checkpoint; when that cannot be resumed it publishes the events stored after
+// the checkpoint's position instead.
 func (c *ChangeStreamPublisher) Start(ctx context.Context) error {
-	pipeline := mongo.Pipeline{
-		{{Key: "$match", Value: bson.D{
-			{Key: "operationType", Value: bson.D{{Key: "$in", Value: bson.A{"insert", "update"}}}},
-		}}},
+	cp, err := c.checkpoints.load(ctx, checkpointName)
+	if err != nil {
+		return err
+	}
+
+	stream, err := c.watch(ctx, cp.Token)
+	if isResumeTokenLost(err) {
+		c.log.Warn("Resume token is no longer in the oplog, catching up from the event store",
+			"position", cp.Position,
+		)
+		cp.Token = nil
+		stream, err = c.watch(ctx, nil)
 	}
-	opts := options.ChangeStream().SetFullDocument(options.UpdateLookup)
-	stream, err := c.store.collection.Watch(ctx, pipeline, opts)
 	if err != nil {
 		return fmt.Errorf("change stream: watch: %w", err)
 	}
-	defer stream.Close(ctx)
+	defer stream.Close(context.Background())
+
+	// The stream is opened before the scan, so nothing appended in between is
+	// missed; events seen by both are published twice.
+	if cp.Token == nil && (cp.Position > 0 || !cp.OccurredAt.IsZero()) {
+		if cp, err = c.catchUp(ctx, cp); err != nil {
+			return err
+		}
+	}
 
 	for stream.Next(ctx) {
 		var changeEvent struct {
-			OperationType string `bson:"operationType"`
-			FullDocument  struct {
-				AggregateID string        `bson:"aggregate_id"`
-				Events      []eventRecord `bson:"events"`
-			} `bson:"fullDocument"`
+			FullDocument eventRecord `bson:"fullDocument"`
 		}
 
 		if err := stream.Decode(&changeEvent); err != nil {
-			//log
+			c.log.Error("Failed to decode change event", "error", err)
 			continue
 		}
 
-		for _, record := range changeEvent.FullDocument.Events {
-			evt, err := c.store.deserializeEvent(record)
-			if err != nil {
-				//log
-				continue
-			}
-			topic := c.getTopicForEvent(evt)
-			if err := c.publisher.Publish(ctx, topic, evt); err != nil {
-				//log
-				// Consider retrying or sending to a DLQ here
-			}
+		record := changeEvent.FullDocument
+		if err := c.publish(ctx, record); err != nil {
+			return err
+		}
+		cp.Position = max(cp.Position, record.Position)
+		if record.OccurredAt.After(cp.OccurredAt) {
+			cp.OccurredAt = record.OccurredAt
+		}
+
+		cp.Token = stream.ResumeToken()
+		if err := c.checkpoints.save(ctx, cp); err != nil {
+			return err
 		}
 	}
 
 	if err := stream.Err(); err != nil {
+		if isResumeTokenLost(err) {
+			// Catch up from Position on the next start.
+			cp.Token = nil
+			if saveErr := c.checkpoints.save(ctx, cp); saveErr != nil {
+				c.log.Error("Failed to reset change stream checkpoint", "error", saveErr)
+			}
+		}
 		return fmt.Errorf("change stream: error: %w", err)
 	}
 
 	return nil
 }
+
+func (c *ChangeStreamPublisher) watch(ctx context.Context, token bson.Raw) (*mongo.ChangeStream, error) {
+	// Events are only ever inserted, and an insert carries the whole event.
+	// Migrated events were published before they were copied.
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{
+			{Key: "operationType", Value: "insert"},
+			{Key: "fullDocument.migrated", Value: bson.M{"$ne": true}},
+		}}},
+	}
+	opts := options.ChangeStream()
+	if token != nil {
+		opts.SetStartAfter(token)
+	}
+	return c.store.collection.Watch(ctx, pipeline, opts)
+}
+
+// catchUp publishes the events stored after cp.Position, in position order,
+// and returns the checkpoint moved past them. Positions follow insertion, not
+// OccurredAt: the outbox relay appends an aggregate's held back events after
+// newer events of other aggregates. Checkpoints without a position fall back
+// to OccurredAt, and events at exactly that time are published again.
+func (c *ChangeStreamPublisher) catchUp(ctx context.Context, cp checkpoint) (checkpoint, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "position", Value: 1}})
+	filter := bson.M{"position": bson.M{"$gt": cp.Position}, "migrated": bson.M{"$ne": true}}
+	if cp.Position == 0 {
+		filter = bson.M{"occurred_at": bson.M{"$gte": cp.OccurredAt}, "migrated": bson.M{"$ne": true}}
+	}
+	cursor, err := c.store.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return cp, fmt.Errorf("change stream: catch up: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	published := 0
+	for cursor.Next(ctx) {
+		var record eventRecord
+		if err := cursor.Decode(&record); err != nil {
+			return cp, fmt.Errorf("change stream: catch up: %w", err)
+		}
+		if err := c.publish(ctx, record); err != nil {
+			return cp, err
+		}
+		published++
+		cp.Position = max(cp.Position, record.Position)
+		if record.OccurredAt.After(cp.OccurredAt) {
+			cp.OccurredAt = record.OccurredAt
+		}
+		if err := c.checkpoints.save(ctx, cp); err != nil {
+			return cp, err
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return cp, fmt.Errorf("change stream: catch up: %w", err)
+	}
+
+	c.log.Info("Caught up with the event store", "published", published)
+	return cp, nil
+}
+
+// publish publishes record. Records that cannot be decoded are logged and
+// skipped, since they never will be; a failed publish is returned so the
+// publisher restarts from the last checkpoint.
+func (c *ChangeStreamPublisher) publish(ctx context.Context, record eventRecord) error {
+	evt, err := c.store.deserializeEvent(record)
+	if err != nil {
+		c.log.Error("Failed to decode stored event", "error", err, "type", record.Type)
+		return nil
+	}
+	topic := c.getTopicForEvent(evt)
+	if err := c.publisher.Publish(ctx, topic, evt); err != nil {
+		return fmt.Errorf("change stream: publish %s: %w", evt.EventType(), err)
+	}
+	return nil
+}
+
 func (c *ChangeStreamPublisher) getTopicForEvent(evt event.DomainEvent) string {
-	switch evt.EventType() {
-	case event.PaymentCompleted:
-		return event.TopicNotificationPaymentCompleted
-	default:
-		return "events.unknown"
+	return event.TopicFor(evt.EventType())
+}
+
+// isResumeTokenLost reports whether err means the stream cannot be resumed
+// from its token, e.g. because it fell off the oplog.
+func isResumeTokenLost(err error) bool {
+	var serverErr mongo.ServerError
+	if !errors.As(err, &serverErr) {
+		return false
 	}
+	return serverErr.HasErrorCode(errCodeChangeStreamHistoryLost) || serverErr.HasErrorCode(errCodeChangeStreamFatal)
 }
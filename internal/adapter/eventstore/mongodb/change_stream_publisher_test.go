@@ -0,0 +1,168 @@
+package mongodb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/omerbeden/paymentgateway/internal/domain/event"
+	"github.com/omerbeden/paymentgateway/internal/domain/money"
+	"github.com/omerbeden/paymentgateway/internal/infrastructure/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// channelPublisher hands every published event to the test.
+type channelPublisher struct {
+	events chan event.DomainEvent
+}
+
+func newChannelPublisher() *channelPublisher {
+	return &channelPublisher{events: make(chan event.DomainEvent, 16)}
+}
+
+func (p *channelPublisher) Publish(ctx context.Context, topic string, evt event.DomainEvent) error {
+	p.events <- evt
+	return nil
+}
+
+// next returns the aggregate IDs of the next n published events.
+func (p *channelPublisher) next(t *testing.T, n int) []string {
+	t.Helper()
+	var ids []string
+	for len(ids) < n {
+		select {
+		case evt := <-p.events:
+			ids = append(ids, evt.AggregateID())
+		case <-time.After(10 * time.Second):
+			t.Fatalf("published %v, want %d events", ids, n)
+		}
+	}
+	return ids
+}
+
+func appendCreated(t *testing.T, store *MongoEventStore, paymentID string) {
+	t.Helper()
+	created := event.NewPaymentCreatedEvent(paymentID, "stripe", "capture", "pending", money.New(1000, "USD"))
+	require.NoError(t, store.Append(context.Background(), paymentID, 0, created))
+}
+
+// startPublisher runs Start until the test stops it with the returned func,
+// which waits for Start to return.
+func startPublisher(t *testing.T, c *ChangeStreamPublisher) (stop func()) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = c.Start(ctx)
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+func TestCheckpointStore_SaveAndLoad(t *testing.T) {
+	// Arrange
+	checkpoints := newCheckpointStore(testDatabase(t))
+	ctx := context.Background()
+	token, err := bson.Marshal(bson.M{"_data": "8263F1"})
+	require.NoError(t, err)
+	occurredAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	// Act
+	empty, err := checkpoints.load(ctx, checkpointName)
+	require.NoError(t, err)
+	err = checkpoints.save(ctx, checkpoint{Name: checkpointName, Token: token, Position: 42, OccurredAt: occurredAt})
+	require.NoError(t, err)
+	loaded, err := checkpoints.load(ctx, checkpointName)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, checkpoint{Name: checkpointName}, empty)
+	assert.Equal(t, checkpointName, loaded.Name)
+	assert.Equal(t, bson.Raw(token), loaded.Token)
+	assert.Equal(t, int64(42), loaded.Position)
+	assert.True(t, occurredAt.Equal(loaded.OccurredAt))
+	assert.False(t, loaded.UpdatedAt.IsZero())
+}
+
+func TestChangeStreamPublisher_ResumesFromSavedToken(t *testing.T) {
+	// Arrange
+	store := NewMongoEventStore(testDatabase(t))
+	ctx := context.Background()
+	publisher := newChannelPublisher()
+	c := NewChangeStreamPublisher(store, publisher, logger.NewNoOp())
+
+	// Take the token after pay_a the way a previous run would have.
+	stream, err := c.watch(ctx, nil)
+	require.NoError(t, err)
+	appendCreated(t, store, "pay_a")
+	appendCreated(t, store, "pay_b")
+	require.True(t, stream.Next(ctx))
+	token := stream.ResumeToken()
+	require.NoError(t, stream.Close(ctx))
+	require.NoError(t, c.checkpoints.save(ctx, checkpoint{Name: checkpointName, Token: token, Position: 1}))
+
+	// Act
+	stop := startPublisher(t, c)
+	published := publisher.next(t, 1)
+	appendCreated(t, store, "pay_c")
+	published = append(published, publisher.next(t, 1)...)
+	stop()
+
+	// Assert
+	assert.Equal(t, []string{"pay_b", "pay_c"}, published, "pay_a was published before the token")
+	cp, err := c.checkpoints.load(ctx, checkpointName)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), cp.Position)
+	assert.NotNil(t, cp.Token)
+}
+
+func TestChangeStreamPublisher_CatchesUpByPositionWithoutToken(t *testing.T) {
+	// Arrange
+	store := NewMongoEventStore(testDatabase(t))
+	ctx := context.Background()
+	publisher := newChannelPublisher()
+	c := NewChangeStreamPublisher(store, publisher, logger.NewNoOp())
+
+	appendCreated(t, store, "pay_a")
+	appendCreated(t, store, "pay_b")
+	appendCreated(t, store, "pay_c")
+	// What Start saves when the stream reports the token fell off the oplog.
+	require.NoError(t, c.checkpoints.save(ctx, checkpoint{Name: checkpointName, Position: 1}))
+
+	// Act
+	stop := startPublisher(t, c)
+	published := publisher.next(t, 2)
+	stop()
+
+	// Assert
+	assert.Equal(t, []string{"pay_b", "pay_c"}, published)
+	cp, err := c.checkpoints.load(ctx, checkpointName)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), cp.Position)
+}
+
+func TestIsResumeTokenLost(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"history lost", mongo.CommandError{Code: errCodeChangeStreamHistoryLost}, true},
+		{"fatal", mongo.CommandError{Code: errCodeChangeStreamFatal}, true},
+		{"other server error", mongo.CommandError{Code: 11000}, false},
+		{"not a server error", context.Canceled, false},
+		{"nil", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, isResumeTokenLost(tt.err))
+		})
+	}
+}
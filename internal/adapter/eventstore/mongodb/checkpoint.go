@@ -0,0 +1,54 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// checkpoint is how far a change stream consumer got. Token resumes the
+// stream; Position is the highest store position published, for a catch-up
+// scan when the token can no longer be resumed. OccurredAt is the newest event
+// published, which is all checkpoints saved before positions have.
+type checkpoint struct {
+	Name       string    `bson:"_id"`
+	Token      bson.Raw  `bson:"token,omitempty"`
+	Position   int64     `bson:"position"`
+	OccurredAt time.Time `bson:"occurred_at"`
+	UpdatedAt  time.Time `bson:"updated_at"`
+}
+
+type checkpointStore struct {
+	collection *mongo.Collection
+}
+
+func newCheckpointStore(db *mongo.Database) *checkpointStore {
+	return &checkpointStore{collection: db.Collection("change_stream_checkpoints")}
+}
+
+// load returns the checkpoint saved under name, or an empty one.
+func (s *checkpointStore) load(ctx context.Context, name string) (checkpoint, error) {
+	var cp checkpoint
+	err := s.collection.FindOne(ctx, bson.M{"_id": name}).Decode(&cp)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return checkpoint{Name: name}, nil
+	}
+	if err != nil {
+		return checkpoint{}, fmt.Errorf("change stream checkpoint: load: %w", err)
+	}
+	return cp, nil
+}
+
+func (s *checkpointStore) save(ctx context.Context, cp checkpoint) error {
+	cp.UpdatedAt = time.Now().UTC()
+	_, err := s.collection.ReplaceOne(ctx, bson.M{"_id": cp.Name}, cp, options.Replace().SetUpsert(true))
+	if err != nil {
+		return fmt.Errorf("change stream checkpoint: save: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// legacyCollection is where events were kept before each event became a
+// document of its own: one document per aggregate, its events in an array.
+const legacyCollection = "events"
+
+type legacyDocument struct {
+	ID          string `bson:"_id"`
+	AggregateID string `bson:"aggregate_id"`
+	Events      []struct {
+		Type       string                 `bson:"type"`
+		Data       map[string]interface{} `bson:"data"`
+		OccurredAt time.Time              `bson:"occurred_at"`
+	} `bson:"events"`
+}
+
+// MigrateLegacyEvents copies the events of every aggregate in the legacy
+// collection into the store, numbered 1, 2, ... in array order and given new
+// positions. Events the aggregate already has in the store were appended after
+// the legacy ones, so they are renumbered to follow them. Copied events are
+// marked as migrated, which keeps the ChangeStreamPublisher from publishing
+// them a second time, and so is each legacy document once copied, so the
+// migration can run on every start and again after a failure. Nothing else may
+// append to the store while it runs. It returns how many aggregates it
+// migrated.
+func (s *MongoEventStore) MigrateLegacyEvents(ctx context.Context) (int, error) {
+	legacy := s.collection.Database().Collection(legacyCollection)
+	cursor, err := legacy.Find(ctx, bson.M{"migrated_at": bson.M{"$exists": false}})
+	if err != nil {
+		return 0, fmt.Errorf("mongo event store: migrate: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	migrated := 0
+	for cursor.Next(ctx) {
+		var doc legacyDocument
+		if err := cursor.Decode(&doc); err != nil {
+			return migrated, fmt.Errorf("mongo event store: migrate: decode: %w", err)
+		}
+		if err := s.migrateAggregate(ctx, doc); err != nil {
+			return migrated, err
+		}
+
+		_, err := legacy.UpdateOne(ctx, bson.M{"_id": doc.ID}, bson.M{"$set": bson.M{"migrated_at": time.Now().UTC()}})
+		if err != nil {
+			return migrated, fmt.Errorf("mongo event store: migrate %s: %w", doc.AggregateID, err)
+		}
+		migrated++
+	}
+	if err := cursor.Err(); err != nil {
+		return migrated, fmt.Errorf("mongo event store: migrate: %w", err)
+	}
+
+	// A saved resume token belongs to the legacy collection's stream; without
+	// it the publisher catches up from the store instead.
+	if migrated > 0 {
+		if err := s.resetCheckpoint(ctx); err != nil {
+			return migrated, err
+		}
+	}
+	return migrated, nil
+}
+
+func (s *MongoEventStore) migrateAggregate(ctx context.Context, doc legacyDocument) error {
+	// Drop whatever an interrupted run copied before copying again.
+	_, err := s.collection.DeleteMany(ctx, bson.M{"aggregate_id": doc.AggregateID, "migrated": true})
+	if err != nil {
+		return fmt.Errorf("mongo event store: migrate %s: %w", doc.AggregateID, err)
+	}
+	if len(doc.Events) == 0 {
+		return nil
+	}
+
+	if err := s.shiftVersions(ctx, doc.AggregateID, len(doc.Events)); err != nil {
+		return err
+	}
+
+	records := make([]eventRecord, len(doc.Events))
+	for i, e := range doc.Events {
+		records[i] = eventRecord{
+			AggregateID: doc.AggregateID,
+			Type:        e.Type,
+			Data:        e.Data,
+			OccurredAt:  e.OccurredAt,
+			Migrated:    true,
+		}
+	}
+	if err := s.insert(ctx, records, 0); err != nil {
+		return fmt.Errorf("mongo event store: migrate %s: %w", doc.AggregateID, err)
+	}
+	return nil
+}
+
+// shiftVersions moves the events already stored for aggregateID up by n
+// versions, newest first so no two share a version on the way. Moved events
+// are marked, so an interrupted shift resumes where it stopped.
+func (s *MongoEventStore) shiftVersions(ctx context.Context, aggregateID string, n int) error {
+	filter := bson.M{
+		"aggregate_id":      aggregateID,
+		"migrated":          bson.M{"$ne": true},
+		"migration_shifted": bson.M{"$ne": true},
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "version", Value: -1}}).
+		SetProjection(bson.M{"_id": 1})
+	cursor, err := s.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return fmt.Errorf("mongo event store: migrate %s: %w", aggregateID, err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var doc struct {
+			ID interface{} `bson:"_id"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return fmt.Errorf("mongo event store: migrate %s: %w", aggregateID, err)
+		}
+		update := bson.M{
+			"$inc": bson.M{"version": n},
+			"$set": bson.M{"migration_shifted": true},
+		}
+		if _, err := s.collection.UpdateOne(ctx, bson.M{"_id": doc.ID}, update); err != nil {
+			return fmt.Errorf("mongo event store: migrate %s: shift versions: %w", aggregateID, err)
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return fmt.Errorf("mongo event store: migrate %s: %w", aggregateID, err)
+	}
+	return nil
+}
+
+func (s *MongoEventStore) resetCheckpoint(ctx context.Context) error {
+	checkpoints := newCheckpointStore(s.collection.Database())
+	cp, err := checkpoints.load(ctx, checkpointName)
+	if err != nil {
+		return err
+	}
+	if cp.Token == nil {
+		return nil
+	}
+	cp.Token = nil
+	return checkpoints.save(ctx, cp)
+}
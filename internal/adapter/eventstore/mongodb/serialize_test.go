@@ -0,0 +1,58 @@
+package mongodb
+
+import (
+	"testing"
+	"time"
+
+	"github.com/omerbeden/paymentgateway/internal/domain/event"
+	"github.com/omerbeden/paymentgateway/internal/domain/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeserializeEvent_RoundTripsEveryEventType(t *testing.T) {
+	amount := money.New(5000, "USD")
+	expiresAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []event.DomainEvent{
+		event.NewPaymentCreatedEvent("pay_1", "stripe", "capture", "pending", amount),
+		event.NewPaymentAuthorizedEvent("pay_1", "stripe", amount, expiresAt),
+		event.NewPaymentCapturedEvent("pay_1", "stripe", amount),
+		event.NewPaymentCompletedEvent("pay_1", "stripe", "Payment completed", amount),
+		event.NewPaymentFailedEvent("pay_1", "stripe", "card_declined", amount),
+		event.NewPaymentCancelledEvent("pay_1", "stripe", "voided", amount),
+		event.NewPaymentRefundedEvent("pay_1", "stripe", "re_1", "succeeded", "requested_by_customer", amount, money.New(1000, "USD")),
+		event.NewPaymentExpiredEvent("pay_1", "stripe", amount, expiresAt),
+		event.NewWebhookReceivedEvent("we_1", "stripe", "pi_1", "payment_intent.succeeded"),
+	}
+
+	store := &MongoEventStore{}
+	covered := make(map[event.EventType]bool)
+	for _, evt := range tests {
+		t.Run(string(evt.EventType()), func(t *testing.T) {
+			// Arrange
+			record, err := store.serializeEvent(evt)
+			require.NoError(t, err)
+
+			// Act
+			decoded, err := store.deserializeEvent(record)
+
+			// Assert
+			require.NoError(t, err)
+			assert.Equal(t, evt, decoded)
+		})
+		covered[evt.EventType()] = true
+	}
+
+	for _, eventType := range event.EventTypes {
+		assert.True(t, covered[eventType], "event type %s is not round-tripped", eventType)
+	}
+}
+
+func TestDeserializeEvent_RejectsUnknownType(t *testing.T) {
+	store := &MongoEventStore{}
+
+	_, err := store.deserializeEvent(eventRecord{Type: "payment.unknown", Data: map[string]interface{}{}})
+
+	assert.Error(t, err)
+}
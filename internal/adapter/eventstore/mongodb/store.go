@@ -3,6 +3,7 @@ package mongodb
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
@@ -12,62 +13,227 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// maxAppendAttempts bounds the retries of an Append with AnyVersion that lost
+// the race for a version to a concurrent append to the same aggregate.
+const maxAppendAttempts = 5
+
+// MongoEventStore keeps one document per event. Events of an aggregate are
+// numbered 1, 2, ... by Version; a unique index on it turns two concurrent
+// appends of the same version into a conflict instead of a duplicate.
+// Positions come from a counter document, so they increase with every append
+// but may skip numbers after a failed one. With one writer at a time, as the
+// outbox relay is, they also follow insertion order. Appending several events
+// at once uses a transaction, which needs a replica set.
 type MongoEventStore struct {
 	collection *mongo.Collection
-}
-
-type eventDocument struct {
-	AggregateID string        `bson:"aggregate_id"`
-	Version     int           `bson:"version"`
-	Events      []eventRecord `bson:"events"`
-	UpdatedAt   time.Time     `bson:"updated_at"`
+	counters   *mongo.Collection
 }
 
 type eventRecord struct {
-	Version    int                    `bson:"version"`
-	Type       string                 `bson:"type"`
-	Data       map[string]interface{} `bson:"data"`
-	OccurredAt time.Time              `bson:"occurred_at"`
+	AggregateID string                 `bson:"aggregate_id"`
+	Version     int                    `bson:"version"`
+	Position    int64                  `bson:"position"`
+	Type        string                 `bson:"type"`
+	Data        map[string]interface{} `bson:"data"`
+	OccurredAt  time.Time              `bson:"occurred_at"`
+	// Migrated marks events copied from the legacy collection, which were
+	// published before they were copied; see MigrateLegacyEvents.
+	Migrated bool `bson:"migrated,omitempty"`
 }
 
 func NewMongoEventStore(db *mongo.Database) *MongoEventStore {
-	collection := db.Collection("events")
+	// Earlier versions kept an array of events per aggregate in the events
+	// collection; MigrateLegacyEvents copies them over.
+	collection := db.Collection("domain_events")
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
 	_, _ = collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
 		{
-			Keys: bson.D{{Key: "events.occurred_at", Value: 1}},
+			Keys:    bson.D{{Key: "aggregate_id", Value: 1}, {Key: "version", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "position", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "occurred_at", Value: 1}},
 		},
 		{
-			Keys: bson.D{{Key: "events.type", Value: 1}},
+			Keys: bson.D{{Key: "type", Value: 1}},
 		},
 	})
 
-	return &MongoEventStore{collection: collection}
+	return &MongoEventStore{collection: collection, counters: db.Collection("counters")}
+}
+
+func (s *MongoEventStore) Append(ctx context.Context, aggregateID string, expectedVersion int, events ...event.DomainEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	records := make([]eventRecord, len(events))
+	for i, devent := range events {
+		if devent.AggregateID() != aggregateID {
+			return fmt.Errorf("mongo event store: append: %s event of %s appended to %s",
+				devent.EventType(), devent.AggregateID(), aggregateID)
+		}
+		record, err := s.serializeEvent(devent)
+		if err != nil {
+			return err
+		}
+		records[i] = record
+	}
+
+	for attempt := 0; attempt < maxAppendAttempts; attempt++ {
+		current, err := s.lastVersion(ctx, aggregateID)
+		if err != nil {
+			return err
+		}
+		if expectedVersion != event.AnyVersion && current != expectedVersion {
+			return &event.ConcurrencyError{AggregateID: aggregateID, Expected: expectedVersion, Actual: current}
+		}
+
+		// A concurrent append took the next version; the next attempt reports
+		// the conflict, or appends after it for AnyVersion.
+		err = s.insert(ctx, records, current)
+		if mongo.IsDuplicateKeyError(err) {
+			continue
+		}
+		return err
+	}
+
+	return fmt.Errorf("mongo event store: append: version of %s kept changing", aggregateID)
 }
 
-func (s *MongoEventStore) Append(ctx context.Context, devent event.DomainEvent) error {
-	record, err := s.serializeEvent(devent)
+// insert stores records as the versions after version. A concurrent append
+// may have taken any of those versions, not just the first, so several records
+// are inserted in a transaction: a conflict on one of them leaves none of them
+// stored, and a retry cannot duplicate the ones before it.
+func (s *MongoEventStore) insert(ctx context.Context, records []eventRecord, version int) error {
+	first, err := s.nextPositions(ctx, len(records))
 	if err != nil {
 		return err
 	}
 
-	filter := bson.M{"aggregate_id": devent.AggregateID()}
-	update := bson.M{
-		"$push":        bson.M{"events": record},
-		"$inc":         bson.M{"version": 1},
-		"$set":         bson.M{"updated_at": time.Now().UTC()},
-		"$setOnInsert": bson.M{"_id": devent.AggregateID()},
+	docs := make([]interface{}, len(records))
+	for i := range records {
+		records[i].Version = version + i + 1
+		records[i].Position = first + int64(i)
+		docs[i] = records[i]
+	}
+
+	if len(docs) == 1 {
+		_, err = s.collection.InsertOne(ctx, docs[0])
+	} else {
+		err = s.insertMany(ctx, docs)
 	}
-	opts := options.Update().SetUpsert(true)
-	_, err = s.collection.UpdateOne(ctx, filter, update, opts)
 	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return err
+		}
 		return fmt.Errorf("mongo event store: append: %w", err)
 	}
-
 	return nil
+}
+
+func (s *MongoEventStore) insertMany(ctx context.Context, docs []interface{}) error {
+	session, err := s.collection.Database().Client().StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		return s.collection.InsertMany(sessCtx, docs)
+	})
+	return err
+}
+
+// nextPositions reserves n positions and returns the first of them.
+func (s *MongoEventStore) nextPositions(ctx context.Context, n int) (int64, error) {
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var counter struct {
+		Seq int64 `bson:"seq"`
+	}
+	err := s.counters.FindOneAndUpdate(ctx,
+		bson.M{"_id": s.collection.Name()},
+		bson.M{"$inc": bson.M{"seq": int64(n)}},
+		opts,
+	).Decode(&counter)
+	if err != nil {
+		return 0, fmt.Errorf("mongo event store: reserve positions: %w", err)
+	}
+	return counter.Seq - int64(n) + 1, nil
+}
+
+// lastVersion returns the version of the aggregate's newest event, or 0 when
+// it has none.
+func (s *MongoEventStore) lastVersion(ctx context.Context, aggregateID string) (int, error) {
+	opts := options.FindOne().
+		SetSort(bson.D{{Key: "version", Value: -1}}).
+		SetProjection(bson.M{"version": 1})
+
+	var last struct {
+		Version int `bson:"version"`
+	}
+	err := s.collection.FindOne(ctx, bson.M{"aggregate_id": aggregateID}, opts).Decode(&last)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("mongo event store: last version: %w", err)
+	}
+	return last.Version, nil
+}
 
+func (s *MongoEventStore) Load(ctx context.Context, aggregateID string) ([]event.RecordedEvent, error) {
+	return s.LoadSince(ctx, aggregateID, 0)
+}
+
+func (s *MongoEventStore) LoadSince(ctx context.Context, aggregateID string, version int) ([]event.RecordedEvent, error) {
+	filter := bson.M{"aggregate_id": aggregateID, "version": bson.M{"$gt": version}}
+	opts := options.Find().SetSort(bson.D{{Key: "version", Value: 1}})
+	return s.find(ctx, filter, opts)
+}
+
+func (s *MongoEventStore) ReadAll(ctx context.Context, fromPosition int64, limit int) ([]event.RecordedEvent, error) {
+	filter := bson.M{"position": bson.M{"$gte": fromPosition}}
+	opts := options.Find().SetSort(bson.D{{Key: "position", Value: 1}}).SetLimit(int64(limit))
+	return s.find(ctx, filter, opts)
+}
+
+func (s *MongoEventStore) find(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]event.RecordedEvent, error) {
+	cursor, err := s.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("mongo event store: find: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var events []event.RecordedEvent
+	for cursor.Next(ctx) {
+		var record eventRecord
+		if err := cursor.Decode(&record); err != nil {
+			return nil, fmt.Errorf("mongo event store: decode: %w", err)
+		}
+		evt, err := s.deserializeEvent(record)
+		if err != nil {
+			return nil, fmt.Errorf("mongo event store: decode %s v%d: %w", record.AggregateID, record.Version, err)
+		}
+		events = append(events, event.RecordedEvent{Event: evt, Version: record.Version, Position: record.Position})
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("mongo event store: find: %w", err)
+	}
+	return events, nil
+}
+
+// Publish appends evt to the store, which lets the outbox relay write to it
+// when the ChangeStreamPublisher publishes from the store. The topic is
+// derived again when the event is published.
+func (s *MongoEventStore) Publish(ctx context.Context, _ string, evt event.DomainEvent) error {
+	return s.Append(ctx, evt.AggregateID(), event.AnyVersion, evt)
 }
 
 func (s *MongoEventStore) serializeEvent(evt event.DomainEvent) (eventRecord, error) {
@@ -83,9 +249,10 @@ func (s *MongoEventStore) serializeEvent(evt event.DomainEvent) (eventRecord, er
 	}
 
 	return eventRecord{
-		Type:       string(evt.EventType()),
-		Data:       dataMap,
-		OccurredAt: evt.OccurredAt(),
+		AggregateID: evt.AggregateID(),
+		Type:        string(evt.EventType()),
+		Data:        dataMap,
+		OccurredAt:  evt.OccurredAt(),
 	}, nil
 }
 
@@ -95,12 +262,60 @@ func (s *MongoEventStore) deserializeEvent(record eventRecord) (event.DomainEven
 		return nil, err
 	}
 	switch record.Type {
+	case string(event.PaymentCreated):
+		var evt event.PaymentCreatedEvent
+		if err := json.Unmarshal(data, &evt); err != nil {
+			return nil, err
+		}
+		return evt, nil
+	case string(event.PaymentAuthorized):
+		var evt event.PaymentAuthorizedEvent
+		if err := json.Unmarshal(data, &evt); err != nil {
+			return nil, err
+		}
+		return evt, nil
+	case string(event.PaymentCaptured):
+		var evt event.PaymentCapturedEvent
+		if err := json.Unmarshal(data, &evt); err != nil {
+			return nil, err
+		}
+		return evt, nil
 	case string(event.PaymentCompleted):
 		var evt event.PaymentCompletedEvent
 		if err := json.Unmarshal(data, &evt); err != nil {
 			return nil, err
 		}
 		return evt, nil
+	case string(event.PaymentFailed):
+		var evt event.PaymentFailedEvent
+		if err := json.Unmarshal(data, &evt); err != nil {
+			return nil, err
+		}
+		return evt, nil
+	case string(event.PaymentCancelled):
+		var evt event.PaymentCancelledEvent
+		if err := json.Unmarshal(data, &evt); err != nil {
+			return nil, err
+		}
+		return evt, nil
+	case string(event.PaymentRefunded):
+		var evt event.PaymentRefundedEvent
+		if err := json.Unmarshal(data, &evt); err != nil {
+			return nil, err
+		}
+		return evt, nil
+	case string(event.PaymentExpired):
+		var evt event.PaymentExpiredEvent
+		if err := json.Unmarshal(data, &evt); err != nil {
+			return nil, err
+		}
+		return evt, nil
+	case string(event.WebhookReceived):
+		var evt event.WebhookReceivedEvent
+		if err := json.Unmarshal(data, &evt); err != nil {
+			return nil, err
+		}
+		return evt, nil
 	default:
 		return nil, fmt.Errorf("unknown event type: %s", record.Type)
 	}
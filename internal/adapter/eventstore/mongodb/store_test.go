@@ -0,0 +1,99 @@
+package mongodb
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/omerbeden/paymentgateway/internal/domain/event"
+	"github.com/omerbeden/paymentgateway/internal/domain/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// testDatabase returns an empty database on the replica set at MONGO_URI, or
+// localhost by default, and drops it after the test. Without one the test is
+// skipped; see deployments/docker for a replica set to run against.
+func testDatabase(t *testing.T) *mongo.Database {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("skipping mongodb test in short mode")
+	}
+	uri := os.Getenv("MONGO_URI")
+	if uri == "" {
+		uri = "mongodb://localhost:27017"
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri).SetServerSelectionTimeout(2*time.Second))
+	if err != nil {
+		t.Skipf("mongodb not available: %v", err)
+	}
+	if err := client.Ping(ctx, nil); err != nil {
+		_ = client.Disconnect(ctx)
+		t.Skipf("mongodb not available: %v", err)
+	}
+
+	db := client.Database("test_eventstore_" + time.Now().Format("150405.000000"))
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = db.Drop(ctx)
+		_ = client.Disconnect(ctx)
+	})
+	return db
+}
+
+func versions(t *testing.T, store *MongoEventStore, aggregateID string) []int {
+	t.Helper()
+	events, err := store.Load(context.Background(), aggregateID)
+	require.NoError(t, err)
+	var vs []int
+	for _, e := range events {
+		vs = append(vs, e.Version)
+	}
+	return vs
+}
+
+func TestInsert_ConflictInTheMiddleOfABatchStoresNothing(t *testing.T) {
+	// Arrange
+	store := NewMongoEventStore(testDatabase(t))
+	ctx := context.Background()
+	paymentID := "pay_conflict"
+	amount := money.New(1000, "USD")
+	require.NoError(t, store.Append(ctx, paymentID, 0, event.NewPaymentCreatedEvent(paymentID, "stripe", "capture", "pending", amount)))
+
+	// A concurrent append took version 3 after this one read version 1.
+	concurrent, err := store.serializeEvent(event.NewPaymentFailedEvent(paymentID, "stripe", "declined", amount))
+	require.NoError(t, err)
+	concurrent.Version = 3
+	concurrent.Position = 100
+	_, err = store.collection.InsertOne(ctx, concurrent)
+	require.NoError(t, err)
+
+	batch := []event.DomainEvent{
+		event.NewPaymentAuthorizedEvent(paymentID, "stripe", amount, time.Time{}),
+		event.NewPaymentCapturedEvent(paymentID, "stripe", amount),
+		event.NewPaymentCompletedEvent(paymentID, "stripe", "", amount),
+	}
+	records := make([]eventRecord, len(batch))
+	for i, evt := range batch {
+		records[i], err = store.serializeEvent(evt)
+		require.NoError(t, err)
+	}
+
+	// Act
+	err = store.insert(ctx, records, 1)
+
+	// Assert
+	assert.True(t, mongo.IsDuplicateKeyError(err), "got %v", err)
+	assert.Equal(t, []int{1, 3}, versions(t, store, paymentID), "version 2 must not stay behind")
+
+	// Appending again stores the batch once, after the concurrent event.
+	require.NoError(t, store.Append(ctx, paymentID, event.AnyVersion, batch...))
+	assert.Equal(t, []int{1, 3, 4, 5, 6}, versions(t, store, paymentID))
+}
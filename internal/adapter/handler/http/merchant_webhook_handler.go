@@ -0,0 +1,228 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omerbeden/paymentgateway/internal/domain/entity"
+	"github.com/omerbeden/paymentgateway/internal/domain/repository"
+	"github.com/omerbeden/paymentgateway/internal/usecase/merchantwebhook"
+)
+
+type MerchantWebhookHandler struct {
+	registerEndpointUC *merchantwebhook.RegisterEndpointUseCase
+	updateEndpointUC   *merchantwebhook.UpdateEndpointUseCase
+	listEndpointsUC    *merchantwebhook.ListEndpointsUseCase
+}
+
+func NewMerchantWebhookHandler(registerEndpointUC *merchantwebhook.RegisterEndpointUseCase,
+	updateEndpointUC *merchantwebhook.UpdateEndpointUseCase,
+	listEndpointsUC *merchantwebhook.ListEndpointsUseCase) *MerchantWebhookHandler {
+	return &MerchantWebhookHandler{
+		registerEndpointUC: registerEndpointUC,
+		updateEndpointUC:   updateEndpointUC,
+		listEndpointsUC:    listEndpointsUC,
+	}
+}
+
+type RegisterEndpointRequest struct {
+	URL         string   `json:"url" binding:"required"`
+	Description string   `json:"description" binding:"max=255"`
+	EventTypes  []string `json:"event_types" binding:"required,min=1"`
+}
+
+type UpdateEndpointRequest struct {
+	URL         *string  `json:"url"`
+	Description *string  `json:"description" binding:"omitempty,max=255"`
+	EventTypes  []string `json:"event_types" binding:"omitempty,min=1"`
+	Enabled     *bool    `json:"enabled"`
+}
+
+type WebhookEndpointResponse struct {
+	ID                  string     `json:"id"`
+	URL                 string     `json:"url"`
+	Description         string     `json:"description,omitempty"`
+	EventTypes          []string   `json:"event_types"`
+	Status              string     `json:"status"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	DisabledAt          *time.Time `json:"disabled_at,omitempty"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+	// Secret is only returned when the endpoint is registered.
+	Secret string `json:"secret,omitempty"`
+}
+
+type WebhookDeliveryResponse struct {
+	ID            string     `json:"id"`
+	EventID       string     `json:"event_id"`
+	EventType     string     `json:"event_type"`
+	Status        string     `json:"status"`
+	AttemptCount  int        `json:"attempt_count"`
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+	LastError     string     `json:"last_error,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+type WebhookDeliveryAttemptResponse struct {
+	AttemptNumber  int       `json:"attempt_number"`
+	ResponseStatus int       `json:"response_status,omitempty"`
+	ResponseBody   string    `json:"response_body,omitempty"`
+	Error          string    `json:"error,omitempty"`
+	DurationMs     int64     `json:"duration_ms"`
+	AttemptedAt    time.Time `json:"attempted_at"`
+}
+
+func (h *MerchantWebhookHandler) RegisterEndpoint(c *gin.Context) {
+	var req RegisterEndpointRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	endpoint, err := h.registerEndpointUC.Execute(c.Request.Context(), merchantwebhook.RegisterEndpointInput{
+		URL:         req.URL,
+		Description: req.Description,
+		EventTypes:  req.EventTypes,
+	})
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	resp := newWebhookEndpointResponse(endpoint)
+	resp.Secret = endpoint.Secret
+	c.JSON(http.StatusCreated, resp)
+}
+
+func (h *MerchantWebhookHandler) ListEndpoints(c *gin.Context) {
+	endpoints, err := h.listEndpointsUC.List(c.Request.Context())
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	data := make([]WebhookEndpointResponse, 0, len(endpoints))
+	for _, e := range endpoints {
+		data = append(data, newWebhookEndpointResponse(e))
+	}
+	c.JSON(http.StatusOK, gin.H{"data": data})
+}
+
+func (h *MerchantWebhookHandler) GetEndpoint(c *gin.Context) {
+	endpoint, err := h.listEndpointsUC.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, newWebhookEndpointResponse(endpoint))
+}
+
+func (h *MerchantWebhookHandler) UpdateEndpoint(c *gin.Context) {
+	var req UpdateEndpointRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	endpoint, err := h.updateEndpointUC.Execute(c.Request.Context(), merchantwebhook.UpdateEndpointInput{
+		EndpointID:  c.Param("id"),
+		URL:         req.URL,
+		Description: req.Description,
+		EventTypes:  req.EventTypes,
+		Enabled:     req.Enabled,
+	})
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, newWebhookEndpointResponse(endpoint))
+}
+
+func (h *MerchantWebhookHandler) ListDeliveries(c *gin.Context) {
+	var req struct {
+		Limit int `form:"limit" binding:"omitempty,min=1,max=100"`
+	}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	deliveries, err := h.listEndpointsUC.ListDeliveries(c.Request.Context(), c.Param("id"), req.Limit)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	data := make([]WebhookDeliveryResponse, 0, len(deliveries))
+	for _, d := range deliveries {
+		resp := WebhookDeliveryResponse{
+			ID:           d.ID,
+			EventID:      d.EventID,
+			EventType:    d.EventType,
+			Status:       string(d.Status),
+			AttemptCount: d.AttemptCount,
+			LastError:    d.LastError,
+			CreatedAt:    d.CreatedAt,
+		}
+		if d.Status == entity.WebhookDeliveryStatusPending && !d.NextAttemptAt.IsZero() {
+			nextAttemptAt := d.NextAttemptAt
+			resp.NextAttemptAt = &nextAttemptAt
+		}
+		data = append(data, resp)
+	}
+	c.JSON(http.StatusOK, gin.H{"data": data})
+}
+
+func (h *MerchantWebhookHandler) ListDeliveryAttempts(c *gin.Context) {
+	attempts, err := h.listEndpointsUC.ListAttempts(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	data := make([]WebhookDeliveryAttemptResponse, 0, len(attempts))
+	for _, a := range attempts {
+		data = append(data, WebhookDeliveryAttemptResponse{
+			AttemptNumber:  a.AttemptNumber,
+			ResponseStatus: a.ResponseStatus,
+			ResponseBody:   a.ResponseBody,
+			Error:          a.Error,
+			DurationMs:     a.Duration.Milliseconds(),
+			AttemptedAt:    a.AttemptedAt,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"data": data})
+}
+
+func (h *MerchantWebhookHandler) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repository.ErrWebhookEndpointNotFound), errors.Is(err, repository.ErrWebhookDeliveryNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, merchantwebhook.ErrInvalidEndpointURL),
+		errors.Is(err, merchantwebhook.ErrNoEventTypes),
+		errors.Is(err, merchantwebhook.ErrUnsupportedEventType):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+	}
+}
+
+func newWebhookEndpointResponse(e *entity.WebhookEndpoint) WebhookEndpointResponse {
+	resp := WebhookEndpointResponse{
+		ID:                  e.ID,
+		URL:                 e.URL,
+		Description:         e.Description,
+		EventTypes:          e.EventTypes,
+		Status:              string(e.Status),
+		ConsecutiveFailures: e.ConsecutiveFailures,
+		CreatedAt:           e.CreatedAt,
+		UpdatedAt:           e.UpdatedAt,
+	}
+	if !e.DisabledAt.IsZero() {
+		disabledAt := e.DisabledAt
+		resp.DisabledAt = &disabledAt
+	}
+	return resp
+}
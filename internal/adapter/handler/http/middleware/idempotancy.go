@@ -27,7 +27,20 @@ func NewIdempotancyMiddleware(redis *redis.Client) *IdempotencyMiddleware {
 	}
 }
 
+// Check replays the cached response of a POST sent again with the same
+// X-Idempotency-Key, or with the same body when the header is missing.
 func (im *IdempotencyMiddleware) Check() gin.HandlerFunc {
+	return im.check(false)
+}
+
+// RequireKey is Check for routes where two identical bodies can be two
+// intended requests, such as a second partial refund of the same amount. It
+// rejects a POST without X-Idempotency-Key instead of keying it by its body.
+func (im *IdempotencyMiddleware) RequireKey() gin.HandlerFunc {
+	return im.check(true)
+}
+
+func (im *IdempotencyMiddleware) check(requireKey bool) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		if c.Request.Method != "POST" {
 			c.Next()
@@ -36,6 +49,10 @@ func (im *IdempotencyMiddleware) Check() gin.HandlerFunc {
 
 		idempotencyKey := c.GetHeader("X-Idempotency-Key")
 
+		if idempotencyKey == "" && requireKey {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "X-Idempotency-Key header is required"})
+			return
+		}
 		if idempotencyKey == "" {
 			body, _ := io.ReadAll(c.Request.Body)
 			idempotencyKey = im.generateKey(body)
@@ -43,7 +60,10 @@ func (im *IdempotencyMiddleware) Check() gin.HandlerFunc {
 		}
 
 		ctx := context.Background()
-		key := fmt.Sprintf("idempotency:%s", idempotencyKey)
+		// Responses are cached per method and path, which includes the
+		// payment ID, so the same key or body sent for another payment or
+		// operation is not answered with this one's response.
+		key := fmt.Sprintf("idempotency:%s:%s:%s", c.Request.Method, c.Request.URL.Path, idempotencyKey)
 
 		cachedResponse, err := im.redis.Get(ctx, key).Result()
 		if err == nil {
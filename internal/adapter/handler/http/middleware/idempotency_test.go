@@ -183,3 +183,79 @@ func TestIdempotencyMW_Generate_Key_Should_Return_Cache_For_Second_Request(t *te
 	assert.Equal(t, w1.Body.String(), w2.Body.String())
 
 }
+
+func TestIdempotencyMW_Same_Body_On_Another_Payment_Creates_New(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	redis := setupMockRedis(t)
+	mw := NewIdempotancyMiddleware(redis)
+
+	router := gin.New()
+	router.POST("/payments/:id/void", mw.Check(), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"id": c.Param("id")})
+	})
+	router.POST("/payments/:id/cancel", mw.Check(), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"id": c.Param("id"), "cancelled": true})
+	})
+
+	send := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w1 := send("/payments/pay_a/void")
+	w2 := send("/payments/pay_b/void")
+	w3 := send("/payments/pay_b/cancel")
+
+	assert.JSONEq(t, `{"id":"pay_a"}`, w1.Body.String())
+	assert.JSONEq(t, `{"id":"pay_b"}`, w2.Body.String())
+	assert.JSONEq(t, `{"id":"pay_b","cancelled":true}`, w3.Body.String())
+}
+
+func TestIdempotencyMW_RequireKey_Rejects_Missing_Key(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	redis := setupMockRedis(t)
+	mw := NewIdempotancyMiddleware(redis)
+
+	router := gin.New()
+
+	callCount := 0
+	router.POST("/payments/:id/refunds", mw.RequireKey(), func(c *gin.Context) {
+		callCount++
+		c.JSON(http.StatusCreated, gin.H{"refund_id": fmt.Sprintf("re_%d", callCount)})
+	})
+
+	body := []byte(`{"amount":500}`)
+	req := httptest.NewRequest("POST", "/payments/pay_1/refunds", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, 0, callCount)
+}
+
+func TestIdempotencyMW_RequireKey_Same_Body_New_Key_Refunds_Again(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	redis := setupMockRedis(t)
+	mw := NewIdempotancyMiddleware(redis)
+
+	router := gin.New()
+
+	callCount := 0
+	router.POST("/payments/:id/refunds", mw.RequireKey(), func(c *gin.Context) {
+		callCount++
+		c.JSON(http.StatusCreated, gin.H{"refund_id": fmt.Sprintf("re_%d", callCount)})
+	})
+
+	body := []byte(`{"amount":500}`)
+	for _, key := range []string{"refund-1", "refund-2", "refund-2"} {
+		req := httptest.NewRequest("POST", "/payments/pay_1/refunds", bytes.NewBuffer(body))
+		req.Header.Set("X-Idempotency-Key", key)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Contains(t, w.Body.String(), "re_")
+	}
+
+	assert.Equal(t, 2, callCount)
+}
@@ -1,38 +1,131 @@
 package handler
 
 import (
+	"errors"
+	"io"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/omerbeden/paymentgateway/internal/adapter/provider"
+	"github.com/omerbeden/paymentgateway/internal/domain/currency"
+	"github.com/omerbeden/paymentgateway/internal/domain/entity"
+	"github.com/omerbeden/paymentgateway/internal/domain/money"
+	"github.com/omerbeden/paymentgateway/internal/domain/repository"
 	"github.com/omerbeden/paymentgateway/internal/usecase/payment"
 )
 
 type PaymentHandler struct {
-	createPaymentUC *payment.CreatePaymentUseCase
+	createPaymentUC  *payment.CreatePaymentUseCase
+	getPaymentUC     *payment.GetPaymentUseCase
+	listPaymentsUC   *payment.ListPaymentsUseCase
+	refundPaymentUC  *payment.RefundPaymentUseCase
+	capturePaymentUC *payment.CapturePaymentUseCase
+	voidPaymentUC    *payment.VoidPaymentUseCase
+	cancelPaymentUC  *payment.CancelPaymentUseCase
+	currencies       *currency.Registry
 }
 
-func NewPaymentHandler(createPaymentUC *payment.CreatePaymentUseCase) *PaymentHandler {
+func NewPaymentHandler(
+	createPaymentUC *payment.CreatePaymentUseCase,
+	getPaymentUC *payment.GetPaymentUseCase,
+	listPaymentsUC *payment.ListPaymentsUseCase,
+	refundPaymentUC *payment.RefundPaymentUseCase,
+	capturePaymentUC *payment.CapturePaymentUseCase,
+	voidPaymentUC *payment.VoidPaymentUseCase,
+	cancelPaymentUC *payment.CancelPaymentUseCase,
+	currencies *currency.Registry,
+) *PaymentHandler {
 	return &PaymentHandler{
-		createPaymentUC: createPaymentUC,
+		createPaymentUC:  createPaymentUC,
+		getPaymentUC:     getPaymentUC,
+		listPaymentsUC:   listPaymentsUC,
+		refundPaymentUC:  refundPaymentUC,
+		capturePaymentUC: capturePaymentUC,
+		voidPaymentUC:    voidPaymentUC,
+		cancelPaymentUC:  cancelPaymentUC,
+		currencies:       currencies,
 	}
 }
 
+// Amounts in requests and responses are integers in the currency's minor unit,
+// e.g. 1050 is 10.50 USD, 1050 JPY or 1.050 KWD.
 type CreatePaymentRequest struct {
-	Amount     float64           `json:"amount" binding:"required,gt=0"`
-	Currency   string            `json:"currency" binding:"required,oneof=USD EUR TRY GBP"`
-	ProviderID string            `json:"provider_id" binding:"required"`
-	Metadata   map[string]string `json:"metadata"`
+	Amount     int64  `json:"amount" binding:"required,gt=0"`
+	Currency   string `json:"currency" binding:"required,len=3"`
+	ProviderID string `json:"provider_id" binding:"required"`
+	// Intent is capture unless the funds should only be authorized and
+	// captured later through the capture endpoint.
+	Intent   string            `json:"intent" binding:"omitempty,oneof=capture authorize"`
+	Metadata map[string]string `json:"metadata"`
 }
 
 type CreatePaymentResponse struct {
 	ID        string    `json:"payment_id"`
 	Status    string    `json:"status"`
-	Amount    float64   `json:"amount"`
+	Amount    int64     `json:"amount"`
 	Currency  string    `json:"currency"`
 	CreatedAt time.Time `json:"created_at"`
 }
 
+type ListPaymentsRequest struct {
+	Status        string    `form:"status" binding:"omitempty,oneof=pending processing authorized succeeded failed cancelled expired refunded partial_refund"`
+	ProviderID    string    `form:"provider_id"`
+	Currency      string    `form:"currency" binding:"omitempty,len=3"`
+	CreatedAfter  time.Time `form:"created_after" time_format:"2006-01-02T15:04:05Z07:00"`
+	CreatedBefore time.Time `form:"created_before" time_format:"2006-01-02T15:04:05Z07:00"`
+	Cursor        string    `form:"cursor"`
+	Limit         int       `form:"limit" binding:"omitempty,min=1,max=100"`
+}
+
+type ListPaymentsResponse struct {
+	Data       []PaymentResponse `json:"data"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+}
+
+type RefundPaymentRequest struct {
+	// Amount is optional; when omitted the remaining captured amount is refunded.
+	Amount int64  `json:"amount" binding:"omitempty,gt=0"`
+	Reason string `json:"reason" binding:"max=255"`
+}
+
+type CapturePaymentRequest struct {
+	// Amount is optional; when omitted the full authorized amount is captured.
+	Amount int64 `json:"amount" binding:"omitempty,gt=0"`
+}
+
+type CancelPaymentRequest struct {
+	Reason string `json:"reason" binding:"max=255"`
+}
+
+type RefundPaymentResponse struct {
+	RefundID       string `json:"refund_id"`
+	PaymentID      string `json:"payment_id"`
+	RefundStatus   string `json:"refund_status"`
+	Amount         int64  `json:"amount"`
+	Currency       string `json:"currency"`
+	PaymentStatus  string `json:"payment_status"`
+	RefundedAmount int64  `json:"refunded_amount"`
+}
+
+type PaymentResponse struct {
+	ID                string            `json:"payment_id"`
+	Status            string            `json:"status"`
+	Intent            string            `json:"intent"`
+	Amount            int64             `json:"amount"`
+	CapturedAmount    int64             `json:"captured_amount"`
+	RefundedAmount    int64             `json:"refunded_amount"`
+	Currency          string            `json:"currency"`
+	ProviderID        string            `json:"provider_id"`
+	ProviderPaymentID string            `json:"provider_payment_id,omitempty"`
+	RedirectURL       string            `json:"redirect_url,omitempty"`
+	Metadata          map[string]string `json:"metadata,omitempty"`
+	CreatedAt         time.Time         `json:"created_at"`
+	UpdatedAt         time.Time         `json:"updated_at"`
+	CompletedAt       *time.Time        `json:"completed_at,omitempty"`
+	ExpiresAt         *time.Time        `json:"expires_at,omitempty"`
+}
+
 func (h *PaymentHandler) CreatePayment(c *gin.Context) {
 
 	var req CreatePaymentRequest
@@ -41,23 +134,232 @@ func (h *PaymentHandler) CreatePayment(c *gin.Context) {
 		return
 	}
 
+	amount := money.New(req.Amount, req.Currency)
+	if err := h.currencies.Validate(req.ProviderID, amount); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	payment, err := h.createPaymentUC.Execute(c.Request.Context(), payment.CreatePaymentInput{
-		Amount:     req.Amount,
-		Currency:   req.Currency,
-		Metadata:   req.Metadata,
-		ProviderID: req.ProviderID,
+		IdempotencyKey: c.GetString("idempotency_key"),
+		Amount:         amount,
+		Metadata:       req.Metadata,
+		ProviderID:     req.ProviderID,
+		Intent:         entity.PaymentIntent(req.Intent),
 	})
 	if err != nil {
 		//h.log.Error("Failed to create payment", "error", err)
+		if isCurrencyError(err) || errors.Is(err, provider.ErrUnsupportedIntent) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create payment"})
 		return
 	}
 	resp := CreatePaymentResponse{
 		ID:        payment.ID,
 		Status:    string(payment.Status),
-		Amount:    payment.Amount,
-		Currency:  string(payment.Currency),
+		Amount:    payment.Amount.Amount,
+		Currency:  payment.Amount.Currency,
 		CreatedAt: payment.CreatedAt,
 	}
 	c.JSON(http.StatusCreated, resp)
 }
+
+func (h *PaymentHandler) GetPayment(c *gin.Context) {
+	p, err := h.getPaymentUC.Execute(c.Request.Context(), payment.GetPaymentInput{
+		PaymentID: c.Param("id"),
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrPaymentNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Payment not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get payment"})
+		return
+	}
+
+	c.JSON(http.StatusOK, newPaymentResponse(p))
+}
+
+// ListPayments supports filtering on metadata with query parameters of the
+// form metadata[key]=value.
+func (h *PaymentHandler) ListPayments(c *gin.Context) {
+	var req ListPaymentsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	out, err := h.listPaymentsUC.Execute(c.Request.Context(), payment.ListPaymentsInput{
+		Status:        entity.PaymentStatus(req.Status),
+		ProviderID:    req.ProviderID,
+		Currency:      req.Currency,
+		CreatedAfter:  req.CreatedAfter,
+		CreatedBefore: req.CreatedBefore,
+		Metadata:      c.QueryMap("metadata"),
+		Cursor:        req.Cursor,
+		Limit:         req.Limit,
+	})
+	if err != nil {
+		if errors.Is(err, payment.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list payments"})
+		return
+	}
+
+	resp := ListPaymentsResponse{
+		Data:       make([]PaymentResponse, 0, len(out.Payments)),
+		NextCursor: out.NextCursor,
+	}
+	for _, p := range out.Payments {
+		resp.Data = append(resp.Data, newPaymentResponse(p))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *PaymentHandler) RefundPayment(c *gin.Context) {
+	var req RefundPaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	out, err := h.refundPaymentUC.Execute(c.Request.Context(), payment.RefundPaymentInput{
+		PaymentID: c.Param("id"),
+		Amount:    req.Amount,
+		Reason:    req.Reason,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrPaymentNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Payment not found"})
+		case errors.Is(err, payment.ErrPaymentNotRefundable), errors.Is(err, payment.ErrRefundExceedsCapture),
+			errors.Is(err, entity.ErrInvalidTransition):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refund payment"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, RefundPaymentResponse{
+		RefundID:       out.Refund.ProviderRefundID,
+		PaymentID:      out.Payment.ID,
+		RefundStatus:   string(out.Refund.Status),
+		Amount:         out.Refund.Amount.Amount,
+		Currency:       out.Refund.Amount.Currency,
+		PaymentStatus:  string(out.Payment.Status),
+		RefundedAmount: out.Payment.RefundedAmount.Amount,
+	})
+}
+
+func (h *PaymentHandler) CapturePayment(c *gin.Context) {
+	var req CapturePaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	p, err := h.capturePaymentUC.Execute(c.Request.Context(), payment.CapturePaymentInput{
+		PaymentID: c.Param("id"),
+		Amount:    req.Amount,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrPaymentNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Payment not found"})
+		case errors.Is(err, payment.ErrInvalidCaptureAmount):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, payment.ErrPaymentNotCapturable), errors.Is(err, payment.ErrCaptureExceedsAuthorization),
+			errors.Is(err, entity.ErrInvalidTransition):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to capture payment"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, newPaymentResponse(p))
+}
+
+func (h *PaymentHandler) VoidPayment(c *gin.Context) {
+	p, err := h.voidPaymentUC.Execute(c.Request.Context(), payment.VoidPaymentInput{
+		PaymentID: c.Param("id"),
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrPaymentNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Payment not found"})
+		case errors.Is(err, payment.ErrPaymentNotVoidable), errors.Is(err, entity.ErrInvalidTransition):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to void payment"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, newPaymentResponse(p))
+}
+
+func (h *PaymentHandler) CancelPayment(c *gin.Context) {
+	var req CancelPaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	p, err := h.cancelPaymentUC.Execute(c.Request.Context(), payment.CancelPaymentInput{
+		PaymentID: c.Param("id"),
+		Reason:    req.Reason,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrPaymentNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Payment not found"})
+		case errors.Is(err, payment.ErrPaymentAlreadyCaptured), errors.Is(err, payment.ErrPaymentNotCancellable),
+			errors.Is(err, entity.ErrInvalidTransition):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel payment"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, newPaymentResponse(p))
+}
+
+func isCurrencyError(err error) bool {
+	return errors.Is(err, currency.ErrUnsupportedCurrency) ||
+		errors.Is(err, currency.ErrUnsupportedProvider) ||
+		errors.Is(err, currency.ErrAmountOutOfRange)
+}
+
+func newPaymentResponse(p *entity.Payment) PaymentResponse {
+	resp := PaymentResponse{
+		ID:                p.ID,
+		Status:            string(p.Status),
+		Intent:            string(p.Intent),
+		Amount:            p.Amount.Amount,
+		CapturedAmount:    p.CapturedAmount.Amount,
+		RefundedAmount:    p.RefundedAmount.Amount,
+		Currency:          p.Amount.Currency,
+		ProviderID:        p.ProviderID,
+		ProviderPaymentID: p.ProviderPaymentID,
+		RedirectURL:       p.PaymentURL,
+		Metadata:          p.Metadata,
+		CreatedAt:         p.CreatedAt,
+		UpdatedAt:         p.UpdatedAt,
+	}
+	if !p.CompletedAt.IsZero() {
+		completedAt := p.CompletedAt
+		resp.CompletedAt = &completedAt
+	}
+	if !p.ExpiresAt.IsZero() {
+		expiresAt := p.ExpiresAt
+		resp.ExpiresAt = &expiresAt
+	}
+	return resp
+}
@@ -10,20 +10,28 @@ import (
 	handler "github.com/omerbeden/paymentgateway/internal/adapter/handler/http"
 	"github.com/omerbeden/paymentgateway/internal/adapter/handler/http/middleware"
 	"github.com/omerbeden/paymentgateway/internal/adapter/handler/messaging"
+	messagingconsumer "github.com/omerbeden/paymentgateway/internal/adapter/handler/messaging/consumer"
 	"github.com/omerbeden/paymentgateway/internal/adapter/provider"
+	"github.com/omerbeden/paymentgateway/internal/adapter/provider/iyzico"
+	"github.com/omerbeden/paymentgateway/internal/adapter/provider/mock"
 	"github.com/omerbeden/paymentgateway/internal/adapter/provider/paypal"
+	"github.com/omerbeden/paymentgateway/internal/adapter/provider/stripe"
 	"github.com/omerbeden/paymentgateway/internal/adapter/repository/postgres"
+	"github.com/omerbeden/paymentgateway/internal/domain/currency"
+	"github.com/omerbeden/paymentgateway/internal/domain/event"
 	"github.com/omerbeden/paymentgateway/internal/infrastructure/config"
 	"github.com/omerbeden/paymentgateway/internal/infrastructure/database"
 	"github.com/omerbeden/paymentgateway/internal/infrastructure/logger"
 	"github.com/omerbeden/paymentgateway/internal/infrastructure/metrics"
+	"github.com/omerbeden/paymentgateway/internal/usecase/merchantwebhook"
+	"github.com/omerbeden/paymentgateway/internal/usecase/outbox"
 	"github.com/omerbeden/paymentgateway/internal/usecase/payment"
 	"github.com/omerbeden/paymentgateway/internal/usecase/webhook"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/redis/go-redis/v9"
 )
 
-func SetupRoutes(db *sql.DB, redis *redis.Client, cfg *config.Config, publisher *messaging.KafkaPublisher) *gin.Engine {
+func SetupRoutes(db *sql.DB, redis *redis.Client, cfg *config.Config, publisher *messaging.KafkaPublisher, consumer event.Consumer) *gin.Engine {
 	r := gin.New()
 	var log logger.Logger
 
@@ -42,32 +50,138 @@ func SetupRoutes(db *sql.DB, redis *redis.Client, cfg *config.Config, publisher
 	r.Use(middleware.Timeout(30 * time.Second))
 
 	paymentRepository := postgres.NewPaymentRepository(db, m)
-	mondodatabase, err := database.ConnectMongo(context.Background(), *cfg.Mongo)
-	if err != nil {
-		log.Fatal("Failed to connect to MongoDB: %v", err)
+	transactionRepository := postgres.NewTransactionRepository(db, m)
+	webhookEventRepository := postgres.NewWebHookEventRepository(db)
+	outboxRepository := postgres.NewOutboxRepository(db)
+
+	// Payment events are published from the Postgres outbox. The Mongo event
+	// store either mirrors them or, with the change stream publisher, sits in
+	// between and publishes them itself.
+	var relayPublisher event.Publisher = publisher
+	var eventProjection event.Store
+	var mongoStore *mongodb.MongoEventStore
+	if cfg.Outbox.MongoProjection || cfg.Mongo.ChangeStreamPublisher {
+		mondodatabase, err := database.ConnectMongo(context.Background(), *cfg.Mongo)
+		if err != nil {
+			log.Fatal("Failed to connect to MongoDB: %v", err)
+		}
+		mongoStore = mongodb.NewMongoEventStore(mondodatabase)
+
+		if cfg.Mongo.ChangeStreamPublisher {
+			relayPublisher = mongoStore
+			changeStreamPublisher := mongodb.NewChangeStreamPublisher(mongoStore, publisher, log)
+			changeStreamLeader := database.NewLeader(db, database.LockKeyChangeStreamPublisher, 5*time.Second, log)
+			go changeStreamLeader.Run(context.Background(), changeStreamPublisher.Run)
+		} else {
+			eventProjection = mongoStore
+		}
 	}
-	mongoStore := mongodb.NewMongoEventStore(mondodatabase)
-	changeStreamPublisher := mongodb.NewChangeStreamPublisher(mongoStore, publisher)
-	go func() {
-		if err := changeStreamPublisher.Start(context.Background()); err != nil {
-			log.Error("change stream publisher error: %v", err)
+
+	accepted := make([]currency.Currency, 0, len(cfg.Currencies))
+	for _, c := range cfg.Currencies {
+		defined, err := currency.Define(c.Code, c.MinAmount, c.MaxAmount)
+		if err != nil {
+			log.Fatal("Invalid currency", "currency", c.Code, "error", err)
 		}
-	}()
+		accepted = append(accepted, defined)
+	}
+	currencies, err := currency.NewRegistry(accepted...)
+	if err != nil {
+		log.Fatal("Failed to build currency registry", "error", err)
+	}
 
 	providerFactory := provider.NewProviderFactory()
 	if cfg.Paypal.Enabled {
+		if err := currencies.SetProviderCurrencies("paypal", cfg.Paypal.Currencies...); err != nil {
+			log.Fatal("Invalid PayPal currencies", "error", err)
+		}
 		if cfg.Environment == "developmet" {
 			cfg.Paypal.BaseURL = cfg.Paypal.SandBoxURL
 		}
 		providerFactory.RegisterProvider("paypal", paypal.NewProvider(*cfg.Paypal, m))
 	}
+	if cfg.Stripe.Enabled {
+		if err := currencies.SetProviderCurrencies("stripe", cfg.Stripe.Currencies...); err != nil {
+			log.Fatal("Invalid Stripe currencies", "error", err)
+		}
+		providerFactory.RegisterProvider("stripe", stripe.NewProvider(*cfg.Stripe, m))
+	}
+	if cfg.Iyzico.Enabled {
+		if err := currencies.SetProviderCurrencies("iyzico", cfg.Iyzico.Currencies...); err != nil {
+			log.Fatal("Invalid Iyzico currencies", "error", err)
+		}
+		providerFactory.RegisterProvider("iyzico", iyzico.NewProvider(*cfg.Iyzico, m))
+	}
+	if cfg.Mock.Enabled {
+		if cfg.Environment == "production" {
+			log.Fatal("The mock provider must not be enabled in production")
+		}
+		if err := currencies.SetProviderCurrencies("mock", cfg.Mock.Currencies...); err != nil {
+			log.Fatal("Invalid mock provider currencies", "error", err)
+		}
+		providerFactory.RegisterProvider("mock", mock.NewProvider(*cfg.Mock, m))
+	}
+
+	createPaymentUC := payment.NewCreatePaymentUseCase(paymentRepository, transactionRepository, providerFactory, currencies, cfg.PaymentExpiration.TTL, log, m)
+	getPaymentUC := payment.NewGetPaymentUseCase(paymentRepository, log)
+	listPaymentsUC := payment.NewListPaymentsUseCase(paymentRepository, log)
+	refundPaymentUC := payment.NewRefundPaymentUseCase(paymentRepository, transactionRepository, providerFactory, log, m)
+	capturePaymentUC := payment.NewCapturePaymentUseCase(paymentRepository, transactionRepository, providerFactory, log, m)
+	voidPaymentUC := payment.NewVoidPaymentUseCase(paymentRepository, transactionRepository, providerFactory, log, m)
+	cancelPaymentUC := payment.NewCancelPaymentUseCase(paymentRepository, transactionRepository, providerFactory, log, m)
+	receiveWebhookUC := webhook.NewReceiveWebHookUseCase(webhookEventRepository, paymentRepository, transactionRepository, providerFactory, publisher, log, m)
+	processWebhookUC := webhook.NewProcessWebHookUseCase(paymentRepository, webhookEventRepository, transactionRepository, providerFactory, log, m)
+
+	listWebhookEventsUC := webhook.NewListWebhookEventsUseCase(webhookEventRepository, log)
+	replayWebhookEventsUC := webhook.NewReplayWebhookEventsUseCase(webhookEventRepository, processWebhookUC, log)
+
+	webhookEndpointRepository := postgres.NewWebhookEndpointRepository(db)
+	webhookDeliveryRepository := postgres.NewWebhookDeliveryRepository(db)
+	registerEndpointUC := merchantwebhook.NewRegisterEndpointUseCase(webhookEndpointRepository, log)
+	updateEndpointUC := merchantwebhook.NewUpdateEndpointUseCase(webhookEndpointRepository, log)
+	listEndpointsUC := merchantwebhook.NewListEndpointsUseCase(webhookEndpointRepository, webhookDeliveryRepository)
 
-	createPaymentUC := payment.NewCreatePaymentUseCase(paymentRepository, providerFactory, log, m)
-	webhookUC := webhook.NewProcessWebHookUseCase(paymentRepository, providerFactory, mongoStore)
+	requeueWebhookEventsUC := webhook.NewRequeueWebhookEventsUseCase(webhookEventRepository, publisher, *cfg.WebhookRequeue, log)
+	requeueLeader := database.NewLeader(db, database.LockKeyWebhookRequeue, cfg.WebhookRequeue.PollInterval, log)
+	go requeueLeader.Run(context.Background(), requeueWebhookEventsUC.Run)
+
+	expirePaymentsUC := payment.NewExpirePaymentsUseCase(paymentRepository, transactionRepository, providerFactory, *cfg.PaymentExpiration, log, m)
+	expirationLeader := database.NewLeader(db, database.LockKeyPaymentExpiration, cfg.PaymentExpiration.PollInterval, log)
+	go expirationLeader.Run(context.Background(), expirePaymentsUC.Run)
+
+	reconcilePaymentsUC := webhook.NewReconcilePaymentsUseCase(paymentRepository, processWebhookUC, providerFactory, *cfg.Reconciliation, log, m)
+	reconciliationLeader := database.NewLeader(db, database.LockKeyPaymentReconciliation, cfg.Reconciliation.PollInterval, log)
+	go reconciliationLeader.Run(context.Background(), reconcilePaymentsUC.Run)
+
+	relayOutboxUC := outbox.NewRelayOutboxUseCase(outboxRepository, relayPublisher, eventProjection, *cfg.Outbox, log, m)
+	outboxLeader := database.NewLeader(db, database.LockKeyOutboxRelay, cfg.Outbox.PollInterval, log)
+	relayJob := relayOutboxUC.Run
+	if mongoStore != nil {
+		// The relay is the only writer to the event store, so its leader
+		// moves the legacy events over before appending anything. A failed
+		// migration must not hold up publishing: events appended meanwhile
+		// are renumbered when it runs again under the next leader.
+		relayJob = func(ctx context.Context) {
+			if _, err := mongoStore.MigrateLegacyEvents(ctx); err != nil {
+				log.Error("Failed to migrate legacy events to the event store, relaying without them", "error", err)
+			}
+			relayOutboxUC.Run(ctx)
+		}
+	}
+	go outboxLeader.Run(context.Background(), relayJob)
+
+	webhookEventConsumer := messagingconsumer.NewWebhookEventConsumer(processWebhookUC, log)
+	go func() {
+		if err := consumer.Subscribe(context.Background(), []string{event.TopicWebhookReceived}, webhookEventConsumer.Handle); err != nil {
+			log.Error("webhook consumer error", "error", err)
+		}
+	}()
 
 	healthHandler := handler.NewHealthHandler(db, redis)
-	paymentHandler := handler.NewPaymentHandler(createPaymentUC)
-	webhookHandler := handler.NewWebhookHandler(webhookUC)
+	paymentHandler := handler.NewPaymentHandler(createPaymentUC, getPaymentUC, listPaymentsUC, refundPaymentUC, capturePaymentUC, voidPaymentUC, cancelPaymentUC, currencies)
+	webhookHandler := handler.NewWebhookHandler(receiveWebhookUC, providerFactory)
+	webhookAdminHandler := handler.NewWebhookAdminHandler(listWebhookEventsUC, replayWebhookEventsUC)
+	merchantWebhookHandler := handler.NewMerchantWebhookHandler(registerEndpointUC, updateEndpointUC, listEndpointsUC)
 
 	idempotancyMW := middleware.NewIdempotancyMiddleware(redis)
 
@@ -80,11 +194,33 @@ func SetupRoutes(db *sql.DB, redis *redis.Client, cfg *config.Config, publisher
 		payments := v1.Group("/payments")
 		{
 			payments.POST("/payments", idempotancyMW.Check(), paymentHandler.CreatePayment)
+			payments.GET("", paymentHandler.ListPayments)
+			payments.GET("/:id", paymentHandler.GetPayment)
+			payments.POST("/:id/refunds", idempotancyMW.RequireKey(), paymentHandler.RefundPayment)
+			payments.POST("/:id/capture", idempotancyMW.Check(), paymentHandler.CapturePayment)
+			payments.POST("/:id/void", idempotancyMW.Check(), paymentHandler.VoidPayment)
+			payments.POST("/:id/cancel", idempotancyMW.Check(), paymentHandler.CancelPayment)
 		}
 
 		webhooks := v1.Group("/webhooks")
 		{
-			webhooks.POST("/paypal", webhookHandler.HandlePaypal)
+			webhooks.POST("/:provider", webhookHandler.HandleWebhook)
+		}
+
+		if cfg.AdminAPIKey != "" {
+			admin := v1.Group("/admin", middleware.AdminAuth(cfg.AdminAPIKey))
+			{
+				admin.GET("/webhooks/events", webhookAdminHandler.ListWebhookEvents)
+				admin.POST("/webhooks/events/replay", webhookAdminHandler.ReplayWebhookEvents)
+				admin.POST("/webhooks/events/:id/replay", webhookAdminHandler.ReplayWebhookEvent)
+
+				admin.POST("/webhook-endpoints", merchantWebhookHandler.RegisterEndpoint)
+				admin.GET("/webhook-endpoints", merchantWebhookHandler.ListEndpoints)
+				admin.GET("/webhook-endpoints/:id", merchantWebhookHandler.GetEndpoint)
+				admin.PATCH("/webhook-endpoints/:id", merchantWebhookHandler.UpdateEndpoint)
+				admin.GET("/webhook-endpoints/:id/deliveries", merchantWebhookHandler.ListDeliveries)
+				admin.GET("/webhook-deliveries/:id/attempts", merchantWebhookHandler.ListDeliveryAttempts)
+			}
 		}
 	}
 
@@ -0,0 +1,173 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omerbeden/paymentgateway/internal/domain/entity"
+	"github.com/omerbeden/paymentgateway/internal/domain/repository"
+	"github.com/omerbeden/paymentgateway/internal/usecase/webhook"
+)
+
+type WebhookAdminHandler struct {
+	listWebhookEventsUC   *webhook.ListWebhookEventsUseCase
+	replayWebhookEventsUC *webhook.ReplayWebhookEventsUseCase
+}
+
+func NewWebhookAdminHandler(listWebhookEventsUC *webhook.ListWebhookEventsUseCase,
+	replayWebhookEventsUC *webhook.ReplayWebhookEventsUseCase) *WebhookAdminHandler {
+	return &WebhookAdminHandler{
+		listWebhookEventsUC:   listWebhookEventsUC,
+		replayWebhookEventsUC: replayWebhookEventsUC,
+	}
+}
+
+type ListWebhookEventsRequest struct {
+	ProviderID        string `form:"provider_id" json:"provider_id"`
+	ProviderPaymentID string `form:"provider_payment_id" json:"provider_payment_id"`
+	State             string `form:"state" json:"state" binding:"omitempty,oneof=pending processed failed unverified"`
+	Limit             int    `form:"limit" json:"limit" binding:"omitempty,min=1,max=100"`
+}
+
+type ListWebhookEventsResponse struct {
+	Data []WebhookEventResponse `json:"data"`
+}
+
+type WebhookEventResponse struct {
+	ID                string     `json:"id"`
+	ProviderID        string     `json:"provider_id"`
+	ProviderEventID   string     `json:"provider_event_id,omitempty"`
+	ProviderPaymentID string     `json:"provider_payment_id,omitempty"`
+	EventType         string     `json:"event_type,omitempty"`
+	Payload           string     `json:"payload"`
+	IsVerified        bool       `json:"is_verified"`
+	IsProcessed       bool       `json:"is_processed"`
+	ProcessingError   string     `json:"processing_error,omitempty"`
+	ReceivedAt        time.Time  `json:"received_at"`
+	ProcessedAt       *time.Time `json:"processed_at,omitempty"`
+}
+
+type ReplayWebhookEventRequest struct {
+	SkipVerification bool `json:"skip_verification"`
+}
+
+// ReplayWebhookEventsRequest replays up to Limit events matching the filter.
+type ReplayWebhookEventsRequest struct {
+	ListWebhookEventsRequest
+	SkipVerification bool `json:"skip_verification"`
+}
+
+type ReplayWebhookEventsResponse struct {
+	Data []ReplayResultResponse `json:"data"`
+}
+
+type ReplayResultResponse struct {
+	WebhookEventID string `json:"webhook_event_id"`
+	Status         string `json:"status"`
+	Error          string `json:"error,omitempty"`
+}
+
+func (h *WebhookAdminHandler) ListWebhookEvents(c *gin.Context) {
+	var req ListWebhookEventsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	events, err := h.listWebhookEventsUC.Execute(c.Request.Context(), req.input())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list webhook events"})
+		return
+	}
+
+	resp := ListWebhookEventsResponse{Data: make([]WebhookEventResponse, 0, len(events))}
+	for _, e := range events {
+		resp.Data = append(resp.Data, newWebhookEventResponse(e))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *WebhookAdminHandler) ReplayWebhookEvent(c *gin.Context) {
+	var req ReplayWebhookEventRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	h.replay(c, webhook.ReplayWebhookEventsInput{
+		WebhookEventID:   c.Param("id"),
+		SkipVerification: req.SkipVerification,
+	})
+}
+
+func (h *WebhookAdminHandler) ReplayWebhookEvents(c *gin.Context) {
+	var req ReplayWebhookEventsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.replay(c, webhook.ReplayWebhookEventsInput{
+		Filter:           req.input(),
+		SkipVerification: req.SkipVerification,
+	})
+}
+
+func (h *WebhookAdminHandler) replay(c *gin.Context, input webhook.ReplayWebhookEventsInput) {
+	results, err := h.replayWebhookEventsUC.Execute(c.Request.Context(), input)
+	if err != nil {
+		if errors.Is(err, repository.ErrWebhookEventNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to replay webhook events"})
+		return
+	}
+
+	resp := ReplayWebhookEventsResponse{Data: make([]ReplayResultResponse, 0, len(results))}
+	for _, r := range results {
+		status := "processed"
+		if r.Error != "" {
+			status = "failed"
+		}
+		resp.Data = append(resp.Data, ReplayResultResponse{
+			WebhookEventID: r.WebhookEventID,
+			Status:         status,
+			Error:          r.Error,
+		})
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func (req ListWebhookEventsRequest) input() webhook.ListWebhookEventsInput {
+	return webhook.ListWebhookEventsInput{
+		ProviderID:        req.ProviderID,
+		ProviderPaymentID: req.ProviderPaymentID,
+		State:             repository.WebhookEventState(req.State),
+		Limit:             req.Limit,
+	}
+}
+
+func newWebhookEventResponse(e *entity.WebhookEvent) WebhookEventResponse {
+	resp := WebhookEventResponse{
+		ID:                e.ID,
+		ProviderID:        e.ProviderID,
+		ProviderEventID:   e.ProviderEventID,
+		ProviderPaymentID: e.ProviderPaymentID,
+		EventType:         e.EventType,
+		Payload:           e.Payload,
+		IsVerified:        e.IsVerified,
+		IsProcessed:       e.IsProcessed,
+		ProcessingError:   e.ProcessingError,
+		ReceivedAt:        e.ReceivedAt,
+	}
+	if !e.ProcessedAt.IsZero() {
+		processedAt := e.ProcessedAt
+		resp.ProcessedAt = &processedAt
+	}
+	return resp
+}
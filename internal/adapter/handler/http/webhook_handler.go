@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"io"
 	"net/http"
 
@@ -10,16 +11,30 @@ import (
 )
 
 type WebhookHandler struct {
-	weebhookUseCase *webhook.ProcessWebHookUseCase
+	weebhookUseCase *webhook.ReceiveWebHookUseCase
+	providerFactory *provider.Factory
 }
 
-func NewWebhookHandler(weebhookUseCase *webhook.ProcessWebHookUseCase) *WebhookHandler {
+func NewWebhookHandler(weebhookUseCase *webhook.ReceiveWebHookUseCase, providerFactory *provider.Factory) *WebhookHandler {
 	return &WebhookHandler{
 		weebhookUseCase: weebhookUseCase,
+		providerFactory: providerFactory,
 	}
 }
 
-func (h *WebhookHandler) HandlePaypal(c *gin.Context) {
+// HandleWebhook serves /webhooks/:provider for every registered provider.
+// Providers implementing provider.WebhookSignatureExtractor get their signature
+// header lifted into the webhook context; all headers are passed on either way.
+// The webhook is acknowledged once stored and is applied by the webhook worker,
+// so a 5xx only means storing failed and the provider should retry.
+func (h *WebhookHandler) HandleWebhook(c *gin.Context) {
+	providerID := c.Param("provider")
+	providerAdapter, err := h.providerFactory.GetProvider(providerID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
 	payload, err := io.ReadAll(c.Request.Body)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload"})
@@ -27,20 +42,25 @@ func (h *WebhookHandler) HandlePaypal(c *gin.Context) {
 	}
 
 	webhookCtx := &provider.WebhookContext{
-		Payload:   payload,
-		Headers:   c.Request.Header,
-		Signature: c.GetHeader("PAYPAL-TRANSMISSION-SIG"),
+		Payload: payload,
+		Headers: c.Request.Header,
+	}
+	if extractor, ok := providerAdapter.(provider.WebhookSignatureExtractor); ok {
+		webhookCtx.Signature = extractor.WebhookSignature(c.Request.Header)
 	}
-	input := webhook.ProcessWebHookInput{
-		ProviderId:     "paypal",
+	input := webhook.ReceiveWebHookInput{
+		ProviderId:     providerID,
 		WebhookContext: webhookCtx,
 	}
 
 	if err := h.weebhookUseCase.Execute(c.Request.Context(), input); err != nil {
-		c.JSON(http.StatusOK, gin.H{"error": err.Error()})
+		if errors.Is(err, provider.ErrInvalidWebhookSignature) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"status": "success"})
-
+	c.JSON(http.StatusOK, gin.H{"status": "accepted"})
 }
@@ -0,0 +1,158 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+	"github.com/omerbeden/paymentgateway/internal/adapter/provider"
+	"github.com/omerbeden/paymentgateway/internal/adapter/provider/mock"
+	"github.com/omerbeden/paymentgateway/internal/adapter/repository/postgres"
+	"github.com/omerbeden/paymentgateway/internal/domain/event"
+	"github.com/omerbeden/paymentgateway/internal/infrastructure/config"
+	"github.com/omerbeden/paymentgateway/internal/infrastructure/logger"
+	"github.com/omerbeden/paymentgateway/internal/usecase/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testWebhookSecret = "whsec_test"
+
+const testWebhookPayload = `{"id":"mock_evt_1","type":"payment.approved","payment_id":"mock_pay_1","amount":1000,"currency":"USD","created":1700000000}`
+
+type recordingPublisher struct {
+	topics []string
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, topic string, e event.DomainEvent) error {
+	p.topics = append(p.topics, topic)
+	return nil
+}
+
+func newWebhookRouter(t *testing.T) (*gin.Engine, sqlmock.Sqlmock, *recordingPublisher) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	db, mockDB, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	providerFactory := provider.NewProviderFactory()
+	providerFactory.RegisterProvider("mock", mock.NewProvider(config.Mock{WebhookSecret: testWebhookSecret}, nil))
+
+	publisher := &recordingPublisher{}
+	log := logger.NewNoOp()
+	receiveWebhookUC := webhook.NewReceiveWebHookUseCase(
+		postgres.NewWebHookEventRepository(db),
+		postgres.NewPaymentRepository(db, nil),
+		postgres.NewTransactionRepository(db, nil),
+		providerFactory,
+		publisher,
+		log,
+		nil,
+	)
+
+	router := gin.New()
+	router.POST("/api/v1/webhooks/:provider", NewWebhookHandler(receiveWebhookUC, providerFactory).HandleWebhook)
+	return router, mockDB, publisher
+}
+
+func postWebhook(router *gin.Engine, providerID, payload, signature string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/webhooks/"+providerID, strings.NewReader(payload))
+	if signature != "" {
+		req.Header.Set(mock.SignatureHeader, signature)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestHandleWebhook_UnknownProvider(t *testing.T) {
+	// Arrange
+	router, mockDB, publisher := newWebhookRouter(t)
+
+	// Act
+	w := postWebhook(router, "unknown", testWebhookPayload, "")
+
+	// Assert
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Empty(t, publisher.topics)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestHandleWebhook_BadSignature(t *testing.T) {
+	// Arrange
+	router, mockDB, publisher := newWebhookRouter(t)
+	// kept for investigation, without a provider event ID
+	mockDB.ExpectExec(`INSERT INTO webhook_events`).
+		WithArgs(sqlmock.AnyArg(), "mock", nil, "", "", "forged", testWebhookPayload,
+			false, false, sqlmock.AnyArg(), sqlmock.AnyArg(), nil).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	// Act
+	w := postWebhook(router, "mock", testWebhookPayload, "forged")
+
+	// Assert
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Empty(t, publisher.topics)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestHandleWebhook_Accepted(t *testing.T) {
+	// Arrange
+	router, mockDB, publisher := newWebhookRouter(t)
+	signature := mock.SignPayload(testWebhookSecret, []byte(testWebhookPayload))
+	mockDB.ExpectExec(`INSERT INTO webhook_events`).
+		WithArgs(sqlmock.AnyArg(), "mock", "mock_evt_1", "mock_pay_1", "payment.approved", signature, testWebhookPayload,
+			true, false, nil, sqlmock.AnyArg(), nil).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	// Act
+	w := postWebhook(router, "mock", testWebhookPayload, signature)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "accepted")
+	assert.Equal(t, []string{event.TopicWebhookReceived}, publisher.topics)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestHandleWebhook_DuplicateIsAcknowledgedWithoutPublishing(t *testing.T) {
+	// Arrange
+	router, mockDB, publisher := newWebhookRouter(t)
+	signature := mock.SignPayload(testWebhookSecret, []byte(testWebhookPayload))
+	mockDB.ExpectExec(`INSERT INTO webhook_events`).
+		WillReturnError(&pq.Error{Code: "23505"})
+
+	// Act
+	w := postWebhook(router, "mock", testWebhookPayload, signature)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, publisher.topics)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestHandleWebhook_UnparseableIsStoredAsFailed(t *testing.T) {
+	// Arrange
+	router, mockDB, publisher := newWebhookRouter(t)
+	payload := `{"id":"mock_evt_2","type":"payment.approved","currency":"XXX"}`
+	signature := mock.SignPayload(testWebhookSecret, []byte(payload))
+	mockDB.ExpectExec(`INSERT INTO webhook_events`).
+		WithArgs(sqlmock.AnyArg(), "mock", nil, "", "", signature, payload,
+			true, false, sqlmock.AnyArg(), sqlmock.AnyArg(), nil).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	// Act
+	w := postWebhook(router, "mock", payload, signature)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, publisher.topics)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
@@ -0,0 +1,48 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/omerbeden/paymentgateway/internal/domain/event"
+	"github.com/omerbeden/paymentgateway/internal/infrastructure/logger"
+	"github.com/omerbeden/paymentgateway/internal/usecase/merchantwebhook"
+)
+
+// MerchantWebhookConsumer queues merchant webhooks for the payment events the
+// change stream publisher puts on Kafka.
+type MerchantWebhookConsumer struct {
+	dispatchEventUC *merchantwebhook.DispatchEventUseCase
+	log             logger.Logger
+}
+
+func NewMerchantWebhookConsumer(dispatchEventUC *merchantwebhook.DispatchEventUseCase, log logger.Logger) *MerchantWebhookConsumer {
+	return &MerchantWebhookConsumer{
+		dispatchEventUC: dispatchEventUC,
+		log:             log,
+	}
+}
+
+func (c *MerchantWebhookConsumer) Handle(ctx context.Context, msg event.Message) error {
+	var e event.BaseEvent
+	if err := json.Unmarshal(msg.Value, &e); err != nil {
+		c.log.Error("failed to unmarshal event message", "error", err, "topic", msg.Topic)
+		return nil
+	}
+
+	input := merchantwebhook.DispatchEventInput{
+		EventType:  string(e.EventType()),
+		OccurredAt: e.OccurredAt(),
+		Data:       msg.Value,
+	}
+
+	if err := c.dispatchEventUC.Execute(ctx, input); err != nil {
+		c.log.Error("failed to queue merchant webhooks",
+			"event_type", e.EventType(),
+			"aggregate_id", e.AggregateID(),
+			"error", err,
+		)
+		return err
+	}
+	return nil
+}
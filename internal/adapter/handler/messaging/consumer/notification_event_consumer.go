@@ -31,8 +31,8 @@ func (c *NotificationEventConsumer) Handle(ctx context.Context, msg event.Messag
 
 	c.log.Info("dispatching payment completion notification",
 		"payment_id", e.PaymentID,
-		"amount", e.Amount,
-		"currency", e.Currency,
+		"amount", e.Amount.Decimal(),
+		"currency", e.Amount.Currency,
 	)
 	input := notificaiton.SendPaymentNotificationInput{
 		PaymentID:     e.PaymentID,
@@ -40,7 +40,6 @@ func (c *NotificationEventConsumer) Handle(ctx context.Context, msg event.Messag
 		CustomerEmail: "dummy@example.com",
 		CustomerPhone: "dummy-phone",
 		Amount:        e.Amount,
-		Currency:      e.Currency,
 		Provider:      e.Provider,
 		CompletedAt:   e.OccurredAt(),
 	}
@@ -0,0 +1,55 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/omerbeden/paymentgateway/internal/domain/event"
+	"github.com/omerbeden/paymentgateway/internal/infrastructure/logger"
+	"github.com/omerbeden/paymentgateway/internal/usecase/webhook"
+)
+
+// WebhookEventConsumer processes webhooks queued by the webhook endpoint.
+type WebhookEventConsumer struct {
+	processWebhookUC *webhook.ProcessWebHookUseCase
+	log              logger.Logger
+}
+
+func NewWebhookEventConsumer(processWebhookUC *webhook.ProcessWebHookUseCase, log logger.Logger) *WebhookEventConsumer {
+	return &WebhookEventConsumer{
+		processWebhookUC: processWebhookUC,
+		log:              log,
+	}
+}
+
+func (c *WebhookEventConsumer) Handle(ctx context.Context, msg event.Message) error {
+	var e event.WebhookReceivedEvent
+	if err := json.Unmarshal(msg.Value, &e); err != nil {
+		c.log.Error("failed to unmarshal webhook event message", "error", err)
+		return nil
+	}
+
+	// The signature was verified when the webhook was received.
+	input := webhook.ProcessWebHookInput{
+		WebhookEventID:   e.WebhookEventID,
+		SkipVerification: true,
+	}
+
+	// Failures are recorded on the stored webhook event, which is where they
+	// are replayed from, so the message is committed either way.
+	if err := c.processWebhookUC.Execute(ctx, input); err != nil {
+		c.log.Error("webhook processing failed",
+			"webhook_event_id", e.WebhookEventID,
+			"provider", e.ProviderID,
+			"event_type", e.ProviderEventType,
+			"error", err,
+		)
+		return nil
+	}
+
+	c.log.Info("webhook processed",
+		"webhook_event_id", e.WebhookEventID,
+		"provider", e.ProviderID,
+	)
+	return nil
+}
@@ -0,0 +1,47 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidWebhookSignature is wrapped by adapters when a webhook fails
+// verification, so the webhook handler can reject it without knowing which
+// provider sent it.
+var ErrInvalidWebhookSignature = errors.New("invalid webhook signature")
+
+// ErrUnsupportedIntent is returned by providers that cannot create a payment
+// with the requested intent, or authorize and void it.
+var ErrUnsupportedIntent = errors.New("payment intent not supported by provider")
+
+// Exchange holds the raw bodies of a provider API call so that the call can be
+// stored with its transaction and inspected afterwards.
+type Exchange struct {
+	Request  string
+	Response string
+}
+
+// Error is returned by provider adapters when an API call fails. It keeps the
+// exchange that led to the failure.
+type Error struct {
+	Operation string
+	Exchange  Exchange
+	Err       error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %v", e.Operation, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// ExchangeFromError returns the exchange carried by err, if any.
+func ExchangeFromError(err error) Exchange {
+	var providerErr *Error
+	if errors.As(err, &providerErr) {
+		return providerErr.Exchange
+	}
+	return Exchange{}
+}
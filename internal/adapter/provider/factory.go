@@ -1,9 +1,12 @@
 package provider
 
 import (
+	"errors"
 	"fmt"
 )
 
+var ErrProviderNotFound = errors.New("provider not found")
+
 type Factory struct {
 	providers map[string]PaymentProvider
 }
@@ -23,7 +26,7 @@ func (f *Factory) RegisterProvider(providerID string, provider PaymentProvider)
 func (f *Factory) GetProvider(providerID string) (PaymentProvider, error) {
 	provider, exists := f.providers[providerID]
 	if !exists {
-		return nil, fmt.Errorf("provider not found: %s", providerID)
+		return nil, fmt.Errorf("%w: %s", ErrProviderNotFound, providerID)
 	}
 	return provider, nil
 }
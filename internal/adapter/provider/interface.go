@@ -6,25 +6,56 @@ import (
 	"time"
 
 	"github.com/omerbeden/paymentgateway/internal/domain/entity"
+	"github.com/omerbeden/paymentgateway/internal/domain/money"
 )
 
 type PaymentProvider interface {
 	CreatePayment(ctx context.Context, payment *entity.Payment) (*CreatePaymentResult, error)
-	Capture(ctx context.Context, id string) error
+	// Authorize holds the funds of an approved payment created with the
+	// authorize intent without capturing them.
+	Authorize(ctx context.Context, id string) (*AuthorizeResult, error)
+	Capture(ctx context.Context, req CaptureRequest) (*CaptureResult, error)
+	// Void releases the funds held by an authorization that was not captured.
+	Void(ctx context.Context, req VoidRequest) (*VoidResult, error)
 	VerifyWebhook(ctx context.Context, webhookCtx *WebhookContext) error
 	ParseWebhook(payload []byte) (*WebhookEvent, error)
+	Refund(ctx context.Context, req RefundRequest) (*RefundResult, error)
+	// GetPaymentStatus asks the provider for the current state of a payment,
+	// for when its webhook never arrived.
+	GetPaymentStatus(ctx context.Context, providerPaymentID string) (*PaymentStatusResult, error)
+}
+
+// WebhookSignatureExtractor is implemented by providers that send a webhook
+// signature in a request header. The signature ends up in
+// WebhookContext.Signature; providers without it still get all headers.
+type WebhookSignatureExtractor interface {
+	WebhookSignature(headers http.Header) string
+}
+
+// RemoteWebhookVerifier is implemented by providers that verify a webhook by
+// calling their API. VerifyWebhookExchange verifies it like VerifyWebhook and
+// also returns the call, so it can be stored with the webhook's payment.
+type RemoteWebhookVerifier interface {
+	VerifyWebhookExchange(ctx context.Context, webhookCtx *WebhookContext) (Exchange, error)
+}
+
+// Canceller is implemented by providers that can cancel a payment the buyer
+// has not approved yet. Payments at other providers are only cancelled here and
+// left to expire at the provider.
+type Canceller interface {
+	Cancel(ctx context.Context, req CancelRequest) (*CancelResult, error)
 }
 
 type CreatePaymentResult struct {
 	ProviderPaymentID string
 	Status            entity.PaymentStatus
-	Amount            float64
-	Currency          string
-	ProviderFee       float64
+	Amount            money.Money
+	ProviderFee       money.Money
 	PaymentURL        string
 	Metadata          map[string]string
 	ErrorCode         string
 	ErrorMessage      string
+	Exchange          Exchange
 }
 
 type WebhookContext struct {
@@ -34,18 +65,94 @@ type WebhookContext struct {
 }
 
 type WebhookEvent struct {
-	ProviderID        string
+	ProviderID string
+	// EventID is the provider's ID for the event. Retried deliveries carry the
+	// same ID, which is how duplicates are detected.
+	EventID           string
 	EventType         string
 	ProviderPaymentID string
 	Status            entity.PaymentStatus
-	Amount            float64
-	Currency          string
-	CreateTime        time.Time
-	RawPayload        string
+	// Ignored is set for events that report no status change, e.g. event
+	// types the provider sends but the gateway does not act on. They are
+	// acknowledged and recorded as processed without touching the payment.
+	Ignored bool
+	Amount  money.Money
+	// CreateTime is when the provider created the event. It orders events for
+	// the same payment, which providers may deliver out of order. It is zero
+	// for events without a provider timestamp, which are not ordered.
+	CreateTime time.Time
+	RawPayload string
+}
+
+type AuthorizeResult struct {
+	ProviderPaymentID       string
+	ProviderAuthorizationID string
+	Status                  entity.PaymentStatus
+	Amount                  money.Money
+	// ExpiresAt is when the provider releases the hold, if it says so.
+	ExpiresAt time.Time
+	Exchange  Exchange
+}
+
+type CaptureRequest struct {
+	ProviderPaymentID string
+	// Amount may be less than an authorized amount; the rest is released. Zero
+	// captures the full amount.
+	Amount         money.Money
+	IdempotencyKey string
 }
 
 type CaptureResult struct {
+	ProviderPaymentID string
+	ProviderCaptureID string
+	Status            entity.PaymentStatus
+	Amount            money.Money
+	ProviderFee       money.Money
+	Exchange          Exchange
+}
+
+type VoidRequest struct {
+	ProviderPaymentID string
+	IdempotencyKey    string
+}
+
+type VoidResult struct {
+	ProviderVoidID string
+	Exchange       Exchange
+}
+
+type CancelRequest struct {
+	ProviderPaymentID string
+	IdempotencyKey    string
+}
+
+type CancelResult struct {
+	ProviderCancelID string
+	Exchange         Exchange
+}
+
+// PaymentStatusResult reports a payment's status the way WebhookEvent does:
+// pending means the buyer approved and the payment is ready to capture or
+// authorize. Status is empty while the provider is still waiting for the
+// buyer.
+type PaymentStatusResult struct {
 	ProviderPaymentID string
 	Status            entity.PaymentStatus
-	ProviderFee       float64
+	Amount            money.Money
+	Exchange          Exchange
+}
+
+type RefundRequest struct {
+	ProviderPaymentID string
+	Amount            money.Money
+	Reason            string
+	// IdempotencyKey lets the provider recognise a retried refund request.
+	IdempotencyKey string
+}
+
+type RefundResult struct {
+	ProviderRefundID string
+	Status           entity.TransactionStatus
+	Amount           money.Money
+	Exchange         Exchange
 }
@@ -0,0 +1,491 @@
+package iyzico
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/omerbeden/paymentgateway/internal/adapter/provider"
+	"github.com/omerbeden/paymentgateway/internal/domain/entity"
+	"github.com/omerbeden/paymentgateway/internal/domain/money"
+	"github.com/omerbeden/paymentgateway/internal/infrastructure/config"
+	"github.com/omerbeden/paymentgateway/internal/infrastructure/metrics"
+	"github.com/omerbeden/paymentgateway/internal/pkg/httpclient"
+)
+
+type Provider struct {
+	httpClient *http.Client
+	cfg        config.Iyzico
+	metrics    *metrics.Metrics
+}
+
+const (
+	pathInitializeCheckoutForm = "/payment/iyzipos/checkoutform/initialize/auth/ecom"
+	pathRetrieveCheckoutForm   = "/payment/iyzipos/checkoutform/auth/ecom/detail"
+	pathRefund                 = "/v2/payment/refund"
+	providerID                 = "iyzico"
+)
+
+// ErrInvalidResponseSignature also rejects checkout form callbacks, which are
+// verified through the signature of the retrieved form.
+var ErrInvalidResponseSignature = fmt.Errorf("iyzico response signature mismatch: %w", provider.ErrInvalidWebhookSignature)
+
+func NewProvider(cfg config.Iyzico, metrics *metrics.Metrics) *Provider {
+	return &Provider{
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		cfg:        cfg,
+		metrics:    metrics,
+	}
+}
+
+// CreatePayment initializes a hosted checkout form. The form token identifies
+// the payment at Iyzico until the buyer completes it. Checkout forms charge the
+// buyer on submission, so the authorize intent is not supported.
+func (p *Provider) CreatePayment(ctx context.Context, payment *entity.Payment) (*provider.CreatePaymentResult, error) {
+	start := time.Now()
+	operation := "create_payment"
+
+	if payment.Intent == entity.PaymentIntentAuthorize {
+		return nil, &provider.Error{Operation: operation, Err: provider.ErrUnsupportedIntent}
+	}
+
+	price := payment.Amount.Decimal()
+	body := iyzicoCheckoutFormRequest{
+		Locale:         p.cfg.Locale,
+		ConversationID: payment.ID,
+		Price:          price,
+		PaidPrice:      price,
+		Currency:       payment.Amount.Currency,
+		BasketID:       payment.ID,
+		PaymentGroup:   "PRODUCT",
+		CallbackURL:    p.cfg.CallbackURL,
+		Buyer:          newBuyer(payment),
+		BasketItems: []iyzicoBasketItem{{
+			ID:        payment.ID,
+			Name:      metadataOr(payment.Metadata, "item_name", "Order "+payment.ID),
+			Category1: metadataOr(payment.Metadata, "item_category", "General"),
+			ItemType:  "VIRTUAL",
+			Price:     price,
+		}},
+	}
+	body.BillingAddress = newAddress(body.Buyer)
+	body.ShippingAddress = body.BillingAddress
+
+	var response iyzicoCheckoutFormResponse
+	exchange, err := p.call(ctx, pathInitializeCheckoutForm, body, &response)
+	p.recordRequest(operation, start, err)
+	if err != nil {
+		return nil, &provider.Error{Operation: operation, Exchange: exchange, Err: err}
+	}
+
+	return &provider.CreatePaymentResult{
+		ProviderPaymentID: response.Token,
+		Status:            entity.PaymentStatusPending,
+		Amount:            payment.Amount,
+		PaymentURL:        response.PaymentPageURL,
+		Metadata:          map[string]string{"iyzico_token_expire_time": strconv.Itoa(response.TokenExpireTime)},
+		Exchange:          exchange,
+	}, nil
+}
+
+// Capture confirms the checkout form result. Checkout forms charge the buyer
+// when they are submitted, so there is nothing left to capture; the form is
+// retrieved and its signature checked before the payment is trusted.
+func (p *Provider) Capture(ctx context.Context, req provider.CaptureRequest) (*provider.CaptureResult, error) {
+	start := time.Now()
+	operation := "capture_payment"
+	id := req.ProviderPaymentID
+
+	form, exchange, err := p.retrieveCheckoutForm(ctx, id)
+	if err == nil && form.PaymentStatus != "SUCCESS" {
+		err = fmt.Errorf("unexpected checkout form payment status %q", form.PaymentStatus)
+	}
+	p.recordRequest(operation, start, err)
+	if err != nil {
+		return nil, &provider.Error{Operation: operation, Exchange: exchange, Err: fmt.Errorf("error while capturing payment %w", err)}
+	}
+
+	result := &provider.CaptureResult{
+		ProviderPaymentID: form.Token,
+		ProviderCaptureID: form.PaymentID,
+		Status:            entity.PaymentStatusSucceeded,
+		Exchange:          exchange,
+	}
+	if amount, err := money.Parse(form.PaidPrice.String(), form.Currency); err == nil {
+		result.Amount = amount
+	}
+	result.ProviderFee = commission(form)
+
+	return result, nil
+}
+
+func (p *Provider) Authorize(ctx context.Context, id string) (*provider.AuthorizeResult, error) {
+	return nil, &provider.Error{Operation: "authorize_payment", Err: provider.ErrUnsupportedIntent}
+}
+
+func (p *Provider) Void(ctx context.Context, req provider.VoidRequest) (*provider.VoidResult, error) {
+	return nil, &provider.Error{Operation: "void_payment", Err: provider.ErrUnsupportedIntent}
+}
+
+// Refund refunds against the Iyzico payment ID, which is only known once the
+// checkout form has been completed, so the form is retrieved first.
+func (p *Provider) Refund(ctx context.Context, req provider.RefundRequest) (*provider.RefundResult, error) {
+	start := time.Now()
+	operation := "refund_payment"
+
+	form, exchange, err := p.retrieveCheckoutForm(ctx, req.ProviderPaymentID)
+	if err != nil {
+		p.recordRequest(operation, start, err)
+		return nil, &provider.Error{Operation: operation, Exchange: exchange, Err: fmt.Errorf("failed to get iyzico checkout form %s: %w", req.ProviderPaymentID, err)}
+	}
+
+	conversationID := req.IdempotencyKey
+	if conversationID == "" {
+		conversationID = form.PaymentID + "-refund-" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	}
+	body := iyzicoRefundRequest{
+		Locale:         p.cfg.Locale,
+		ConversationID: conversationID,
+		PaymentID:      form.PaymentID,
+		Price:          req.Amount.Decimal(),
+		Currency:       req.Amount.Currency,
+		IP:             "127.0.0.1",
+	}
+
+	var response iyzicoRefundResponse
+	exchange, err = p.call(ctx, pathRefund, body, &response)
+	p.recordRequest(operation, start, err)
+	if err != nil {
+		return nil, &provider.Error{Operation: operation, Exchange: exchange, Err: fmt.Errorf("error while refunding payment %w", err)}
+	}
+
+	refundID := response.RefundHostReference
+	if refundID == "" {
+		refundID = conversationID
+	}
+	amount, err := money.Parse(response.Price.String(), response.Currency)
+	if err != nil {
+		amount = req.Amount
+	}
+	return &provider.RefundResult{
+		ProviderRefundID: refundID,
+		Status:           entity.TransactionStatusSucceeded,
+		Amount:           amount,
+		Exchange:         exchange,
+	}, nil
+}
+
+// GetPaymentStatus retrieves the checkout form. A paid form is reported as
+// pending, like a successful callback, so it is confirmed through Capture.
+func (p *Provider) GetPaymentStatus(ctx context.Context, providerPaymentID string) (*provider.PaymentStatusResult, error) {
+	start := time.Now()
+	operation := "get_payment_status"
+
+	form, exchange, err := p.retrieveCheckoutForm(ctx, providerPaymentID)
+	p.recordRequest(operation, start, err)
+	if err != nil {
+		return nil, &provider.Error{Operation: operation, Exchange: exchange, Err: fmt.Errorf("error while getting payment status %w", err)}
+	}
+
+	result := &provider.PaymentStatusResult{
+		ProviderPaymentID: form.Token,
+		Exchange:          exchange,
+	}
+	if amount, err := money.Parse(form.PaidPrice.String(), form.Currency); err == nil {
+		result.Amount = amount
+	}
+	switch form.PaymentStatus {
+	case "SUCCESS":
+		result.Status = entity.PaymentStatusPending
+	case "FAILURE":
+		result.Status = entity.PaymentStatusFailed
+	}
+
+	return result, nil
+}
+
+func (p *Provider) retrieveCheckoutForm(ctx context.Context, token string) (*iyzicoCheckoutFormResult, provider.Exchange, error) {
+	body := iyzicoRetrieveRequest{
+		Locale:         p.cfg.Locale,
+		ConversationID: token,
+		Token:          token,
+	}
+
+	var form iyzicoCheckoutFormResult
+	exchange, err := p.call(ctx, pathRetrieveCheckoutForm, body, &form)
+	if err != nil {
+		return nil, exchange, err
+	}
+	if !hmac.Equal([]byte(form.Signature), []byte(p.checkoutFormSignature(&form))) {
+		return nil, exchange, ErrInvalidResponseSignature
+	}
+	return &form, exchange, nil
+}
+
+// checkoutFormSignature is the signature Iyzico attaches to checkout form
+// results: a hex HMAC-SHA256 over the colon-joined fields below.
+func (p *Provider) checkoutFormSignature(form *iyzicoCheckoutFormResult) string {
+	message := strings.Join([]string{
+		form.PaymentStatus,
+		form.PaymentID,
+		form.Currency,
+		form.BasketID,
+		form.ConversationID,
+		form.PaidPrice.String(),
+		form.Price.String(),
+		form.Token,
+	}, ":")
+	mac := hmac.New(sha256.New, []byte(p.cfg.SecretKey))
+	mac.Write([]byte(message))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// call sends a signed Iyzico API request. Iyzico answers most business errors
+// with HTTP 200 and status "failure", so those are turned into errors here.
+func (p *Provider) call(ctx context.Context, path string, body any, out iyzicoResult) (provider.Exchange, error) {
+	var exchange provider.Exchange
+	requestBody, err := json.Marshal(body)
+	if err != nil {
+		return exchange, fmt.Errorf("failed to marshal request body: %w", err)
+	}
+	exchange.Request = string(requestBody)
+
+	randomKey, err := newRandomKey()
+	if err != nil {
+		return exchange, err
+	}
+	headers := http.Header{}
+	headers.Set("Content-Type", "application/json")
+	headers.Set("Accept", "application/json")
+	headers.Set("x-iyzi-rnd", randomKey)
+	headers.Set("Authorization", SignRequest(p.cfg.APIKey, p.cfg.SecretKey, randomKey, path, requestBody))
+
+	var raw json.RawMessage
+	err = httpclient.MakeRequest(httpclient.RequestParam[string]{
+		Client: p.httpClient,
+		Header: &headers,
+		Ctx:    ctx,
+		Method: http.MethodPost,
+		URL:    p.cfg.BaseURL + path,
+		Body:   exchange.Request,
+	}, &raw)
+	if err != nil {
+		var statusErr *httpclient.StatusError
+		if errors.As(err, &statusErr) {
+			exchange.Response = statusErr.Body
+		}
+		return exchange, err
+	}
+	exchange.Response = string(raw)
+
+	if err := json.Unmarshal(raw, out); err != nil {
+		return exchange, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if status := out.result(); status.Status != "success" {
+		return exchange, fmt.Errorf("iyzico error %s: %s", status.ErrorCode, status.ErrorMessage)
+	}
+	return exchange, nil
+}
+
+// SignRequest builds the IYZWSv2 Authorization header: the HMAC-SHA256 of
+// randomKey, the URI path and the body, keyed with the secret key, wrapped
+// together with the API key in base64.
+func SignRequest(apiKey, secretKey, randomKey, uriPath string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secretKey))
+	mac.Write([]byte(randomKey + uriPath))
+	mac.Write(body)
+	params := "apiKey:" + apiKey + "&randomKey:" + randomKey + "&signature:" + hex.EncodeToString(mac.Sum(nil))
+	return "IYZWSv2 " + base64.StdEncoding.EncodeToString([]byte(params))
+}
+
+func newRandomKey() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate iyzico random key: %w", err)
+	}
+	return strconv.FormatInt(time.Now().UnixMilli(), 10) + hex.EncodeToString(b), nil
+}
+
+// commission adds up Iyzico's fees. Fees can carry more decimals than the
+// currency allows, in which case they are left out rather than rounded.
+func commission(form *iyzicoCheckoutFormResult) money.Money {
+	rate, err := money.Parse(form.IyziCommissionRateAmount.String(), form.Currency)
+	if err != nil {
+		return money.Money{}
+	}
+	fixed, err := money.Parse(form.IyziCommissionFee.String(), form.Currency)
+	if err != nil {
+		return rate
+	}
+	total, _ := rate.Add(fixed)
+	return total
+}
+
+func (p *Provider) recordRequest(operation string, start time.Time, err error) {
+	p.metrics.ProviderRequestDuration.WithLabelValues(
+		providerID,
+		operation,
+	).Observe(time.Since(start).Seconds())
+
+	status := "success"
+	if err != nil {
+		status = "error"
+		p.metrics.ProviderErrors.WithLabelValues(
+			providerID,
+			"api_error",
+		).Inc()
+	}
+	p.metrics.ProviderRequestsTotal.WithLabelValues(
+		providerID,
+		operation,
+		status,
+	).Inc()
+}
+
+func metadataOr(metadata map[string]string, key, fallback string) string {
+	if v := metadata[key]; v != "" {
+		return v
+	}
+	return fallback
+}
+
+// newBuyer fills the buyer block Iyzico requires from the payment metadata.
+func newBuyer(payment *entity.Payment) iyzicoBuyer {
+	m := payment.Metadata
+	return iyzicoBuyer{
+		ID:                  metadataOr(m, "buyer_id", payment.ID),
+		Name:                metadataOr(m, "buyer_name", "Guest"),
+		Surname:             metadataOr(m, "buyer_surname", "Buyer"),
+		Email:               metadataOr(m, "buyer_email", "guest@example.com"),
+		GsmNumber:           m["buyer_phone"],
+		IdentityNumber:      metadataOr(m, "buyer_identity_number", "11111111111"),
+		RegistrationAddress: metadataOr(m, "buyer_address", "N/A"),
+		City:                metadataOr(m, "buyer_city", "Istanbul"),
+		Country:             metadataOr(m, "buyer_country", "Turkey"),
+		IP:                  metadataOr(m, "buyer_ip", "127.0.0.1"),
+	}
+}
+
+func newAddress(buyer iyzicoBuyer) iyzicoAddress {
+	return iyzicoAddress{
+		ContactName: buyer.Name + " " + buyer.Surname,
+		City:        buyer.City,
+		Country:     buyer.Country,
+		Address:     buyer.RegistrationAddress,
+	}
+}
+
+type iyzicoResult interface {
+	result() iyzicoStatus
+}
+
+type iyzicoStatus struct {
+	Status         string `json:"status"`
+	ErrorCode      string `json:"errorCode,omitempty"`
+	ErrorMessage   string `json:"errorMessage,omitempty"`
+	Locale         string `json:"locale,omitempty"`
+	SystemTime     int64  `json:"systemTime,omitempty"`
+	ConversationID string `json:"conversationId,omitempty"`
+}
+
+func (s iyzicoStatus) result() iyzicoStatus { return s }
+
+type iyzicoBuyer struct {
+	ID                  string `json:"id"`
+	Name                string `json:"name"`
+	Surname             string `json:"surname"`
+	Email               string `json:"email"`
+	GsmNumber           string `json:"gsmNumber,omitempty"`
+	IdentityNumber      string `json:"identityNumber"`
+	RegistrationAddress string `json:"registrationAddress"`
+	City                string `json:"city"`
+	Country             string `json:"country"`
+	IP                  string `json:"ip"`
+}
+
+type iyzicoAddress struct {
+	ContactName string `json:"contactName"`
+	City        string `json:"city"`
+	Country     string `json:"country"`
+	Address     string `json:"address"`
+}
+
+type iyzicoBasketItem struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Category1 string `json:"category1"`
+	ItemType  string `json:"itemType"`
+	Price     string `json:"price"`
+}
+
+type iyzicoCheckoutFormRequest struct {
+	Locale          string             `json:"locale"`
+	ConversationID  string             `json:"conversationId"`
+	Price           string             `json:"price"`
+	PaidPrice       string             `json:"paidPrice"`
+	Currency        string             `json:"currency"`
+	BasketID        string             `json:"basketId"`
+	PaymentGroup    string             `json:"paymentGroup"`
+	CallbackURL     string             `json:"callbackUrl"`
+	Buyer           iyzicoBuyer        `json:"buyer"`
+	ShippingAddress iyzicoAddress      `json:"shippingAddress"`
+	BillingAddress  iyzicoAddress      `json:"billingAddress"`
+	BasketItems     []iyzicoBasketItem `json:"basketItems"`
+}
+
+type iyzicoCheckoutFormResponse struct {
+	iyzicoStatus
+	Token               string `json:"token"`
+	CheckoutFormContent string `json:"checkoutFormContent"`
+	PaymentPageURL      string `json:"paymentPageUrl"`
+	TokenExpireTime     int    `json:"tokenExpireTime"`
+}
+
+type iyzicoRetrieveRequest struct {
+	Locale         string `json:"locale"`
+	ConversationID string `json:"conversationId"`
+	Token          string `json:"token"`
+}
+
+type iyzicoCheckoutFormResult struct {
+	iyzicoStatus
+	Token                    string      `json:"token"`
+	PaymentStatus            string      `json:"paymentStatus"`
+	PaymentID                string      `json:"paymentId"`
+	Price                    json.Number `json:"price"`
+	PaidPrice                json.Number `json:"paidPrice"`
+	Currency                 string      `json:"currency"`
+	BasketID                 string      `json:"basketId"`
+	FraudStatus              int         `json:"fraudStatus"`
+	IyziCommissionRateAmount json.Number `json:"iyziCommissionRateAmount"`
+	IyziCommissionFee        json.Number `json:"iyziCommissionFee"`
+	Signature                string      `json:"signature"`
+}
+
+type iyzicoRefundRequest struct {
+	Locale         string `json:"locale"`
+	ConversationID string `json:"conversationId"`
+	PaymentID      string `json:"paymentId"`
+	Price          string `json:"price"`
+	Currency       string `json:"currency"`
+	IP             string `json:"ip"`
+}
+
+type iyzicoRefundResponse struct {
+	iyzicoStatus
+	PaymentID           string      `json:"paymentId"`
+	Price               json.Number `json:"price"`
+	Currency            string      `json:"currency"`
+	HostReference       string      `json:"hostReference"`
+	RefundHostReference string      `json:"refundHostReference"`
+}
@@ -0,0 +1,304 @@
+package iyzico
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/omerbeden/paymentgateway/internal/adapter/provider"
+	"github.com/omerbeden/paymentgateway/internal/adapter/provider/iyzico/iyzicotest"
+	"github.com/omerbeden/paymentgateway/internal/domain/entity"
+	"github.com/omerbeden/paymentgateway/internal/domain/money"
+	"github.com/omerbeden/paymentgateway/internal/infrastructure/config"
+	"github.com/omerbeden/paymentgateway/internal/infrastructure/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testAPIKey    = "sandbox-api-key"
+	testSecretKey = "sandbox-secret-key"
+)
+
+// metrics.New registers collectors globally, so the package shares one set.
+var testMetrics = metrics.New()
+
+func newTestProvider(t *testing.T) (*Provider, *iyzicotest.Server) {
+	t.Helper()
+	server := iyzicotest.NewServer(testAPIKey, testSecretKey)
+	t.Cleanup(server.Close)
+
+	p := NewProvider(config.Iyzico{
+		BaseURL:     server.URL,
+		APIKey:      testAPIKey,
+		SecretKey:   testSecretKey,
+		CallbackURL: "http://localhost:8080/api/v1/webhooks/iyzico",
+		Locale:      "tr",
+	}, testMetrics)
+	return p, server
+}
+
+func newTestPayment() *entity.Payment {
+	return &entity.Payment{
+		ID:         "pay_123",
+		Amount:     money.New(1050, "TRY"),
+		ProviderID: providerID,
+		Status:     entity.PaymentStatusPending,
+		Metadata:   map[string]string{"buyer_email": "buyer@example.com"},
+	}
+}
+
+func TestCreatePayment_Success(t *testing.T) {
+	// Arrange
+	p, server := newTestProvider(t)
+
+	// Act
+	result, err := p.CreatePayment(context.Background(), newTestPayment())
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, entity.PaymentStatusPending, result.Status)
+	assert.Equal(t, money.New(1050, "TRY"), result.Amount)
+	assert.Contains(t, result.PaymentURL, result.ProviderPaymentID)
+	assert.Contains(t, result.Exchange.Request, `"email":"buyer@example.com"`)
+
+	form, ok := server.Form(result.ProviderPaymentID)
+	require.True(t, ok)
+	assert.Equal(t, "10.5", form.Price)
+	assert.Equal(t, "TRY", form.Currency)
+	assert.Equal(t, "pay_123", form.BasketID)
+}
+
+func TestCreatePayment_InvalidAPIKey(t *testing.T) {
+	// Arrange
+	p, _ := newTestProvider(t)
+	p.cfg.SecretKey = "wrong-secret"
+
+	// Act
+	result, err := p.CreatePayment(context.Background(), newTestPayment())
+
+	// Assert
+	assert.Nil(t, result)
+	var providerErr *provider.Error
+	require.True(t, errors.As(err, &providerErr))
+	assert.Contains(t, providerErr.Exchange.Response, "Invalid signature")
+}
+
+func TestCreatePayment_AuthorizeIntentUnsupported(t *testing.T) {
+	// Arrange
+	p, _ := newTestProvider(t)
+	payment := newTestPayment()
+	payment.Intent = entity.PaymentIntentAuthorize
+
+	// Act
+	result, err := p.CreatePayment(context.Background(), payment)
+
+	// Assert
+	assert.Nil(t, result)
+	assert.ErrorIs(t, err, provider.ErrUnsupportedIntent)
+}
+
+func TestCapture_Success(t *testing.T) {
+	// Arrange
+	p, server := newTestProvider(t)
+	ctx := context.Background()
+	created, err := p.CreatePayment(ctx, newTestPayment())
+	require.NoError(t, err)
+	server.Complete(created.ProviderPaymentID, true)
+
+	// Act
+	result, err := p.Capture(ctx, provider.CaptureRequest{ProviderPaymentID: created.ProviderPaymentID})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, entity.PaymentStatusSucceeded, result.Status)
+	assert.Equal(t, money.New(1050, "TRY"), result.Amount)
+	assert.NotEmpty(t, result.ProviderCaptureID)
+	assert.Equal(t, money.New(50, "TRY"), result.ProviderFee)
+}
+
+func TestCapture_FormNotCompleted(t *testing.T) {
+	// Arrange
+	p, server := newTestProvider(t)
+	ctx := context.Background()
+	created, err := p.CreatePayment(ctx, newTestPayment())
+	require.NoError(t, err)
+	server.Complete(created.ProviderPaymentID, false)
+
+	// Act
+	result, err := p.Capture(ctx, provider.CaptureRequest{ProviderPaymentID: created.ProviderPaymentID})
+
+	// Assert
+	assert.Nil(t, result)
+	assert.ErrorContains(t, err, "FAILURE")
+}
+
+func TestGetPaymentStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		success bool
+		want    entity.PaymentStatus
+	}{
+		{"paid", true, entity.PaymentStatusPending},
+		{"failed", false, entity.PaymentStatusFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			p, server := newTestProvider(t)
+			ctx := context.Background()
+			created, err := p.CreatePayment(ctx, newTestPayment())
+			require.NoError(t, err)
+			server.Complete(created.ProviderPaymentID, tt.success)
+
+			// Act
+			result, err := p.GetPaymentStatus(ctx, created.ProviderPaymentID)
+
+			// Assert
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, result.Status)
+			assert.Equal(t, created.ProviderPaymentID, result.ProviderPaymentID)
+		})
+	}
+}
+
+func TestCapture_TamperedResponseSignature(t *testing.T) {
+	// Arrange
+	p, server := newTestProvider(t)
+	ctx := context.Background()
+	created, err := p.CreatePayment(ctx, newTestPayment())
+	require.NoError(t, err)
+	server.Complete(created.ProviderPaymentID, true)
+	server.TamperSignatures = true
+
+	// Act
+	result, err := p.Capture(ctx, provider.CaptureRequest{ProviderPaymentID: created.ProviderPaymentID})
+
+	// Assert
+	assert.Nil(t, result)
+	assert.True(t, errors.Is(err, ErrInvalidResponseSignature))
+}
+
+func TestRefund_Partial(t *testing.T) {
+	// Arrange
+	p, server := newTestProvider(t)
+	ctx := context.Background()
+	created, err := p.CreatePayment(ctx, newTestPayment())
+	require.NoError(t, err)
+	server.Complete(created.ProviderPaymentID, true)
+
+	// Act
+	result, err := p.Refund(ctx, provider.RefundRequest{
+		ProviderPaymentID: created.ProviderPaymentID,
+		Amount:            money.New(500, "TRY"),
+		IdempotencyKey:    "refund-1",
+	})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, entity.TransactionStatusSucceeded, result.Status)
+	assert.Equal(t, money.New(500, "TRY"), result.Amount)
+	assert.NotEmpty(t, result.ProviderRefundID)
+
+	_, err = p.Refund(ctx, provider.RefundRequest{
+		ProviderPaymentID: created.ProviderPaymentID,
+		Amount:            money.New(600, "TRY"),
+		IdempotencyKey:    "refund-2",
+	})
+	assert.ErrorContains(t, err, "exceeds")
+}
+
+func TestVerifyWebhook_Notification(t *testing.T) {
+	p, _ := newTestProvider(t)
+	payload := []byte(`{"paymentConversationId":"pay_123","merchantId":1,"token":"tok-1","status":"SUCCESS",` +
+		`"iyziReferenceCode":"ref","iyziEventType":"CHECKOUT_FORM_AUTH","iyziEventTime":1700000000000,"iyziPaymentId":20000001}`)
+
+	tests := []struct {
+		name    string
+		header  string
+		wantErr error
+	}{
+		{"valid", SignNotification(testSecretKey, "CHECKOUT_FORM_AUTH", "20000001", "tok-1", "pay_123", "SUCCESS"), nil},
+		{"wrong secret", SignNotification("other", "CHECKOUT_FORM_AUTH", "20000001", "tok-1", "pay_123", "SUCCESS"), ErrInvalidSignature},
+		{"tampered status", SignNotification(testSecretKey, "CHECKOUT_FORM_AUTH", "20000001", "tok-1", "pay_123", "FAILURE"), ErrInvalidSignature},
+		{"missing", "", ErrInvalidSignature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := http.Header{}
+			headers.Set(SignatureHeader, tt.header)
+
+			err := p.VerifyWebhook(context.Background(), &provider.WebhookContext{
+				Payload: payload,
+				Headers: headers,
+			})
+
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.True(t, errors.Is(err, tt.wantErr), "got %v", err)
+		})
+	}
+}
+
+func TestVerifyWebhook_Callback(t *testing.T) {
+	// Arrange
+	p, server := newTestProvider(t)
+	ctx := context.Background()
+	created, err := p.CreatePayment(ctx, newTestPayment())
+	require.NoError(t, err)
+	server.Complete(created.ProviderPaymentID, true)
+
+	// Act
+	err = p.VerifyWebhook(ctx, &provider.WebhookContext{Payload: []byte("token=" + created.ProviderPaymentID)})
+	unknownErr := p.VerifyWebhook(ctx, &provider.WebhookContext{Payload: []byte("token=tok-unknown")})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.ErrorContains(t, unknownErr, "Token not found")
+}
+
+func TestParseWebhook(t *testing.T) {
+	p, _ := newTestProvider(t)
+
+	tests := []struct {
+		name       string
+		payload    string
+		wantType   string
+		wantStatus entity.PaymentStatus
+		wantID     string
+	}{
+		{"callback", "token=tok-1", "CHECKOUT_FORM_CALLBACK", entity.PaymentStatusPending, "CHECKOUT_FORM_CALLBACK:tok-1"},
+		{"notification success", `{"token":"tok-1","status":"SUCCESS","iyziEventType":"CHECKOUT_FORM_AUTH","iyziEventTime":1700000000000}`,
+			"CHECKOUT_FORM_AUTH", entity.PaymentStatusPending, "CHECKOUT_FORM_AUTH:tok-1:SUCCESS"},
+		{"notification failure", `{"token":"tok-1","status":"FAILURE","iyziEventType":"CHECKOUT_FORM_AUTH","iyziEventTime":1700000000000}`,
+			"CHECKOUT_FORM_AUTH", entity.PaymentStatusFailed, "CHECKOUT_FORM_AUTH:tok-1:FAILURE"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evt, err := p.ParseWebhook([]byte(tt.payload))
+
+			require.NoError(t, err)
+			assert.Equal(t, "tok-1", evt.ProviderPaymentID)
+			assert.Equal(t, tt.wantType, evt.EventType)
+			assert.Equal(t, tt.wantID, evt.EventID)
+			assert.Equal(t, tt.wantStatus, evt.Status)
+			assert.False(t, evt.Ignored)
+		})
+	}
+}
+
+func TestParseWebhook_IgnoresUnmappedStatus(t *testing.T) {
+	p, _ := newTestProvider(t)
+
+	evt, err := p.ParseWebhook([]byte(`{"token":"tok-1","status":"INIT_THREEDS","iyziEventType":"CHECKOUT_FORM_AUTH","iyziEventTime":1700000000000}`))
+
+	require.NoError(t, err)
+	assert.True(t, evt.Ignored)
+	assert.Empty(t, evt.Status)
+}
@@ -0,0 +1,296 @@
+// Package iyzicotest provides an in-process fake of the Iyzico checkout form and
+// refund API. It checks IYZWSv2 signatures independently of the provider code.
+package iyzicotest
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/omerbeden/paymentgateway/internal/domain/money"
+)
+
+// CheckoutForm is the fake's record of an initialized form.
+type CheckoutForm struct {
+	Token          string
+	ConversationID string
+	BasketID       string
+	Price          string
+	PaidPrice      string
+	Currency       string
+	CallbackURL    string
+	PaymentStatus  string
+	PaymentID      string
+	// Refunded is in minor units of Currency.
+	Refunded int64
+}
+
+type Server struct {
+	*httptest.Server
+	APIKey    string
+	SecretKey string
+
+	mu    sync.Mutex
+	seq   int
+	forms map[string]*CheckoutForm
+	// TamperSignatures makes the server sign retrieve responses with a wrong key.
+	TamperSignatures bool
+}
+
+func NewServer(apiKey, secretKey string) *Server {
+	s := &Server{
+		APIKey:    apiKey,
+		SecretKey: secretKey,
+		forms:     make(map[string]*CheckoutForm),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /payment/iyzipos/checkoutform/initialize/auth/ecom", s.initialize)
+	mux.HandleFunc("POST /payment/iyzipos/checkoutform/auth/ecom/detail", s.retrieve)
+	mux.HandleFunc("POST /v2/payment/refund", s.refund)
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// Complete simulates the buyer submitting the form. A successful form gets an
+// Iyzico payment ID.
+func (s *Server) Complete(token string, success bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	form, ok := s.forms[token]
+	if !ok {
+		return
+	}
+	if success {
+		form.PaymentStatus = "SUCCESS"
+		s.seq++
+		form.PaymentID = strconv.Itoa(20000000 + s.seq)
+	} else {
+		form.PaymentStatus = "FAILURE"
+	}
+}
+
+func (s *Server) Form(token string) (CheckoutForm, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	form, ok := s.forms[token]
+	if !ok {
+		return CheckoutForm{}, false
+	}
+	return *form, true
+}
+
+// readSigned checks the IYZWSv2 Authorization header and decodes the body.
+func (s *Server) readSigned(w http.ResponseWriter, r *http.Request, v any) bool {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeFailure(w, "11", "Invalid request")
+		return false
+	}
+
+	auth := strings.TrimPrefix(r.Header.Get("Authorization"), "IYZWSv2 ")
+	decoded, err := base64.StdEncoding.DecodeString(auth)
+	if err != nil {
+		writeFailure(w, "1000", "Invalid signature")
+		return false
+	}
+	params := map[string]string{}
+	for _, part := range strings.Split(string(decoded), "&") {
+		if k, v, ok := strings.Cut(part, ":"); ok {
+			params[k] = v
+		}
+	}
+
+	mac := hmac.New(sha256.New, []byte(s.SecretKey))
+	mac.Write([]byte(params["randomKey"] + r.URL.Path))
+	mac.Write(body)
+	expected := hex.EncodeToString(mac.Sum(nil))
+	if params["apiKey"] != s.APIKey || params["randomKey"] != r.Header.Get("x-iyzi-rnd") || params["signature"] != expected {
+		writeFailure(w, "1000", "Invalid signature")
+		return false
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		writeFailure(w, "11", "Invalid request")
+		return false
+	}
+	return true
+}
+
+func (s *Server) initialize(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ConversationID string `json:"conversationId"`
+		Price          string `json:"price"`
+		PaidPrice      string `json:"paidPrice"`
+		Currency       string `json:"currency"`
+		BasketID       string `json:"basketId"`
+		CallbackURL    string `json:"callbackUrl"`
+		Buyer          struct {
+			IdentityNumber string `json:"identityNumber"`
+		} `json:"buyer"`
+		BasketItems []json.RawMessage `json:"basketItems"`
+	}
+	if !s.readSigned(w, r, &req) {
+		return
+	}
+	if req.Price == "" || req.Currency == "" || req.CallbackURL == "" || req.Buyer.IdentityNumber == "" || len(req.BasketItems) == 0 {
+		writeFailure(w, "11", "Invalid request")
+		return
+	}
+
+	s.mu.Lock()
+	s.seq++
+	token := fmt.Sprintf("tok-%d", s.seq)
+	s.forms[token] = &CheckoutForm{
+		Token:          token,
+		ConversationID: req.ConversationID,
+		BasketID:       req.BasketID,
+		Price:          trimPrice(req.Price),
+		PaidPrice:      trimPrice(req.PaidPrice),
+		Currency:       req.Currency,
+		CallbackURL:    req.CallbackURL,
+		PaymentStatus:  "INIT_THREEDS",
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, map[string]any{
+		"status":              "success",
+		"locale":              "tr",
+		"systemTime":          time.Now().UnixMilli(),
+		"conversationId":      req.ConversationID,
+		"token":               token,
+		"checkoutFormContent": "<script>/* iyzico checkout form */</script>",
+		"tokenExpireTime":     1800,
+		"paymentPageUrl":      s.URL + "/checkout?token=" + token,
+	})
+}
+
+func (s *Server) retrieve(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ConversationID string `json:"conversationId"`
+		Token          string `json:"token"`
+	}
+	if !s.readSigned(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	form, ok := s.forms[req.Token]
+	var snapshot CheckoutForm
+	if ok {
+		snapshot = *form
+	}
+	s.mu.Unlock()
+	if !ok {
+		writeFailure(w, "5010", "Token not found")
+		return
+	}
+
+	key := s.SecretKey
+	if s.TamperSignatures {
+		key = "not-the-secret"
+	}
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(strings.Join([]string{
+		snapshot.PaymentStatus, snapshot.PaymentID, snapshot.Currency, snapshot.BasketID,
+		req.ConversationID, snapshot.PaidPrice, snapshot.Price, snapshot.Token,
+	}, ":")))
+
+	writeJSON(w, map[string]any{
+		"status":                   "success",
+		"locale":                   "tr",
+		"systemTime":               time.Now().UnixMilli(),
+		"conversationId":           req.ConversationID,
+		"token":                    snapshot.Token,
+		"paymentStatus":            snapshot.PaymentStatus,
+		"paymentId":                snapshot.PaymentID,
+		"price":                    json.Number(snapshot.Price),
+		"paidPrice":                json.Number(snapshot.PaidPrice),
+		"currency":                 snapshot.Currency,
+		"basketId":                 snapshot.BasketID,
+		"fraudStatus":              1,
+		"iyziCommissionRateAmount": json.Number("0.25"),
+		"iyziCommissionFee":        json.Number("0.25"),
+		"signature":                hex.EncodeToString(mac.Sum(nil)),
+	})
+}
+
+func (s *Server) refund(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ConversationID string `json:"conversationId"`
+		PaymentID      string `json:"paymentId"`
+		Price          string `json:"price"`
+		Currency       string `json:"currency"`
+	}
+	if !s.readSigned(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var form *CheckoutForm
+	for _, f := range s.forms {
+		if f.PaymentID != "" && f.PaymentID == req.PaymentID {
+			form = f
+		}
+	}
+	if form == nil {
+		writeFailure(w, "5092", "Payment not found")
+		return
+	}
+	price, err := money.Parse(req.Price, form.Currency)
+	paid, _ := money.Parse(form.PaidPrice, form.Currency)
+	if err != nil || !price.IsPositive() || form.Refunded+price.Amount > paid.Amount {
+		writeFailure(w, "5093", "Refund amount exceeds the paid amount")
+		return
+	}
+	form.Refunded += price.Amount
+
+	s.seq++
+	writeJSON(w, map[string]any{
+		"status":              "success",
+		"locale":              "tr",
+		"systemTime":          time.Now().UnixMilli(),
+		"conversationId":      req.ConversationID,
+		"paymentId":           req.PaymentID,
+		"price":               json.Number(trimPrice(req.Price)),
+		"currency":            req.Currency,
+		"hostReference":       fmt.Sprintf("host-%d", s.seq),
+		"refundHostReference": fmt.Sprintf("refund-%d", s.seq),
+	})
+}
+
+// trimPrice formats prices the way Iyzico echoes them, without trailing zeros.
+func trimPrice(price string) string {
+	if !strings.Contains(price, ".") {
+		return price
+	}
+	price = strings.TrimRight(price, "0")
+	return strings.TrimSuffix(price, ".")
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeFailure answers the way Iyzico does: HTTP 200 with status "failure".
+func writeFailure(w http.ResponseWriter, code, message string) {
+	writeJSON(w, map[string]any{
+		"status":       "failure",
+		"errorCode":    code,
+		"errorMessage": message,
+		"locale":       "tr",
+		"systemTime":   time.Now().UnixMilli(),
+	})
+}
@@ -0,0 +1,158 @@
+package iyzico
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/omerbeden/paymentgateway/internal/adapter/provider"
+	"github.com/omerbeden/paymentgateway/internal/domain/entity"
+)
+
+// SignatureHeader carries the signature of Iyzico webhook notifications.
+const SignatureHeader = "X-IYZ-SIGNATURE-V3"
+
+var ErrInvalidSignature = fmt.Errorf("iyzico webhook signature mismatch: %w", provider.ErrInvalidWebhookSignature)
+
+func (p *Provider) WebhookSignature(headers http.Header) string {
+	return headers.Get(SignatureHeader)
+}
+
+// VerifyWebhook accepts two kinds of payloads on the same endpoint:
+//   - JSON notifications sent by Iyzico, signed with X-IYZ-SIGNATURE-V3;
+//   - the form the buyer's browser posts to the callback URL after the
+//     checkout form. It carries only the token and no signature, so it is
+//     verified by retrieving the form and checking Iyzico's response signature.
+func (p *Provider) VerifyWebhook(ctx context.Context, webhookCtx *provider.WebhookContext) error {
+	operation := "verify_webhook"
+	start := time.Now()
+
+	var err error
+	if isNotification(webhookCtx.Payload) {
+		signature := webhookCtx.Signature
+		if signature == "" && webhookCtx.Headers != nil {
+			signature = webhookCtx.Headers.Get(SignatureHeader)
+		}
+		err = p.verifyNotification(webhookCtx.Payload, signature)
+	} else {
+		err = p.verifyCallback(ctx, webhookCtx.Payload)
+	}
+
+	p.recordRequest(operation, start, err)
+	return err
+}
+
+func (p *Provider) verifyNotification(payload []byte, signature string) error {
+	var n iyzicoNotification
+	if err := json.Unmarshal(payload, &n); err != nil {
+		return fmt.Errorf("failed to parse iyzico notification %w", err)
+	}
+
+	expected := SignNotification(p.cfg.SecretKey, n.IyziEventType, n.IyziPaymentID.String(), n.Token, n.PaymentConversationID, n.Status)
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func (p *Provider) verifyCallback(ctx context.Context, payload []byte) error {
+	token, err := callbackToken(payload)
+	if err != nil {
+		return err
+	}
+	_, _, err = p.retrieveCheckoutForm(ctx, token)
+	return err
+}
+
+// SignNotification computes X-IYZ-SIGNATURE-V3 for a checkout form
+// notification: a hex HMAC-SHA256 keyed with the secret key over the secret key
+// followed by the event fields.
+func SignNotification(secretKey, eventType, paymentID, token, conversationID, status string) string {
+	mac := hmac.New(sha256.New, []byte(secretKey))
+	mac.Write([]byte(secretKey + eventType + paymentID + token + conversationID + status))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ParseWebhook maps a successful checkout to pending, meaning ready to be
+// confirmed, so the webhook use case retrieves the form through Capture before
+// marking the payment as succeeded.
+func (p *Provider) ParseWebhook(payload []byte) (*provider.WebhookEvent, error) {
+	if !isNotification(payload) {
+		token, err := callbackToken(payload)
+		if err != nil {
+			return nil, err
+		}
+		return &provider.WebhookEvent{
+			ProviderID: providerID,
+			// a form is completed once, so a repeated post of its token is a
+			// browser resubmission
+			EventID:           "CHECKOUT_FORM_CALLBACK:" + token,
+			EventType:         "CHECKOUT_FORM_CALLBACK",
+			ProviderPaymentID: token,
+			Status:            entity.PaymentStatusPending,
+			CreateTime:        time.Now().UTC(),
+			RawPayload:        string(payload),
+		}, nil
+	}
+
+	var n iyzicoNotification
+	if err := json.Unmarshal(payload, &n); err != nil {
+		return nil, fmt.Errorf("failed to parse iyzico notification %w", err)
+	}
+
+	// Iyzico notifications have no ID of their own; retries repeat the event
+	// type, form token and status.
+	event := &provider.WebhookEvent{
+		ProviderID:        providerID,
+		EventID:           n.IyziEventType + ":" + n.Token + ":" + n.Status,
+		EventType:         n.IyziEventType,
+		ProviderPaymentID: n.Token,
+		CreateTime:        time.UnixMilli(n.IyziEventTime).UTC(),
+		RawPayload:        string(payload),
+	}
+
+	switch n.Status {
+	case "SUCCESS":
+		event.Status = entity.PaymentStatusPending
+	case "FAILURE":
+		event.Status = entity.PaymentStatusFailed
+	default:
+		event.Ignored = true
+	}
+
+	return event, nil
+}
+
+func isNotification(payload []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(payload), []byte("{"))
+}
+
+func callbackToken(payload []byte) (string, error) {
+	form, err := url.ParseQuery(string(payload))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse iyzico callback %w", err)
+	}
+	token := form.Get("token")
+	if token == "" {
+		return "", fmt.Errorf("iyzico callback has no token")
+	}
+	return token, nil
+}
+
+type iyzicoNotification struct {
+	PaymentConversationID string      `json:"paymentConversationId"`
+	MerchantID            json.Number `json:"merchantId"`
+	Token                 string      `json:"token"`
+	Status                string      `json:"status"`
+	IyziReferenceCode     string      `json:"iyziReferenceCode"`
+	IyziEventType         string      `json:"iyziEventType"`
+	IyziEventTime         int64       `json:"iyziEventTime"`
+	IyziPaymentID         json.Number `json:"iyziPaymentId"`
+}
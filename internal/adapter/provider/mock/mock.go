@@ -0,0 +1,491 @@
+// Package mock is a sandbox payment provider for local development and
+// end-to-end tests. Outcomes are scripted per payment, either through the
+// mock_scenario metadata key or through magic amounts, and successful payments
+// are confirmed with a signed webhook posted back to this service, so the whole
+// flow runs without real provider credentials.
+package mock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/omerbeden/paymentgateway/internal/adapter/provider"
+	"github.com/omerbeden/paymentgateway/internal/domain/entity"
+	"github.com/omerbeden/paymentgateway/internal/domain/money"
+	"github.com/omerbeden/paymentgateway/internal/infrastructure/config"
+	"github.com/omerbeden/paymentgateway/internal/infrastructure/metrics"
+)
+
+// Scenario scripts how the mock provider handles a payment.
+type Scenario string
+
+const (
+	// ScenarioSuccess accepts the payment and sends the approval webhook after
+	// the configured webhook delay.
+	ScenarioSuccess Scenario = "success"
+	// ScenarioDecline rejects the payment when it is created.
+	ScenarioDecline Scenario = "decline"
+	// ScenarioTimeout blocks the create call until the caller gives up.
+	ScenarioTimeout Scenario = "timeout"
+	// ScenarioAsyncWebhook accepts the payment and sends the approval webhook
+	// after the delay given in mock_webhook_delay, 5s by default.
+	ScenarioAsyncWebhook Scenario = "async_webhook"
+	// ScenarioCaptureFailure accepts the payment and sends the approval
+	// webhook, but every capture fails.
+	ScenarioCaptureFailure Scenario = "capture_failure"
+)
+
+const (
+	ScenarioMetadataKey = "mock_scenario"
+	// WebhookDelayMetadataKey holds a Go duration ("1m30s") or a number of
+	// seconds ("10").
+	WebhookDelayMetadataKey = "mock_webhook_delay"
+)
+
+// Magic amounts, in minor units, select a scenario when the payment has no
+// mock_scenario metadata. Any other amount succeeds.
+const (
+	AmountAsyncWebhook   int64 = 20200
+	AmountDecline        int64 = 40002
+	AmountTimeout        int64 = 40800
+	AmountCaptureFailure int64 = 50200
+)
+
+const (
+	providerID               = "mock"
+	defaultAsyncWebhookDelay = 5 * time.Second
+	webhookDeliveryTimeout   = 10 * time.Second
+)
+
+var (
+	ErrDeclined            = errors.New("mock payment declined")
+	ErrCaptureFailed       = errors.New("mock capture failed")
+	ErrPaymentNotFound     = errors.New("mock payment not found")
+	ErrRefundExceedsAmount = errors.New("mock refund exceeds the captured amount")
+	ErrCaptureExceedsHold  = errors.New("mock capture exceeds the authorized amount")
+	ErrAlreadyCaptured     = errors.New("mock payment already captured")
+	ErrVoided              = errors.New("mock payment voided")
+	ErrCancelled           = errors.New("mock payment cancelled")
+	ErrUnknownScenario     = errors.New("unknown mock scenario")
+)
+
+// Provider keeps its payments in memory, so payments created before a restart
+// can no longer be captured or refunded.
+type Provider struct {
+	httpClient *http.Client
+	cfg        config.Mock
+	metrics    *metrics.Metrics
+
+	mu       sync.Mutex
+	seq      int
+	payments map[string]*mockPayment
+}
+
+type mockPayment struct {
+	ID        string
+	PaymentID string
+	Scenario  Scenario
+	Amount    money.Money
+	// Approved is set once the approval webhook has been sent.
+	Approved        bool
+	AuthorizationID string
+	CaptureID       string
+	Captured        money.Money
+	Voided          bool
+	Cancelled       bool
+	Refunded        money.Money
+}
+
+func NewProvider(cfg config.Mock, metrics *metrics.Metrics) *Provider {
+	return &Provider{
+		httpClient: &http.Client{Timeout: webhookDeliveryTimeout},
+		cfg:        cfg,
+		metrics:    metrics,
+		payments:   make(map[string]*mockPayment),
+	}
+}
+
+func (p *Provider) CreatePayment(ctx context.Context, payment *entity.Payment) (*provider.CreatePaymentResult, error) {
+	start := time.Now()
+	operation := "create_payment"
+
+	scenario, err := scenarioFor(payment)
+	request, _ := json.Marshal(mockPaymentRequest{
+		PaymentID: payment.ID,
+		Amount:    payment.Amount.Amount,
+		Currency:  payment.Amount.Currency,
+		Scenario:  scenario,
+	})
+	exchange := provider.Exchange{Request: string(request)}
+
+	if err == nil {
+		switch scenario {
+		case ScenarioDecline:
+			err = ErrDeclined
+			exchange.Response = `{"error":"card_declined"}`
+		case ScenarioTimeout:
+			err = p.wait(ctx)
+		}
+	}
+	if err != nil {
+		p.recordRequest(operation, start, err)
+		return nil, &provider.Error{Operation: operation, Exchange: exchange, Err: err}
+	}
+
+	delay := p.cfg.WebhookDelay
+	if scenario == ScenarioAsyncWebhook {
+		delay = webhookDelay(payment.Metadata)
+	}
+
+	p.mu.Lock()
+	mp := &mockPayment{
+		ID:        p.nextID("mock_pay"),
+		PaymentID: payment.ID,
+		Scenario:  scenario,
+		Amount:    payment.Amount,
+		Refunded:  money.New(0, payment.Amount.Currency),
+	}
+	p.payments[mp.ID] = mp
+	p.mu.Unlock()
+
+	response, _ := json.Marshal(mockPaymentResponse{ID: mp.ID, Status: "requires_capture", Scenario: scenario})
+	exchange.Response = string(response)
+
+	time.AfterFunc(delay, func() { p.deliverWebhook(mp.ID, eventApproved) })
+	p.recordRequest(operation, start, nil)
+
+	return &provider.CreatePaymentResult{
+		ProviderPaymentID: mp.ID,
+		Status:            entity.PaymentStatusPending,
+		Amount:            payment.Amount,
+		Metadata:          map[string]string{ScenarioMetadataKey: string(scenario)},
+		Exchange:          exchange,
+	}, nil
+}
+
+// Authorize is idempotent: authorizing an authorized payment returns the same
+// authorization.
+func (p *Provider) Authorize(ctx context.Context, id string) (*provider.AuthorizeResult, error) {
+	start := time.Now()
+	operation := "authorize_payment"
+	exchange := provider.Exchange{Request: fmt.Sprintf(`{"id":%q}`, id)}
+
+	p.mu.Lock()
+	mp, ok := p.payments[id]
+	var err error
+	switch {
+	case !ok:
+		err = ErrPaymentNotFound
+	case mp.Voided:
+		err = ErrVoided
+	case mp.Cancelled:
+		err = ErrCancelled
+	case mp.CaptureID != "":
+		err = ErrAlreadyCaptured
+	case mp.AuthorizationID == "":
+		mp.AuthorizationID = p.nextID("mock_auth")
+	}
+	var result provider.AuthorizeResult
+	if err == nil {
+		result = provider.AuthorizeResult{
+			ProviderPaymentID:       mp.ID,
+			ProviderAuthorizationID: mp.AuthorizationID,
+			Status:                  entity.PaymentStatusAuthorized,
+			Amount:                  mp.Amount,
+		}
+	}
+	p.mu.Unlock()
+
+	p.recordRequest(operation, start, err)
+	if err != nil {
+		return nil, &provider.Error{Operation: operation, Exchange: exchange, Err: err}
+	}
+
+	exchange.Response = fmt.Sprintf(`{"id":%q,"status":"authorized","authorization_id":%q}`, result.ProviderPaymentID, result.ProviderAuthorizationID)
+	result.Exchange = exchange
+	return &result, nil
+}
+
+// Capture is idempotent: capturing a captured payment returns the same capture.
+// A partial capture releases the rest of the amount.
+func (p *Provider) Capture(ctx context.Context, req provider.CaptureRequest) (*provider.CaptureResult, error) {
+	start := time.Now()
+	operation := "capture_payment"
+	request, _ := json.Marshal(req)
+	exchange := provider.Exchange{Request: string(request)}
+
+	p.mu.Lock()
+	mp, ok := p.payments[req.ProviderPaymentID]
+	var err error
+	switch {
+	case !ok:
+		err = ErrPaymentNotFound
+	case mp.Scenario == ScenarioCaptureFailure:
+		err = ErrCaptureFailed
+		exchange.Response = `{"error":"capture_failed"}`
+	case mp.Voided:
+		err = ErrVoided
+	case mp.Cancelled:
+		err = ErrCancelled
+	case mp.CaptureID != "":
+		// already captured, the same capture is returned
+	case req.Amount.IsPositive() && (req.Amount.Currency != mp.Amount.Currency || req.Amount.Amount > mp.Amount.Amount):
+		err = ErrCaptureExceedsHold
+	default:
+		mp.CaptureID = p.nextID("mock_cap")
+		mp.Captured = mp.Amount
+		if req.Amount.IsPositive() {
+			mp.Captured = req.Amount
+		}
+	}
+	var result provider.CaptureResult
+	if err == nil {
+		result = provider.CaptureResult{
+			ProviderPaymentID: mp.ID,
+			ProviderCaptureID: mp.CaptureID,
+			Status:            entity.PaymentStatusSucceeded,
+			Amount:            mp.Captured,
+			ProviderFee:       money.New(0, mp.Amount.Currency),
+		}
+	}
+	p.mu.Unlock()
+
+	p.recordRequest(operation, start, err)
+	if err != nil {
+		return nil, &provider.Error{Operation: operation, Exchange: exchange, Err: err}
+	}
+
+	response, _ := json.Marshal(mockPaymentResponse{ID: result.ProviderPaymentID, Status: "succeeded", CaptureID: result.ProviderCaptureID})
+	exchange.Response = string(response)
+	result.Exchange = exchange
+	return &result, nil
+}
+
+func (p *Provider) Void(ctx context.Context, req provider.VoidRequest) (*provider.VoidResult, error) {
+	start := time.Now()
+	operation := "void_payment"
+	request, _ := json.Marshal(req)
+	exchange := provider.Exchange{Request: string(request)}
+
+	p.mu.Lock()
+	mp, ok := p.payments[req.ProviderPaymentID]
+	var err error
+	switch {
+	case !ok:
+		err = ErrPaymentNotFound
+	case mp.CaptureID != "":
+		err = ErrAlreadyCaptured
+	default:
+		mp.Voided = true
+	}
+	p.mu.Unlock()
+
+	p.recordRequest(operation, start, err)
+	if err != nil {
+		return nil, &provider.Error{Operation: operation, Exchange: exchange, Err: err}
+	}
+
+	exchange.Response = fmt.Sprintf(`{"id":%q,"status":"voided"}`, mp.ID)
+	return &provider.VoidResult{
+		ProviderVoidID: mp.ID,
+		Exchange:       exchange,
+	}, nil
+}
+
+// Cancel is idempotent. A cancelled payment is never approved, so its pending
+// webhook is dropped.
+func (p *Provider) Cancel(ctx context.Context, req provider.CancelRequest) (*provider.CancelResult, error) {
+	start := time.Now()
+	operation := "cancel_payment"
+	request, _ := json.Marshal(req)
+	exchange := provider.Exchange{Request: string(request)}
+
+	p.mu.Lock()
+	mp, ok := p.payments[req.ProviderPaymentID]
+	var err error
+	switch {
+	case !ok:
+		err = ErrPaymentNotFound
+	case mp.CaptureID != "":
+		err = ErrAlreadyCaptured
+	default:
+		mp.Cancelled = true
+	}
+	p.mu.Unlock()
+
+	p.recordRequest(operation, start, err)
+	if err != nil {
+		return nil, &provider.Error{Operation: operation, Exchange: exchange, Err: err}
+	}
+
+	exchange.Response = fmt.Sprintf(`{"id":%q,"status":"cancelled"}`, mp.ID)
+	return &provider.CancelResult{
+		ProviderCancelID: mp.ID,
+		Exchange:         exchange,
+	}, nil
+}
+
+func (p *Provider) Refund(ctx context.Context, req provider.RefundRequest) (*provider.RefundResult, error) {
+	start := time.Now()
+	operation := "refund_payment"
+	request, _ := json.Marshal(req)
+	exchange := provider.Exchange{Request: string(request)}
+
+	p.mu.Lock()
+	var (
+		refundID string
+		err      error
+	)
+	mp, ok := p.payments[req.ProviderPaymentID]
+	if !ok || mp.CaptureID == "" {
+		err = ErrPaymentNotFound
+	} else if refunded, addErr := mp.Refunded.Add(req.Amount); addErr != nil {
+		err = addErr
+	} else if !req.Amount.IsPositive() || refunded.Amount > mp.Captured.Amount {
+		// Add succeeded, so both amounts are in the payment's currency.
+		err = ErrRefundExceedsAmount
+	} else {
+		mp.Refunded = refunded
+		refundID = p.nextID("mock_ref")
+	}
+	p.mu.Unlock()
+
+	p.recordRequest(operation, start, err)
+	if err != nil {
+		return nil, &provider.Error{Operation: operation, Exchange: exchange, Err: err}
+	}
+
+	exchange.Response = fmt.Sprintf(`{"id":%q,"status":"succeeded"}`, refundID)
+	return &provider.RefundResult{
+		ProviderRefundID: refundID,
+		Status:           entity.TransactionStatusSucceeded,
+		Amount:           req.Amount,
+		Exchange:         exchange,
+	}, nil
+}
+
+func (p *Provider) GetPaymentStatus(ctx context.Context, providerPaymentID string) (*provider.PaymentStatusResult, error) {
+	start := time.Now()
+	operation := "get_payment_status"
+	exchange := provider.Exchange{Request: fmt.Sprintf(`{"id":%q}`, providerPaymentID)}
+
+	p.mu.Lock()
+	mp, ok := p.payments[providerPaymentID]
+	var result provider.PaymentStatusResult
+	if ok {
+		result = provider.PaymentStatusResult{ProviderPaymentID: mp.ID, Amount: mp.Amount}
+		switch {
+		case mp.Voided, mp.Cancelled:
+			result.Status = entity.PaymentStatusCancelled
+		case mp.CaptureID != "":
+			result.Status = entity.PaymentStatusSucceeded
+			result.Amount = mp.Captured
+		case mp.AuthorizationID != "":
+			result.Status = entity.PaymentStatusAuthorized
+		case mp.Approved:
+			result.Status = entity.PaymentStatusPending
+		}
+	}
+	p.mu.Unlock()
+
+	if !ok {
+		p.recordRequest(operation, start, ErrPaymentNotFound)
+		return nil, &provider.Error{Operation: operation, Exchange: exchange, Err: ErrPaymentNotFound}
+	}
+	p.recordRequest(operation, start, nil)
+
+	exchange.Response = fmt.Sprintf(`{"id":%q,"status":%q}`, result.ProviderPaymentID, result.Status)
+	result.Exchange = exchange
+	return &result, nil
+}
+
+func (p *Provider) wait(ctx context.Context) error {
+	timer := time.NewTimer(p.cfg.Timeout)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("mock provider timed out: %w", ctx.Err())
+	case <-timer.C:
+		return fmt.Errorf("mock provider timed out: %w", context.DeadlineExceeded)
+	}
+}
+
+// nextID must be called with p.mu held.
+func (p *Provider) nextID(prefix string) string {
+	p.seq++
+	return fmt.Sprintf("%s_%d_%d", prefix, time.Now().UnixNano(), p.seq)
+}
+
+func scenarioFor(payment *entity.Payment) (Scenario, error) {
+	if v := payment.Metadata[ScenarioMetadataKey]; v != "" {
+		switch s := Scenario(v); s {
+		case ScenarioSuccess, ScenarioDecline, ScenarioTimeout, ScenarioAsyncWebhook, ScenarioCaptureFailure:
+			return s, nil
+		}
+		return "", fmt.Errorf("%w: %q", ErrUnknownScenario, v)
+	}
+
+	switch payment.Amount.Amount {
+	case AmountAsyncWebhook:
+		return ScenarioAsyncWebhook, nil
+	case AmountDecline:
+		return ScenarioDecline, nil
+	case AmountTimeout:
+		return ScenarioTimeout, nil
+	case AmountCaptureFailure:
+		return ScenarioCaptureFailure, nil
+	}
+	return ScenarioSuccess, nil
+}
+
+func webhookDelay(metadata map[string]string) time.Duration {
+	v := metadata[WebhookDelayMetadataKey]
+	if seconds, err := strconv.Atoi(v); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+		return d
+	}
+	return defaultAsyncWebhookDelay
+}
+
+func (p *Provider) recordRequest(operation string, start time.Time, err error) {
+	p.metrics.ProviderRequestDuration.WithLabelValues(
+		providerID,
+		operation,
+	).Observe(time.Since(start).Seconds())
+
+	status := "success"
+	if err != nil {
+		status = "error"
+		p.metrics.ProviderErrors.WithLabelValues(
+			providerID,
+			"api_error",
+		).Inc()
+	}
+	p.metrics.ProviderRequestsTotal.WithLabelValues(
+		providerID,
+		operation,
+		status,
+	).Inc()
+}
+
+type mockPaymentRequest struct {
+	PaymentID string   `json:"payment_id"`
+	Amount    int64    `json:"amount"`
+	Currency  string   `json:"currency"`
+	Scenario  Scenario `json:"scenario,omitempty"`
+}
+
+type mockPaymentResponse struct {
+	ID        string   `json:"id"`
+	Status    string   `json:"status"`
+	Scenario  Scenario `json:"scenario,omitempty"`
+	CaptureID string   `json:"capture_id,omitempty"`
+}
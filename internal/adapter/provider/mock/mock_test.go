@@ -0,0 +1,311 @@
+package mock
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/omerbeden/paymentgateway/internal/adapter/provider"
+	"github.com/omerbeden/paymentgateway/internal/domain/entity"
+	"github.com/omerbeden/paymentgateway/internal/domain/money"
+	"github.com/omerbeden/paymentgateway/internal/infrastructure/config"
+	"github.com/omerbeden/paymentgateway/internal/infrastructure/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testWebhookSecret = "mock_secret"
+
+// metrics.New registers collectors globally, so the package shares one set.
+var testMetrics = metrics.New()
+
+type receivedWebhook struct {
+	payload   []byte
+	signature string
+}
+
+// newTestProvider returns a provider whose webhooks are delivered to a local
+// server and handed to the test through the returned channel.
+func newTestProvider(t *testing.T) (*Provider, <-chan receivedWebhook) {
+	t.Helper()
+	webhooks := make(chan receivedWebhook, 4)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload, _ := io.ReadAll(r.Body)
+		webhooks <- receivedWebhook{payload: payload, signature: r.Header.Get(SignatureHeader)}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status":"success"}`))
+	}))
+	t.Cleanup(server.Close)
+
+	p := NewProvider(config.Mock{
+		WebhookURL:    server.URL,
+		WebhookSecret: testWebhookSecret,
+		WebhookDelay:  0,
+		Timeout:       time.Second,
+	}, testMetrics)
+	return p, webhooks
+}
+
+func newTestPayment(amount int64, metadata map[string]string) *entity.Payment {
+	return &entity.Payment{
+		ID:         "pay_123",
+		Amount:     money.New(amount, "USD"),
+		ProviderID: providerID,
+		Status:     entity.PaymentStatusPending,
+		Metadata:   metadata,
+	}
+}
+
+func waitForWebhook(t *testing.T, webhooks <-chan receivedWebhook, timeout time.Duration) receivedWebhook {
+	t.Helper()
+	select {
+	case w := <-webhooks:
+		return w
+	case <-time.After(timeout):
+		t.Fatal("webhook was not delivered")
+		return receivedWebhook{}
+	}
+}
+
+func TestCreatePayment_SuccessDeliversSignedWebhook(t *testing.T) {
+	// Arrange
+	p, webhooks := newTestProvider(t)
+	ctx := context.Background()
+
+	// Act
+	result, err := p.CreatePayment(ctx, newTestPayment(1050, nil))
+	require.NoError(t, err)
+	webhook := waitForWebhook(t, webhooks, time.Second)
+
+	// Assert
+	assert.Equal(t, entity.PaymentStatusPending, result.Status)
+	assert.Equal(t, string(ScenarioSuccess), result.Metadata[ScenarioMetadataKey])
+
+	require.NoError(t, p.VerifyWebhook(ctx, &provider.WebhookContext{Payload: webhook.payload, Signature: webhook.signature}))
+	evt, err := p.ParseWebhook(webhook.payload)
+	require.NoError(t, err)
+	assert.NotEmpty(t, evt.EventID)
+	assert.Equal(t, result.ProviderPaymentID, evt.ProviderPaymentID)
+	assert.Equal(t, entity.PaymentStatusPending, evt.Status)
+	assert.Equal(t, money.New(1050, "USD"), evt.Amount)
+
+	capture, err := p.Capture(ctx, provider.CaptureRequest{ProviderPaymentID: evt.ProviderPaymentID})
+	require.NoError(t, err)
+	assert.Equal(t, entity.PaymentStatusSucceeded, capture.Status)
+	assert.Equal(t, money.New(1050, "USD"), capture.Amount)
+}
+
+func TestCreatePayment_Scenarios(t *testing.T) {
+	tests := []struct {
+		name    string
+		payment *entity.Payment
+		wantErr error
+	}{
+		{"decline by amount", newTestPayment(AmountDecline, nil), ErrDeclined},
+		{"decline by metadata", newTestPayment(1050, map[string]string{ScenarioMetadataKey: "decline"}), ErrDeclined},
+		{"timeout", newTestPayment(AmountTimeout, nil), context.DeadlineExceeded},
+		{"unknown scenario", newTestPayment(1050, map[string]string{ScenarioMetadataKey: "explode"}), ErrUnknownScenario},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _ := newTestProvider(t)
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+
+			result, err := p.CreatePayment(ctx, tt.payment)
+
+			assert.Nil(t, result)
+			var providerErr *provider.Error
+			require.True(t, errors.As(err, &providerErr))
+			assert.True(t, errors.Is(err, tt.wantErr), "got %v", err)
+		})
+	}
+}
+
+func TestCreatePayment_AsyncWebhookWaitsForDelay(t *testing.T) {
+	// Arrange
+	p, webhooks := newTestProvider(t)
+	payment := newTestPayment(1050, map[string]string{
+		ScenarioMetadataKey:     string(ScenarioAsyncWebhook),
+		WebhookDelayMetadataKey: "200ms",
+	})
+
+	// Act
+	start := time.Now()
+	_, err := p.CreatePayment(context.Background(), payment)
+	require.NoError(t, err)
+	waitForWebhook(t, webhooks, 2*time.Second)
+
+	// Assert
+	assert.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+}
+
+func TestCapture_Failure(t *testing.T) {
+	// Arrange
+	p, webhooks := newTestProvider(t)
+	ctx := context.Background()
+	created, err := p.CreatePayment(ctx, newTestPayment(AmountCaptureFailure, nil))
+	require.NoError(t, err)
+	waitForWebhook(t, webhooks, time.Second)
+
+	// Act
+	result, err := p.Capture(ctx, provider.CaptureRequest{ProviderPaymentID: created.ProviderPaymentID})
+
+	// Assert
+	assert.Nil(t, result)
+	assert.True(t, errors.Is(err, ErrCaptureFailed))
+}
+
+func TestAuthorize_PartialCapture(t *testing.T) {
+	// Arrange
+	p, _ := newTestProvider(t)
+	ctx := context.Background()
+	created, err := p.CreatePayment(ctx, newTestPayment(1050, nil))
+	require.NoError(t, err)
+
+	// Act
+	authorization, err := p.Authorize(ctx, created.ProviderPaymentID)
+	require.NoError(t, err)
+	_, exceedErr := p.Capture(ctx, provider.CaptureRequest{
+		ProviderPaymentID: created.ProviderPaymentID,
+		Amount:            money.New(2000, "USD"),
+	})
+	capture, err := p.Capture(ctx, provider.CaptureRequest{
+		ProviderPaymentID: created.ProviderPaymentID,
+		Amount:            money.New(700, "USD"),
+	})
+
+	// Assert
+	assert.Equal(t, entity.PaymentStatusAuthorized, authorization.Status)
+	assert.True(t, errors.Is(exceedErr, ErrCaptureExceedsHold))
+	require.NoError(t, err)
+	assert.Equal(t, money.New(700, "USD"), capture.Amount)
+
+	_, err = p.Refund(ctx, provider.RefundRequest{
+		ProviderPaymentID: created.ProviderPaymentID,
+		Amount:            money.New(800, "USD"),
+	})
+	assert.True(t, errors.Is(err, ErrRefundExceedsAmount))
+}
+
+func TestVoid(t *testing.T) {
+	// Arrange
+	p, _ := newTestProvider(t)
+	ctx := context.Background()
+	created, err := p.CreatePayment(ctx, newTestPayment(1050, nil))
+	require.NoError(t, err)
+	_, err = p.Authorize(ctx, created.ProviderPaymentID)
+	require.NoError(t, err)
+
+	// Act
+	_, err = p.Void(ctx, provider.VoidRequest{ProviderPaymentID: created.ProviderPaymentID})
+
+	// Assert
+	require.NoError(t, err)
+	_, err = p.Capture(ctx, provider.CaptureRequest{ProviderPaymentID: created.ProviderPaymentID})
+	assert.True(t, errors.Is(err, ErrVoided))
+}
+
+func TestCancel_DropsApprovalWebhook(t *testing.T) {
+	// Arrange
+	p, webhooks := newTestProvider(t)
+	ctx := context.Background()
+	payment := newTestPayment(1050, map[string]string{
+		ScenarioMetadataKey:     string(ScenarioAsyncWebhook),
+		WebhookDelayMetadataKey: "100ms",
+	})
+	created, err := p.CreatePayment(ctx, payment)
+	require.NoError(t, err)
+
+	// Act
+	_, err = p.Cancel(ctx, provider.CancelRequest{ProviderPaymentID: created.ProviderPaymentID})
+
+	// Assert
+	require.NoError(t, err)
+	select {
+	case <-webhooks:
+		t.Fatal("webhook delivered for a cancelled payment")
+	case <-time.After(300 * time.Millisecond):
+	}
+	_, err = p.Capture(ctx, provider.CaptureRequest{ProviderPaymentID: created.ProviderPaymentID})
+	assert.True(t, errors.Is(err, ErrCancelled))
+}
+
+func TestGetPaymentStatus(t *testing.T) {
+	// Arrange
+	p, webhooks := newTestProvider(t)
+	ctx := context.Background()
+	created, err := p.CreatePayment(ctx, newTestPayment(1050, nil))
+	require.NoError(t, err)
+	waitForWebhook(t, webhooks, time.Second)
+
+	// Act
+	approved, err := p.GetPaymentStatus(ctx, created.ProviderPaymentID)
+	require.NoError(t, err)
+	_, err = p.Capture(ctx, provider.CaptureRequest{ProviderPaymentID: created.ProviderPaymentID})
+	require.NoError(t, err)
+	captured, err := p.GetPaymentStatus(ctx, created.ProviderPaymentID)
+	require.NoError(t, err)
+
+	// Assert
+	assert.Equal(t, entity.PaymentStatusPending, approved.Status)
+	assert.Equal(t, entity.PaymentStatusSucceeded, captured.Status)
+	assert.Equal(t, money.New(1050, "USD"), captured.Amount)
+
+	_, err = p.GetPaymentStatus(ctx, "mock_pay_unknown")
+	assert.True(t, errors.Is(err, ErrPaymentNotFound))
+}
+
+func TestRefund_Partial(t *testing.T) {
+	// Arrange
+	p, _ := newTestProvider(t)
+	ctx := context.Background()
+	created, err := p.CreatePayment(ctx, newTestPayment(1050, nil))
+	require.NoError(t, err)
+	_, err = p.Capture(ctx, provider.CaptureRequest{ProviderPaymentID: created.ProviderPaymentID})
+	require.NoError(t, err)
+
+	// Act
+	result, err := p.Refund(ctx, provider.RefundRequest{
+		ProviderPaymentID: created.ProviderPaymentID,
+		Amount:            money.New(500, "USD"),
+	})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, entity.TransactionStatusSucceeded, result.Status)
+	assert.Equal(t, money.New(500, "USD"), result.Amount)
+
+	_, err = p.Refund(ctx, provider.RefundRequest{
+		ProviderPaymentID: created.ProviderPaymentID,
+		Amount:            money.New(600, "USD"),
+	})
+	assert.True(t, errors.Is(err, ErrRefundExceedsAmount))
+}
+
+func TestVerifyWebhook_InvalidSignature(t *testing.T) {
+	p, _ := newTestProvider(t)
+	payload := []byte(`{"id":"evt_1","type":"payment.approved"}`)
+
+	err := p.VerifyWebhook(context.Background(), &provider.WebhookContext{
+		Payload:   payload,
+		Signature: SignPayload("other_secret", payload),
+	})
+
+	assert.True(t, errors.Is(err, ErrInvalidSignature))
+}
+
+func TestParseWebhook_IgnoresUnknownType(t *testing.T) {
+	p, _ := newTestProvider(t)
+
+	evt, err := p.ParseWebhook([]byte(`{"id":"evt_1","type":"payment.created","payment_id":"mock_1","amount":1050,"currency":"USD","created":1700000000}`))
+
+	require.NoError(t, err)
+	assert.True(t, evt.Ignored)
+	assert.Empty(t, evt.Status)
+}
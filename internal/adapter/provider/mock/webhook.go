@@ -0,0 +1,145 @@
+package mock
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/omerbeden/paymentgateway/internal/adapter/provider"
+	"github.com/omerbeden/paymentgateway/internal/domain/entity"
+	"github.com/omerbeden/paymentgateway/internal/domain/money"
+	"github.com/omerbeden/paymentgateway/internal/pkg/httpclient"
+)
+
+// SignatureHeader carries the hex HMAC-SHA256 of the webhook body, keyed with
+// the configured webhook secret.
+const SignatureHeader = "X-Mock-Signature"
+
+const (
+	eventApproved = "payment.approved"
+	eventFailed   = "payment.failed"
+)
+
+var ErrInvalidSignature = fmt.Errorf("mock webhook signature mismatch: %w", provider.ErrInvalidWebhookSignature)
+
+func (p *Provider) WebhookSignature(headers http.Header) string {
+	return headers.Get(SignatureHeader)
+}
+
+func (p *Provider) VerifyWebhook(ctx context.Context, webhookCtx *provider.WebhookContext) error {
+	signature := webhookCtx.Signature
+	if signature == "" && webhookCtx.Headers != nil {
+		signature = webhookCtx.Headers.Get(SignatureHeader)
+	}
+	if !hmac.Equal([]byte(signature), []byte(SignPayload(p.cfg.WebhookSecret, webhookCtx.Payload))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// ParseWebhook maps an approval to pending, meaning ready to capture, the same
+// way real providers report an approved order.
+func (p *Provider) ParseWebhook(payload []byte) (*provider.WebhookEvent, error) {
+	var w mockWebhook
+	if err := json.Unmarshal(payload, &w); err != nil {
+		return nil, fmt.Errorf("failed to parse mock webhook %w", err)
+	}
+	if _, err := money.Exponent(w.Currency); err != nil {
+		return nil, fmt.Errorf("failed to parse mock webhook amount: %w", err)
+	}
+
+	event := &provider.WebhookEvent{
+		ProviderID:        providerID,
+		EventID:           w.ID,
+		EventType:         w.Type,
+		ProviderPaymentID: w.PaymentID,
+		Amount:            money.New(w.Amount, w.Currency),
+		CreateTime:        time.Unix(w.Created, 0).UTC(),
+		RawPayload:        string(payload),
+	}
+	switch w.Type {
+	case eventApproved:
+		event.Status = entity.PaymentStatusPending
+	case eventFailed:
+		event.Status = entity.PaymentStatusFailed
+	default:
+		event.Ignored = true
+	}
+	return event, nil
+}
+
+// SignPayload computes the SignatureHeader value for payload.
+func SignPayload(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// deliverWebhook posts a signed event for the payment to the configured
+// webhook URL. It runs on a timer, so failures only show up in the metrics.
+func (p *Provider) deliverWebhook(id, eventType string) {
+	start := time.Now()
+	operation := "deliver_webhook"
+
+	p.mu.Lock()
+	mp, ok := p.payments[id]
+	if ok && mp.Cancelled {
+		ok = false
+	}
+	var w mockWebhook
+	if ok {
+		if eventType == eventApproved {
+			mp.Approved = true
+		}
+		w = mockWebhook{
+			ID:        p.nextID("mock_evt"),
+			Type:      eventType,
+			PaymentID: mp.ID,
+			Amount:    mp.Amount.Amount,
+			Currency:  mp.Amount.Currency,
+			Created:   time.Now().Unix(),
+		}
+	}
+	p.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	payload, err := json.Marshal(w)
+	if err != nil {
+		p.recordRequest(operation, start, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), webhookDeliveryTimeout)
+	defer cancel()
+
+	headers := http.Header{}
+	headers.Set("Content-Type", "application/json")
+	headers.Set(SignatureHeader, SignPayload(p.cfg.WebhookSecret, payload))
+
+	var response json.RawMessage
+	err = httpclient.MakeRequest(httpclient.RequestParam[string]{
+		Client: p.httpClient,
+		Header: &headers,
+		Ctx:    ctx,
+		Method: http.MethodPost,
+		URL:    p.cfg.WebhookURL,
+		Body:   string(payload),
+	}, &response)
+	p.recordRequest(operation, start, err)
+}
+
+type mockWebhook struct {
+	ID        string `json:"id"`
+	Type      string `json:"type"`
+	PaymentID string `json:"payment_id"`
+	Amount    int64  `json:"amount"`
+	Currency  string `json:"currency"`
+	Created   int64  `json:"created"`
+}
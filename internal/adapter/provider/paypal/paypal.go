@@ -3,13 +3,14 @@ package paypal
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
-	"strconv"
 	"time"
 
 	"github.com/omerbeden/paymentgateway/internal/adapter/provider"
 	"github.com/omerbeden/paymentgateway/internal/domain/entity"
+	"github.com/omerbeden/paymentgateway/internal/domain/money"
 	"github.com/omerbeden/paymentgateway/internal/infrastructure/config"
 	"github.com/omerbeden/paymentgateway/internal/infrastructure/metrics"
 	"github.com/omerbeden/paymentgateway/internal/pkg/httpclient"
@@ -25,10 +26,17 @@ const (
 	pathCreatePayment        = "/v2/checkout/orders"
 	pathAuthz                = "/v1/oauth2/token"
 	pathVerifyEventSignature = "/v1/notifications/verify-webhook-signature"
-	pathCaptureOrder         = "/v2/checkout/orders/:%s/capture"
+	pathCaptureOrder         = "/v2/checkout/orders/%s/capture"
+	pathAuthorizeOrder       = "/v2/checkout/orders/%s/authorize"
+	pathCaptureAuthorization = "/v2/payments/authorizations/%s/capture"
+	pathVoidAuthorization    = "/v2/payments/authorizations/%s/void"
+	pathGetOrder             = "/v2/checkout/orders/%s"
+	pathRefundCapture        = "/v2/payments/captures/%s/refund"
 	providerID               = "paypal"
 )
 
+var ErrInvalidSignature = fmt.Errorf("paypal webhook event verification failed: %w", provider.ErrInvalidWebhookSignature)
+
 func NewProvider(cfg config.Paypal, metrics *metrics.Metrics) *Provider {
 	return &Provider{
 		httpClient: &http.Client{},
@@ -41,196 +49,475 @@ func (p *Provider) CreatePayment(ctx context.Context, payment *entity.Payment) (
 	start := time.Now()
 	operation := "create_payment"
 
-	body := PaypalRequest{
-		intent: "CAPTURE",
-		purchase_units: []struct {
-			amount struct {
-				currency_code string
-				value         string
-			}
-		}{
+	intent := "CAPTURE"
+	if payment.Intent == entity.PaymentIntentAuthorize {
+		intent = "AUTHORIZE"
+	}
+	body := paypalOrderRequest{
+		Intent: intent,
+		PurchaseUnits: []paypalPurchaseUnitRequest{
 			{
-				amount: struct {
-					currency_code string
-					value         string
-				}{
-					currency_code: payment.Currency,
-					value:         strconv.FormatFloat(payment.Amount, 'f', -1, 64),
-				},
+				ReferenceID: payment.ID,
+				Amount:      newPaypalAmount(payment.Amount),
 			},
 		},
 	}
 
-	// Fetch access token and set Authorization header
-	token, err := p.getAccessToken(ctx)
+	headers, err := p.authHeaders(ctx)
 	if err != nil {
-		p.metrics.ProviderErrors.WithLabelValues(
-			payment.ProviderID,
-			"api_auth_error",
-		).Inc()
 		return nil, err
 	}
-
-	headers := http.Header{}
-	headers.Set("Content-Type", "application/json")
 	headers.Set("Accept", "application/json")
-	headers.Set("Authorization", "Bearer "+token)
+	headers.Set("PayPal-Request-Id", payment.ID)
 
-	var response PayPalResponse
-	if err := httpclient.MakeRequest(httpclient.RequestParam[PaypalRequest]{
-		Client: p.httpClient,
-		Header: &headers,
-		Ctx:    ctx,
-		Method: http.MethodPost,
-		URL:    p.cfg.BaseURL + pathCreatePayment,
-		Body:   body,
-	}, &response); err != nil {
-		p.metrics.ProviderErrors.WithLabelValues(
-			payment.ProviderID,
-			"api_error",
-		).Inc()
-		p.metrics.ProviderRequestsTotal.WithLabelValues(
-			payment.ProviderID,
-			operation,
-			"error",
-		).Inc()
+	var response paypalOrderResponse
+	exchange, err := p.call(ctx, http.MethodPost, pathCreatePayment, headers, body, &response)
+	p.recordRequest(operation, start, err)
+	if err != nil {
+		return nil, &provider.Error{Operation: operation, Exchange: exchange, Err: err}
+	}
+
+	result := &provider.CreatePaymentResult{
+		ProviderPaymentID: response.ID,
+		Status:            orderStatus(response.Status),
+		Amount:            payment.Amount,
+		PaymentURL:        response.link("payer-action", "approve"),
+		Metadata:          map[string]string{},
+		Exchange:          exchange,
+	}
+	if len(response.PurchaseUnits) > 0 {
+		if amount, err := response.PurchaseUnits[0].Amount.money(); err == nil {
+			result.Amount = amount
+		}
+	}
+
+	return result, nil
+}
+
+// Authorize authorizes an approved order created with the AUTHORIZE intent.
+func (p *Provider) Authorize(ctx context.Context, id string) (*provider.AuthorizeResult, error) {
+	start := time.Now()
+	operation := "authorize_payment"
+
+	headers, err := p.authHeaders(ctx)
+	if err != nil {
 		return nil, err
 	}
+	headers.Set("PayPal-Request-Id", "authorize-"+id)
+
+	var response paypalOrderResponse
+	exchange, err := p.call(ctx, http.MethodPost, fmt.Sprintf(pathAuthorizeOrder, id), headers, struct{}{}, &response)
+	var authorization paypalAuthorization
+	if err == nil {
+		var ok bool
+		if authorization, ok = response.firstAuthorization(); !ok {
+			err = fmt.Errorf("paypal order %s has no authorization", id)
+		} else if authorization.Status != "CREATED" {
+			err = fmt.Errorf("unexpected authorization status %q", authorization.Status)
+		}
+	}
+	p.recordRequest(operation, start, err)
+	if err != nil {
+		return nil, &provider.Error{Operation: operation, Exchange: exchange, Err: fmt.Errorf("error while authorizing payment %w", err)}
+	}
 
-	duration := time.Since(start).Seconds()
-	p.metrics.ProviderRequestsTotal.WithLabelValues(
-		payment.ProviderID,
-		operation,
-		"success",
-	).Inc()
+	result := &provider.AuthorizeResult{
+		ProviderPaymentID:       response.ID,
+		ProviderAuthorizationID: authorization.ID,
+		Status:                  entity.PaymentStatusAuthorized,
+		Exchange:                exchange,
+	}
+	result.Amount, _ = authorization.Amount.money()
+	result.ExpiresAt, _ = time.Parse(time.RFC3339, authorization.ExpirationTime)
 
-	p.metrics.ProviderRequestDuration.WithLabelValues(
-		payment.ProviderID,
-		operation,
-	).Observe(duration)
-
-	amount, _ := strconv.ParseFloat(response.purchase_units[0].amount.value, 64)
-	return &provider.CreatePaymentResult{
-		ProviderPaymentID: response.id,
-		Status:            entity.PaymentStatus(response.status),
-		Amount:            amount,
-		Currency:          response.purchase_units[0].amount.currency_code,
-		PaymentURL:        response.links[1].href,
-		Metadata:          map[string]string{},
-	}, nil
+	return result, nil
 }
 
-func (p *Provider) Capture(ctx context.Context, id string) error {
+// Capture captures an order, or the authorization of an order created with
+// the AUTHORIZE intent. Only authorizations can be captured partially.
+func (p *Provider) Capture(ctx context.Context, req provider.CaptureRequest) (*provider.CaptureResult, error) {
 	start := time.Now()
 	operation := "capture_payment"
+	id := req.ProviderPaymentID
 
-	var paypalResponse paypalCaptureResponse
+	headers, err := p.authHeaders(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-	header := http.Header{}
-	header.Set("Content-Type", "application/json")
+	var order paypalOrderResponse
+	exchange, err := p.call(ctx, http.MethodGet, fmt.Sprintf(pathGetOrder, id), headers, nil, &order)
+	if err != nil {
+		p.recordRequest(operation, start, err)
+		return nil, &provider.Error{Operation: operation, Exchange: exchange, Err: fmt.Errorf("failed to get paypal order %s: %w", id, err)}
+	}
+	if order.Intent == "AUTHORIZE" {
+		return p.captureAuthorization(ctx, headers, order, req, start)
+	}
 
-	err := httpclient.MakeRequest(httpclient.RequestParam[any]{
-		Client: p.httpClient,
-		Header: &header,
-		Method: http.MethodPost,
-		URL:    p.cfg.BaseURL + fmt.Sprintf(pathCaptureOrder, id),
-		Ctx:    ctx,
-	}, &paypalResponse)
+	if req.IdempotencyKey != "" {
+		headers.Set("PayPal-Request-Id", req.IdempotencyKey)
+	} else {
+		headers.Set("PayPal-Request-Id", "capture-"+id)
+	}
 
-	duration := time.Since(start).Seconds()
-	p.metrics.ProviderRequestDuration.WithLabelValues(
-		providerID,
-		operation,
-	).Observe(duration)
+	var response paypalOrderResponse
+	exchange, err = p.call(ctx, http.MethodPost, fmt.Sprintf(pathCaptureOrder, id), headers, struct{}{}, &response)
+	if err == nil && response.Status != "COMPLETED" {
+		err = fmt.Errorf("unexpected order status %q", response.Status)
+	}
+	p.recordRequest(operation, start, err)
+	if err != nil {
+		return nil, &provider.Error{Operation: operation, Exchange: exchange, Err: fmt.Errorf("error while capturing payment %w", err)}
+	}
 
-	if paypalResponse.status == "COMPLETED" {
-		p.metrics.ProviderRequestsTotal.WithLabelValues(
-			providerID,
-			operation,
-			"success",
-		).Inc()
-		return nil
+	result := &provider.CaptureResult{
+		ProviderPaymentID: response.ID,
+		Status:            entity.PaymentStatusSucceeded,
+		Exchange:          exchange,
+	}
+	if capture, ok := response.firstCapture(); ok {
+		result.ProviderCaptureID = capture.ID
+		result.Amount, _ = capture.Amount.money()
+		result.ProviderFee, _ = capture.SellerReceivableBreakdown.PaypalFee.money()
 	}
 
-	p.metrics.ProviderRequestsTotal.WithLabelValues(
-		providerID,
-		operation,
-		"error",
-	).Inc()
+	return result, nil
+}
 
-	p.metrics.ProviderErrors.WithLabelValues(
-		providerID,
-		"api_error",
-	).Inc()
+func (p *Provider) captureAuthorization(ctx context.Context, headers http.Header, order paypalOrderResponse, req provider.CaptureRequest, start time.Time) (*provider.CaptureResult, error) {
+	operation := "capture_payment"
+
+	authorization, ok := order.firstAuthorization()
+	if !ok {
+		err := fmt.Errorf("paypal order %s has no authorization", order.ID)
+		p.recordRequest(operation, start, err)
+		return nil, &provider.Error{Operation: operation, Err: err}
+	}
+
+	if req.IdempotencyKey != "" {
+		headers.Set("PayPal-Request-Id", req.IdempotencyKey)
+	} else {
+		headers.Set("PayPal-Request-Id", "capture-"+authorization.ID)
+	}
 
-	return fmt.Errorf("error while capturing payment %w", err)
+	// A final capture releases whatever was not captured.
+	body := paypalCaptureAuthorizationRequest{FinalCapture: true}
+	if req.Amount.IsPositive() {
+		amount := newPaypalAmount(req.Amount)
+		body.Amount = &amount
+	}
+
+	var capture paypalCapture
+	exchange, err := p.call(ctx, http.MethodPost, fmt.Sprintf(pathCaptureAuthorization, authorization.ID), headers, body, &capture)
+	if err == nil && capture.Status != "COMPLETED" && capture.Status != "PENDING" {
+		err = fmt.Errorf("unexpected capture status %q", capture.Status)
+	}
+	p.recordRequest(operation, start, err)
+	if err != nil {
+		return nil, &provider.Error{Operation: operation, Exchange: exchange, Err: fmt.Errorf("error while capturing authorization %w", err)}
+	}
+
+	result := &provider.CaptureResult{
+		ProviderPaymentID: order.ID,
+		ProviderCaptureID: capture.ID,
+		Status:            entity.PaymentStatusSucceeded,
+		Exchange:          exchange,
+	}
+	result.Amount, _ = capture.Amount.money()
+	result.ProviderFee, _ = capture.SellerReceivableBreakdown.PaypalFee.money()
+
+	return result, nil
 }
-func (p *Provider) VerifyWebhook(ctx context.Context, webhookCtx *provider.WebhookContext) error {
-	operation := "verify_webhook"
-	webhookID := p.cfg.WebhookID
+
+// Void voids the authorization of an order created with the AUTHORIZE intent.
+func (p *Provider) Void(ctx context.Context, req provider.VoidRequest) (*provider.VoidResult, error) {
 	start := time.Now()
+	operation := "void_payment"
 
-	body := PaypalVerifySignatureRequest{
-		webhook_id:        webhookID,
-		transmission_id:   webhookCtx.Headers.Get("TRANSMISSION-ID"),
-		transmission_time: webhookCtx.Headers.Get("PAYPAL-TRANSMISSION-TIME"),
-		cert_url:          webhookCtx.Headers.Get("PAYPAL-CERT-URL"),
-		auth_algo:         webhookCtx.Headers.Get("PAYPAL-AUTH-ALGO"),
-		transmission_sig:  webhookCtx.Signature,
-		webhook_event:     json.RawMessage(webhookCtx.Payload),
+	headers, err := p.authHeaders(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	var response struct {
-		verification_status string
+	var order paypalOrderResponse
+	exchange, err := p.call(ctx, http.MethodGet, fmt.Sprintf(pathGetOrder, req.ProviderPaymentID), headers, nil, &order)
+	if err != nil {
+		p.recordRequest(operation, start, err)
+		return nil, &provider.Error{Operation: operation, Exchange: exchange, Err: fmt.Errorf("failed to get paypal order %s: %w", req.ProviderPaymentID, err)}
+	}
+	authorization, ok := order.firstAuthorization()
+	if !ok {
+		err := fmt.Errorf("paypal order %s has no authorization", req.ProviderPaymentID)
+		p.recordRequest(operation, start, err)
+		return nil, &provider.Error{Operation: operation, Exchange: exchange, Err: err}
+	}
+
+	if req.IdempotencyKey != "" {
+		headers.Set("PayPal-Request-Id", req.IdempotencyKey)
+	}
+	// Without it PayPal answers 204 with no body.
+	headers.Set("Prefer", "return=representation")
+
+	var response paypalAuthorization
+	exchange, err = p.call(ctx, http.MethodPost, fmt.Sprintf(pathVoidAuthorization, authorization.ID), headers, struct{}{}, &response)
+	if err == nil && response.Status != "VOIDED" {
+		err = fmt.Errorf("unexpected authorization status %q", response.Status)
+	}
+	p.recordRequest(operation, start, err)
+	if err != nil {
+		return nil, &provider.Error{Operation: operation, Exchange: exchange, Err: fmt.Errorf("error while voiding payment %w", err)}
+	}
+
+	return &provider.VoidResult{
+		ProviderVoidID: response.ID,
+		Exchange:       exchange,
+	}, nil
+}
+
+// Refund refunds the capture belonging to the order. PayPal refunds are issued
+// against a capture rather than an order, so the capture ID is looked up first.
+func (p *Provider) Refund(ctx context.Context, req provider.RefundRequest) (*provider.RefundResult, error) {
+	start := time.Now()
+	operation := "refund_payment"
+
+	headers, err := p.authHeaders(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var order paypalOrderResponse
+	exchange, err := p.call(ctx, http.MethodGet, fmt.Sprintf(pathGetOrder, req.ProviderPaymentID), headers, nil, &order)
+	if err != nil {
+		p.recordRequest(operation, start, err)
+		return nil, &provider.Error{Operation: operation, Exchange: exchange, Err: fmt.Errorf("failed to get paypal order %s: %w", req.ProviderPaymentID, err)}
+	}
+	capture, ok := order.firstCapture()
+	if !ok {
+		err := fmt.Errorf("paypal order %s has no capture", req.ProviderPaymentID)
+		p.recordRequest(operation, start, err)
+		return nil, &provider.Error{Operation: operation, Exchange: exchange, Err: err}
+	}
+
+	if req.IdempotencyKey != "" {
+		headers.Set("PayPal-Request-Id", req.IdempotencyKey)
+	}
+
+	body := paypalRefundRequest{
+		Amount:      newPaypalAmount(req.Amount),
+		NoteToPayer: req.Reason,
+	}
+
+	var response paypalRefundResponse
+	exchange, err = p.call(ctx, http.MethodPost, fmt.Sprintf(pathRefundCapture, capture.ID), headers, body, &response)
+	p.recordRequest(operation, start, err)
+	if err != nil {
+		return nil, &provider.Error{Operation: operation, Exchange: exchange, Err: fmt.Errorf("error while refunding payment %w", err)}
+	}
+
+	amount, err := response.Amount.money()
+	if err != nil {
+		amount = req.Amount
 	}
+	return &provider.RefundResult{
+		ProviderRefundID: response.ID,
+		Status:           refundStatus(response.Status),
+		Amount:           amount,
+		Exchange:         exchange,
+	}, nil
+}
+
+// GetPaymentStatus reads the order. An approved order is reported as pending,
+// like the CHECKOUT.ORDER.APPROVED webhook; orders created with the AUTHORIZE
+// intent complete once authorized, so their authorization decides.
+func (p *Provider) GetPaymentStatus(ctx context.Context, providerPaymentID string) (*provider.PaymentStatusResult, error) {
+	start := time.Now()
+	operation := "get_payment_status"
+
+	headers, err := p.authHeaders(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var order paypalOrderResponse
+	exchange, err := p.call(ctx, http.MethodGet, fmt.Sprintf(pathGetOrder, providerPaymentID), headers, nil, &order)
+	p.recordRequest(operation, start, err)
+	if err != nil {
+		return nil, &provider.Error{Operation: operation, Exchange: exchange, Err: fmt.Errorf("failed to get paypal order %s: %w", providerPaymentID, err)}
+	}
+
+	result := &provider.PaymentStatusResult{
+		ProviderPaymentID: order.ID,
+		Exchange:          exchange,
+	}
+	if len(order.PurchaseUnits) > 0 {
+		result.Amount, _ = order.PurchaseUnits[0].Amount.money()
+	}
+
+	switch order.Status {
+	case "APPROVED":
+		result.Status = entity.PaymentStatusPending
+	case "VOIDED":
+		result.Status = entity.PaymentStatusCancelled
+	case "COMPLETED":
+		result.Status = entity.PaymentStatusSucceeded
+		if authorization, ok := order.firstAuthorization(); ok && order.Intent == "AUTHORIZE" {
+			switch authorization.Status {
+			case "CREATED", "PARTIALLY_CAPTURED":
+				result.Status = entity.PaymentStatusAuthorized
+			case "VOIDED", "EXPIRED":
+				result.Status = entity.PaymentStatusCancelled
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// call performs an authenticated PayPal API request, decodes the response into
+// out and returns the raw exchange for auditing.
+func (p *Provider) call(ctx context.Context, method, path string, headers http.Header, body, out any) (provider.Exchange, error) {
+	var exchange provider.Exchange
+	if body != nil {
+		requestBody, err := json.Marshal(body)
+		if err != nil {
+			return exchange, fmt.Errorf("failed to marshal request body: %w", err)
+		}
+		exchange.Request = string(requestBody)
+	}
+
+	var raw json.RawMessage
+	err := httpclient.MakeRequest(httpclient.RequestParam[any]{
+		Client: p.httpClient,
+		Header: &headers,
+		Ctx:    ctx,
+		Method: method,
+		URL:    p.cfg.BaseURL + path,
+		Body:   body,
+	}, &raw)
+	if err != nil {
+		var statusErr *httpclient.StatusError
+		if errors.As(err, &statusErr) {
+			exchange.Response = statusErr.Body
+		}
+		return exchange, err
+	}
+	exchange.Response = string(raw)
+
+	if err := json.Unmarshal(raw, out); err != nil {
+		return exchange, fmt.Errorf("failed to parse response: %w", err)
+	}
+	return exchange, nil
+}
 
-	token, err := p.getAccessToken(context.Background())
+func (p *Provider) authHeaders(ctx context.Context) (http.Header, error) {
+	token, err := p.getAccessToken(ctx)
 	if err != nil {
 		p.metrics.ProviderErrors.WithLabelValues(
 			providerID,
 			"api_auth_error",
 		).Inc()
-		return err
+		return nil, err
 	}
 
 	headers := http.Header{}
 	headers.Set("Content-Type", "application/json")
 	headers.Set("Authorization", "Bearer "+token)
+	return headers, nil
+}
 
-	if err := httpclient.MakeRequest(httpclient.RequestParam[PaypalVerifySignatureRequest]{
-		Client: p.httpClient,
-		Header: &headers,
-		URL:    p.cfg.BaseURL + pathVerifyEventSignature,
-		Body:   body,
-	}, &response); err != nil {
-		p.metrics.ProviderErrors.WithLabelValues(
-			providerID,
-			"api_error",
-		).Inc()
-		return err
+func orderStatus(status string) entity.PaymentStatus {
+	switch status {
+	case "COMPLETED":
+		return entity.PaymentStatusSucceeded
+	case "VOIDED":
+		return entity.PaymentStatusCancelled
+	case "APPROVED":
+		return entity.PaymentStatusProcessing
+	default:
+		// CREATED, SAVED and PAYER_ACTION_REQUIRED all wait for the buyer
+		return entity.PaymentStatusPending
 	}
-	duration := time.Since(start).Seconds()
+}
+
+func refundStatus(status string) entity.TransactionStatus {
+	switch status {
+	case "COMPLETED":
+		return entity.TransactionStatusSucceeded
+	case "PENDING":
+		return entity.TransactionStatusPending
+	default:
+		return entity.TransactionStatusFailed
+	}
+}
+
+func (p *Provider) recordRequest(operation string, start time.Time, err error) {
 	p.metrics.ProviderRequestDuration.WithLabelValues(
 		providerID,
 		operation,
-	).Observe(duration)
+	).Observe(time.Since(start).Seconds())
 
-	if response.verification_status == "SUCCESS" {
-		p.metrics.ProviderRequestsTotal.WithLabelValues(
+	status := "success"
+	if err != nil {
+		status = "error"
+		p.metrics.ProviderErrors.WithLabelValues(
 			providerID,
-			operation,
-			"success",
+			"api_error",
 		).Inc()
-		return nil
 	}
-
 	p.metrics.ProviderRequestsTotal.WithLabelValues(
 		providerID,
 		operation,
-		"error",
+		status,
 	).Inc()
-	return fmt.Errorf("paypal webhook event verification failed")
+}
+
+// WebhookSignature returns the transmission signature PayPal sends with every
+// webhook; the remaining transmission headers are read in VerifyWebhook.
+func (p *Provider) WebhookSignature(headers http.Header) string {
+	return headers.Get("PAYPAL-TRANSMISSION-SIG")
+}
 
+func (p *Provider) VerifyWebhook(ctx context.Context, webhookCtx *provider.WebhookContext) error {
+	_, err := p.VerifyWebhookExchange(ctx, webhookCtx)
+	return err
+}
+
+// VerifyWebhookExchange asks PayPal to verify the webhook's signature and
+// returns that call. A rejected signature is returned as ErrInvalidSignature in
+// a *provider.Error that carries the call as well.
+func (p *Provider) VerifyWebhookExchange(ctx context.Context, webhookCtx *provider.WebhookContext) (provider.Exchange, error) {
+	operation := "verify_webhook"
+	start := time.Now()
+
+	body := PaypalVerifySignatureRequest{
+		WebhookID:        p.cfg.WebhookID,
+		TransmissionID:   webhookCtx.Headers.Get("PAYPAL-TRANSMISSION-ID"),
+		TransmissionTime: webhookCtx.Headers.Get("PAYPAL-TRANSMISSION-TIME"),
+		CertURL:          webhookCtx.Headers.Get("PAYPAL-CERT-URL"),
+		AuthAlgo:         webhookCtx.Headers.Get("PAYPAL-AUTH-ALGO"),
+		TransmissionSig:  webhookCtx.Signature,
+		WebhookEvent:     json.RawMessage(webhookCtx.Payload),
+	}
+
+	var response struct {
+		VerificationStatus string `json:"verification_status"`
+	}
+
+	headers, err := p.authHeaders(ctx)
+	if err != nil {
+		return provider.Exchange{}, err
+	}
+
+	exchange, err := p.call(ctx, http.MethodPost, pathVerifyEventSignature, headers, body, &response)
+	if err == nil && response.VerificationStatus != "SUCCESS" {
+		err = ErrInvalidSignature
+	}
+	p.recordRequest(operation, start, err)
+	if err != nil {
+		return exchange, &provider.Error{Operation: operation, Exchange: exchange, Err: err}
+	}
+	return exchange, nil
 }
 
 func (p *Provider) ParseWebhook(payload []byte) (*provider.WebhookEvent, error) {
@@ -240,30 +527,52 @@ func (p *Provider) ParseWebhook(payload []byte) (*provider.WebhookEvent, error)
 		return nil, fmt.Errorf("failed to parse paypal webhook %w", err)
 	}
 
-	createTime, err := time.Parse(time.RFC3339, webhookData.create_time)
+	createTime, err := time.Parse(time.RFC3339, webhookData.CreateTime)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse paypal webhook createtime %w", err)
 	}
 
-	total, err := strconv.ParseFloat(webhookData.resource.amount.total, 64)
-
 	event := &provider.WebhookEvent{
 		ProviderID:        "paypal",
-		EventType:         webhookData.event_type,
-		Amount:            total,
-		Currency:          webhookData.resource.amount.currency,
+		EventID:           webhookData.ID,
+		EventType:         webhookData.EventType,
 		CreateTime:        createTime,
 		RawPayload:        string(payload),
-		ProviderPaymentID: webhookData.resource.id,
+		ProviderPaymentID: webhookData.Resource.ID,
 	}
 
-	switch webhookData.event_type {
+	if units := webhookData.Resource.PurchaseUnits; len(units) > 0 {
+		total, err := units[0].Amount.money()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse paypal webhook amount %w", err)
+		}
+		event.Amount = total
+	}
+
+	switch webhookData.EventType {
 	case "CHECKOUT.ORDER.APPROVED":
 		event.Status = entity.PaymentStatusPending
 	case "CHECKOUT.ORDER.COMPLETED":
+		// Orders with the AUTHORIZE intent complete once authorized; they
+		// succeed only when PAYMENT.CAPTURE.COMPLETED reports the capture.
+		event.Status = entity.PaymentStatusSucceeded
+		if webhookData.Resource.Intent == "AUTHORIZE" {
+			event.Status = entity.PaymentStatusAuthorized
+		}
+	case "PAYMENT.CAPTURE.COMPLETED":
 		event.Status = entity.PaymentStatusSucceeded
+		event.ProviderPaymentID = webhookData.Resource.SupplementaryData.RelatedIDs.OrderID
+		if webhookData.Resource.Amount != nil {
+			captured, err := webhookData.Resource.Amount.money()
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse paypal webhook amount %w", err)
+			}
+			event.Amount = captured
+		}
 	case "CHECKOUT.PAYMENT-APPROVAL.REVERSED":
 		event.Status = entity.PaymentStatusFailed
+	default:
+		event.Ignored = true
 	}
 
 	return event, nil
@@ -304,118 +613,155 @@ func (p *Provider) getAccessToken(ctx context.Context) (string, error) {
 	return response.AccessToken, nil
 }
 
-type PaypalRequest struct {
-	intent         string
-	purchase_units []struct {
-		amount struct {
-			currency_code string
-			value         string
+type PaypalVerifySignatureRequest struct {
+	WebhookID        string          `json:"webhook_id"`
+	TransmissionID   string          `json:"transmission_id"`
+	TransmissionTime string          `json:"transmission_time"`
+	CertURL          string          `json:"cert_url"`
+	AuthAlgo         string          `json:"auth_algo"`
+	TransmissionSig  string          `json:"transmission_sig"`
+	WebhookEvent     json.RawMessage `json:"webhook_event"`
+}
+
+type PaypalWebhookEvent struct {
+	ID           string `json:"id"`
+	CreateTime   string `json:"create_time"`
+	ResourceType string `json:"resource_type"`
+	EventVersion string `json:"event_version"`
+	EventType    string `json:"event_type"`
+	Summary      string `json:"summary"`
+	Resource     struct {
+		ID         string `json:"id"`
+		CreateTime string `json:"create_time"`
+		UpdateTime string `json:"update_time"`
+		Status     string `json:"status"`
+		// Intent is set on checkout order events.
+		Intent string `json:"intent"`
+		// PurchaseUnits carry the amount of checkout order events.
+		PurchaseUnits []struct {
+			Amount paypalAmount `json:"amount"`
+		} `json:"purchase_units"`
+		// Amount and SupplementaryData are set on capture events, whose
+		// resource is the capture rather than the order.
+		Amount            *paypalAmount `json:"amount"`
+		SupplementaryData struct {
+			RelatedIDs struct {
+				OrderID string `json:"order_id"`
+			} `json:"related_ids"`
+		} `json:"supplementary_data"`
+	} `json:"resource"`
+}
+
+type paypalAmount struct {
+	CurrencyCode string `json:"currency_code"`
+	Value        string `json:"value"`
+}
+
+// newPaypalAmount formats m the way PayPal expects: a decimal string with the
+// currency's own number of decimals.
+func newPaypalAmount(m money.Money) paypalAmount {
+	return paypalAmount{CurrencyCode: m.Currency, Value: m.Decimal()}
+}
+
+func (a paypalAmount) money() (money.Money, error) {
+	return money.Parse(a.Value, a.CurrencyCode)
+}
+
+type paypalLink struct {
+	Href   string `json:"href"`
+	Rel    string `json:"rel"`
+	Method string `json:"method"`
+}
+
+type paypalPurchaseUnitRequest struct {
+	ReferenceID string       `json:"reference_id,omitempty"`
+	Amount      paypalAmount `json:"amount"`
+}
+
+type paypalOrderRequest struct {
+	Intent        string                      `json:"intent"`
+	PurchaseUnits []paypalPurchaseUnitRequest `json:"purchase_units"`
+}
+
+type paypalCapture struct {
+	ID                        string       `json:"id"`
+	Status                    string       `json:"status"`
+	Amount                    paypalAmount `json:"amount"`
+	FinalCapture              bool         `json:"final_capture"`
+	SellerReceivableBreakdown struct {
+		GrossAmount paypalAmount `json:"gross_amount"`
+		PaypalFee   paypalAmount `json:"paypal_fee"`
+		NetAmount   paypalAmount `json:"net_amount"`
+	} `json:"seller_receivable_breakdown"`
+	CreateTime string `json:"create_time"`
+	UpdateTime string `json:"update_time"`
+}
+
+type paypalAuthorization struct {
+	ID             string       `json:"id"`
+	Status         string       `json:"status"`
+	Amount         paypalAmount `json:"amount"`
+	ExpirationTime string       `json:"expiration_time"`
+}
+
+type paypalCaptureAuthorizationRequest struct {
+	Amount       *paypalAmount `json:"amount,omitempty"`
+	FinalCapture bool          `json:"final_capture"`
+}
+
+type paypalOrderResponse struct {
+	ID            string `json:"id"`
+	Intent        string `json:"intent"`
+	Status        string `json:"status"`
+	PurchaseUnits []struct {
+		ReferenceID string       `json:"reference_id"`
+		Amount      paypalAmount `json:"amount"`
+		Payments    struct {
+			Authorizations []paypalAuthorization `json:"authorizations"`
+			Captures       []paypalCapture       `json:"captures"`
+		} `json:"payments"`
+	} `json:"purchase_units"`
+	CreateTime string       `json:"create_time"`
+	Links      []paypalLink `json:"links"`
+}
+
+// link returns the href of the first link matching one of rels.
+func (r paypalOrderResponse) link(rels ...string) string {
+	for _, rel := range rels {
+		for _, l := range r.Links {
+			if l.Rel == rel {
+				return l.Href
+			}
 		}
 	}
+	return ""
 }
-type PayPalResponse struct {
-	id             string
-	intent         string
-	status         string
-	purchase_units []struct {
-		reference_id string
-		amount       struct {
-			currency_code string
-			value         string
-		}
-		payee struct {
-			email_address string
-			merchant_id   string
+
+func (r paypalOrderResponse) firstCapture() (paypalCapture, bool) {
+	for _, unit := range r.PurchaseUnits {
+		if len(unit.Payments.Captures) > 0 {
+			return unit.Payments.Captures[0], true
 		}
 	}
-	create_time string
-	links       []struct {
-		href   string
-		rel    string
-		method string
-	}
-}
-
-type paypalCaptureResponse struct {
-	id             string
-	status         string
-	purchase_units []struct {
-		reference_id string
-		payments     struct {
-			captures []struct {
-				id     string
-				status string
-				amount struct {
-					currency_code string
-					value         string
-				}
-				final_capture               bool
-				seller_receivable_breakdown struct {
-					gross_amount struct {
-						currency_code string
-						value         string
-					}
-					paypal_fee struct {
-						currency_code string
-						value         string
-					}
-					net_amount struct {
-						currency_code string
-						value         string
-					}
-				}
-				links []struct {
-					href   string
-					rel    string
-					method string
-				}
-				create_time string
-				update_time string
-			}
+	return paypalCapture{}, false
+}
+
+func (r paypalOrderResponse) firstAuthorization() (paypalAuthorization, bool) {
+	for _, unit := range r.PurchaseUnits {
+		if len(unit.Payments.Authorizations) > 0 {
+			return unit.Payments.Authorizations[0], true
 		}
 	}
+	return paypalAuthorization{}, false
 }
 
-type PaypalVerifySignatureRequest struct {
-	webhook_id        string
-	transmission_id   string
-	transmission_time string
-	cert_url          string
-	auth_algo         string
-	transmission_sig  string
-	webhook_event     []byte
+type paypalRefundRequest struct {
+	Amount      paypalAmount `json:"amount"`
+	NoteToPayer string       `json:"note_to_payer,omitempty"`
 }
 
-type PaypalWebhookEvent struct {
-	id            string
-	create_time   string
-	resource_type string
-	event_version string
-	event_type    string
-	summary       string
-	resource      struct {
-		id          string
-		create_time string
-		update_time string
-		state       string
-		amount      struct {
-			total    string
-			currency string
-			details  struct {
-				subtotal string
-			}
-		}
-		parent_payment string
-		valid_until    string
-		links          []struct {
-			href   string
-			rel    string
-			method string
-		}
-	}
-	links []struct {
-		href   string
-		rel    string
-		method string
-	}
+type paypalRefundResponse struct {
+	ID     string       `json:"id"`
+	Status string       `json:"status"`
+	Amount paypalAmount `json:"amount"`
 }
@@ -0,0 +1,152 @@
+package paypal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/omerbeden/paymentgateway/internal/adapter/provider"
+	"github.com/omerbeden/paymentgateway/internal/domain/entity"
+	"github.com/omerbeden/paymentgateway/internal/domain/money"
+	"github.com/omerbeden/paymentgateway/internal/infrastructure/config"
+	"github.com/omerbeden/paymentgateway/internal/infrastructure/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	orderApprovedWebhook = `{"id":"WH-1","create_time":"2026-01-01T10:00:00Z","event_type":"CHECKOUT.ORDER.APPROVED",
+		"resource":{"id":"ORDER-1","status":"APPROVED","intent":"AUTHORIZE",
+		"purchase_units":[{"amount":{"currency_code":"USD","value":"10.50"}}]}}`
+	orderCompletedWebhook = `{"id":"WH-2","create_time":"2026-01-01T10:00:05Z","event_type":"CHECKOUT.ORDER.COMPLETED",
+		"resource":{"id":"ORDER-1","status":"COMPLETED","intent":"%s",
+		"purchase_units":[{"amount":{"currency_code":"USD","value":"10.50"}}]}}`
+	captureCompletedWebhook = `{"id":"WH-3","create_time":"2026-01-01T11:00:00Z","event_type":"PAYMENT.CAPTURE.COMPLETED",
+		"resource":{"id":"CAPTURE-1","status":"COMPLETED","amount":{"currency_code":"USD","value":"8.00"},
+		"supplementary_data":{"related_ids":{"order_id":"ORDER-1","authorization_id":"AUTH-1"}}}}`
+)
+
+// metrics.New registers collectors globally, so the package shares one set.
+var testMetrics = metrics.New()
+
+func newTestProvider() *Provider {
+	return NewProvider(config.Paypal{}, nil)
+}
+
+// newVerifyingProvider returns a provider whose signature verification calls
+// are answered with verificationStatus.
+func newVerifyingProvider(t *testing.T, verificationStatus string) *Provider {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case pathAuthz:
+			fmt.Fprint(w, `{"access_token":"token"}`)
+		case pathVerifyEventSignature:
+			fmt.Fprintf(w, `{"verification_status":%q}`, verificationStatus)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return NewProvider(config.Paypal{BaseURL: server.URL, WebhookID: "WH-ID"}, testMetrics)
+}
+
+func TestVerifyWebhookExchange_ReturnsTheCall(t *testing.T) {
+	// Arrange
+	p := newVerifyingProvider(t, "SUCCESS")
+
+	// Act
+	exchange, err := p.VerifyWebhookExchange(context.Background(), &provider.WebhookContext{
+		Payload:   []byte(captureCompletedWebhook),
+		Headers:   http.Header{},
+		Signature: "sig",
+	})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Contains(t, exchange.Request, `"webhook_id":"WH-ID"`)
+	assert.Contains(t, exchange.Response, "SUCCESS")
+}
+
+func TestVerifyWebhookExchange_RejectedSignatureKeepsTheCall(t *testing.T) {
+	// Arrange
+	p := newVerifyingProvider(t, "FAILURE")
+
+	// Act
+	_, err := p.VerifyWebhookExchange(context.Background(), &provider.WebhookContext{
+		Payload:   []byte(captureCompletedWebhook),
+		Headers:   http.Header{},
+		Signature: "forged",
+	})
+
+	// Assert
+	assert.True(t, errors.Is(err, provider.ErrInvalidWebhookSignature))
+	assert.Contains(t, provider.ExchangeFromError(err).Response, "FAILURE")
+}
+
+func TestParseWebhook_AuthorizeOrderSequence(t *testing.T) {
+	// Arrange
+	p := newTestProvider()
+	payloads := []string{
+		orderApprovedWebhook,
+		fmt.Sprintf(orderCompletedWebhook, "AUTHORIZE"),
+		captureCompletedWebhook,
+	}
+	status := entity.PaymentStatusProcessing
+
+	// Act
+	var statuses []entity.PaymentStatus
+	for _, payload := range payloads {
+		event, err := p.ParseWebhook([]byte(payload))
+		require.NoError(t, err)
+		assert.Equal(t, "ORDER-1", event.ProviderPaymentID)
+
+		// The approval is authorized by the gateway itself, as ProcessWebHookUseCase does.
+		next := event.Status
+		if next == entity.PaymentStatusPending {
+			next = entity.PaymentStatusAuthorized
+		}
+		require.True(t, status.CanTransitionTo(next), "%s to %s", status, next)
+		status = next
+		statuses = append(statuses, event.Status)
+	}
+
+	// Assert
+	assert.Equal(t, []entity.PaymentStatus{
+		entity.PaymentStatusPending,
+		entity.PaymentStatusAuthorized,
+		entity.PaymentStatusSucceeded,
+	}, statuses)
+	assert.Equal(t, entity.PaymentStatusSucceeded, status)
+}
+
+func TestParseWebhook_CaptureOrderCompletedSucceeds(t *testing.T) {
+	// Arrange
+	p := newTestProvider()
+
+	// Act
+	event, err := p.ParseWebhook([]byte(fmt.Sprintf(orderCompletedWebhook, "CAPTURE")))
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, entity.PaymentStatusSucceeded, event.Status)
+	assert.Equal(t, money.New(1050, "USD"), event.Amount)
+}
+
+func TestParseWebhook_CaptureCompletedReportsCapturedAmount(t *testing.T) {
+	// Arrange
+	p := newTestProvider()
+
+	// Act
+	event, err := p.ParseWebhook([]byte(captureCompletedWebhook))
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "WH-3", event.EventID)
+	assert.Equal(t, entity.PaymentStatusSucceeded, event.Status)
+	assert.Equal(t, money.New(800, "USD"), event.Amount)
+}
@@ -0,0 +1,398 @@
+package stripe
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/omerbeden/paymentgateway/internal/adapter/provider"
+	"github.com/omerbeden/paymentgateway/internal/domain/entity"
+	"github.com/omerbeden/paymentgateway/internal/domain/money"
+	"github.com/omerbeden/paymentgateway/internal/infrastructure/config"
+	"github.com/omerbeden/paymentgateway/internal/infrastructure/metrics"
+	"github.com/omerbeden/paymentgateway/internal/pkg/httpclient"
+)
+
+type Provider struct {
+	httpClient *http.Client
+	cfg        config.Stripe
+	metrics    *metrics.Metrics
+	now        func() time.Time
+}
+
+const (
+	pathPaymentIntents = "/v1/payment_intents"
+	pathPaymentIntent  = "/v1/payment_intents/%s"
+	pathCaptureIntent  = "/v1/payment_intents/%s/capture"
+	pathCancelIntent   = "/v1/payment_intents/%s/cancel"
+	pathRefunds        = "/v1/refunds"
+	providerID         = "stripe"
+)
+
+func NewProvider(cfg config.Stripe, metrics *metrics.Metrics) *Provider {
+	return &Provider{
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		cfg:        cfg,
+		metrics:    metrics,
+		now:        time.Now,
+	}
+}
+
+// CreatePayment creates a PaymentIntent with manual capture. The buyer confirms
+// it client side with the returned client secret and the payment is captured
+// once Stripe reports it as capturable.
+func (p *Provider) CreatePayment(ctx context.Context, payment *entity.Payment) (*provider.CreatePaymentResult, error) {
+	start := time.Now()
+	operation := "create_payment"
+
+	form := url.Values{}
+	form.Set("amount", strconv.FormatInt(payment.Amount.Amount, 10))
+	form.Set("currency", strings.ToLower(payment.Amount.Currency))
+	form.Set("capture_method", "manual")
+	form.Set("automatic_payment_methods[enabled]", "true")
+	form.Set("metadata[payment_id]", payment.ID)
+
+	var intent stripePaymentIntent
+	exchange, err := p.call(ctx, http.MethodPost, pathPaymentIntents, payment.ID, form, &intent)
+	p.recordRequest(operation, start, err)
+	if err != nil {
+		return nil, &provider.Error{Operation: operation, Exchange: exchange, Err: err}
+	}
+
+	result := &provider.CreatePaymentResult{
+		ProviderPaymentID: intent.ID,
+		Status:            intentStatus(intent.Status),
+		Amount:            intent.money(intent.Amount),
+		Metadata:          map[string]string{"stripe_client_secret": intent.ClientSecret},
+		Exchange:          exchange,
+	}
+	if intent.NextAction != nil && intent.NextAction.RedirectToURL != nil {
+		result.PaymentURL = intent.NextAction.RedirectToURL.URL
+	}
+
+	return result, nil
+}
+
+// Authorize checks that the buyer confirmed the intent. Intents are always
+// created with manual capture, so a confirmed intent already holds the funds.
+func (p *Provider) Authorize(ctx context.Context, id string) (*provider.AuthorizeResult, error) {
+	start := time.Now()
+	operation := "authorize_payment"
+
+	var intent stripePaymentIntent
+	exchange, err := p.call(ctx, http.MethodGet, fmt.Sprintf(pathPaymentIntent, id), "", url.Values{}, &intent)
+	if err == nil && intent.Status != "requires_capture" {
+		err = fmt.Errorf("unexpected payment intent status %q", intent.Status)
+	}
+	p.recordRequest(operation, start, err)
+	if err != nil {
+		return nil, &provider.Error{Operation: operation, Exchange: exchange, Err: fmt.Errorf("error while authorizing payment %w", err)}
+	}
+
+	return &provider.AuthorizeResult{
+		ProviderPaymentID:       intent.ID,
+		ProviderAuthorizationID: intent.ID,
+		Status:                  entity.PaymentStatusAuthorized,
+		Amount:                  intent.money(intent.Amount),
+		Exchange:                exchange,
+	}, nil
+}
+
+// Capture captures the intent; a smaller amount releases the rest of the hold.
+func (p *Provider) Capture(ctx context.Context, req provider.CaptureRequest) (*provider.CaptureResult, error) {
+	start := time.Now()
+	operation := "capture_payment"
+	id := req.ProviderPaymentID
+
+	form := url.Values{}
+	form.Add("expand[]", "latest_charge.balance_transaction")
+	if req.Amount.IsPositive() {
+		form.Set("amount_to_capture", strconv.FormatInt(req.Amount.Amount, 10))
+	}
+	idempotencyKey := req.IdempotencyKey
+	if idempotencyKey == "" {
+		idempotencyKey = "capture-" + id
+	}
+
+	var intent stripePaymentIntent
+	exchange, err := p.call(ctx, http.MethodPost, fmt.Sprintf(pathCaptureIntent, id), idempotencyKey, form, &intent)
+	if err == nil && intent.Status != "succeeded" {
+		err = fmt.Errorf("unexpected payment intent status %q", intent.Status)
+	}
+	p.recordRequest(operation, start, err)
+	if err != nil {
+		return nil, &provider.Error{Operation: operation, Exchange: exchange, Err: fmt.Errorf("error while capturing payment %w", err)}
+	}
+
+	result := &provider.CaptureResult{
+		ProviderPaymentID: intent.ID,
+		Status:            entity.PaymentStatusSucceeded,
+		Amount:            intent.money(intent.AmountReceived),
+		Exchange:          exchange,
+	}
+	if charge := intent.LatestCharge; charge != nil {
+		result.ProviderCaptureID = charge.ID
+		if charge.BalanceTransaction != nil {
+			result.ProviderFee = intent.money(charge.BalanceTransaction.Fee)
+		}
+	}
+
+	return result, nil
+}
+
+// Void cancels an intent that has not been captured.
+func (p *Provider) Void(ctx context.Context, req provider.VoidRequest) (*provider.VoidResult, error) {
+	intent, exchange, err := p.cancelIntent(ctx, "void_payment", req.ProviderPaymentID, req.IdempotencyKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &provider.VoidResult{
+		ProviderVoidID: intent.ID,
+		Exchange:       exchange,
+	}, nil
+}
+
+// Cancel cancels an intent the buyer has not confirmed yet.
+func (p *Provider) Cancel(ctx context.Context, req provider.CancelRequest) (*provider.CancelResult, error) {
+	intent, exchange, err := p.cancelIntent(ctx, "cancel_payment", req.ProviderPaymentID, req.IdempotencyKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &provider.CancelResult{
+		ProviderCancelID: intent.ID,
+		Exchange:         exchange,
+	}, nil
+}
+
+// cancelIntent backs both Void and Cancel: Stripe cancels an intent the same
+// way whether or not its funds are held.
+func (p *Provider) cancelIntent(ctx context.Context, operation, id, idempotencyKey string) (*stripePaymentIntent, provider.Exchange, error) {
+	start := time.Now()
+
+	var intent stripePaymentIntent
+	exchange, err := p.call(ctx, http.MethodPost, fmt.Sprintf(pathCancelIntent, id), idempotencyKey, url.Values{}, &intent)
+	if err == nil && intent.Status != "canceled" {
+		err = fmt.Errorf("unexpected payment intent status %q", intent.Status)
+	}
+	p.recordRequest(operation, start, err)
+	if err != nil {
+		return nil, exchange, &provider.Error{Operation: operation, Exchange: exchange, Err: fmt.Errorf("error while cancelling payment %w", err)}
+	}
+	return &intent, exchange, nil
+}
+
+func (p *Provider) Refund(ctx context.Context, req provider.RefundRequest) (*provider.RefundResult, error) {
+	start := time.Now()
+	operation := "refund_payment"
+
+	form := url.Values{}
+	form.Set("payment_intent", req.ProviderPaymentID)
+	form.Set("amount", strconv.FormatInt(req.Amount.Amount, 10))
+	if req.Reason != "" {
+		// Stripe's own reason field only takes a fixed set of values.
+		form.Set("metadata[reason]", req.Reason)
+	}
+
+	var refund stripeRefund
+	exchange, err := p.call(ctx, http.MethodPost, pathRefunds, req.IdempotencyKey, form, &refund)
+	p.recordRequest(operation, start, err)
+	if err != nil {
+		return nil, &provider.Error{Operation: operation, Exchange: exchange, Err: fmt.Errorf("error while refunding payment %w", err)}
+	}
+
+	return &provider.RefundResult{
+		ProviderRefundID: refund.ID,
+		Status:           refundStatus(refund.Status),
+		Amount:           money.New(refund.Amount, strings.ToUpper(refund.Currency)),
+		Exchange:         exchange,
+	}, nil
+}
+
+func (p *Provider) GetPaymentStatus(ctx context.Context, providerPaymentID string) (*provider.PaymentStatusResult, error) {
+	start := time.Now()
+	operation := "get_payment_status"
+
+	var intent stripePaymentIntent
+	exchange, err := p.call(ctx, http.MethodGet, fmt.Sprintf(pathPaymentIntent, providerPaymentID), "", url.Values{}, &intent)
+	p.recordRequest(operation, start, err)
+	if err != nil {
+		return nil, &provider.Error{Operation: operation, Exchange: exchange, Err: fmt.Errorf("error while getting payment status %w", err)}
+	}
+
+	amount := intent.AmountReceived
+	if amount == 0 {
+		amount = intent.Amount
+	}
+	result := &provider.PaymentStatusResult{
+		ProviderPaymentID: intent.ID,
+		Amount:            intent.money(amount),
+		Exchange:          exchange,
+	}
+	// The same statuses as the webhook events in ParseWebhook; the
+	// requires_* statuses before requires_capture wait for the buyer.
+	switch intent.Status {
+	case "requires_capture":
+		result.Status = entity.PaymentStatusPending
+	case "processing":
+		result.Status = entity.PaymentStatusProcessing
+	case "succeeded":
+		result.Status = entity.PaymentStatusSucceeded
+	case "canceled":
+		result.Status = entity.PaymentStatusCancelled
+	}
+
+	return result, nil
+}
+
+// call sends a form-encoded Stripe API request, decodes the response into out
+// and returns the raw exchange for auditing.
+func (p *Provider) call(ctx context.Context, method, path, idempotencyKey string, form url.Values, out any) (provider.Exchange, error) {
+	exchange := provider.Exchange{Request: form.Encode()}
+
+	headers := http.Header{}
+	headers.Set("Authorization", "Bearer "+p.cfg.SecretKey)
+	headers.Set("Content-Type", "application/x-www-form-urlencoded")
+	if idempotencyKey != "" {
+		headers.Set("Idempotency-Key", idempotencyKey)
+	}
+
+	var raw json.RawMessage
+	err := httpclient.MakeRequest(httpclient.RequestParam[string]{
+		Client: p.httpClient,
+		Header: &headers,
+		Ctx:    ctx,
+		Method: method,
+		URL:    p.cfg.BaseURL + path,
+		Body:   exchange.Request,
+	}, &raw)
+	if err != nil {
+		var statusErr *httpclient.StatusError
+		if errors.As(err, &statusErr) {
+			exchange.Response = statusErr.Body
+		}
+		return exchange, err
+	}
+	exchange.Response = string(raw)
+
+	if err := json.Unmarshal(raw, out); err != nil {
+		return exchange, fmt.Errorf("failed to parse response: %w", err)
+	}
+	return exchange, nil
+}
+
+func intentStatus(status string) entity.PaymentStatus {
+	switch status {
+	case "succeeded":
+		return entity.PaymentStatusSucceeded
+	case "canceled":
+		return entity.PaymentStatusCancelled
+	case "processing", "requires_capture":
+		return entity.PaymentStatusProcessing
+	default:
+		// requires_payment_method, requires_confirmation and requires_action
+		// all wait for the buyer
+		return entity.PaymentStatusPending
+	}
+}
+
+func refundStatus(status string) entity.TransactionStatus {
+	switch status {
+	case "succeeded":
+		return entity.TransactionStatusSucceeded
+	case "pending", "requires_action":
+		return entity.TransactionStatusPending
+	default:
+		return entity.TransactionStatusFailed
+	}
+}
+
+func (p *Provider) recordRequest(operation string, start time.Time, err error) {
+	p.metrics.ProviderRequestDuration.WithLabelValues(
+		providerID,
+		operation,
+	).Observe(time.Since(start).Seconds())
+
+	status := "success"
+	if err != nil {
+		status = "error"
+		p.metrics.ProviderErrors.WithLabelValues(
+			providerID,
+			"api_error",
+		).Inc()
+	}
+	p.metrics.ProviderRequestsTotal.WithLabelValues(
+		providerID,
+		operation,
+		status,
+	).Inc()
+}
+
+type stripePaymentIntent struct {
+	ID             string            `json:"id"`
+	Status         string            `json:"status"`
+	Amount         int64             `json:"amount"`
+	AmountReceived int64             `json:"amount_received"`
+	Currency       string            `json:"currency"`
+	ClientSecret   string            `json:"client_secret"`
+	Metadata       map[string]string `json:"metadata"`
+	Created        int64             `json:"created"`
+	NextAction     *struct {
+		RedirectToURL *struct {
+			URL string `json:"url"`
+		} `json:"redirect_to_url"`
+	} `json:"next_action"`
+	LatestCharge *stripeCharge `json:"latest_charge"`
+}
+
+// money returns amount in the intent's currency. Stripe already uses minor
+// units but sends the currency code in lower case.
+func (i stripePaymentIntent) money(amount int64) money.Money {
+	return money.New(amount, strings.ToUpper(i.Currency))
+}
+
+// stripeCharge is the expanded latest_charge of a PaymentIntent. Without
+// expansion Stripe sends only the charge ID.
+type stripeCharge struct {
+	ID                 string                    `json:"id"`
+	BalanceTransaction *stripeBalanceTransaction `json:"balance_transaction"`
+}
+
+func (c *stripeCharge) UnmarshalJSON(data []byte) error {
+	var id string
+	if err := json.Unmarshal(data, &id); err == nil {
+		c.ID = id
+		return nil
+	}
+	type expanded stripeCharge
+	return json.Unmarshal(data, (*expanded)(c))
+}
+
+type stripeBalanceTransaction struct {
+	ID  string `json:"id"`
+	Fee int64  `json:"fee"`
+}
+
+func (b *stripeBalanceTransaction) UnmarshalJSON(data []byte) error {
+	var id string
+	if err := json.Unmarshal(data, &id); err == nil {
+		b.ID = id
+		return nil
+	}
+	type expanded stripeBalanceTransaction
+	return json.Unmarshal(data, (*expanded)(b))
+}
+
+type stripeRefund struct {
+	ID       string `json:"id"`
+	Status   string `json:"status"`
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+}
@@ -0,0 +1,369 @@
+package stripe
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/omerbeden/paymentgateway/internal/adapter/provider"
+	"github.com/omerbeden/paymentgateway/internal/adapter/provider/stripe/stripetest"
+	"github.com/omerbeden/paymentgateway/internal/domain/entity"
+	"github.com/omerbeden/paymentgateway/internal/domain/money"
+	"github.com/omerbeden/paymentgateway/internal/infrastructure/config"
+	"github.com/omerbeden/paymentgateway/internal/infrastructure/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testSecretKey     = "sk_test_123"
+	testWebhookSecret = "whsec_test_123"
+)
+
+// metrics.New registers collectors globally, so the package shares one set.
+var testMetrics = metrics.New()
+
+func newTestProvider(t *testing.T) (*Provider, *stripetest.Server) {
+	t.Helper()
+	server := stripetest.NewServer(testSecretKey)
+	t.Cleanup(server.Close)
+
+	p := NewProvider(config.Stripe{
+		BaseURL:          server.URL,
+		SecretKey:        testSecretKey,
+		WebhookSecret:    testWebhookSecret,
+		WebhookTolerance: 5 * time.Minute,
+	}, testMetrics)
+	return p, server
+}
+
+func newTestPayment() *entity.Payment {
+	return &entity.Payment{
+		ID:         "pay_123",
+		Amount:     money.New(1050, "USD"),
+		ProviderID: providerID,
+		Status:     entity.PaymentStatusPending,
+	}
+}
+
+func TestCreatePayment_Success(t *testing.T) {
+	// Arrange
+	p, server := newTestProvider(t)
+	ctx := context.Background()
+
+	// Act
+	result, err := p.CreatePayment(ctx, newTestPayment())
+
+	// Assert
+	require.NoError(t, err)
+	assert.NotEmpty(t, result.ProviderPaymentID)
+	assert.Equal(t, entity.PaymentStatusPending, result.Status)
+	assert.Equal(t, money.New(1050, "USD"), result.Amount)
+	assert.Equal(t, result.ProviderPaymentID+"_secret_test", result.Metadata["stripe_client_secret"])
+	assert.Contains(t, result.Exchange.Request, "capture_method=manual")
+
+	intent, ok := server.PaymentIntent(result.ProviderPaymentID)
+	require.True(t, ok)
+	assert.Equal(t, "usd", intent.Currency)
+	assert.Equal(t, "pay_123", intent.Metadata["payment_id"])
+	assert.Equal(t, "pay_123", server.Requests()[0].Header.Get("Idempotency-Key"))
+}
+
+func TestCreatePayment_InvalidAPIKey(t *testing.T) {
+	// Arrange
+	p, _ := newTestProvider(t)
+	p.cfg.SecretKey = "sk_wrong"
+
+	// Act
+	result, err := p.CreatePayment(context.Background(), newTestPayment())
+
+	// Assert
+	assert.Nil(t, result)
+	var providerErr *provider.Error
+	require.True(t, errors.As(err, &providerErr))
+	assert.Contains(t, providerErr.Exchange.Response, "Invalid API Key")
+}
+
+func TestCapture_Success(t *testing.T) {
+	// Arrange
+	p, server := newTestProvider(t)
+	ctx := context.Background()
+	created, err := p.CreatePayment(ctx, newTestPayment())
+	require.NoError(t, err)
+	server.Authorize(created.ProviderPaymentID)
+
+	// Act
+	result, err := p.Capture(ctx, provider.CaptureRequest{ProviderPaymentID: created.ProviderPaymentID})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, entity.PaymentStatusSucceeded, result.Status)
+	assert.Equal(t, money.New(1050, "USD"), result.Amount)
+	assert.NotEmpty(t, result.ProviderCaptureID)
+	assert.Equal(t, money.New(60, "USD"), result.ProviderFee)
+}
+
+func TestCapture_NotAuthorized(t *testing.T) {
+	// Arrange
+	p, _ := newTestProvider(t)
+	ctx := context.Background()
+	created, err := p.CreatePayment(ctx, newTestPayment())
+	require.NoError(t, err)
+
+	// Act
+	result, err := p.Capture(ctx, provider.CaptureRequest{ProviderPaymentID: created.ProviderPaymentID})
+
+	// Assert
+	assert.Nil(t, result)
+	var providerErr *provider.Error
+	require.True(t, errors.As(err, &providerErr))
+	assert.Contains(t, providerErr.Exchange.Response, "payment_intent_unexpected_state")
+}
+
+func TestCapture_Partial(t *testing.T) {
+	// Arrange
+	p, server := newTestProvider(t)
+	ctx := context.Background()
+	created, err := p.CreatePayment(ctx, newTestPayment())
+	require.NoError(t, err)
+	server.Authorize(created.ProviderPaymentID)
+
+	// Act
+	result, err := p.Capture(ctx, provider.CaptureRequest{
+		ProviderPaymentID: created.ProviderPaymentID,
+		Amount:            money.New(700, "USD"),
+	})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, money.New(700, "USD"), result.Amount)
+	assert.Contains(t, result.Exchange.Request, "amount_to_capture=700")
+}
+
+func TestAuthorize(t *testing.T) {
+	// Arrange
+	p, server := newTestProvider(t)
+	ctx := context.Background()
+	created, err := p.CreatePayment(ctx, newTestPayment())
+	require.NoError(t, err)
+
+	// Act
+	_, notConfirmedErr := p.Authorize(ctx, created.ProviderPaymentID)
+	server.Authorize(created.ProviderPaymentID)
+	result, err := p.Authorize(ctx, created.ProviderPaymentID)
+
+	// Assert
+	assert.Error(t, notConfirmedErr)
+	require.NoError(t, err)
+	assert.Equal(t, entity.PaymentStatusAuthorized, result.Status)
+	assert.Equal(t, money.New(1050, "USD"), result.Amount)
+}
+
+func TestVoid(t *testing.T) {
+	// Arrange
+	p, server := newTestProvider(t)
+	ctx := context.Background()
+	created, err := p.CreatePayment(ctx, newTestPayment())
+	require.NoError(t, err)
+	server.Authorize(created.ProviderPaymentID)
+
+	// Act
+	result, err := p.Void(ctx, provider.VoidRequest{ProviderPaymentID: created.ProviderPaymentID})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, created.ProviderPaymentID, result.ProviderVoidID)
+	intent, _ := server.PaymentIntent(created.ProviderPaymentID)
+	assert.Equal(t, "canceled", intent.Status)
+
+	_, err = p.Capture(ctx, provider.CaptureRequest{ProviderPaymentID: created.ProviderPaymentID})
+	assert.Error(t, err)
+}
+
+func TestCancel(t *testing.T) {
+	// Arrange
+	p, server := newTestProvider(t)
+	ctx := context.Background()
+	created, err := p.CreatePayment(ctx, newTestPayment())
+	require.NoError(t, err)
+
+	// Act
+	result, err := p.Cancel(ctx, provider.CancelRequest{ProviderPaymentID: created.ProviderPaymentID})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, created.ProviderPaymentID, result.ProviderCancelID)
+	intent, _ := server.PaymentIntent(created.ProviderPaymentID)
+	assert.Equal(t, "canceled", intent.Status)
+
+	_, err = p.Cancel(ctx, provider.CancelRequest{ProviderPaymentID: created.ProviderPaymentID})
+	assert.Error(t, err)
+}
+
+func TestGetPaymentStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		arrange func(p *Provider, server *stripetest.Server, id string)
+		want    entity.PaymentStatus
+	}{
+		{"waiting for the buyer", func(*Provider, *stripetest.Server, string) {}, ""},
+		{"authorized", func(_ *Provider, server *stripetest.Server, id string) { server.Authorize(id) }, entity.PaymentStatusPending},
+		{"captured", func(p *Provider, server *stripetest.Server, id string) {
+			server.Authorize(id)
+			_, err := p.Capture(context.Background(), provider.CaptureRequest{ProviderPaymentID: id})
+			require.NoError(t, err)
+		}, entity.PaymentStatusSucceeded},
+		{"cancelled", func(p *Provider, _ *stripetest.Server, id string) {
+			_, err := p.Cancel(context.Background(), provider.CancelRequest{ProviderPaymentID: id})
+			require.NoError(t, err)
+		}, entity.PaymentStatusCancelled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			p, server := newTestProvider(t)
+			ctx := context.Background()
+			created, err := p.CreatePayment(ctx, newTestPayment())
+			require.NoError(t, err)
+			tt.arrange(p, server, created.ProviderPaymentID)
+
+			// Act
+			result, err := p.GetPaymentStatus(ctx, created.ProviderPaymentID)
+
+			// Assert
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, result.Status)
+			assert.Equal(t, money.New(1050, "USD"), result.Amount)
+		})
+	}
+}
+
+func TestRefund_Partial(t *testing.T) {
+	// Arrange
+	p, server := newTestProvider(t)
+	ctx := context.Background()
+	created, err := p.CreatePayment(ctx, newTestPayment())
+	require.NoError(t, err)
+	server.Authorize(created.ProviderPaymentID)
+	_, err = p.Capture(ctx, provider.CaptureRequest{ProviderPaymentID: created.ProviderPaymentID})
+	require.NoError(t, err)
+
+	// Act
+	result, err := p.Refund(ctx, provider.RefundRequest{
+		ProviderPaymentID: created.ProviderPaymentID,
+		Amount:            money.New(500, "USD"),
+		Reason:            "customer_request",
+		IdempotencyKey:    "refund-1",
+	})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, entity.TransactionStatusSucceeded, result.Status)
+	assert.Equal(t, money.New(500, "USD"), result.Amount)
+
+	_, err = p.Refund(ctx, provider.RefundRequest{
+		ProviderPaymentID: created.ProviderPaymentID,
+		Amount:            money.New(600, "USD"),
+		IdempotencyKey:    "refund-2",
+	})
+	assert.Error(t, err)
+}
+
+func TestVerifyWebhook(t *testing.T) {
+	p, _ := newTestProvider(t)
+	payload := []byte(`{"id":"evt_1","type":"payment_intent.succeeded"}`)
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		header  string
+		wantErr error
+	}{
+		{"valid", SignPayload(testWebhookSecret, payload, now), nil},
+		{"wrong secret", SignPayload("whsec_other", payload, now), ErrInvalidSignature},
+		{"stale timestamp", SignPayload(testWebhookSecret, payload, now.Add(-10*time.Minute)), ErrStaleSignature},
+		{"malformed", "garbage", ErrInvalidSignature},
+		{"missing", "", ErrInvalidSignature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := http.Header{}
+			headers.Set(SignatureHeader, tt.header)
+
+			err := p.VerifyWebhook(context.Background(), &provider.WebhookContext{
+				Payload: payload,
+				Headers: headers,
+			})
+
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.True(t, errors.Is(err, tt.wantErr), "got %v", err)
+		})
+	}
+}
+
+func TestVerifyWebhook_TamperedPayload(t *testing.T) {
+	p, _ := newTestProvider(t)
+	header := SignPayload(testWebhookSecret, []byte(`{"amount":1050}`), time.Now())
+
+	err := p.VerifyWebhook(context.Background(), &provider.WebhookContext{
+		Payload:   []byte(`{"amount":1}`),
+		Signature: header,
+	})
+
+	assert.True(t, errors.Is(err, ErrInvalidSignature))
+}
+
+func TestParseWebhook(t *testing.T) {
+	p, _ := newTestProvider(t)
+
+	tests := []struct {
+		eventType  string
+		wantStatus entity.PaymentStatus
+	}{
+		{"payment_intent.amount_capturable_updated", entity.PaymentStatusPending},
+		{"payment_intent.processing", entity.PaymentStatusProcessing},
+		{"payment_intent.succeeded", entity.PaymentStatusSucceeded},
+		{"payment_intent.payment_failed", entity.PaymentStatusFailed},
+		{"payment_intent.canceled", entity.PaymentStatusCancelled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.eventType, func(t *testing.T) {
+			payload := []byte(`{"id":"evt_1","type":"` + tt.eventType + `","created":1700000000,` +
+				`"data":{"object":{"id":"pi_123","object":"payment_intent","amount":1050,"currency":"jpy"}}}`)
+
+			evt, err := p.ParseWebhook(payload)
+
+			require.NoError(t, err)
+			assert.Equal(t, "evt_1", evt.EventID)
+			assert.Equal(t, "pi_123", evt.ProviderPaymentID)
+			assert.Equal(t, tt.wantStatus, evt.Status)
+			assert.Equal(t, money.New(1050, "JPY"), evt.Amount)
+			assert.Equal(t, time.Unix(1700000000, 0).UTC(), evt.CreateTime)
+		})
+	}
+}
+
+func TestParseWebhook_IgnoresUnmappedEventTypes(t *testing.T) {
+	p, _ := newTestProvider(t)
+
+	for _, eventType := range []string{"payment_intent.created", "charge.succeeded"} {
+		t.Run(eventType, func(t *testing.T) {
+			payload := []byte(`{"id":"evt_1","type":"` + eventType + `","created":1700000000,` +
+				`"data":{"object":{"id":"pi_123","object":"payment_intent","amount":1050,"currency":"jpy"}}}`)
+
+			evt, err := p.ParseWebhook(payload)
+
+			require.NoError(t, err)
+			assert.True(t, evt.Ignored)
+			assert.Empty(t, evt.Status)
+		})
+	}
+}
@@ -0,0 +1,306 @@
+// Package stripetest provides an in-process fake of the parts of the Stripe API
+// the stripe provider uses, so it can be exercised without network access.
+package stripetest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type PaymentIntent struct {
+	ID             string            `json:"id"`
+	Object         string            `json:"object"`
+	Status         string            `json:"status"`
+	Amount         int64             `json:"amount"`
+	AmountReceived int64             `json:"amount_received"`
+	AmountRefunded int64             `json:"-"`
+	Currency       string            `json:"currency"`
+	CaptureMethod  string            `json:"capture_method"`
+	ClientSecret   string            `json:"client_secret"`
+	Metadata       map[string]string `json:"metadata"`
+	Created        int64             `json:"created"`
+	LatestCharge   any               `json:"latest_charge"`
+}
+
+type Refund struct {
+	ID            string            `json:"id"`
+	Object        string            `json:"object"`
+	Status        string            `json:"status"`
+	Amount        int64             `json:"amount"`
+	Currency      string            `json:"currency"`
+	PaymentIntent string            `json:"payment_intent"`
+	Metadata      map[string]string `json:"metadata"`
+}
+
+// Server is a fake Stripe API backed by an httptest.Server. Intents start in
+// requires_payment_method; tests move them along with Authorize.
+type Server struct {
+	*httptest.Server
+	SecretKey string
+
+	mu          sync.Mutex
+	seq         int
+	intents     map[string]*PaymentIntent
+	refunds     map[string]*Refund
+	idempotency map[string][]byte
+	requests    []*http.Request
+}
+
+func NewServer(secretKey string) *Server {
+	s := &Server{
+		SecretKey:   secretKey,
+		intents:     make(map[string]*PaymentIntent),
+		refunds:     make(map[string]*Refund),
+		idempotency: make(map[string][]byte),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/payment_intents", s.createPaymentIntent)
+	mux.HandleFunc("GET /v1/payment_intents/{id}", s.getPaymentIntent)
+	mux.HandleFunc("POST /v1/payment_intents/{id}/capture", s.capturePaymentIntent)
+	mux.HandleFunc("POST /v1/payment_intents/{id}/cancel", s.cancelPaymentIntent)
+	mux.HandleFunc("POST /v1/refunds", s.createRefund)
+	s.Server = httptest.NewServer(s.authenticate(mux))
+	return s
+}
+
+// Authorize simulates the buyer confirming the intent, leaving it ready for
+// capture.
+func (s *Server) Authorize(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if intent, ok := s.intents[id]; ok {
+		intent.Status = "requires_capture"
+	}
+}
+
+func (s *Server) PaymentIntent(id string) (PaymentIntent, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	intent, ok := s.intents[id]
+	if !ok {
+		return PaymentIntent{}, false
+	}
+	return *intent, true
+}
+
+// Requests returns every request the server received, in order.
+func (s *Server) Requests() []*http.Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*http.Request(nil), s.requests...)
+}
+
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+s.SecretKey {
+			writeError(w, http.StatusUnauthorized, "invalid_request_error", "", "Invalid API Key provided")
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_request_error", "", err.Error())
+			return
+		}
+
+		s.mu.Lock()
+		s.requests = append(s.requests, r)
+		key := r.Header.Get("Idempotency-Key")
+		cached, replay := s.idempotency[r.URL.Path+"|"+key]
+		s.mu.Unlock()
+
+		if key != "" && replay {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.Write(cached)
+			return
+		}
+
+		rec := httptest.NewRecorder()
+		next.ServeHTTP(rec, r)
+		if key != "" && rec.Code < http.StatusInternalServerError {
+			s.mu.Lock()
+			s.idempotency[r.URL.Path+"|"+key] = rec.Body.Bytes()
+			s.mu.Unlock()
+		}
+		for k, v := range rec.Header() {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(rec.Code)
+		w.Write(rec.Body.Bytes())
+	})
+}
+
+func (s *Server) createPaymentIntent(w http.ResponseWriter, r *http.Request) {
+	amount, err := strconv.ParseInt(r.PostForm.Get("amount"), 10, 64)
+	if err != nil || amount <= 0 {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "parameter_invalid_integer", "Invalid integer: amount")
+		return
+	}
+	currency := r.PostForm.Get("currency")
+	if currency == "" {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "parameter_missing", "Missing required param: currency.")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := s.nextID("pi")
+	intent := &PaymentIntent{
+		ID:            id,
+		Object:        "payment_intent",
+		Status:        "requires_payment_method",
+		Amount:        amount,
+		Currency:      currency,
+		CaptureMethod: r.PostForm.Get("capture_method"),
+		ClientSecret:  id + "_secret_test",
+		Metadata:      formMap(r, "metadata"),
+		Created:       time.Now().Unix(),
+	}
+	s.intents[id] = intent
+	writeJSON(w, http.StatusOK, intent)
+}
+
+func (s *Server) getPaymentIntent(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	intent, ok := s.intents[r.PathValue("id")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "invalid_request_error", "resource_missing", "No such payment_intent")
+		return
+	}
+	writeJSON(w, http.StatusOK, intent)
+}
+
+func (s *Server) capturePaymentIntent(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	intent, ok := s.intents[r.PathValue("id")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "invalid_request_error", "resource_missing", "No such payment_intent")
+		return
+	}
+	if intent.Status != "requires_capture" {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "payment_intent_unexpected_state",
+			fmt.Sprintf("This PaymentIntent could not be captured because it has a status of %s.", intent.Status))
+		return
+	}
+
+	amount := intent.Amount
+	if v := r.PostForm.Get("amount_to_capture"); v != "" {
+		parsed, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || parsed <= 0 || parsed > intent.Amount {
+			writeError(w, http.StatusBadRequest, "invalid_request_error", "amount_too_large",
+				"The amount to capture must be positive and at most the authorized amount.")
+			return
+		}
+		amount = parsed
+	}
+
+	intent.Status = "succeeded"
+	intent.AmountReceived = amount
+	chargeID := s.nextID("ch")
+	// Stripe's card fee: 2.9% + 30 minor units.
+	fee := amount*29/1000 + 30
+	intent.LatestCharge = map[string]any{
+		"id":                  chargeID,
+		"object":              "charge",
+		"balance_transaction": map[string]any{"id": s.nextID("txn"), "fee": fee},
+	}
+	writeJSON(w, http.StatusOK, intent)
+	// later reads return the charge unexpanded, as Stripe does
+	intent.LatestCharge = chargeID
+}
+
+func (s *Server) cancelPaymentIntent(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	intent, ok := s.intents[r.PathValue("id")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "invalid_request_error", "resource_missing", "No such payment_intent")
+		return
+	}
+	if intent.Status == "succeeded" || intent.Status == "canceled" {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "payment_intent_unexpected_state",
+			fmt.Sprintf("You cannot cancel this PaymentIntent because it has a status of %s.", intent.Status))
+		return
+	}
+
+	intent.Status = "canceled"
+	writeJSON(w, http.StatusOK, intent)
+}
+
+func (s *Server) createRefund(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	intent, ok := s.intents[r.PostForm.Get("payment_intent")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "invalid_request_error", "resource_missing", "No such payment_intent")
+		return
+	}
+	if intent.Status != "succeeded" {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "charge_not_refundable", "This PaymentIntent has no successful charge to refund.")
+		return
+	}
+
+	amount := intent.AmountReceived - intent.AmountRefunded
+	if v := r.PostForm.Get("amount"); v != "" {
+		parsed, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || parsed <= 0 {
+			writeError(w, http.StatusBadRequest, "invalid_request_error", "parameter_invalid_integer", "Invalid integer: amount")
+			return
+		}
+		amount = parsed
+	}
+	if amount > intent.AmountReceived-intent.AmountRefunded {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "amount_too_large",
+			"Refund amount is greater than the unrefunded amount on the charge.")
+		return
+	}
+
+	intent.AmountRefunded += amount
+	refund := &Refund{
+		ID:            s.nextID("re"),
+		Object:        "refund",
+		Status:        "succeeded",
+		Amount:        amount,
+		Currency:      intent.Currency,
+		PaymentIntent: intent.ID,
+		Metadata:      formMap(r, "metadata"),
+	}
+	s.refunds[refund.ID] = refund
+	writeJSON(w, http.StatusOK, refund)
+}
+
+// nextID must be called with s.mu held.
+func (s *Server) nextID(prefix string) string {
+	s.seq++
+	return fmt.Sprintf("%s_test_%d", prefix, s.seq)
+}
+
+// formMap collects bracketed form keys such as metadata[order_id].
+func formMap(r *http.Request, name string) map[string]string {
+	m := map[string]string{}
+	for key, values := range r.PostForm {
+		if len(key) > len(name)+2 && key[:len(name)+1] == name+"[" && key[len(key)-1] == ']' {
+			m[key[len(name)+1:len(key)-1]] = values[0]
+		}
+	}
+	return m
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, errType, code, message string) {
+	writeJSON(w, status, map[string]any{
+		"error": map[string]string{"type": errType, "code": code, "message": message},
+	})
+}
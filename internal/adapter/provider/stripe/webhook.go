@@ -0,0 +1,165 @@
+package stripe
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/omerbeden/paymentgateway/internal/adapter/provider"
+	"github.com/omerbeden/paymentgateway/internal/domain/entity"
+)
+
+// SignatureHeader carries the timestamp and HMAC signatures of a webhook.
+const SignatureHeader = "Stripe-Signature"
+
+var (
+	ErrInvalidSignature = fmt.Errorf("stripe webhook signature mismatch: %w", provider.ErrInvalidWebhookSignature)
+	ErrStaleSignature   = fmt.Errorf("stripe webhook timestamp outside tolerance: %w", provider.ErrInvalidWebhookSignature)
+)
+
+func (p *Provider) WebhookSignature(headers http.Header) string {
+	return headers.Get(SignatureHeader)
+}
+
+// VerifyWebhook checks the Stripe-Signature header locally: every v1 entry is
+// an HMAC-SHA256 of "<timestamp>.<payload>" keyed with the endpoint secret.
+func (p *Provider) VerifyWebhook(ctx context.Context, webhookCtx *provider.WebhookContext) error {
+	operation := "verify_webhook"
+
+	header := webhookCtx.Signature
+	if header == "" && webhookCtx.Headers != nil {
+		header = webhookCtx.Headers.Get(SignatureHeader)
+	}
+
+	err := p.verifySignature(webhookCtx.Payload, header)
+	status := "success"
+	if err != nil {
+		status = "error"
+		p.metrics.ProviderErrors.WithLabelValues(
+			providerID,
+			"webhook_signature",
+		).Inc()
+	}
+	p.metrics.ProviderRequestsTotal.WithLabelValues(
+		providerID,
+		operation,
+		status,
+	).Inc()
+	return err
+}
+
+func (p *Provider) verifySignature(payload []byte, header string) error {
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	if timestamp == "" || len(signatures) == 0 {
+		return fmt.Errorf("%w: malformed %s header", ErrInvalidSignature, SignatureHeader)
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: bad timestamp %q", ErrInvalidSignature, timestamp)
+	}
+	if p.cfg.WebhookTolerance > 0 {
+		if age := p.now().Sub(time.Unix(unix, 0)); age > p.cfg.WebhookTolerance || age < -p.cfg.WebhookTolerance {
+			return ErrStaleSignature
+		}
+	}
+
+	expected := computeSignature(p.cfg.WebhookSecret, timestamp, payload)
+	for _, sig := range signatures {
+		decoded, err := hex.DecodeString(sig)
+		if err == nil && hmac.Equal(decoded, expected) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
+
+func computeSignature(secret, timestamp string, payload []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// SignPayload builds a Stripe-Signature header value for payload, the way
+// Stripe does when delivering an event.
+func SignPayload(secret string, payload []byte, at time.Time) string {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	return "t=" + timestamp + ",v1=" + hex.EncodeToString(computeSignature(secret, timestamp, payload))
+}
+
+func (p *Provider) ParseWebhook(payload []byte) (*provider.WebhookEvent, error) {
+	var evt stripeEvent
+	if err := json.Unmarshal(payload, &evt); err != nil {
+		return nil, fmt.Errorf("failed to parse stripe webhook %w", err)
+	}
+
+	var intent stripePaymentIntent
+	if err := json.Unmarshal(evt.Data.Object, &intent); err != nil {
+		return nil, fmt.Errorf("failed to parse stripe webhook object %w", err)
+	}
+
+	amount := intent.AmountReceived
+	if amount == 0 {
+		amount = intent.Amount
+	}
+
+	event := &provider.WebhookEvent{
+		ProviderID:        providerID,
+		EventID:           evt.ID,
+		EventType:         evt.Type,
+		ProviderPaymentID: intent.ID,
+		Amount:            intent.money(amount),
+		CreateTime:        time.Unix(evt.Created, 0).UTC(),
+		RawPayload:        string(payload),
+	}
+
+	switch evt.Type {
+	case "payment_intent.amount_capturable_updated":
+		// authorised and waiting for capture, like PayPal's CHECKOUT.ORDER.APPROVED
+		event.Status = entity.PaymentStatusPending
+	case "payment_intent.processing":
+		event.Status = entity.PaymentStatusProcessing
+	case "payment_intent.succeeded":
+		event.Status = entity.PaymentStatusSucceeded
+	case "payment_intent.payment_failed":
+		event.Status = entity.PaymentStatusFailed
+	case "payment_intent.canceled":
+		event.Status = entity.PaymentStatusCancelled
+	default:
+		// e.g. payment_intent.created or charge.* events
+		event.Ignored = true
+	}
+
+	return event, nil
+}
+
+type stripeEvent struct {
+	ID      string `json:"id"`
+	Type    string `json:"type"`
+	Created int64  `json:"created"`
+	Data    struct {
+		Object json.RawMessage `json:"object"`
+	} `json:"data"`
+}
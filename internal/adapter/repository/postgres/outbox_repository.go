@@ -0,0 +1,114 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/omerbeden/paymentgateway/internal/domain/entity"
+	"github.com/omerbeden/paymentgateway/internal/domain/event"
+)
+
+const outboxColumns = `id, aggregate_id, event_type, topic, payload, occurred_at, created_at, published_at, attempts, last_error`
+
+type OutboxRepository struct {
+	db *sql.DB
+}
+
+func NewOutboxRepository(db *sql.DB) *OutboxRepository {
+	return &OutboxRepository{db: db}
+}
+
+// insertOutbox writes events to the outbox as part of tx.
+func insertOutbox(ctx context.Context, tx *sql.Tx, events []event.DomainEvent) error {
+	query := `INSERT INTO outbox (aggregate_id, event_type, topic, payload, occurred_at)
+	VALUES ($1, $2, $3, $4, $5)`
+
+	for _, evt := range events {
+		payload, err := json.Marshal(evt)
+		if err != nil {
+			return fmt.Errorf("failed to marshal %s event: %w", evt.EventType(), err)
+		}
+		_, err = tx.ExecContext(ctx, query,
+			evt.AggregateID(),
+			evt.EventType(),
+			event.TopicFor(evt.EventType()),
+			string(payload),
+			evt.OccurredAt(),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to write outbox message: %w", err)
+		}
+	}
+	return nil
+}
+
+func (r *OutboxRepository) ListUnpublished(ctx context.Context, limit int) ([]*entity.OutboxMessage, error) {
+	query := `SELECT ` + outboxColumns + ` FROM outbox
+	WHERE published_at IS NULL ORDER BY id LIMIT $1`
+
+	rows, err := r.db.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list outbox messages: %w", err)
+	}
+	defer rows.Close()
+
+	var messages []*entity.OutboxMessage
+	for rows.Next() {
+		var m entity.OutboxMessage
+		var publishedAt sql.NullTime
+		var lastError sql.NullString
+		err := rows.Scan(
+			&m.ID,
+			&m.AggregateID,
+			&m.EventType,
+			&m.Topic,
+			&m.Payload,
+			&m.OccurredAt,
+			&m.CreatedAt,
+			&publishedAt,
+			&m.Attempts,
+			&lastError,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan outbox message: %w", err)
+		}
+		m.PublishedAt = publishedAt.Time
+		m.LastError = lastError.String
+		messages = append(messages, &m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list outbox messages: %w", err)
+	}
+	return messages, nil
+}
+
+func (r *OutboxRepository) MarkPublished(ctx context.Context, id int64, at time.Time) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE outbox SET published_at=$1 WHERE id=$2`, at, id)
+	if err != nil {
+		return fmt.Errorf("failed to mark outbox message published: %w", err)
+	}
+	return nil
+}
+
+func (r *OutboxRepository) MarkFailed(ctx context.Context, id int64, reason string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE outbox SET attempts=attempts+1, last_error=$1 WHERE id=$2`, reason, id)
+	if err != nil {
+		return fmt.Errorf("failed to mark outbox message failed: %w", err)
+	}
+	return nil
+}
+
+func (r *OutboxRepository) DeletePublished(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM outbox WHERE published_at IS NOT NULL AND published_at<$1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete published outbox messages: %w", err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete published outbox messages: %w", err)
+	}
+	return deleted, nil
+}
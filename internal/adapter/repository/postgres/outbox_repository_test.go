@@ -0,0 +1,86 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListUnpublished(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := NewOutboxRepository(db)
+	ctx := context.Background()
+
+	now := time.Now()
+	rows := sqlmock.NewRows([]string{"id", "aggregate_id", "event_type", "topic", "payload", "occurred_at", "created_at", "published_at", "attempts", "last_error"}).
+		AddRow(1, "pay_1", "payment.created", "payment.created", `{"payment_id":"pay_1"}`, now, now, nil, 0, nil).
+		AddRow(2, "pay_1", "payment.captured", "payment.processed", `{"payment_id":"pay_1"}`, now, now, nil, 2, "broker unavailable")
+
+	mock.ExpectQuery(`SELECT (.+) FROM outbox\s+WHERE published_at IS NULL ORDER BY id LIMIT \$1`).
+		WithArgs(100).
+		WillReturnRows(rows)
+
+	// Act
+	messages, err := repo.ListUnpublished(ctx, 100)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Len(t, messages, 2)
+	assert.Equal(t, int64(1), messages[0].ID)
+	assert.Equal(t, "payment.processed", messages[1].Topic)
+	assert.Equal(t, 2, messages[1].Attempts)
+	assert.Equal(t, "broker unavailable", messages[1].LastError)
+	assert.True(t, messages[0].PublishedAt.IsZero())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMarkFailed(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := NewOutboxRepository(db)
+	ctx := context.Background()
+
+	mock.ExpectExec(`UPDATE outbox SET attempts=attempts\+1, last_error=\$1 WHERE id=\$2`).
+		WithArgs("broker unavailable", int64(7)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// Act
+	err = repo.MarkFailed(ctx, 7, "broker unavailable")
+
+	// Assert
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeletePublished(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := NewOutboxRepository(db)
+	ctx := context.Background()
+	before := time.Now().Add(-24 * time.Hour)
+
+	mock.ExpectExec(`DELETE FROM outbox WHERE published_at IS NOT NULL AND published_at<\$1`).
+		WithArgs(before).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+
+	// Act
+	deleted, err := repo.DeletePublished(ctx, before)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), deleted)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -6,13 +6,19 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/lib/pq"
 	"github.com/omerbeden/paymentgateway/internal/domain/entity"
+	"github.com/omerbeden/paymentgateway/internal/domain/event"
+	"github.com/omerbeden/paymentgateway/internal/domain/repository"
 	"github.com/omerbeden/paymentgateway/internal/infrastructure/metrics"
 )
 
+const paymentColumns = `id, amount, refunded_amount, currency, idempotency_key, provider_id, provider_payment_id, payment_url,
+	status, created_at, updated_at, completed_at, expires_at, metadata, last_provider_event_at, intent, captured_amount`
+
 type PaymentRepository struct {
 	db      *sql.DB
 	metrics *metrics.Metrics
@@ -24,8 +30,8 @@ func NewPaymentRepository(db *sql.DB, metrics *metrics.Metrics) *PaymentReposito
 
 func (r *PaymentRepository) CreatePayment(ctx context.Context, payment *entity.Payment) error {
 	start := time.Now()
-	query := `INSERT INTO payments (id, amount, currency, idempotency_key, provider_id, status, created_at, updated_at, expires_at,metadata)
-	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+	query := `INSERT INTO payments (id, amount, currency, idempotency_key, provider_id, status, created_at, updated_at, expires_at,metadata, intent)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`
 
 	jsonMetadata, err := json.Marshal(payment.Metadata)
 	if err != nil {
@@ -34,15 +40,16 @@ func (r *PaymentRepository) CreatePayment(ctx context.Context, payment *entity.P
 
 	_, err = r.db.ExecContext(ctx, query,
 		payment.ID,
-		payment.Amount,
-		payment.Currency,
+		payment.Amount.Amount,
+		payment.Amount.Currency,
 		payment.IdempotencyKey,
 		payment.ProviderID,
 		payment.Status,
 		payment.CreatedAt,
 		payment.UpdatedAt,
-		payment.ExpiresAt,
-		jsonMetadata)
+		nullTime(payment.ExpiresAt),
+		jsonMetadata,
+		payment.Intent)
 
 	if err != nil {
 		// Check for unique constraint violation (idempotency key)
@@ -54,33 +61,378 @@ func (r *PaymentRepository) CreatePayment(ctx context.Context, payment *entity.P
 		return fmt.Errorf("failed to create payment: %w", err)
 	}
 
-	duration := time.Since(start).Seconds()
-	r.metrics.DBQueryDuration.WithLabelValues("create_payment").Observe(duration)
+	r.observeQuery("create_payment", start)
 
 	return nil
 
 }
 
+func (r *PaymentRepository) GetByID(ctx context.Context, id string) (*entity.Payment, error) {
+	start := time.Now()
+	query := `SELECT ` + paymentColumns + ` FROM payments WHERE id=$1`
+	row := r.db.QueryRowContext(ctx, query, id)
+
+	p, err := scanPayment(row)
+	if err != nil {
+		return nil, err
+	}
+
+	r.observeQuery("get_payment_by_id", start)
+
+	return p, nil
+}
+
 func (r *PaymentRepository) GetByProviderPaymentID(ctx context.Context, providerPaymentID, providerID string) (*entity.Payment, error) {
 	start := time.Now()
-	query := `SELECT * FROM payments WHERE provider_payment_id=$1 AND provider_id=$2`
+	query := `SELECT ` + paymentColumns + ` FROM payments WHERE provider_payment_id=$1 AND provider_id=$2`
 	row := r.db.QueryRowContext(ctx, query, providerPaymentID, providerID)
 
+	p, err := scanPayment(row)
+	if err != nil {
+		return nil, err
+	}
+
+	r.observeQuery("get_payment_by_provider_payment_id", start)
+
+	return p, nil
+}
+
+func (r *PaymentRepository) List(ctx context.Context, filter repository.PaymentFilter) ([]*entity.Payment, error) {
+	start := time.Now()
+
+	var conditions []string
+	var args []any
+	addCondition := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.Status != "" {
+		addCondition("status=$%d", filter.Status)
+	}
+	if filter.ProviderID != "" {
+		addCondition("provider_id=$%d", filter.ProviderID)
+	}
+	if filter.Currency != "" {
+		addCondition("currency=$%d", filter.Currency)
+	}
+	if !filter.CreatedAfter.IsZero() {
+		addCondition("created_at>=$%d", filter.CreatedAfter)
+	}
+	if !filter.CreatedBefore.IsZero() {
+		addCondition("created_at<$%d", filter.CreatedBefore)
+	}
+	if len(filter.Metadata) > 0 {
+		jsonMetadata, err := json.Marshal(filter.Metadata)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal metadata filter: %w", err)
+		}
+		addCondition("metadata @> $%d::jsonb", string(jsonMetadata))
+	}
+	if filter.After != nil {
+		args = append(args, filter.After.CreatedAt, filter.After.ID)
+		conditions = append(conditions, fmt.Sprintf("(created_at, id) < ($%d, $%d)", len(args)-1, len(args)))
+	}
+
+	query := `SELECT ` + paymentColumns + ` FROM payments`
+	if len(conditions) > 0 {
+		query += ` WHERE ` + strings.Join(conditions, " AND ")
+	}
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(` ORDER BY created_at DESC, id DESC LIMIT $%d`, len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list payments: %w", err)
+	}
+	defer rows.Close()
+
+	payments := make([]*entity.Payment, 0, filter.Limit)
+	for rows.Next() {
+		p, err := scanPayment(rows)
+		if err != nil {
+			return nil, err
+		}
+		payments = append(payments, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list payments: %w", err)
+	}
+
+	r.observeQuery("list_payments", start)
+
+	return payments, nil
+}
+
+func (r *PaymentRepository) ListExpired(ctx context.Context, before, attemptedBefore time.Time, limit int) ([]*entity.Payment, error) {
+	start := time.Now()
+	query := `SELECT ` + paymentColumns + ` FROM payments
+	WHERE status=$1 AND expires_at<=$2
+	AND (expire_attempted_at IS NULL OR expire_attempted_at<=$3)
+	ORDER BY COALESCE(expire_attempted_at, expires_at) LIMIT $4`
+
+	payments, err := r.queryPayments(ctx, query, entity.PaymentStatusPending, before, attemptedBefore, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list expired payments: %w", err)
+	}
+
+	r.observeQuery("list_expired_payments", start)
+
+	return payments, nil
+}
+
+func (r *PaymentRepository) ListStale(ctx context.Context, statuses []entity.PaymentStatus, before time.Time, limit int) ([]*entity.Payment, error) {
+	start := time.Now()
+	query := `SELECT ` + paymentColumns + ` FROM payments
+	WHERE status = ANY($1) AND provider_payment_id IS NOT NULL
+	AND updated_at<=$2 AND (reconciled_at IS NULL OR reconciled_at<=$2)
+	ORDER BY COALESCE(reconciled_at, updated_at) LIMIT $3`
+
+	payments, err := r.queryPayments(ctx, query, pq.Array(statuses), before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stale payments: %w", err)
+	}
+
+	r.observeQuery("list_stale_payments", start)
+
+	return payments, nil
+}
+
+func (r *PaymentRepository) MarkReconciled(ctx context.Context, id string, at time.Time) error {
+	start := time.Now()
+
+	result, err := r.db.ExecContext(ctx, `UPDATE payments SET reconciled_at=$1 WHERE id=$2`, at, id)
+	if err != nil {
+		return fmt.Errorf("failed to mark payment reconciled: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to mark payment reconciled: %w", err)
+	}
+	if rows == 0 {
+		return ErrPaymentNotFound
+	}
+
+	r.observeQuery("mark_payment_reconciled", start)
+
+	return nil
+}
+
+func (r *PaymentRepository) MarkExpireAttempted(ctx context.Context, id string, at time.Time) error {
+	start := time.Now()
+
+	result, err := r.db.ExecContext(ctx, `UPDATE payments SET expire_attempted_at=$1 WHERE id=$2`, at, id)
+	if err != nil {
+		return fmt.Errorf("failed to mark payment expire attempted: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to mark payment expire attempted: %w", err)
+	}
+	if rows == 0 {
+		return ErrPaymentNotFound
+	}
+
+	r.observeQuery("mark_payment_expire_attempted", start)
+
+	return nil
+}
+
+func (r *PaymentRepository) queryPayments(ctx context.Context, query string, args ...any) ([]*entity.Payment, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var payments []*entity.Payment
+	for rows.Next() {
+		p, err := scanPayment(rows)
+		if err != nil {
+			return nil, err
+		}
+		payments = append(payments, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return payments, nil
+}
+
+// dbtx is satisfied by both *sql.DB and *sql.Tx.
+type dbtx interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func (r *PaymentRepository) UpdatePayment(ctx context.Context, payment *entity.Payment, events ...event.DomainEvent) error {
+	if len(events) == 0 {
+		return r.updatePayment(ctx, r.db, payment)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// The outbox rows are written after the UPDATE, which locks the payment
+	// row until commit, so events of one payment get ids in commit order.
+	if err := r.updatePayment(ctx, tx, payment); err != nil {
+		return err
+	}
+	if err := insertOutbox(ctx, tx, events); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit payment update: %w", err)
+	}
+	return nil
+}
+
+func (r *PaymentRepository) UpdatePaymentLocked(ctx context.Context, id string, update func(payment *entity.Payment) (repository.PaymentUpdate, error)) (*entity.Payment, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	start := time.Now()
+	query := `SELECT ` + paymentColumns + ` FROM payments WHERE id=$1 FOR UPDATE`
+	payment, err := scanPayment(tx.QueryRowContext(ctx, query, id))
+	if err != nil {
+		return nil, err
+	}
+	r.observeQuery("lock_payment", start)
+
+	changes, err := update(payment)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.updatePayment(ctx, tx, payment); err != nil {
+		return nil, err
+	}
+	for _, txn := range changes.Transactions {
+		if err := insertTransaction(ctx, tx, txn); err != nil {
+			return nil, err
+		}
+	}
+	if err := insertOutbox(ctx, tx, changes.Events); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit payment update: %w", err)
+	}
+	return payment, nil
+}
+
+func (r *PaymentRepository) updatePayment(ctx context.Context, db dbtx, payment *entity.Payment) error {
+	start := time.Now()
+	query := `UPDATE payments SET 
+	amount=$1,
+	refunded_amount=$2,
+	currency=$3, 
+	idempotency_key=$4, 
+	provider_id=$5, 
+	provider_payment_id=$6,
+	payment_url=$7,
+	status=$8, 
+	updated_at=$9, 
+	completed_at=$10,
+	expires_at=$11, 
+	metadata=$12,
+	last_provider_event_at=$13,
+	captured_amount=$14 WHERE id=$15 AND status = ANY($16)`
+
+	jsonMetadata, err := json.Marshal(payment.Metadata)
+	if err != nil {
+		return fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+
+	res, err := db.ExecContext(ctx, query,
+		payment.Amount.Amount,
+		payment.RefundedAmount.Amount,
+		payment.Amount.Currency,
+		payment.IdempotencyKey,
+		payment.ProviderID,
+		payment.ProviderPaymentID,
+		payment.PaymentURL,
+		payment.Status,
+		payment.UpdatedAt,
+		nullTime(payment.CompletedAt),
+		nullTime(payment.ExpiresAt),
+		jsonMetadata,
+		nullTime(payment.LastProviderEventAt),
+		payment.CapturedAmount.Amount,
+		payment.ID,
+		pq.Array(entity.StatusesTransitioningTo(payment.Status)))
+
+	if err != nil {
+		return fmt.Errorf("failed to update payment: %w", err)
+	}
+
+	r.observeQuery("update_payment", start)
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update payment: %w", err)
+	}
+	if affected == 0 {
+		return r.rejectedUpdateError(ctx, db, payment)
+	}
+	return nil
+}
+
+// rejectedUpdateError explains why UpdatePayment matched no row: either the
+// payment does not exist or its stored status cannot move to the new one.
+func (r *PaymentRepository) rejectedUpdateError(ctx context.Context, db dbtx, payment *entity.Payment) error {
+	var current entity.PaymentStatus
+	err := db.QueryRowContext(ctx, `SELECT status FROM payments WHERE id=$1`, payment.ID).Scan(&current)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrPaymentNotFound
+		}
+		return fmt.Errorf("failed to update payment: %w", err)
+	}
+
+	if r.metrics != nil {
+		r.metrics.PaymentTransitionsRejected.WithLabelValues(string(current), string(payment.Status)).Inc()
+	}
+	return &entity.InvalidTransitionError{From: current, To: payment.Status}
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanPayment(row rowScanner) (*entity.Payment, error) {
 	var p entity.Payment
+	var providerPaymentID, paymentURL sql.NullString
+	var completedAt, expiresAt, lastProviderEventAt sql.NullTime
 	var metadataBytes []byte
 
 	err := row.Scan(
 		&p.ID,
-		&p.Amount,
-		&p.Currency,
+		&p.Amount.Amount,
+		&p.RefundedAmount.Amount,
+		&p.Amount.Currency,
 		&p.IdempotencyKey,
 		&p.ProviderID,
-		&p.ProviderPaymentID,
+		&providerPaymentID,
+		&paymentURL,
 		&p.Status,
 		&p.CreatedAt,
 		&p.UpdatedAt,
-		&p.ExpiresAt,
+		&completedAt,
+		&expiresAt,
 		&metadataBytes,
+		&lastProviderEventAt,
+		&p.Intent,
+		&p.CapturedAmount.Amount,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -89,8 +441,13 @@ func (r *PaymentRepository) GetByProviderPaymentID(ctx context.Context, provider
 		return nil, fmt.Errorf("failed to get payment: %w", err)
 	}
 
-	duration := time.Since(start).Seconds()
-	r.metrics.DBQueryDuration.WithLabelValues("create_payment").Observe(duration)
+	p.CapturedAmount.Currency = p.Amount.Currency
+	p.RefundedAmount.Currency = p.Amount.Currency
+	p.ProviderPaymentID = providerPaymentID.String
+	p.PaymentURL = paymentURL.String
+	p.CompletedAt = completedAt.Time
+	p.ExpiresAt = expiresAt.Time
+	p.LastProviderEventAt = lastProviderEventAt.Time
 
 	if len(metadataBytes) > 0 {
 		var metadata map[string]string
@@ -103,45 +460,18 @@ func (r *PaymentRepository) GetByProviderPaymentID(ctx context.Context, provider
 	return &p, nil
 }
 
-func (r *PaymentRepository) UpdatePayment(ctx context.Context, payment *entity.Payment) error {
-	start := time.Now()
-	query := `UPDATE payments SET 
-	amount=$1,
-	currency=$2, 
-	idempotency_key=$3, 
-	provider_id=$4, 
-	status=$5, 
-	updated_at=$6, 
-	expires_at=$7, 
-	metadata=$8 WHERE id=$9`
-
-	jsonMetadata, err := json.Marshal(payment.Metadata)
-	if err != nil {
-		return fmt.Errorf("failed to marshal metadata: %w", err)
-	}
-
-	_, err = r.db.ExecContext(ctx, query,
-		payment.Amount,
-		payment.Currency,
-		payment.IdempotencyKey,
-		payment.ProviderID,
-		payment.Status,
-		payment.UpdatedAt,
-		payment.ExpiresAt,
-		jsonMetadata,
-		payment.ID)
-
-	if err != nil {
-		return fmt.Errorf("failed to update payment: %w", err)
+func (r *PaymentRepository) observeQuery(operation string, start time.Time) {
+	if r.metrics == nil {
+		return
 	}
+	r.metrics.DBQueryDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+}
 
-	duration := time.Since(start).Seconds()
-	r.metrics.DBQueryDuration.WithLabelValues("create_payment").Observe(duration)
-	return nil
-
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
 }
 
 var (
-	ErrPaymentNotFound         = errors.New("payment not found")
+	ErrPaymentNotFound         = repository.ErrPaymentNotFound
 	ErrDuplicateIdempotencyKey = errors.New("duplicate idempotency key")
 )
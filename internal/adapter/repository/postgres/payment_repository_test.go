@@ -8,7 +8,11 @@ import (
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
 	"github.com/omerbeden/paymentgateway/internal/domain/entity"
+	"github.com/omerbeden/paymentgateway/internal/domain/event"
+	"github.com/omerbeden/paymentgateway/internal/domain/money"
+	"github.com/omerbeden/paymentgateway/internal/domain/repository"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -23,8 +27,7 @@ func TestCreatePayment_Success(t *testing.T) {
 
 	payment := &entity.Payment{
 		ID:             "pay_123456",
-		Amount:         99.99,
-		Currency:       "USD",
+		Amount:         money.New(9999, "USD"),
 		IdempotencyKey: "idem_key_123",
 		ProviderID:     "provider_123",
 		Status:         entity.PaymentStatusPending,
@@ -40,8 +43,8 @@ func TestCreatePayment_Success(t *testing.T) {
 	mock.ExpectExec(`INSERT INTO payments`).
 		WithArgs(
 			payment.ID,
-			payment.Amount,
-			payment.Currency,
+			payment.Amount.Amount,
+			payment.Amount.Currency,
 			payment.IdempotencyKey,
 			payment.ProviderID,
 			payment.Status,
@@ -49,6 +52,7 @@ func TestCreatePayment_Success(t *testing.T) {
 			payment.UpdatedAt,
 			payment.ExpiresAt,
 			sqlmock.AnyArg(), // Metadata JSON
+			payment.Intent,
 		).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
@@ -71,8 +75,7 @@ func TestCreatePayment_DuplicateIdempotencyKey(t *testing.T) {
 
 	payment := &entity.Payment{
 		ID:             "pay_123456",
-		Amount:         99.99,
-		Currency:       "USD",
+		Amount:         money.New(9999, "USD"),
 		IdempotencyKey: "idem_key_duplicate",
 		ProviderID:     "provider_123",
 		Status:         entity.PaymentStatusPending,
@@ -84,8 +87,8 @@ func TestCreatePayment_DuplicateIdempotencyKey(t *testing.T) {
 	mock.ExpectExec(`INSERT INTO payments`).
 		WithArgs(
 			payment.ID,
-			payment.Amount,
-			payment.Currency,
+			payment.Amount.Amount,
+			payment.Amount.Currency,
 			payment.IdempotencyKey,
 			payment.ProviderID,
 			payment.Status,
@@ -93,6 +96,7 @@ func TestCreatePayment_DuplicateIdempotencyKey(t *testing.T) {
 			payment.UpdatedAt,
 			payment.ExpiresAt,
 			sqlmock.AnyArg(),
+			payment.Intent,
 		).
 		WillReturnError(errors.New("pq: duplicate key value violates unique constraint \"payments_idempotency_key_key\""))
 
@@ -115,8 +119,7 @@ func TestCreatePayment_InvalidMetadata(t *testing.T) {
 
 	payment := &entity.Payment{
 		ID:             "pay_123456",
-		Amount:         99.99,
-		Currency:       "USD",
+		Amount:         money.New(9999, "USD"),
 		IdempotencyKey: "idem_key_123",
 		ProviderID:     "provider_123",
 		Status:         entity.PaymentStatusPending,
@@ -132,8 +135,8 @@ func TestCreatePayment_InvalidMetadata(t *testing.T) {
 	mock.ExpectExec(`INSERT INTO payments`).
 		WithArgs(
 			payment.ID,
-			payment.Amount,
-			payment.Currency,
+			payment.Amount.Amount,
+			payment.Amount.Currency,
 			payment.IdempotencyKey,
 			payment.ProviderID,
 			payment.Status,
@@ -141,6 +144,7 @@ func TestCreatePayment_InvalidMetadata(t *testing.T) {
 			payment.UpdatedAt,
 			payment.ExpiresAt,
 			sqlmock.AnyArg(),
+			payment.Intent,
 		).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
@@ -163,8 +167,7 @@ func TestCreatePayment_DatabaseError(t *testing.T) {
 
 	payment := &entity.Payment{
 		ID:             "pay_123456",
-		Amount:         99.99,
-		Currency:       "USD",
+		Amount:         money.New(9999, "USD"),
 		IdempotencyKey: "idem_key_123",
 		ProviderID:     "provider_123",
 		Status:         entity.PaymentStatusPending,
@@ -177,8 +180,8 @@ func TestCreatePayment_DatabaseError(t *testing.T) {
 	mock.ExpectExec(`INSERT INTO payments`).
 		WithArgs(
 			payment.ID,
-			payment.Amount,
-			payment.Currency,
+			payment.Amount.Amount,
+			payment.Amount.Currency,
 			payment.IdempotencyKey,
 			payment.ProviderID,
 			payment.Status,
@@ -186,6 +189,7 @@ func TestCreatePayment_DatabaseError(t *testing.T) {
 			payment.UpdatedAt,
 			payment.ExpiresAt,
 			sqlmock.AnyArg(),
+			payment.Intent,
 		).
 		WillReturnError(sql.ErrConnDone)
 
@@ -210,8 +214,7 @@ func TestCreatePayment_ContextCancellation(t *testing.T) {
 
 	payment := &entity.Payment{
 		ID:             "pay_123456",
-		Amount:         99.99,
-		Currency:       "USD",
+		Amount:         money.New(9999, "USD"),
 		IdempotencyKey: "idem_key_123",
 		ProviderID:     "provider_123",
 		Status:         entity.PaymentStatusPending,
@@ -224,8 +227,8 @@ func TestCreatePayment_ContextCancellation(t *testing.T) {
 	mock.ExpectExec(`INSERT INTO payments`).
 		WithArgs(
 			payment.ID,
-			payment.Amount,
-			payment.Currency,
+			payment.Amount.Amount,
+			payment.Amount.Currency,
 			payment.IdempotencyKey,
 			payment.ProviderID,
 			payment.Status,
@@ -233,6 +236,7 @@ func TestCreatePayment_ContextCancellation(t *testing.T) {
 			payment.UpdatedAt,
 			payment.ExpiresAt,
 			sqlmock.AnyArg(),
+			payment.Intent,
 		).
 		WillReturnError(context.Canceled)
 
@@ -255,8 +259,7 @@ func TestCreatePayment_WithNilMetadata(t *testing.T) {
 
 	payment := &entity.Payment{
 		ID:             "pay_123456",
-		Amount:         50.00,
-		Currency:       "EUR",
+		Amount:         money.New(5000, "EUR"),
 		IdempotencyKey: "idem_key_789",
 		ProviderID:     "provider_456",
 		Status:         entity.PaymentStatusPending,
@@ -269,8 +272,8 @@ func TestCreatePayment_WithNilMetadata(t *testing.T) {
 	mock.ExpectExec(`INSERT INTO payments`).
 		WithArgs(
 			payment.ID,
-			payment.Amount,
-			payment.Currency,
+			payment.Amount.Amount,
+			payment.Amount.Currency,
 			payment.IdempotencyKey,
 			payment.ProviderID,
 			payment.Status,
@@ -278,6 +281,7 @@ func TestCreatePayment_WithNilMetadata(t *testing.T) {
 			payment.UpdatedAt,
 			payment.ExpiresAt,
 			sqlmock.AnyArg(),
+			payment.Intent,
 		).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
@@ -300,8 +304,7 @@ func TestUpdatePayment_Success(t *testing.T) {
 
 	payment := &entity.Payment{
 		ID:             "pay_123456",
-		Amount:         150.50,
-		Currency:       "USD",
+		Amount:         money.New(15050, "USD"),
 		IdempotencyKey: "idem_key_123",
 		ProviderID:     "provider_123",
 		Status:         entity.PaymentStatusSucceeded,
@@ -315,15 +318,22 @@ func TestUpdatePayment_Success(t *testing.T) {
 
 	mock.ExpectExec(`UPDATE payments SET`).
 		WithArgs(
-			payment.Amount,
-			payment.Currency,
+			payment.Amount.Amount,
+			payment.RefundedAmount.Amount,
+			payment.Amount.Currency,
 			payment.IdempotencyKey,
 			payment.ProviderID,
+			payment.ProviderPaymentID,
+			payment.PaymentURL,
 			payment.Status,
 			payment.UpdatedAt,
+			nil, // CompletedAt
 			payment.ExpiresAt,
 			sqlmock.AnyArg(), // Metadata JSON
+			nil,              // LastProviderEventAt
+			payment.CapturedAmount.Amount,
 			payment.ID,
+			pq.Array(entity.StatusesTransitioningTo(payment.Status)),
 		).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
@@ -346,8 +356,7 @@ func TestUpdatePayment_StatusChangeToFailed(t *testing.T) {
 
 	payment := &entity.Payment{
 		ID:             "pay_failed_123",
-		Amount:         99.99,
-		Currency:       "USD",
+		Amount:         money.New(9999, "USD"),
 		IdempotencyKey: "idem_key_failed",
 		ProviderID:     "provider_123",
 		Status:         entity.PaymentStatusFailed,
@@ -360,15 +369,22 @@ func TestUpdatePayment_StatusChangeToFailed(t *testing.T) {
 
 	mock.ExpectExec(`UPDATE payments SET`).
 		WithArgs(
-			payment.Amount,
-			payment.Currency,
+			payment.Amount.Amount,
+			payment.RefundedAmount.Amount,
+			payment.Amount.Currency,
 			payment.IdempotencyKey,
 			payment.ProviderID,
+			payment.ProviderPaymentID,
+			payment.PaymentURL,
 			payment.Status,
 			payment.UpdatedAt,
+			nil, // CompletedAt
 			payment.ExpiresAt,
 			sqlmock.AnyArg(),
+			nil, // LastProviderEventAt
+			payment.CapturedAmount.Amount,
 			payment.ID,
+			pq.Array(entity.StatusesTransitioningTo(payment.Status)),
 		).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
@@ -391,8 +407,7 @@ func TestUpdatePayment_WithEmptyMetadata(t *testing.T) {
 
 	payment := &entity.Payment{
 		ID:             "pay_123456",
-		Amount:         99.99,
-		Currency:       "USD",
+		Amount:         money.New(9999, "USD"),
 		IdempotencyKey: "idem_key_123",
 		ProviderID:     "provider_123",
 		Status:         entity.PaymentStatusProcessing,
@@ -403,15 +418,22 @@ func TestUpdatePayment_WithEmptyMetadata(t *testing.T) {
 
 	mock.ExpectExec(`UPDATE payments SET`).
 		WithArgs(
-			payment.Amount,
-			payment.Currency,
+			payment.Amount.Amount,
+			payment.RefundedAmount.Amount,
+			payment.Amount.Currency,
 			payment.IdempotencyKey,
 			payment.ProviderID,
+			payment.ProviderPaymentID,
+			payment.PaymentURL,
 			payment.Status,
 			payment.UpdatedAt,
+			nil, // CompletedAt
 			payment.ExpiresAt,
 			sqlmock.AnyArg(),
+			nil, // LastProviderEventAt
+			payment.CapturedAmount.Amount,
 			payment.ID,
+			pq.Array(entity.StatusesTransitioningTo(payment.Status)),
 		).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
@@ -434,8 +456,7 @@ func TestUpdatePayment_WithNilMetadata(t *testing.T) {
 
 	payment := &entity.Payment{
 		ID:             "pay_123456",
-		Amount:         75.25,
-		Currency:       "EUR",
+		Amount:         money.New(7525, "EUR"),
 		IdempotencyKey: "idem_key_456",
 		ProviderID:     "provider_789",
 		Status:         entity.PaymentStatusPending,
@@ -446,15 +467,22 @@ func TestUpdatePayment_WithNilMetadata(t *testing.T) {
 
 	mock.ExpectExec(`UPDATE payments SET`).
 		WithArgs(
-			payment.Amount,
-			payment.Currency,
+			payment.Amount.Amount,
+			payment.RefundedAmount.Amount,
+			payment.Amount.Currency,
 			payment.IdempotencyKey,
 			payment.ProviderID,
+			payment.ProviderPaymentID,
+			payment.PaymentURL,
 			payment.Status,
 			payment.UpdatedAt,
+			nil, // CompletedAt
 			payment.ExpiresAt,
 			sqlmock.AnyArg(),
+			nil, // LastProviderEventAt
+			payment.CapturedAmount.Amount,
 			payment.ID,
+			pq.Array(entity.StatusesTransitioningTo(payment.Status)),
 		).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
@@ -477,8 +505,7 @@ func TestUpdatePayment_DatabaseError(t *testing.T) {
 
 	payment := &entity.Payment{
 		ID:             "pay_123456",
-		Amount:         99.99,
-		Currency:       "USD",
+		Amount:         money.New(9999, "USD"),
 		IdempotencyKey: "idem_key_123",
 		ProviderID:     "provider_123",
 		Status:         entity.PaymentStatusSucceeded,
@@ -489,15 +516,22 @@ func TestUpdatePayment_DatabaseError(t *testing.T) {
 
 	mock.ExpectExec(`UPDATE payments SET`).
 		WithArgs(
-			payment.Amount,
-			payment.Currency,
+			payment.Amount.Amount,
+			payment.RefundedAmount.Amount,
+			payment.Amount.Currency,
 			payment.IdempotencyKey,
 			payment.ProviderID,
+			payment.ProviderPaymentID,
+			payment.PaymentURL,
 			payment.Status,
 			payment.UpdatedAt,
+			nil, // CompletedAt
 			payment.ExpiresAt,
 			sqlmock.AnyArg(),
+			nil, // LastProviderEventAt
+			payment.CapturedAmount.Amount,
 			payment.ID,
+			pq.Array(entity.StatusesTransitioningTo(payment.Status)),
 		).
 		WillReturnError(sql.ErrConnDone)
 
@@ -522,8 +556,7 @@ func TestUpdatePayment_ContextCancellation(t *testing.T) {
 
 	payment := &entity.Payment{
 		ID:             "pay_123456",
-		Amount:         99.99,
-		Currency:       "USD",
+		Amount:         money.New(9999, "USD"),
 		IdempotencyKey: "idem_key_123",
 		ProviderID:     "provider_123",
 		Status:         entity.PaymentStatusPending,
@@ -534,15 +567,22 @@ func TestUpdatePayment_ContextCancellation(t *testing.T) {
 
 	mock.ExpectExec(`UPDATE payments SET`).
 		WithArgs(
-			payment.Amount,
-			payment.Currency,
+			payment.Amount.Amount,
+			payment.RefundedAmount.Amount,
+			payment.Amount.Currency,
 			payment.IdempotencyKey,
 			payment.ProviderID,
+			payment.ProviderPaymentID,
+			payment.PaymentURL,
 			payment.Status,
 			payment.UpdatedAt,
+			nil, // CompletedAt
 			payment.ExpiresAt,
 			sqlmock.AnyArg(),
+			nil, // LastProviderEventAt
+			payment.CapturedAmount.Amount,
 			payment.ID,
+			pq.Array(entity.StatusesTransitioningTo(payment.Status)),
 		).
 		WillReturnError(context.Canceled)
 
@@ -565,8 +605,7 @@ func TestUpdatePayment_StatusChangeToRefunded(t *testing.T) {
 
 	payment := &entity.Payment{
 		ID:             "pay_refund_123",
-		Amount:         0.00,
-		Currency:       "USD",
+		Amount:         money.New(0, "USD"),
 		IdempotencyKey: "idem_key_refund",
 		ProviderID:     "provider_123",
 		Status:         entity.PaymentStatusRefunded,
@@ -580,15 +619,22 @@ func TestUpdatePayment_StatusChangeToRefunded(t *testing.T) {
 
 	mock.ExpectExec(`UPDATE payments SET`).
 		WithArgs(
-			payment.Amount,
-			payment.Currency,
+			payment.Amount.Amount,
+			payment.RefundedAmount.Amount,
+			payment.Amount.Currency,
 			payment.IdempotencyKey,
 			payment.ProviderID,
+			payment.ProviderPaymentID,
+			payment.PaymentURL,
 			payment.Status,
 			payment.UpdatedAt,
+			nil, // CompletedAt
 			payment.ExpiresAt,
 			sqlmock.AnyArg(),
+			nil, // LastProviderEventAt
+			payment.CapturedAmount.Amount,
 			payment.ID,
+			pq.Array(entity.StatusesTransitioningTo(payment.Status)),
 		).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
@@ -611,8 +657,7 @@ func TestUpdatePayment_MultipleMetadataFields(t *testing.T) {
 
 	payment := &entity.Payment{
 		ID:             "pay_metadata_test",
-		Amount:         250.00,
-		Currency:       "GBP",
+		Amount:         money.New(25000, "GBP"),
 		IdempotencyKey: "idem_key_metadata",
 		ProviderID:     "provider_456",
 		Status:         entity.PaymentStatusSucceeded,
@@ -629,15 +674,22 @@ func TestUpdatePayment_MultipleMetadataFields(t *testing.T) {
 
 	mock.ExpectExec(`UPDATE payments SET`).
 		WithArgs(
-			payment.Amount,
-			payment.Currency,
+			payment.Amount.Amount,
+			payment.RefundedAmount.Amount,
+			payment.Amount.Currency,
 			payment.IdempotencyKey,
 			payment.ProviderID,
+			payment.ProviderPaymentID,
+			payment.PaymentURL,
 			payment.Status,
 			payment.UpdatedAt,
+			nil, // CompletedAt
 			payment.ExpiresAt,
 			sqlmock.AnyArg(),
+			nil, // LastProviderEventAt
+			payment.CapturedAmount.Amount,
 			payment.ID,
+			pq.Array(entity.StatusesTransitioningTo(payment.Status)),
 		).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
@@ -660,8 +712,7 @@ func TestUpdatePayment_LargeAmount(t *testing.T) {
 
 	payment := &entity.Payment{
 		ID:             "pay_large_amt",
-		Amount:         999999.99,
-		Currency:       "USD",
+		Amount:         money.New(99999999, "USD"),
 		IdempotencyKey: "idem_key_large",
 		ProviderID:     "provider_123",
 		Status:         entity.PaymentStatusSucceeded,
@@ -674,15 +725,22 @@ func TestUpdatePayment_LargeAmount(t *testing.T) {
 
 	mock.ExpectExec(`UPDATE payments SET`).
 		WithArgs(
-			payment.Amount,
-			payment.Currency,
+			payment.Amount.Amount,
+			payment.RefundedAmount.Amount,
+			payment.Amount.Currency,
 			payment.IdempotencyKey,
 			payment.ProviderID,
+			payment.ProviderPaymentID,
+			payment.PaymentURL,
 			payment.Status,
 			payment.UpdatedAt,
+			nil, // CompletedAt
 			payment.ExpiresAt,
 			sqlmock.AnyArg(),
+			nil, // LastProviderEventAt
+			payment.CapturedAmount.Amount,
 			payment.ID,
+			pq.Array(entity.StatusesTransitioningTo(payment.Status)),
 		).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
@@ -706,8 +764,7 @@ func TestGetByProviderPaymentID_Success(t *testing.T) {
 	now := time.Now()
 	payment := &entity.Payment{
 		ID:                "pay_123456",
-		Amount:            99.99,
-		Currency:          "USD",
+		Amount:            money.New(9999, "USD"),
 		IdempotencyKey:    "idem_key_123",
 		ProviderID:        "provider_123",
 		ProviderPaymentID: "provider_pay_123",
@@ -720,10 +777,10 @@ func TestGetByProviderPaymentID_Success(t *testing.T) {
 		},
 	}
 
-	rows := sqlmock.NewRows([]string{"id", "amount", "currency", "idempotency_key", "provider_id", "provider_payment_id", "status", "created_at", "updated_at", "expires_at", "metadata"}).
-		AddRow(payment.ID, payment.Amount, payment.Currency, payment.IdempotencyKey, payment.ProviderID, payment.ProviderPaymentID, payment.Status, payment.CreatedAt, payment.UpdatedAt, payment.ExpiresAt, `{"order_id":"order_123"}`)
+	rows := sqlmock.NewRows([]string{"id", "amount", "refunded_amount", "currency", "idempotency_key", "provider_id", "provider_payment_id", "payment_url", "status", "created_at", "updated_at", "completed_at", "expires_at", "metadata", "last_provider_event_at", "intent", "captured_amount"}).
+		AddRow(payment.ID, payment.Amount.Amount, 0, payment.Amount.Currency, payment.IdempotencyKey, payment.ProviderID, payment.ProviderPaymentID, nil, payment.Status, payment.CreatedAt, payment.UpdatedAt, nil, payment.ExpiresAt, `{"order_id":"order_123"}`, nil, entity.PaymentIntentCapture, 0)
 
-	mock.ExpectQuery(`SELECT \* FROM payments WHERE provider_payment_id=\$1 AND provider_id=\$2`).
+	mock.ExpectQuery(`SELECT (.+) FROM payments WHERE provider_payment_id=\$1 AND provider_id=\$2`).
 		WithArgs(payment.ProviderPaymentID, payment.ProviderID).
 		WillReturnRows(rows)
 
@@ -734,7 +791,7 @@ func TestGetByProviderPaymentID_Success(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, payment.ID, result.ID)
 	assert.Equal(t, payment.Amount, result.Amount)
-	assert.Equal(t, payment.Currency, result.Currency)
+	assert.Equal(t, money.New(0, "USD"), result.RefundedAmount)
 	assert.Equal(t, payment.ProviderID, result.ProviderID)
 	assert.Equal(t, payment.Status, result.Status)
 	assert.Equal(t, payment.Metadata, result.Metadata)
@@ -750,7 +807,7 @@ func TestGetByProviderPaymentID_NotFound(t *testing.T) {
 	repo := NewPaymentRepository(db, nil)
 	ctx := context.Background()
 
-	mock.ExpectQuery(`SELECT \* FROM payments WHERE provider_payment_id=\$1 AND provider_id=\$2`).
+	mock.ExpectQuery(`SELECT (.+) FROM payments WHERE provider_payment_id=\$1 AND provider_id=\$2`).
 		WithArgs("nonexistent_pay", "provider_123").
 		WillReturnError(sql.ErrNoRows)
 
@@ -772,7 +829,7 @@ func TestGetByProviderPaymentID_WrongProvider(t *testing.T) {
 	repo := NewPaymentRepository(db, nil)
 	ctx := context.Background()
 
-	mock.ExpectQuery(`SELECT \* FROM payments WHERE provider_payment_id=\$1 AND provider_id=\$2`).
+	mock.ExpectQuery(`SELECT (.+) FROM payments WHERE provider_payment_id=\$1 AND provider_id=\$2`).
 		WithArgs("provider_pay_456", "wrong_provider").
 		WillReturnError(sql.ErrNoRows)
 
@@ -799,10 +856,10 @@ func TestGetByProviderPaymentID_WithoutMetadata(t *testing.T) {
 	providerPaymentID := "provider_pay_no_meta"
 	providerID := "provider_789"
 
-	rows := sqlmock.NewRows([]string{"id", "amount", "currency", "idempotency_key", "provider_id", "provider_payment_id", "status", "created_at", "updated_at", "expires_at", "metadata"}).
-		AddRow(paymentID, 50.00, "EUR", "idem_789", providerID, providerPaymentID, entity.PaymentStatusPending, now, now, now.Add(24*time.Hour), []byte(""))
+	rows := sqlmock.NewRows([]string{"id", "amount", "refunded_amount", "currency", "idempotency_key", "provider_id", "provider_payment_id", "payment_url", "status", "created_at", "updated_at", "completed_at", "expires_at", "metadata", "last_provider_event_at", "intent", "captured_amount"}).
+		AddRow(paymentID, 5000, 0, "EUR", "idem_789", providerID, providerPaymentID, nil, entity.PaymentStatusPending, now, now, nil, now.Add(24*time.Hour), []byte(""), nil, entity.PaymentIntentCapture, 0)
 
-	mock.ExpectQuery(`SELECT \* FROM payments WHERE provider_payment_id=\$1 AND provider_id=\$2`).
+	mock.ExpectQuery(`SELECT (.+) FROM payments WHERE provider_payment_id=\$1 AND provider_id=\$2`).
 		WithArgs(providerPaymentID, providerID).
 		WillReturnRows(rows)
 
@@ -812,7 +869,7 @@ func TestGetByProviderPaymentID_WithoutMetadata(t *testing.T) {
 	// Assert
 	assert.NoError(t, err)
 	assert.Equal(t, paymentID, result.ID)
-	assert.Equal(t, 50.00, result.Amount)
+	assert.Equal(t, money.New(5000, "EUR"), result.Amount)
 	assert.Nil(t, result.Metadata)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
@@ -826,7 +883,7 @@ func TestGetByProviderPaymentID_DatabaseError(t *testing.T) {
 	repo := NewPaymentRepository(db, nil)
 	ctx := context.Background()
 
-	mock.ExpectQuery(`SELECT \* FROM payments WHERE provider_payment_id=\$1 AND provider_id=\$2`).
+	mock.ExpectQuery(`SELECT (.+) FROM payments WHERE provider_payment_id=\$1 AND provider_id=\$2`).
 		WithArgs("provider_pay_error", "provider_123").
 		WillReturnError(sql.ErrConnDone)
 
@@ -857,10 +914,10 @@ func TestGetByProviderPaymentID_WithComplexMetadata(t *testing.T) {
 		"transaction_ref": "txn_666",
 	}
 
-	rows := sqlmock.NewRows([]string{"id", "amount", "currency", "idempotency_key", "provider_id", "provider_payment_id", "status", "created_at", "updated_at", "expires_at", "metadata"}).
-		AddRow("pay_complex", 299.99, "GBP", "idem_complex", "provider_123", "provider_pay_complex", entity.PaymentStatusSucceeded, now, now, now.Add(24*time.Hour), `{"order_id":"order_999","customer_id":"cust_888","invoice_number":"inv_777","transaction_ref":"txn_666"}`)
+	rows := sqlmock.NewRows([]string{"id", "amount", "refunded_amount", "currency", "idempotency_key", "provider_id", "provider_payment_id", "payment_url", "status", "created_at", "updated_at", "completed_at", "expires_at", "metadata", "last_provider_event_at", "intent", "captured_amount"}).
+		AddRow("pay_complex", 29999, 0, "GBP", "idem_complex", "provider_123", "provider_pay_complex", nil, entity.PaymentStatusSucceeded, now, now, nil, now.Add(24*time.Hour), `{"order_id":"order_999","customer_id":"cust_888","invoice_number":"inv_777","transaction_ref":"txn_666"}`, nil, entity.PaymentIntentCapture, 0)
 
-	mock.ExpectQuery(`SELECT \* FROM payments WHERE provider_payment_id=\$1 AND provider_id=\$2`).
+	mock.ExpectQuery(`SELECT (.+) FROM payments WHERE provider_payment_id=\$1 AND provider_id=\$2`).
 		WithArgs("provider_pay_complex", "provider_123").
 		WillReturnRows(rows)
 
@@ -885,10 +942,10 @@ func TestGetByProviderPaymentID_FailedPaymentStatus(t *testing.T) {
 
 	now := time.Now()
 
-	rows := sqlmock.NewRows([]string{"id", "amount", "currency", "idempotency_key", "provider_id", "provider_payment_id", "status", "created_at", "updated_at", "expires_at", "metadata"}).
-		AddRow("pay_failed", 75.50, "USD", "idem_failed", "provider_456", "provider_pay_failed", entity.PaymentStatusFailed, now, now, now.Add(24*time.Hour), `{"error":"insufficient_funds"}`)
+	rows := sqlmock.NewRows([]string{"id", "amount", "refunded_amount", "currency", "idempotency_key", "provider_id", "provider_payment_id", "payment_url", "status", "created_at", "updated_at", "completed_at", "expires_at", "metadata", "last_provider_event_at", "intent", "captured_amount"}).
+		AddRow("pay_failed", 7550, 0, "USD", "idem_failed", "provider_456", "provider_pay_failed", nil, entity.PaymentStatusFailed, now, now, nil, now.Add(24*time.Hour), `{"error":"insufficient_funds"}`, nil, entity.PaymentIntentCapture, 0)
 
-	mock.ExpectQuery(`SELECT \* FROM payments WHERE provider_payment_id=\$1 AND provider_id=\$2`).
+	mock.ExpectQuery(`SELECT (.+) FROM payments WHERE provider_payment_id=\$1 AND provider_id=\$2`).
 		WithArgs("provider_pay_failed", "provider_456").
 		WillReturnRows(rows)
 
@@ -900,3 +957,446 @@ func TestGetByProviderPaymentID_FailedPaymentStatus(t *testing.T) {
 	assert.Equal(t, entity.PaymentStatusFailed, result.Status)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
+
+func TestGetByID_Success(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := NewPaymentRepository(db, nil)
+	ctx := context.Background()
+
+	now := time.Now()
+	completedAt := now.Add(time.Minute)
+
+	rows := sqlmock.NewRows([]string{"id", "amount", "refunded_amount", "currency", "idempotency_key", "provider_id", "provider_payment_id", "payment_url", "status", "created_at", "updated_at", "completed_at", "expires_at", "metadata", "last_provider_event_at", "intent", "captured_amount"}).
+		AddRow("pay_123456", 9999, 0, "USD", "idem_key_123", "paypal", "provider_pay_123", "https://paypal.test/approve", entity.PaymentStatusSucceeded, now, now, completedAt, nil, `{"order_id":"order_123"}`, nil, entity.PaymentIntentCapture, 0)
+
+	mock.ExpectQuery(`SELECT (.+) FROM payments WHERE id=\$1`).
+		WithArgs("pay_123456").
+		WillReturnRows(rows)
+
+	// Act
+	result, err := repo.GetByID(ctx, "pay_123456")
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, "pay_123456", result.ID)
+	assert.Equal(t, "provider_pay_123", result.ProviderPaymentID)
+	assert.Equal(t, "https://paypal.test/approve", result.PaymentURL)
+	assert.Equal(t, entity.PaymentStatusSucceeded, result.Status)
+	assert.Equal(t, completedAt, result.CompletedAt)
+	assert.True(t, result.ExpiresAt.IsZero())
+	assert.Equal(t, map[string]string{"order_id": "order_123"}, result.Metadata)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetByID_NotFound(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := NewPaymentRepository(db, nil)
+	ctx := context.Background()
+
+	mock.ExpectQuery(`SELECT (.+) FROM payments WHERE id=\$1`).
+		WithArgs("nonexistent_pay").
+		WillReturnError(sql.ErrNoRows)
+
+	// Act
+	result, err := repo.GetByID(ctx, "nonexistent_pay")
+
+	// Assert
+	assert.Equal(t, ErrPaymentNotFound, err)
+	assert.Nil(t, result)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestList_WithFiltersAndCursor(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := NewPaymentRepository(db, nil)
+	ctx := context.Background()
+
+	now := time.Now()
+	cursor := &repository.PaymentCursor{CreatedAt: now, ID: "pay_cursor"}
+	filter := repository.PaymentFilter{
+		Status:     entity.PaymentStatusProcessing,
+		ProviderID: "paypal",
+		Currency:   "USD",
+		Metadata:   map[string]string{"order_id": "42"},
+		After:      cursor,
+		Limit:      2,
+	}
+
+	rows := sqlmock.NewRows([]string{"id", "amount", "refunded_amount", "currency", "idempotency_key", "provider_id", "provider_payment_id", "payment_url", "status", "created_at", "updated_at", "completed_at", "expires_at", "metadata", "last_provider_event_at", "intent", "captured_amount"}).
+		AddRow("pay_2", 1000, 0, "USD", "idem_2", "paypal", "pp_2", nil, entity.PaymentStatusProcessing, now.Add(-time.Minute), now, nil, nil, `{"order_id":"42"}`, nil, entity.PaymentIntentCapture, 0).
+		AddRow("pay_1", 2000, 0, "USD", "idem_1", "paypal", "pp_1", nil, entity.PaymentStatusProcessing, now.Add(-2*time.Minute), now, nil, nil, `{"order_id":"42"}`, nil, entity.PaymentIntentCapture, 0)
+
+	mock.ExpectQuery(`SELECT (.+) FROM payments WHERE status=\$1 AND provider_id=\$2 AND currency=\$3 AND metadata @> \$4::jsonb AND \(created_at, id\) < \(\$5, \$6\) ORDER BY created_at DESC, id DESC LIMIT \$7`).
+		WithArgs(entity.PaymentStatusProcessing, "paypal", "USD", `{"order_id":"42"}`, cursor.CreatedAt, cursor.ID, 2).
+		WillReturnRows(rows)
+
+	// Act
+	result, err := repo.List(ctx, filter)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Len(t, result, 2)
+	assert.Equal(t, "pay_2", result[0].ID)
+	assert.Equal(t, "pay_1", result[1].ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestList_NoFilters(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := NewPaymentRepository(db, nil)
+	ctx := context.Background()
+
+	mock.ExpectQuery(`SELECT (.+) FROM payments ORDER BY created_at DESC, id DESC LIMIT \$1`).
+		WithArgs(20).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	// Act
+	result, err := repo.List(ctx, repository.PaymentFilter{Limit: 20})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Empty(t, result)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestList_DatabaseError(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := NewPaymentRepository(db, nil)
+	ctx := context.Background()
+
+	mock.ExpectQuery(`SELECT (.+) FROM payments`).
+		WillReturnError(sql.ErrConnDone)
+
+	// Act
+	result, err := repo.List(ctx, repository.PaymentFilter{Limit: 20})
+
+	// Assert
+	assert.Error(t, err)
+	assert.Nil(t, result)
+	assert.Contains(t, err.Error(), "failed to list payments")
+}
+
+func TestListExpired(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := NewPaymentRepository(db, nil)
+	ctx := context.Background()
+
+	now := time.Now()
+	rows := sqlmock.NewRows([]string{"id", "amount", "refunded_amount", "currency", "idempotency_key", "provider_id", "provider_payment_id", "payment_url", "status", "created_at", "updated_at", "completed_at", "expires_at", "metadata", "last_provider_event_at", "intent", "captured_amount"}).
+		AddRow("pay_1", 1000, 0, "USD", "idem_1", "paypal", "pp_1", nil, entity.PaymentStatusPending, now.Add(-25*time.Hour), now.Add(-25*time.Hour), nil, now.Add(-time.Hour), nil, nil, entity.PaymentIntentCapture, 0)
+
+	attemptedBefore := now.Add(-10 * time.Minute)
+	mock.ExpectQuery(`SELECT (.+) FROM payments\s+WHERE status=\$1 AND expires_at<=\$2\s+AND \(expire_attempted_at IS NULL OR expire_attempted_at<=\$3\)\s+ORDER BY COALESCE\(expire_attempted_at, expires_at\) LIMIT \$4`).
+		WithArgs(entity.PaymentStatusPending, now, attemptedBefore, 50).
+		WillReturnRows(rows)
+
+	// Act
+	result, err := repo.ListExpired(ctx, now, attemptedBefore, 50)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Len(t, result, 1)
+	assert.Equal(t, "pay_1", result[0].ID)
+	assert.Equal(t, now.Add(-time.Hour), result[0].ExpiresAt)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListStale(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := NewPaymentRepository(db, nil)
+	ctx := context.Background()
+
+	before := time.Now().Add(-15 * time.Minute)
+	statuses := []entity.PaymentStatus{entity.PaymentStatusPending, entity.PaymentStatusProcessing}
+	rows := sqlmock.NewRows([]string{"id", "amount", "refunded_amount", "currency", "idempotency_key", "provider_id", "provider_payment_id", "payment_url", "status", "created_at", "updated_at", "completed_at", "expires_at", "metadata", "last_provider_event_at", "intent", "captured_amount"}).
+		AddRow("pay_1", 1000, 0, "USD", "idem_1", "paypal", "pp_1", nil, entity.PaymentStatusProcessing, before.Add(-time.Hour), before.Add(-time.Hour), nil, nil, nil, nil, entity.PaymentIntentCapture, 0)
+
+	mock.ExpectQuery(`SELECT (.+) FROM payments\s+WHERE status = ANY\(\$1\) AND provider_payment_id IS NOT NULL\s+AND updated_at<=\$2 AND \(reconciled_at IS NULL OR reconciled_at<=\$2\)\s+ORDER BY COALESCE\(reconciled_at, updated_at\) LIMIT \$3`).
+		WithArgs(pq.Array(statuses), before, 50).
+		WillReturnRows(rows)
+
+	// Act
+	result, err := repo.ListStale(ctx, statuses, before, 50)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Len(t, result, 1)
+	assert.Equal(t, "pp_1", result[0].ProviderPaymentID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMarkReconciled_NotFound(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := NewPaymentRepository(db, nil)
+	ctx := context.Background()
+	now := time.Now()
+
+	mock.ExpectExec(`UPDATE payments SET reconciled_at=\$1 WHERE id=\$2`).
+		WithArgs(now, "pay_missing").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	// Act
+	err = repo.MarkReconciled(ctx, "pay_missing", now)
+
+	// Assert
+	assert.True(t, errors.Is(err, ErrPaymentNotFound))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMarkExpireAttempted(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := NewPaymentRepository(db, nil)
+	ctx := context.Background()
+	now := time.Now()
+
+	mock.ExpectExec(`UPDATE payments SET expire_attempted_at=\$1 WHERE id=\$2`).
+		WithArgs(now, "pay_1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// Act
+	err = repo.MarkExpireAttempted(ctx, "pay_1", now)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdatePayment_RejectsIllegalTransition(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := NewPaymentRepository(db, nil)
+	ctx := context.Background()
+
+	payment := &entity.Payment{
+		ID:        "pay_late_webhook",
+		Amount:    money.New(9999, "USD"),
+		Status:    entity.PaymentStatusPending,
+		UpdatedAt: time.Now(),
+	}
+
+	mock.ExpectExec(`UPDATE payments SET (.+) WHERE id=\$15 AND status = ANY\(\$16\)`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT status FROM payments WHERE id=\$1`).
+		WithArgs(payment.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(entity.PaymentStatusSucceeded))
+
+	// Act
+	err = repo.UpdatePayment(ctx, payment)
+
+	// Assert
+	var transitionErr *entity.InvalidTransitionError
+	assert.True(t, errors.As(err, &transitionErr))
+	assert.Equal(t, entity.PaymentStatusSucceeded, transitionErr.From)
+	assert.Equal(t, entity.PaymentStatusPending, transitionErr.To)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdatePayment_NotFound(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := NewPaymentRepository(db, nil)
+	ctx := context.Background()
+
+	payment := &entity.Payment{ID: "pay_missing", Status: entity.PaymentStatusFailed}
+
+	mock.ExpectExec(`UPDATE payments SET`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT status FROM payments WHERE id=\$1`).
+		WithArgs(payment.ID).
+		WillReturnError(sql.ErrNoRows)
+
+	// Act
+	err = repo.UpdatePayment(ctx, payment)
+
+	// Assert
+	assert.Equal(t, ErrPaymentNotFound, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdatePayment_WritesEventsToOutbox(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := NewPaymentRepository(db, nil)
+	ctx := context.Background()
+
+	payment := &entity.Payment{
+		ID:         "pay_captured",
+		Amount:     money.New(2500, "USD"),
+		ProviderID: "stripe",
+		Status:     entity.PaymentStatusSucceeded,
+		UpdatedAt:  time.Now(),
+	}
+	captured := event.NewPaymentCapturedEvent(payment.ID, payment.ProviderID, payment.Amount)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE payments SET`).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO outbox \(aggregate_id, event_type, topic, payload, occurred_at\)`).
+		WithArgs(payment.ID, event.PaymentCaptured, event.TopicPaymentCaptured, sqlmock.AnyArg(), captured.OccurredOn).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	// Act
+	err = repo.UpdatePayment(ctx, payment, captured)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdatePayment_RejectedTransitionWritesNoEvents(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := NewPaymentRepository(db, nil)
+	ctx := context.Background()
+
+	payment := &entity.Payment{
+		ID:         "pay_late_webhook",
+		Amount:     money.New(9999, "USD"),
+		ProviderID: "paypal",
+		Status:     entity.PaymentStatusFailed,
+		UpdatedAt:  time.Now(),
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE payments SET`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT status FROM payments WHERE id=\$1`).
+		WithArgs(payment.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(entity.PaymentStatusSucceeded))
+	mock.ExpectRollback()
+
+	// Act
+	err = repo.UpdatePayment(ctx, payment,
+		event.NewPaymentFailedEvent(payment.ID, payment.ProviderID, "DECLINED", payment.Amount))
+
+	// Assert
+	assert.True(t, errors.Is(err, entity.ErrInvalidTransition))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdatePaymentLocked_SavesChangesUnderLock(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := NewPaymentRepository(db, nil)
+	ctx := context.Background()
+
+	now := time.Now()
+	rows := sqlmock.NewRows([]string{"id", "amount", "refunded_amount", "currency", "idempotency_key", "provider_id", "provider_payment_id", "payment_url", "status", "created_at", "updated_at", "completed_at", "expires_at", "metadata", "last_provider_event_at", "intent", "captured_amount"}).
+		AddRow("pay_refund", 5000, 1000, "USD", "idem_key_refund", "stripe", "pi_123", "", entity.PaymentStatusPartialRefund, now, now, now, nil, `{}`, nil, entity.PaymentIntentCapture, 5000)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT (.+) FROM payments WHERE id=\$1 FOR UPDATE`).
+		WithArgs("pay_refund").
+		WillReturnRows(rows)
+	mock.ExpectExec(`UPDATE payments SET`).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO transactions`).
+		WithArgs("txn_refund", "pay_refund", int64(2000), "USD", entity.TransactionTypeRefund, entity.TransactionStatusSucceeded,
+			"stripe", "re_123", "", "", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO outbox`).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	// Act
+	payment, err := repo.UpdatePaymentLocked(ctx, "pay_refund", func(payment *entity.Payment) (repository.PaymentUpdate, error) {
+		payment.RefundedAmount = money.New(3000, "USD")
+		txn := entity.NewTransaction("txn_refund", payment, entity.TransactionTypeRefund)
+		txn.Amount = money.New(2000, "USD")
+		txn.Status = entity.TransactionStatusSucceeded
+		txn.ProviderTxnID = "re_123"
+		return repository.PaymentUpdate{
+			Events: []event.DomainEvent{event.NewPaymentRefundedEvent(payment.ID, payment.ProviderID, "re_123",
+				string(payment.Status), "", money.New(2000, "USD"), payment.RefundedAmount)},
+			Transactions: []*entity.Transaction{txn},
+		}, nil
+	})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, money.New(3000, "USD"), payment.RefundedAmount)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdatePaymentLocked_FailedUpdateSavesNothing(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := NewPaymentRepository(db, nil)
+	ctx := context.Background()
+
+	now := time.Now()
+	rows := sqlmock.NewRows([]string{"id", "amount", "refunded_amount", "currency", "idempotency_key", "provider_id", "provider_payment_id", "payment_url", "status", "created_at", "updated_at", "completed_at", "expires_at", "metadata", "last_provider_event_at", "intent", "captured_amount"}).
+		AddRow("pay_refund", 5000, 5000, "USD", "idem_key_refund", "stripe", "pi_123", "", entity.PaymentStatusRefunded, now, now, now, nil, `{}`, nil, entity.PaymentIntentCapture, 5000)
+	errNotRefundable := errors.New("not refundable")
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT (.+) FROM payments WHERE id=\$1 FOR UPDATE`).
+		WithArgs("pay_refund").
+		WillReturnRows(rows)
+	mock.ExpectRollback()
+
+	// Act
+	payment, err := repo.UpdatePaymentLocked(ctx, "pay_refund", func(payment *entity.Payment) (repository.PaymentUpdate, error) {
+		return repository.PaymentUpdate{}, errNotRefundable
+	})
+
+	// Assert
+	assert.ErrorIs(t, err, errNotRefundable)
+	assert.Nil(t, payment)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
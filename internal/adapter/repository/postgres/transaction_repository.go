@@ -0,0 +1,113 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/omerbeden/paymentgateway/internal/domain/entity"
+	"github.com/omerbeden/paymentgateway/internal/infrastructure/metrics"
+)
+
+type TransactionRepository struct {
+	db      *sql.DB
+	metrics *metrics.Metrics
+}
+
+func NewTransactionRepository(db *sql.DB, metrics *metrics.Metrics) *TransactionRepository {
+	return &TransactionRepository{db: db, metrics: metrics}
+}
+
+func (r *TransactionRepository) Save(ctx context.Context, txn *entity.Transaction) error {
+	start := time.Now()
+	if err := insertTransaction(ctx, r.db, txn); err != nil {
+		return err
+	}
+
+	r.observeQuery("save_transaction", start)
+	return nil
+}
+
+func insertTransaction(ctx context.Context, db dbtx, txn *entity.Transaction) error {
+	query := `INSERT INTO transactions (id, payment_id, amount, currency, type, status, provider_id,
+		provider_txn_id, request_payload, response_payload, processed_at, created_at, updated_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`
+
+	_, err := db.ExecContext(ctx, query,
+		txn.ID,
+		txn.PaymentID,
+		txn.Amount.Amount,
+		txn.Amount.Currency,
+		txn.Type,
+		txn.Status,
+		txn.ProviderID,
+		txn.ProviderTxnID,
+		txn.RequestPayload,
+		txn.ResponsePayload,
+		nullTime(txn.ProcessedAt),
+		txn.CreatedAt,
+		txn.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save transaction: %w", err)
+	}
+	return nil
+}
+
+func (r *TransactionRepository) ListByPaymentID(ctx context.Context, paymentID string) ([]*entity.Transaction, error) {
+	start := time.Now()
+	query := `SELECT id, payment_id, amount, currency, type, status, provider_id, provider_txn_id,
+		request_payload, response_payload, processed_at, created_at, updated_at
+	FROM transactions WHERE payment_id=$1 ORDER BY created_at`
+
+	rows, err := r.db.QueryContext(ctx, query, paymentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list transactions: %w", err)
+	}
+	defer rows.Close()
+
+	var txns []*entity.Transaction
+	for rows.Next() {
+		var t entity.Transaction
+		var providerTxnID, requestPayload, responsePayload sql.NullString
+		var processedAt sql.NullTime
+
+		if err := rows.Scan(
+			&t.ID,
+			&t.PaymentID,
+			&t.Amount.Amount,
+			&t.Amount.Currency,
+			&t.Type,
+			&t.Status,
+			&t.ProviderID,
+			&providerTxnID,
+			&requestPayload,
+			&responsePayload,
+			&processedAt,
+			&t.CreatedAt,
+			&t.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan transaction: %w", err)
+		}
+
+		t.ProviderTxnID = providerTxnID.String
+		t.RequestPayload = requestPayload.String
+		t.ResponsePayload = responsePayload.String
+		t.ProcessedAt = processedAt.Time
+		txns = append(txns, &t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list transactions: %w", err)
+	}
+
+	r.observeQuery("list_transactions", start)
+	return txns, nil
+}
+
+func (r *TransactionRepository) observeQuery(operation string, start time.Time) {
+	if r.metrics == nil {
+		return
+	}
+	r.metrics.DBQueryDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,116 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/omerbeden/paymentgateway/internal/domain/entity"
+	"github.com/omerbeden/paymentgateway/internal/domain/money"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransactionSave_Success(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := NewTransactionRepository(db, nil)
+	ctx := context.Background()
+
+	now := time.Now()
+	txn := &entity.Transaction{
+		ID:              "txn_123",
+		PaymentID:       "pay_123",
+		Amount:          money.New(9999, "USD"),
+		Type:            entity.TransactionTypeCapture,
+		Status:          entity.TransactionStatusFailed,
+		ProviderID:      "paypal",
+		RequestPayload:  `{}`,
+		ResponsePayload: `{"name":"UNPROCESSABLE_ENTITY"}`,
+		ProcessedAt:     now,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+
+	mock.ExpectExec(`INSERT INTO transactions`).
+		WithArgs(
+			txn.ID,
+			txn.PaymentID,
+			txn.Amount.Amount,
+			txn.Amount.Currency,
+			txn.Type,
+			txn.Status,
+			txn.ProviderID,
+			txn.ProviderTxnID,
+			txn.RequestPayload,
+			txn.ResponsePayload,
+			txn.ProcessedAt,
+			txn.CreatedAt,
+			txn.UpdatedAt,
+		).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	// Act
+	err = repo.Save(ctx, txn)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTransactionSave_DatabaseError(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := NewTransactionRepository(db, nil)
+	ctx := context.Background()
+
+	mock.ExpectExec(`INSERT INTO transactions`).
+		WillReturnError(sql.ErrConnDone)
+
+	// Act
+	err = repo.Save(ctx, &entity.Transaction{ID: "txn_123"})
+
+	// Assert
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to save transaction")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTransactionListByPaymentID_Success(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := NewTransactionRepository(db, nil)
+	ctx := context.Background()
+
+	now := time.Now()
+	rows := sqlmock.NewRows([]string{"id", "payment_id", "amount", "currency", "type", "status", "provider_id", "provider_txn_id", "request_payload", "response_payload", "processed_at", "created_at", "updated_at"}).
+		AddRow("txn_1", "pay_123", 9999, "USD", entity.TransactionTypeCharge, entity.TransactionStatusSucceeded, "paypal", "ORDER-1", `{"intent":"CAPTURE"}`, `{"id":"ORDER-1"}`, now, now, now).
+		AddRow("txn_2", "pay_123", 9999, "USD", entity.TransactionTypeCapture, entity.TransactionStatusFailed, "paypal", nil, `{}`, nil, nil, now, now)
+
+	mock.ExpectQuery(`SELECT (.+) FROM transactions WHERE payment_id=\$1 ORDER BY created_at`).
+		WithArgs("pay_123").
+		WillReturnRows(rows)
+
+	// Act
+	result, err := repo.ListByPaymentID(ctx, "pay_123")
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Len(t, result, 2)
+	assert.Equal(t, entity.TransactionTypeCharge, result[0].Type)
+	assert.Equal(t, "ORDER-1", result[0].ProviderTxnID)
+	assert.Equal(t, entity.TransactionStatusFailed, result[1].Status)
+	assert.Empty(t, result[1].ResponsePayload)
+	assert.True(t, result[1].ProcessedAt.IsZero())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
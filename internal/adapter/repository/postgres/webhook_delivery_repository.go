@@ -0,0 +1,235 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/omerbeden/paymentgateway/internal/domain/entity"
+	"github.com/omerbeden/paymentgateway/internal/domain/repository"
+)
+
+const webhookDeliveryColumns = `id, endpoint_id, event_id, event_type, payload, status, attempt_count,
+	next_attempt_at, last_error, created_at, updated_at`
+
+type WebhookDeliveryRepository struct {
+	db *sql.DB
+}
+
+func NewWebhookDeliveryRepository(db *sql.DB) *WebhookDeliveryRepository {
+	return &WebhookDeliveryRepository{db: db}
+}
+
+func (r *WebhookDeliveryRepository) CreateMany(ctx context.Context, deliveries []*entity.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO webhook_deliveries (` + webhookDeliveryColumns + `)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	ON CONFLICT (endpoint_id, event_id) DO NOTHING`
+
+	for _, d := range deliveries {
+		_, err := tx.ExecContext(ctx, query,
+			d.ID,
+			d.EndpointID,
+			d.EventID,
+			d.EventType,
+			d.Payload,
+			d.Status,
+			d.AttemptCount,
+			nullTime(d.NextAttemptAt),
+			nullString(d.LastError),
+			d.CreatedAt,
+			d.UpdatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to create webhook delivery: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit webhook deliveries: %w", err)
+	}
+	return nil
+}
+
+func (r *WebhookDeliveryRepository) GetByID(ctx context.Context, id string) (*entity.WebhookDelivery, error) {
+	query := `SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries WHERE id=$1`
+
+	delivery, err := scanWebhookDelivery(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, repository.ErrWebhookDeliveryNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook delivery: %w", err)
+	}
+	return delivery, nil
+}
+
+func (r *WebhookDeliveryRepository) ListByEndpoint(ctx context.Context, endpointID string, limit int) ([]*entity.WebhookDelivery, error) {
+	query := `SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries
+	WHERE endpoint_id=$1 ORDER BY created_at DESC, id DESC LIMIT $2`
+	return r.query(ctx, query, endpointID, limit)
+}
+
+// ClaimDue uses SKIP LOCKED so workers polling at the same time claim
+// disjoint batches.
+func (r *WebhookDeliveryRepository) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*entity.WebhookDelivery, error) {
+	query := `UPDATE webhook_deliveries SET next_attempt_at=$1
+	WHERE id IN (
+		SELECT d.id FROM webhook_deliveries d
+		JOIN webhook_endpoints e ON e.id = d.endpoint_id
+		WHERE d.status=$2 AND d.next_attempt_at<=$3 AND e.status=$4
+		ORDER BY d.next_attempt_at
+		LIMIT $5
+		FOR UPDATE OF d SKIP LOCKED
+	)
+	RETURNING ` + webhookDeliveryColumns
+
+	return r.query(ctx, query, now.Add(lease), entity.WebhookDeliveryStatusPending, now,
+		entity.WebhookEndpointStatusEnabled, limit)
+}
+
+func (r *WebhookDeliveryRepository) Update(ctx context.Context, delivery *entity.WebhookDelivery) error {
+	query := `UPDATE webhook_deliveries SET status=$1, attempt_count=$2, next_attempt_at=$3, last_error=$4, updated_at=$5
+	WHERE id=$6`
+
+	result, err := r.db.ExecContext(ctx, query,
+		delivery.Status,
+		delivery.AttemptCount,
+		nullTime(delivery.NextAttemptAt),
+		nullString(delivery.LastError),
+		delivery.UpdatedAt,
+		delivery.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update webhook delivery: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update webhook delivery: %w", err)
+	}
+	if rows == 0 {
+		return repository.ErrWebhookDeliveryNotFound
+	}
+	return nil
+}
+
+func (r *WebhookDeliveryRepository) SaveAttempt(ctx context.Context, attempt *entity.WebhookDeliveryAttempt) error {
+	query := `INSERT INTO webhook_delivery_attempts (id, delivery_id, attempt_number, response_status,
+		response_body, error, duration_ms, attempted_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	_, err := r.db.ExecContext(ctx, query,
+		attempt.ID,
+		attempt.DeliveryID,
+		attempt.AttemptNumber,
+		sql.NullInt64{Int64: int64(attempt.ResponseStatus), Valid: attempt.ResponseStatus != 0},
+		nullString(attempt.ResponseBody),
+		nullString(attempt.Error),
+		attempt.Duration.Milliseconds(),
+		attempt.AttemptedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save webhook delivery attempt: %w", err)
+	}
+	return nil
+}
+
+func (r *WebhookDeliveryRepository) ListAttempts(ctx context.Context, deliveryID string) ([]*entity.WebhookDeliveryAttempt, error) {
+	query := `SELECT id, delivery_id, attempt_number, response_status, response_body, error, duration_ms, attempted_at
+	FROM webhook_delivery_attempts WHERE delivery_id=$1 ORDER BY attempt_number`
+
+	rows, err := r.db.QueryContext(ctx, query, deliveryID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook delivery attempts: %w", err)
+	}
+	defer rows.Close()
+
+	var attempts []*entity.WebhookDeliveryAttempt
+	for rows.Next() {
+		var a entity.WebhookDeliveryAttempt
+		var responseStatus sql.NullInt64
+		var responseBody, attemptErr sql.NullString
+		var durationMs int64
+
+		if err := rows.Scan(
+			&a.ID,
+			&a.DeliveryID,
+			&a.AttemptNumber,
+			&responseStatus,
+			&responseBody,
+			&attemptErr,
+			&durationMs,
+			&a.AttemptedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery attempt: %w", err)
+		}
+
+		a.ResponseStatus = int(responseStatus.Int64)
+		a.ResponseBody = responseBody.String
+		a.Error = attemptErr.String
+		a.Duration = time.Duration(durationMs) * time.Millisecond
+		attempts = append(attempts, &a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list webhook delivery attempts: %w", err)
+	}
+	return attempts, nil
+}
+
+func (r *WebhookDeliveryRepository) query(ctx context.Context, query string, args ...any) ([]*entity.WebhookDelivery, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []*entity.WebhookDelivery
+	for rows.Next() {
+		delivery, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+func scanWebhookDelivery(row rowScanner) (*entity.WebhookDelivery, error) {
+	var delivery entity.WebhookDelivery
+	var nextAttemptAt sql.NullTime
+	var lastError sql.NullString
+
+	err := row.Scan(
+		&delivery.ID,
+		&delivery.EndpointID,
+		&delivery.EventID,
+		&delivery.EventType,
+		&delivery.Payload,
+		&delivery.Status,
+		&delivery.AttemptCount,
+		&nextAttemptAt,
+		&lastError,
+		&delivery.CreatedAt,
+		&delivery.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	delivery.NextAttemptAt = nextAttemptAt.Time
+	delivery.LastError = lastError.String
+	return &delivery, nil
+}
@@ -0,0 +1,103 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/omerbeden/paymentgateway/internal/domain/entity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookDeliveryCreateMany(t *testing.T) {
+	//Arrange
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewWebhookDeliveryRepository(db)
+	now := time.Now()
+	deliveries := []*entity.WebhookDelivery{
+		{ID: "del_1", EndpointID: "we_1", EventID: "evt_1", EventType: "payment.completed", Payload: "{}",
+			Status: entity.WebhookDeliveryStatusPending, NextAttemptAt: now, CreatedAt: now, UpdatedAt: now},
+		{ID: "del_2", EndpointID: "we_2", EventID: "evt_1", EventType: "payment.completed", Payload: "{}",
+			Status: entity.WebhookDeliveryStatusPending, NextAttemptAt: now, CreatedAt: now, UpdatedAt: now},
+	}
+
+	mock.ExpectBegin()
+	for _, d := range deliveries {
+		mock.ExpectExec(`INSERT INTO webhook_deliveries (.+) ON CONFLICT \(endpoint_id, event_id\) DO NOTHING`).
+			WithArgs(d.ID, d.EndpointID, "evt_1", "payment.completed", "{}", entity.WebhookDeliveryStatusPending, 0,
+				nullTime(now), nullString(""), now, now).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectCommit()
+
+	//Act
+	err = repo.CreateMany(context.Background(), deliveries)
+
+	//Assert
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWebhookDeliveryClaimDue(t *testing.T) {
+	//Arrange
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewWebhookDeliveryRepository(db)
+	now := time.Now()
+	lease := time.Minute
+	rows := sqlmock.NewRows([]string{"id", "endpoint_id", "event_id", "event_type", "payload", "status", "attempt_count",
+		"next_attempt_at", "last_error", "created_at", "updated_at"}).
+		AddRow("del_1", "we_1", "evt_1", "payment.completed", "{}", entity.WebhookDeliveryStatusPending, 2,
+			now.Add(lease), "unexpected status code 500", now.Add(-time.Hour), now)
+
+	mock.ExpectQuery(`UPDATE webhook_deliveries SET next_attempt_at=\$1\s+WHERE id IN \((.+)FOR UPDATE OF d SKIP LOCKED\s+\)`).
+		WithArgs(now.Add(lease), entity.WebhookDeliveryStatusPending, now, entity.WebhookEndpointStatusEnabled, 10).
+		WillReturnRows(rows)
+
+	//Act
+	deliveries, err := repo.ClaimDue(context.Background(), now, lease, 10)
+
+	//Assert
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, 2, deliveries[0].AttemptCount)
+	assert.Equal(t, "unexpected status code 500", deliveries[0].LastError)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWebhookDeliverySaveAttempt(t *testing.T) {
+	//Arrange
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewWebhookDeliveryRepository(db)
+	attempt := &entity.WebhookDeliveryAttempt{
+		ID:            "att_1",
+		DeliveryID:    "del_1",
+		AttemptNumber: 1,
+		Error:         "connection refused",
+		Duration:      1500 * time.Millisecond,
+		AttemptedAt:   time.Now(),
+	}
+
+	mock.ExpectExec(`INSERT INTO webhook_delivery_attempts`).
+		WithArgs("att_1", "del_1", 1, sql.NullInt64{}, nullString(""), nullString("connection refused"),
+			int64(1500), attempt.AttemptedAt).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	//Act
+	err = repo.SaveAttempt(context.Background(), attempt)
+
+	//Assert
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
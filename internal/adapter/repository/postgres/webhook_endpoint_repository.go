@@ -0,0 +1,166 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/omerbeden/paymentgateway/internal/domain/entity"
+	"github.com/omerbeden/paymentgateway/internal/domain/repository"
+)
+
+const webhookEndpointColumns = `id, url, description, event_types, secret, status, consecutive_failures,
+	disabled_at, created_at, updated_at`
+
+type WebhookEndpointRepository struct {
+	db *sql.DB
+}
+
+func NewWebhookEndpointRepository(db *sql.DB) *WebhookEndpointRepository {
+	return &WebhookEndpointRepository{db: db}
+}
+
+func (r *WebhookEndpointRepository) Create(ctx context.Context, endpoint *entity.WebhookEndpoint) error {
+	query := `INSERT INTO webhook_endpoints (` + webhookEndpointColumns + `)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+
+	_, err := r.db.ExecContext(ctx, query,
+		endpoint.ID,
+		endpoint.URL,
+		nullString(endpoint.Description),
+		pq.Array(endpoint.EventTypes),
+		endpoint.Secret,
+		endpoint.Status,
+		endpoint.ConsecutiveFailures,
+		nullTime(endpoint.DisabledAt),
+		endpoint.CreatedAt,
+		endpoint.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create webhook endpoint: %w", err)
+	}
+	return nil
+}
+
+func (r *WebhookEndpointRepository) GetByID(ctx context.Context, id string) (*entity.WebhookEndpoint, error) {
+	query := `SELECT ` + webhookEndpointColumns + ` FROM webhook_endpoints WHERE id=$1`
+
+	endpoint, err := scanWebhookEndpoint(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, repository.ErrWebhookEndpointNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook endpoint: %w", err)
+	}
+	return endpoint, nil
+}
+
+func (r *WebhookEndpointRepository) List(ctx context.Context) ([]*entity.WebhookEndpoint, error) {
+	query := `SELECT ` + webhookEndpointColumns + ` FROM webhook_endpoints ORDER BY created_at`
+	return r.query(ctx, query)
+}
+
+func (r *WebhookEndpointRepository) ListSubscribed(ctx context.Context, eventType string) ([]*entity.WebhookEndpoint, error) {
+	query := `SELECT ` + webhookEndpointColumns + ` FROM webhook_endpoints
+	WHERE status=$1 AND $2 = ANY(event_types) ORDER BY created_at`
+	return r.query(ctx, query, entity.WebhookEndpointStatusEnabled, eventType)
+}
+
+func (r *WebhookEndpointRepository) Update(ctx context.Context, endpoint *entity.WebhookEndpoint) error {
+	query := `UPDATE webhook_endpoints SET url=$1, description=$2, event_types=$3, status=$4,
+		consecutive_failures=$5, disabled_at=$6, updated_at=$7
+	WHERE id=$8`
+
+	result, err := r.db.ExecContext(ctx, query,
+		endpoint.URL,
+		nullString(endpoint.Description),
+		pq.Array(endpoint.EventTypes),
+		endpoint.Status,
+		endpoint.ConsecutiveFailures,
+		nullTime(endpoint.DisabledAt),
+		endpoint.UpdatedAt,
+		endpoint.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update webhook endpoint: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update webhook endpoint: %w", err)
+	}
+	if rows == 0 {
+		return repository.ErrWebhookEndpointNotFound
+	}
+	return nil
+}
+
+// RecordDeliveryResult updates the counter in a single statement so results
+// reported by concurrent workers are not lost.
+func (r *WebhookEndpointRepository) RecordDeliveryResult(ctx context.Context, id string, succeeded bool, disableAfter int) (*entity.WebhookEndpoint, error) {
+	query := `UPDATE webhook_endpoints SET
+		consecutive_failures = CASE WHEN $1 THEN 0 ELSE consecutive_failures + 1 END,
+		status = CASE WHEN NOT $1 AND consecutive_failures + 1 >= $2 THEN $3 ELSE status END,
+		disabled_at = CASE WHEN NOT $1 AND consecutive_failures + 1 >= $2 AND disabled_at IS NULL THEN $4 ELSE disabled_at END,
+		updated_at = $4
+	WHERE id=$5
+	RETURNING ` + webhookEndpointColumns
+
+	endpoint, err := scanWebhookEndpoint(r.db.QueryRowContext(ctx, query,
+		succeeded, disableAfter, entity.WebhookEndpointStatusDisabled, time.Now(), id))
+	if err == sql.ErrNoRows {
+		return nil, repository.ErrWebhookEndpointNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to record webhook delivery result: %w", err)
+	}
+	return endpoint, nil
+}
+
+func (r *WebhookEndpointRepository) query(ctx context.Context, query string, args ...any) ([]*entity.WebhookEndpoint, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook endpoints: %w", err)
+	}
+	defer rows.Close()
+
+	var endpoints []*entity.WebhookEndpoint
+	for rows.Next() {
+		endpoint, err := scanWebhookEndpoint(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook endpoint: %w", err)
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list webhook endpoints: %w", err)
+	}
+	return endpoints, nil
+}
+
+func scanWebhookEndpoint(row rowScanner) (*entity.WebhookEndpoint, error) {
+	var endpoint entity.WebhookEndpoint
+	var description sql.NullString
+	var disabledAt sql.NullTime
+
+	err := row.Scan(
+		&endpoint.ID,
+		&endpoint.URL,
+		&description,
+		pq.Array(&endpoint.EventTypes),
+		&endpoint.Secret,
+		&endpoint.Status,
+		&endpoint.ConsecutiveFailures,
+		&disabledAt,
+		&endpoint.CreatedAt,
+		&endpoint.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint.Description = description.String
+	endpoint.DisabledAt = disabledAt.Time
+	return &endpoint, nil
+}
@@ -0,0 +1,123 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/omerbeden/paymentgateway/internal/domain/entity"
+	"github.com/omerbeden/paymentgateway/internal/domain/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var webhookEndpointRowColumns = []string{"id", "url", "description", "event_types", "secret", "status",
+	"consecutive_failures", "disabled_at", "created_at", "updated_at"}
+
+func TestWebhookEndpointCreate(t *testing.T) {
+	//Arrange
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewWebhookEndpointRepository(db)
+	now := time.Now()
+	endpoint := &entity.WebhookEndpoint{
+		ID:         "we_1",
+		URL:        "https://merchant.example.com/webhooks",
+		EventTypes: []string{"payment.completed"},
+		Secret:     "whsec_1",
+		Status:     entity.WebhookEndpointStatusEnabled,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+
+	mock.ExpectExec(`INSERT INTO webhook_endpoints`).
+		WithArgs("we_1", endpoint.URL, nullString(""), pq.Array(endpoint.EventTypes), "whsec_1",
+			entity.WebhookEndpointStatusEnabled, 0, nullTime(time.Time{}), now, now).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	//Act
+	err = repo.Create(context.Background(), endpoint)
+
+	//Assert
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWebhookEndpointListSubscribed(t *testing.T) {
+	//Arrange
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewWebhookEndpointRepository(db)
+	now := time.Now()
+	rows := sqlmock.NewRows(webhookEndpointRowColumns).
+		AddRow("we_1", "https://merchant.example.com/webhooks", nil, `{payment.completed}`, "whsec_1",
+			entity.WebhookEndpointStatusEnabled, 0, nil, now, now)
+
+	mock.ExpectQuery(`SELECT (.+) FROM webhook_endpoints\s+WHERE status=\$1 AND \$2 = ANY\(event_types\)`).
+		WithArgs(entity.WebhookEndpointStatusEnabled, "payment.completed").
+		WillReturnRows(rows)
+
+	//Act
+	endpoints, err := repo.ListSubscribed(context.Background(), "payment.completed")
+
+	//Assert
+	require.NoError(t, err)
+	require.Len(t, endpoints, 1)
+	assert.Equal(t, []string{"payment.completed"}, endpoints[0].EventTypes)
+	assert.Empty(t, endpoints[0].Description)
+	assert.True(t, endpoints[0].DisabledAt.IsZero())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWebhookEndpointRecordDeliveryResult_Disables(t *testing.T) {
+	//Arrange
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewWebhookEndpointRepository(db)
+	now := time.Now()
+	rows := sqlmock.NewRows(webhookEndpointRowColumns).
+		AddRow("we_1", "https://merchant.example.com/webhooks", nil, `{payment.completed}`, "whsec_1",
+			entity.WebhookEndpointStatusDisabled, 5, now, now, now)
+
+	mock.ExpectQuery(`UPDATE webhook_endpoints SET`).
+		WithArgs(false, 5, entity.WebhookEndpointStatusDisabled, sqlmock.AnyArg(), "we_1").
+		WillReturnRows(rows)
+
+	//Act
+	endpoint, err := repo.RecordDeliveryResult(context.Background(), "we_1", false, 5)
+
+	//Assert
+	require.NoError(t, err)
+	assert.Equal(t, entity.WebhookEndpointStatusDisabled, endpoint.Status)
+	assert.Equal(t, 5, endpoint.ConsecutiveFailures)
+	assert.False(t, endpoint.DisabledAt.IsZero())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWebhookEndpointGetByID_NotFound(t *testing.T) {
+	//Arrange
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewWebhookEndpointRepository(db)
+	mock.ExpectQuery(`SELECT (.+) FROM webhook_endpoints WHERE id=\$1`).
+		WithArgs("missing").
+		WillReturnError(sql.ErrNoRows)
+
+	//Act
+	endpoint, err := repo.GetByID(context.Background(), "missing")
+
+	//Assert
+	assert.Nil(t, endpoint)
+	assert.ErrorIs(t, err, repository.ErrWebhookEndpointNotFound)
+}
@@ -4,8 +4,12 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strings"
+	"time"
 
+	"github.com/lib/pq"
 	"github.com/omerbeden/paymentgateway/internal/domain/entity"
+	"github.com/omerbeden/paymentgateway/internal/domain/repository"
 )
 
 type WebHookEventRepository struct {
@@ -17,15 +21,154 @@ func NewWebHookEventRepository(db *sql.DB) *WebHookEventRepository {
 }
 func (r *WebHookEventRepository) Save(ctx context.Context, event *entity.WebhookEvent) error {
 
-	query := `INSERT INTO webhook_events (provider_id,
+	query := `INSERT INTO webhook_events (id, provider_id, provider_event_id,
 	 	provider_payment_id, event_type, signature, payload,
-		is_verified, is_processed, processing_error, received_at, processed_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`
+		is_verified, is_processed, processing_error, received_at, processed_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`
 
-	_, err := r.db.ExecContext(ctx, query, event.ProviderID,
+	_, err := r.db.ExecContext(ctx, query, event.ID, event.ProviderID, nullString(event.ProviderEventID),
 		event.ProviderPaymentID, event.EventType, event.Signature, event.Payload,
-		event.IsVerified, event.IsProcessed, event.ProcessingError, event.ReceivedAt, event.ProcessedAt)
+		event.IsVerified, event.IsProcessed, nullString(event.ProcessingError), event.ReceivedAt, nullTime(event.ProcessedAt))
 	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" { // unique_violation
+			return repository.ErrDuplicateWebhookEvent
+		}
 		return fmt.Errorf("failed to save webhook event: %w", err)
 	}
 	return nil
 }
+
+// nullString stores empty strings as NULL, e.g. so events without a provider
+// event ID do not collide on the unique constraint.
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+const webhookEventColumns = `id, provider_id, provider_event_id, provider_payment_id, event_type, signature, payload,
+	is_verified, is_processed, processing_error, received_at, processed_at`
+
+func (r *WebHookEventRepository) GetByID(ctx context.Context, id string) (*entity.WebhookEvent, error) {
+	query := `SELECT ` + webhookEventColumns + ` FROM webhook_events WHERE id=$1`
+
+	event, err := scanWebhookEvent(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, repository.ErrWebhookEventNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook event: %w", err)
+	}
+	return event, nil
+}
+
+func (r *WebHookEventRepository) List(ctx context.Context, filter repository.WebhookEventFilter) ([]*entity.WebhookEvent, error) {
+	var conditions []string
+	var args []any
+	addCondition := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.ProviderID != "" {
+		addCondition("provider_id=$%d", filter.ProviderID)
+	}
+	if filter.ProviderPaymentID != "" {
+		addCondition("provider_payment_id=$%d", filter.ProviderPaymentID)
+	}
+	switch filter.State {
+	case repository.WebhookEventStatePending:
+		conditions = append(conditions, "is_verified=true AND is_processed=false AND processing_error IS NULL")
+	case repository.WebhookEventStateProcessed:
+		conditions = append(conditions, "is_processed=true")
+	case repository.WebhookEventStateFailed:
+		conditions = append(conditions, "is_verified=true AND is_processed=false AND processing_error IS NOT NULL")
+	case repository.WebhookEventStateUnverified:
+		conditions = append(conditions, "is_verified=false")
+	case repository.WebhookEventStateUnprocessed:
+		conditions = append(conditions, "is_verified=true AND is_processed=false")
+	}
+	if !filter.ReceivedBefore.IsZero() {
+		addCondition("received_at<$%d", filter.ReceivedBefore)
+	}
+	if !filter.AttemptedBefore.IsZero() {
+		addCondition("(processed_at IS NULL OR processed_at<$%d)", filter.AttemptedBefore)
+	}
+	if filter.AttemptsBelow > 0 {
+		addCondition("processing_attempts<$%d", filter.AttemptsBelow)
+	}
+
+	query := `SELECT ` + webhookEventColumns + ` FROM webhook_events`
+	if len(conditions) > 0 {
+		query += ` WHERE ` + strings.Join(conditions, " AND ")
+	}
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(` ORDER BY received_at DESC, id DESC LIMIT $%d`, len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook events: %w", err)
+	}
+	defer rows.Close()
+
+	events := make([]*entity.WebhookEvent, 0, filter.Limit)
+	for rows.Next() {
+		event, err := scanWebhookEvent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook event: %w", err)
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list webhook events: %w", err)
+	}
+	return events, nil
+}
+
+func scanWebhookEvent(row rowScanner) (*entity.WebhookEvent, error) {
+	var (
+		event                                                                     entity.WebhookEvent
+		providerEventID, providerPaymentID, eventType, signature, processingError sql.NullString
+		processedAt                                                               sql.NullTime
+	)
+	err := row.Scan(
+		&event.ID,
+		&event.ProviderID,
+		&providerEventID,
+		&providerPaymentID,
+		&eventType,
+		&signature,
+		&event.Payload,
+		&event.IsVerified,
+		&event.IsProcessed,
+		&processingError,
+		&event.ReceivedAt,
+		&processedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	event.ProviderEventID = providerEventID.String
+	event.ProviderPaymentID = providerPaymentID.String
+	event.EventType = eventType.String
+	event.Signature = signature.String
+	event.ProcessingError = processingError.String
+	event.ProcessedAt = processedAt.Time
+	return &event, nil
+}
+
+func (r *WebHookEventRepository) MarkProcessed(ctx context.Context, id string, processed bool, processingError string, processedAt time.Time) error {
+	query := `UPDATE webhook_events SET is_processed=$1, processing_error=$2, processed_at=$3,
+	processing_attempts=processing_attempts+1 WHERE id=$4`
+
+	result, err := r.db.ExecContext(ctx, query, processed, nullString(processingError), processedAt, id)
+	if err != nil {
+		return fmt.Errorf("failed to mark webhook event processed: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to mark webhook event processed: %w", err)
+	}
+	if rows == 0 {
+		return repository.ErrWebhookEventNotFound
+	}
+	return nil
+}
@@ -3,11 +3,14 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"regexp"
 	"testing"
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
 	"github.com/omerbeden/paymentgateway/internal/domain/entity"
+	"github.com/omerbeden/paymentgateway/internal/domain/repository"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -22,7 +25,9 @@ func TestSave_Success(t *testing.T) {
 	ctx := context.Background()
 
 	event := &entity.WebhookEvent{
+		ID:                "evt-1",
 		ProviderID:        "paypal",
+		ProviderEventID:   "WH-123",
 		ProviderPaymentID: "payment123",
 		EventType:         "PAYMENT.CAPTURE.COMPLETED",
 		Signature:         "sig123",
@@ -35,16 +40,19 @@ func TestSave_Success(t *testing.T) {
 	}
 
 	mock.ExpectExec(`INSERT INTO webhook_events`).
-		WithArgs(event.ProviderID,
+		WithArgs(
+			event.ID,
+			event.ProviderID,
+			nullString(event.ProviderEventID),
 			event.ProviderPaymentID,
 			event.EventType,
 			event.Signature,
 			event.Payload,
 			event.IsVerified,
 			event.IsProcessed,
-			event.ProcessingError,
+			nullString(event.ProcessingError),
 			event.ReceivedAt,
-			event.ProcessedAt,
+			nullTime(event.ProcessedAt),
 		).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
@@ -79,16 +87,18 @@ func TestSave_DatabaseError(t *testing.T) {
 
 	mock.ExpectExec(`INSERT INTO webhook_events`).
 		WithArgs(
+			event.ID,
 			event.ProviderID,
+			nullString(event.ProviderEventID),
 			event.ProviderPaymentID,
 			event.EventType,
 			event.Signature,
 			event.Payload,
 			event.IsVerified,
 			event.IsProcessed,
-			event.ProcessingError,
+			nullString(event.ProcessingError),
 			event.ReceivedAt,
-			event.ProcessedAt,
+			nullTime(event.ProcessedAt),
 		).
 		WillReturnError(sql.ErrConnDone)
 
@@ -126,16 +136,18 @@ func TestSave_WithProcessingError(t *testing.T) {
 
 	mock.ExpectExec(`INSERT INTO webhook_events`).
 		WithArgs(
+			event.ID,
 			event.ProviderID,
+			nullString(event.ProviderEventID),
 			event.ProviderPaymentID,
 			event.EventType,
 			event.Signature,
 			event.Payload,
 			event.IsVerified,
 			event.IsProcessed,
-			event.ProcessingError,
+			nullString(event.ProcessingError),
 			event.ReceivedAt,
-			event.ProcessedAt,
+			nullTime(event.ProcessedAt),
 		).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
@@ -169,16 +181,18 @@ func TestSave_UnverifiedEvent(t *testing.T) {
 
 	mock.ExpectExec(`INSERT INTO webhook_events`).
 		WithArgs(
+			event.ID,
 			event.ProviderID,
+			nullString(event.ProviderEventID),
 			event.ProviderPaymentID,
 			event.EventType,
 			event.Signature,
 			event.Payload,
 			event.IsVerified,
 			event.IsProcessed,
-			event.ProcessingError,
+			nullString(event.ProcessingError),
 			event.ReceivedAt,
-			event.ProcessedAt,
+			nullTime(event.ProcessedAt),
 		).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
@@ -203,16 +217,18 @@ func TestSave_EmptyEvent(t *testing.T) {
 
 	mock.ExpectExec(`INSERT INTO webhook_events`).
 		WithArgs(
+			event.ID,
 			event.ProviderID,
+			nullString(event.ProviderEventID),
 			event.ProviderPaymentID,
 			event.EventType,
 			event.Signature,
 			event.Payload,
 			event.IsVerified,
 			event.IsProcessed,
-			event.ProcessingError,
+			nullString(event.ProcessingError),
 			event.ReceivedAt,
-			event.ProcessedAt,
+			nullTime(event.ProcessedAt),
 		).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
@@ -224,6 +240,136 @@ func TestSave_EmptyEvent(t *testing.T) {
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
+func TestSave_DuplicateProviderEvent(t *testing.T) {
+	//Arrange
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := NewWebHookEventRepository(db)
+	ctx := context.Background()
+
+	event := &entity.WebhookEvent{
+		ID:              "evt-2",
+		ProviderID:      "paypal",
+		ProviderEventID: "WH-123",
+		Payload:         `{"id":"WH-123"}`,
+		ReceivedAt:      time.Now(),
+	}
+
+	mock.ExpectExec(`INSERT INTO webhook_events`).
+		WillReturnError(&pq.Error{Code: "23505"})
+
+	//Act
+	err = repo.Save(ctx, event)
+
+	//Assert
+	assert.ErrorIs(t, err, repository.ErrDuplicateWebhookEvent)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWebhookEventGetByID_Success(t *testing.T) {
+	//Arrange
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewWebHookEventRepository(db)
+	receivedAt := time.Now()
+
+	rows := sqlmock.NewRows([]string{"id", "provider_id", "provider_event_id", "provider_payment_id", "event_type",
+		"signature", "payload", "is_verified", "is_processed", "processing_error", "received_at", "processed_at"}).
+		AddRow("evt-1", "paypal", "WH-123", "payment123", "CHECKOUT.ORDER.APPROVED",
+			"sig123", `{"id":"WH-123"}`, true, false, nil, receivedAt, nil)
+	mock.ExpectQuery(`SELECT (.+) FROM webhook_events WHERE id=\$1`).
+		WithArgs("evt-1").
+		WillReturnRows(rows)
+
+	//Act
+	event, err := repo.GetByID(context.Background(), "evt-1")
+
+	//Assert
+	require.NoError(t, err)
+	assert.Equal(t, "WH-123", event.ProviderEventID)
+	assert.Equal(t, "payment123", event.ProviderPaymentID)
+	assert.True(t, event.IsVerified)
+	assert.False(t, event.IsProcessed)
+	assert.Empty(t, event.ProcessingError)
+	assert.True(t, event.ProcessedAt.IsZero())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWebhookEventGetByID_NotFound(t *testing.T) {
+	//Arrange
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewWebHookEventRepository(db)
+	mock.ExpectQuery(`SELECT (.+) FROM webhook_events WHERE id=\$1`).
+		WithArgs("missing").
+		WillReturnError(sql.ErrNoRows)
+
+	//Act
+	event, err := repo.GetByID(context.Background(), "missing")
+
+	//Assert
+	assert.Nil(t, event)
+	assert.ErrorIs(t, err, repository.ErrWebhookEventNotFound)
+}
+
+func TestMarkProcessed(t *testing.T) {
+	processedAt := time.Now()
+
+	tests := []struct {
+		name            string
+		processed       bool
+		processingError string
+	}{
+		{"applied", true, ""},
+		{"failed", false, "payment not found"},
+		{"ignored", true, "stale webhook event"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			//Arrange
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			repo := NewWebHookEventRepository(db)
+			mock.ExpectExec(`UPDATE webhook_events SET is_processed=\$1, processing_error=\$2, processed_at=\$3,\s+processing_attempts=processing_attempts\+1 WHERE id=\$4`).
+				WithArgs(tt.processed, nullString(tt.processingError), processedAt, "evt-1").
+				WillReturnResult(sqlmock.NewResult(0, 1))
+
+			//Act
+			err = repo.MarkProcessed(context.Background(), "evt-1", tt.processed, tt.processingError, processedAt)
+
+			//Assert
+			assert.NoError(t, err)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestMarkProcessed_NotFound(t *testing.T) {
+	//Arrange
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewWebHookEventRepository(db)
+	mock.ExpectExec(`UPDATE webhook_events`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	//Act
+	err = repo.MarkProcessed(context.Background(), "missing", true, "", time.Now())
+
+	//Assert
+	assert.ErrorIs(t, err, repository.ErrWebhookEventNotFound)
+}
+
 func TestNewWebHookEventRepository(t *testing.T) {
 	//Arrange
 	db, _, err := sqlmock.New()
@@ -237,3 +383,125 @@ func TestNewWebHookEventRepository(t *testing.T) {
 	assert.NotNil(t, repo)
 	assert.Equal(t, db, repo.db)
 }
+
+func TestWebhookEventList_WithFilters(t *testing.T) {
+	tests := []struct {
+		name      string
+		state     repository.WebhookEventState
+		condition string
+	}{
+		{"pending", repository.WebhookEventStatePending, `is_verified=true AND is_processed=false AND processing_error IS NULL`},
+		{"processed", repository.WebhookEventStateProcessed, `is_processed=true`},
+		{"failed", repository.WebhookEventStateFailed, `is_verified=true AND is_processed=false AND processing_error IS NOT NULL`},
+		{"unverified", repository.WebhookEventStateUnverified, `is_verified=false`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			//Arrange
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			repo := NewWebHookEventRepository(db)
+			rows := sqlmock.NewRows([]string{"id", "provider_id", "provider_event_id", "provider_payment_id", "event_type",
+				"signature", "payload", "is_verified", "is_processed", "processing_error", "received_at", "processed_at"}).
+				AddRow("evt-1", "paypal", "WH-123", "payment123", "CHECKOUT.ORDER.APPROVED",
+					"sig123", `{"id":"WH-123"}`, true, false, "payment not found", time.Now(), time.Now())
+			mock.ExpectQuery(`SELECT (.+) FROM webhook_events WHERE provider_id=\$1 AND provider_payment_id=\$2 AND `+
+				regexp.QuoteMeta(tt.condition)+` ORDER BY received_at DESC, id DESC LIMIT \$3`).
+				WithArgs("paypal", "payment123", 50).
+				WillReturnRows(rows)
+
+			//Act
+			events, err := repo.List(context.Background(), repository.WebhookEventFilter{
+				ProviderID:        "paypal",
+				ProviderPaymentID: "payment123",
+				State:             tt.state,
+				Limit:             50,
+			})
+
+			//Assert
+			require.NoError(t, err)
+			require.Len(t, events, 1)
+			assert.Equal(t, "evt-1", events[0].ID)
+			assert.Equal(t, "payment not found", events[0].ProcessingError)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestWebhookEventList_ReceivedBefore(t *testing.T) {
+	//Arrange
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewWebHookEventRepository(db)
+	before := time.Now().Add(-5 * time.Minute)
+	mock.ExpectQuery(`SELECT (.+) FROM webhook_events WHERE is_verified=true AND is_processed=false AND processing_error IS NULL `+
+		`AND received_at<\$1 ORDER BY received_at DESC, id DESC LIMIT \$2`).
+		WithArgs(before, 100).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	//Act
+	events, err := repo.List(context.Background(), repository.WebhookEventFilter{
+		State:          repository.WebhookEventStatePending,
+		ReceivedBefore: before,
+		Limit:          100,
+	})
+
+	//Assert
+	assert.NoError(t, err)
+	assert.Empty(t, events)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWebhookEventList_Retryable(t *testing.T) {
+	//Arrange
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewWebHookEventRepository(db)
+	before := time.Now().Add(-5 * time.Minute)
+	mock.ExpectQuery(`SELECT (.+) FROM webhook_events WHERE is_verified=true AND is_processed=false `+
+		`AND received_at<\$1 AND \(processed_at IS NULL OR processed_at<\$2\) AND processing_attempts<\$3 `+
+		`ORDER BY received_at DESC, id DESC LIMIT \$4`).
+		WithArgs(before, before, 5, 100).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	//Act
+	events, err := repo.List(context.Background(), repository.WebhookEventFilter{
+		State:           repository.WebhookEventStateUnprocessed,
+		ReceivedBefore:  before,
+		AttemptedBefore: before,
+		AttemptsBelow:   5,
+		Limit:           100,
+	})
+
+	//Assert
+	assert.NoError(t, err)
+	assert.Empty(t, events)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWebhookEventList_NoFilters(t *testing.T) {
+	//Arrange
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewWebHookEventRepository(db)
+	mock.ExpectQuery(`SELECT (.+) FROM webhook_events ORDER BY received_at DESC, id DESC LIMIT \$1`).
+		WithArgs(20).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	//Act
+	events, err := repo.List(context.Background(), repository.WebhookEventFilter{Limit: 20})
+
+	//Assert
+	assert.NoError(t, err)
+	assert.Empty(t, events)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,103 @@
+// Package webhooksender posts merchant webhooks signed with the endpoint
+// secret.
+//
+// Every request carries the event ID, a Unix timestamp and a signature:
+//
+//	X-PaymentGateway-Event-ID:  evt_...
+//	X-PaymentGateway-Timestamp: 1700000000
+//	X-PaymentGateway-Signature: v1=<hex HMAC-SHA256 of "<timestamp>.<body>">
+//
+// Merchants should recompute the signature over the raw body and reject
+// timestamps that are too old to prevent replays.
+package webhooksender
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/omerbeden/paymentgateway/internal/domain/entity"
+)
+
+const (
+	EventIDHeader   = "X-PaymentGateway-Event-ID"
+	EventTypeHeader = "X-PaymentGateway-Event-Type"
+	TimestampHeader = "X-PaymentGateway-Timestamp"
+	SignatureHeader = "X-PaymentGateway-Signature"
+
+	// maxResponseBody caps how much of the merchant's response is logged.
+	maxResponseBody = 1024
+)
+
+type Sender struct {
+	client *http.Client
+	now    func() time.Time
+}
+
+func NewSender(timeout time.Duration) *Sender {
+	return &Sender{
+		client: &http.Client{
+			Timeout: timeout,
+			// a redirect would resend the signed body somewhere the merchant did not register
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		now: time.Now,
+	}
+}
+
+// Sign returns the signature header value for payload sent at timestamp.
+func Sign(secret string, timestamp int64, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return "v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *Sender) Send(ctx context.Context, endpoint *entity.WebhookEndpoint, delivery *entity.WebhookDelivery) *entity.WebhookDeliveryAttempt {
+	start := s.now()
+	attempt := &entity.WebhookDeliveryAttempt{
+		DeliveryID:  delivery.ID,
+		AttemptedAt: start,
+	}
+	defer func() { attempt.Duration = s.now().Sub(start) }()
+
+	payload := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(payload))
+	if err != nil {
+		attempt.Error = fmt.Sprintf("failed to create request: %v", err)
+		return attempt
+	}
+
+	timestamp := start.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "PaymentGateway-Webhooks/1.0")
+	req.Header.Set(EventIDHeader, delivery.EventID)
+	req.Header.Set(EventTypeHeader, delivery.EventType)
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Sign(endpoint.Secret, timestamp, payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	attempt.ResponseStatus = resp.StatusCode
+	attempt.ResponseBody = string(body)
+	if !attempt.Succeeded() {
+		attempt.Error = fmt.Sprintf("unexpected status code %d", resp.StatusCode)
+	}
+	return attempt
+}
@@ -0,0 +1,98 @@
+package webhooksender
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/omerbeden/paymentgateway/internal/domain/entity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSecret = "whsec_test"
+
+func newTestDelivery() *entity.WebhookDelivery {
+	return &entity.WebhookDelivery{
+		ID:        "del_1",
+		EventID:   "evt_1",
+		EventType: "payment.completed",
+		Payload:   `{"id":"evt_1","type":"payment.completed","data":{}}`,
+	}
+}
+
+func TestSend_SignsPayload(t *testing.T) {
+	// Arrange
+	var received *http.Request
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		body, _ = io.ReadAll(r.Body)
+		w.Write([]byte(`ok`))
+	}))
+	defer server.Close()
+
+	sender := NewSender(time.Second)
+	endpoint := &entity.WebhookEndpoint{URL: server.URL, Secret: testSecret}
+	delivery := newTestDelivery()
+
+	// Act
+	attempt := sender.Send(context.Background(), endpoint, delivery)
+
+	// Assert
+	require.True(t, attempt.Succeeded(), attempt.Error)
+	assert.Equal(t, http.StatusOK, attempt.ResponseStatus)
+	assert.Equal(t, "ok", attempt.ResponseBody)
+	assert.Equal(t, delivery.Payload, string(body))
+	assert.Equal(t, "evt_1", received.Header.Get(EventIDHeader))
+	assert.Equal(t, "payment.completed", received.Header.Get(EventTypeHeader))
+
+	timestamp, err := strconv.ParseInt(received.Header.Get(TimestampHeader), 10, 64)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), time.Unix(timestamp, 0), 5*time.Second)
+	assert.Equal(t, Sign(testSecret, timestamp, body), received.Header.Get(SignatureHeader))
+}
+
+func TestSend_Failures(t *testing.T) {
+	tests := []struct {
+		name       string
+		handler    http.HandlerFunc
+		wantStatus int
+	}{
+		{"server error", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}, http.StatusInternalServerError},
+		{"redirect is not followed", func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, "http://example.com", http.StatusFound)
+		}, http.StatusFound},
+		{"timeout", func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(200 * time.Millisecond)
+		}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+			sender := NewSender(50 * time.Millisecond)
+
+			attempt := sender.Send(context.Background(), &entity.WebhookEndpoint{URL: server.URL, Secret: testSecret}, newTestDelivery())
+
+			assert.False(t, attempt.Succeeded())
+			assert.NotEmpty(t, attempt.Error)
+			assert.Equal(t, tt.wantStatus, attempt.ResponseStatus)
+		})
+	}
+}
+
+func TestSign(t *testing.T) {
+	payload := []byte(`{"id":"evt_1"}`)
+
+	assert.Equal(t, Sign(testSecret, 1700000000, payload), Sign(testSecret, 1700000000, payload))
+	assert.NotEqual(t, Sign(testSecret, 1700000000, payload), Sign(testSecret, 1700000001, payload))
+	assert.NotEqual(t, Sign(testSecret, 1700000000, payload), Sign("other", 1700000000, payload))
+}
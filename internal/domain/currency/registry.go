@@ -0,0 +1,128 @@
+// Package currency keeps the currencies the gateway accepts, their limits and
+// which providers can settle them.
+package currency
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/omerbeden/paymentgateway/internal/domain/money"
+)
+
+var (
+	ErrUnsupportedCurrency = errors.New("unsupported currency")
+	ErrUnsupportedProvider = errors.New("currency not supported by provider")
+	ErrAmountOutOfRange    = errors.New("amount out of range")
+)
+
+// Currency describes an accepted ISO-4217 currency. Amounts are in minor units.
+type Currency struct {
+	Code      string
+	Exponent  int
+	MinAmount int64
+	MaxAmount int64
+}
+
+// Define returns the currency with code and the given limits, its exponent
+// taken from ISO-4217.
+func Define(code string, minAmount, maxAmount int64) (Currency, error) {
+	exp, err := money.Exponent(code)
+	if err != nil {
+		return Currency{}, err
+	}
+	return Currency{Code: code, Exponent: exp, MinAmount: minAmount, MaxAmount: maxAmount}, nil
+}
+
+type Registry struct {
+	currencies map[string]Currency
+	// providers maps a provider ID to the codes it accepts. Providers without
+	// an entry accept every registered currency.
+	providers map[string]map[string]struct{}
+}
+
+func NewRegistry(currencies ...Currency) (*Registry, error) {
+	r := &Registry{
+		currencies: make(map[string]Currency, len(currencies)),
+		providers:  make(map[string]map[string]struct{}),
+	}
+	for _, c := range currencies {
+		exp, err := money.Exponent(c.Code)
+		if err != nil {
+			return nil, err
+		}
+		if c.Exponent != exp {
+			return nil, fmt.Errorf("currency %s: exponent %d, ISO-4217 says %d", c.Code, c.Exponent, exp)
+		}
+		if c.MinAmount <= 0 || c.MaxAmount < c.MinAmount {
+			return nil, fmt.Errorf("currency %s: invalid limits [%d, %d]", c.Code, c.MinAmount, c.MaxAmount)
+		}
+		r.currencies[c.Code] = c
+	}
+	return r, nil
+}
+
+func (r *Registry) Lookup(code string) (Currency, error) {
+	c, ok := r.currencies[code]
+	if !ok {
+		return Currency{}, fmt.Errorf("%w: %q", ErrUnsupportedCurrency, code)
+	}
+	return c, nil
+}
+
+// Codes returns the registered currency codes in alphabetical order.
+func (r *Registry) Codes() []string {
+	codes := make([]string, 0, len(r.currencies))
+	for code := range r.currencies {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// SetProviderCurrencies restricts providerID to codes. Every code must already
+// be registered, and there must be at least one: a provider without
+// currencies could not take any payment.
+func (r *Registry) SetProviderCurrencies(providerID string, codes ...string) error {
+	if len(codes) == 0 {
+		return fmt.Errorf("provider %s: no currencies given", providerID)
+	}
+	supported := make(map[string]struct{}, len(codes))
+	for _, code := range codes {
+		if _, err := r.Lookup(code); err != nil {
+			return fmt.Errorf("provider %s: %w", providerID, err)
+		}
+		supported[code] = struct{}{}
+	}
+	r.providers[providerID] = supported
+	return nil
+}
+
+func (r *Registry) Supports(providerID, code string) bool {
+	if _, ok := r.currencies[code]; !ok {
+		return false
+	}
+	supported, restricted := r.providers[providerID]
+	if !restricted {
+		return true
+	}
+	_, ok := supported[code]
+	return ok
+}
+
+// Validate checks that amount is in a registered currency, within its limits
+// and accepted by providerID.
+func (r *Registry) Validate(providerID string, amount money.Money) error {
+	c, err := r.Lookup(amount.Currency)
+	if err != nil {
+		return err
+	}
+	if !r.Supports(providerID, c.Code) {
+		return fmt.Errorf("%w: %s does not accept %s", ErrUnsupportedProvider, providerID, c.Code)
+	}
+	if amount.Amount < c.MinAmount || amount.Amount > c.MaxAmount {
+		return fmt.Errorf("%w: %s must be between %s and %s", ErrAmountOutOfRange, amount,
+			money.New(c.MinAmount, c.Code).Decimal(), money.New(c.MaxAmount, c.Code).Decimal())
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package currency
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/omerbeden/paymentgateway/internal/domain/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRegistry(t *testing.T) *Registry {
+	t.Helper()
+	var currencies []Currency
+	for _, limits := range []struct {
+		code     string
+		min, max int64
+	}{
+		{"USD", 50, 99999999},
+		{"EUR", 50, 99999999},
+		{"GBP", 30, 99999999},
+		{"TRY", 1000, 99999999},
+		{"JPY", 50, 9999999},
+		{"KWD", 100, 99999999},
+	} {
+		c, err := Define(limits.code, limits.min, limits.max)
+		require.NoError(t, err)
+		currencies = append(currencies, c)
+	}
+	r, err := NewRegistry(currencies...)
+	require.NoError(t, err)
+	require.NoError(t, r.SetProviderCurrencies("paypal", "USD", "EUR", "GBP"))
+	return r
+}
+
+func TestRegistry_Validate(t *testing.T) {
+	r := newTestRegistry(t)
+
+	tests := []struct {
+		name     string
+		provider string
+		amount   money.Money
+		wantErr  error
+	}{
+		{"supported", "paypal", money.New(1050, "USD"), nil},
+		{"unrestricted provider", "mock", money.New(1050, "TRY"), nil},
+		{"provider does not accept currency", "paypal", money.New(1050, "TRY"), ErrUnsupportedProvider},
+		{"unknown currency", "paypal", money.New(1050, "CHF"), ErrUnsupportedCurrency},
+		{"below minimum", "paypal", money.New(49, "USD"), ErrAmountOutOfRange},
+		{"at minimum", "paypal", money.New(30, "GBP"), nil},
+		{"above maximum", "paypal", money.New(100000000, "EUR"), ErrAmountOutOfRange},
+		{"above currency's own maximum", "mock", money.New(10000000, "JPY"), ErrAmountOutOfRange},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := r.Validate(tt.provider, tt.amount)
+
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.True(t, errors.Is(err, tt.wantErr), "got %v", err)
+		})
+	}
+}
+
+func TestRegistry_Lookup(t *testing.T) {
+	r := newTestRegistry(t)
+
+	jpy, err := r.Lookup("JPY")
+	require.NoError(t, err)
+	assert.Equal(t, 0, jpy.Exponent)
+
+	kwd, err := r.Lookup("KWD")
+	require.NoError(t, err)
+	assert.Equal(t, 3, kwd.Exponent)
+
+	assert.Equal(t, []string{"EUR", "GBP", "JPY", "KWD", "TRY", "USD"}, r.Codes())
+}
+
+func TestDefine_RejectsUnknownCode(t *testing.T) {
+	_, err := Define("XXX", 1, 100)
+
+	assert.Error(t, err)
+}
+
+func TestNewRegistry_RejectsWrongExponent(t *testing.T) {
+	_, err := NewRegistry(Currency{Code: "JPY", Exponent: 2, MinAmount: 1, MaxAmount: 100})
+
+	assert.Error(t, err)
+}
+
+func TestSetProviderCurrencies_RejectsUnknownCode(t *testing.T) {
+	r := newTestRegistry(t)
+
+	err := r.SetProviderCurrencies("paypal", "USD", "XXX")
+
+	assert.True(t, errors.Is(err, ErrUnsupportedCurrency))
+}
+
+func TestSetProviderCurrencies_RejectsEmptyList(t *testing.T) {
+	r := newTestRegistry(t)
+
+	err := r.SetProviderCurrencies("paypal")
+
+	assert.Error(t, err)
+	assert.True(t, r.Supports("paypal", "USD"), "the previous restriction must be kept")
+}
@@ -0,0 +1,19 @@
+package entity
+
+import "time"
+
+// OutboxMessage is a domain event stored with the payment change it
+// announces, waiting to be published to Topic. Payload is the JSON encoded
+// event.
+type OutboxMessage struct {
+	ID          int64
+	AggregateID string
+	EventType   string
+	Topic       string
+	Payload     string
+	OccurredAt  time.Time
+	CreatedAt   time.Time
+	PublishedAt time.Time
+	Attempts    int
+	LastError   string
+}
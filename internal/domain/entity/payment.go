@@ -2,31 +2,54 @@ package entity
 
 import (
 	"time"
+
+	"github.com/omerbeden/paymentgateway/internal/domain/money"
 )
 
 type Payment struct {
 	ID                string            `json:"id"`
-	Amount            float64           `json:"amount"`
-	Currency          string            `json:"currency"`
+	Amount            money.Money       `json:"amount"`
+	CapturedAmount    money.Money       `json:"captured_amount"`
+	RefundedAmount    money.Money       `json:"refunded_amount"`
 	IdempotencyKey    string            `json:"idempotency_key"`
 	ProviderID        string            `json:"provider_id"`
 	ProviderPaymentID string            `json:"provider_payment_id"`
+	Intent            PaymentIntent     `json:"intent"`
+	PaymentURL        string            `json:"payment_url,omitempty"`
 	Status            PaymentStatus     `json:"status"`
 	CreatedAt         time.Time         `json:"created_at"`
 	UpdatedAt         time.Time         `json:"updated_at"`
 	CompletedAt       time.Time         `json:"completed_at,omitempty"`
 	ExpiresAt         time.Time         `json:"expires_at,omitempty"`
 	Metadata          map[string]string `json:"metadata,omitempty"`
+	// LastProviderEventAt is when the provider created the last webhook event
+	// applied to the payment; older events arriving late are not applied.
+	LastProviderEventAt time.Time `json:"last_provider_event_at,omitempty"`
 }
 
+// PaymentIntent says what happens once the buyer approves a payment.
+type PaymentIntent string
+
+const (
+	// PaymentIntentCapture captures the funds as soon as the buyer approves.
+	PaymentIntentCapture PaymentIntent = "capture"
+	// PaymentIntentAuthorize only holds the funds; they are captured or voided
+	// later through the API.
+	PaymentIntentAuthorize PaymentIntent = "authorize"
+)
+
 type PaymentStatus string
 
 const (
-	PaymentStatusPending       PaymentStatus = "pending"
-	PaymentStatusProcessing    PaymentStatus = "processing"
-	PaymentStatusSucceeded     PaymentStatus = "succeeded"
-	PaymentStatusFailed        PaymentStatus = "failed"
-	PaymentStatusCancelled     PaymentStatus = "cancelled"
+	PaymentStatusPending    PaymentStatus = "pending"
+	PaymentStatusProcessing PaymentStatus = "processing"
+	PaymentStatusAuthorized PaymentStatus = "authorized"
+	PaymentStatusSucceeded  PaymentStatus = "succeeded"
+	PaymentStatusFailed     PaymentStatus = "failed"
+	PaymentStatusCancelled  PaymentStatus = "cancelled"
+	// PaymentStatusExpired is set on pending payments the buyer did not
+	// approve before ExpiresAt.
+	PaymentStatusExpired       PaymentStatus = "expired"
 	PaymentStatusRefunded      PaymentStatus = "refunded"
 	PaymentStatusPartialRefund PaymentStatus = "partial_refund"
 )
@@ -0,0 +1,84 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidTransition is matched by every *InvalidTransitionError.
+var ErrInvalidTransition = errors.New("invalid payment status transition")
+
+type InvalidTransitionError struct {
+	From PaymentStatus
+	To   PaymentStatus
+}
+
+func (e *InvalidTransitionError) Error() string {
+	return fmt.Sprintf("invalid payment status transition from %q to %q", e.From, e.To)
+}
+
+func (e *InvalidTransitionError) Is(target error) bool {
+	return target == ErrInvalidTransition
+}
+
+// paymentStatuses lists every status in lifecycle order.
+var paymentStatuses = []PaymentStatus{
+	PaymentStatusPending,
+	PaymentStatusProcessing,
+	PaymentStatusAuthorized,
+	PaymentStatusSucceeded,
+	PaymentStatusPartialRefund,
+	PaymentStatusRefunded,
+	PaymentStatusFailed,
+	PaymentStatusCancelled,
+	PaymentStatusExpired,
+}
+
+// paymentTransitions holds the legal status changes. Staying in the same status
+// is always allowed and is not listed here.
+var paymentTransitions = map[PaymentStatus][]PaymentStatus{
+	PaymentStatusPending:       {PaymentStatusProcessing, PaymentStatusAuthorized, PaymentStatusSucceeded, PaymentStatusFailed, PaymentStatusCancelled, PaymentStatusExpired},
+	PaymentStatusProcessing:    {PaymentStatusAuthorized, PaymentStatusSucceeded, PaymentStatusFailed, PaymentStatusCancelled},
+	PaymentStatusAuthorized:    {PaymentStatusSucceeded, PaymentStatusFailed, PaymentStatusCancelled},
+	PaymentStatusSucceeded:     {PaymentStatusPartialRefund, PaymentStatusRefunded},
+	PaymentStatusPartialRefund: {PaymentStatusRefunded},
+}
+
+func (s PaymentStatus) CanTransitionTo(to PaymentStatus) bool {
+	if s == to {
+		return true
+	}
+	for _, next := range paymentTransitions[s] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// IsTerminal reports whether no other status can be reached from s.
+func (s PaymentStatus) IsTerminal() bool {
+	return len(paymentTransitions[s]) == 0
+}
+
+// StatusesTransitioningTo returns every status from which to can be reached,
+// including to itself.
+func StatusesTransitioningTo(to PaymentStatus) []PaymentStatus {
+	var from []PaymentStatus
+	for _, s := range paymentStatuses {
+		if s.CanTransitionTo(to) {
+			from = append(from, s)
+		}
+	}
+	return from
+}
+
+// TransitionTo moves the payment to status, or returns an *InvalidTransitionError
+// and leaves the payment untouched when the change is not allowed.
+func (p *Payment) TransitionTo(status PaymentStatus) error {
+	if !p.Status.CanTransitionTo(status) {
+		return &InvalidTransitionError{From: p.Status, To: status}
+	}
+	p.Status = status
+	return nil
+}
@@ -0,0 +1,84 @@
+package entity
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPaymentStatus_CanTransitionTo(t *testing.T) {
+	tests := []struct {
+		from PaymentStatus
+		to   PaymentStatus
+		want bool
+	}{
+		{PaymentStatusPending, PaymentStatusProcessing, true},
+		{PaymentStatusPending, PaymentStatusSucceeded, true},
+		{PaymentStatusPending, PaymentStatusFailed, true},
+		{PaymentStatusPending, PaymentStatusCancelled, true},
+		{PaymentStatusPending, PaymentStatusRefunded, false},
+		{PaymentStatusPending, PaymentStatusExpired, true},
+		{PaymentStatusProcessing, PaymentStatusExpired, false},
+		{PaymentStatusProcessing, PaymentStatusPending, false},
+		{PaymentStatusProcessing, PaymentStatusSucceeded, true},
+		{PaymentStatusProcessing, PaymentStatusAuthorized, true},
+		{PaymentStatusAuthorized, PaymentStatusSucceeded, true},
+		{PaymentStatusAuthorized, PaymentStatusCancelled, true},
+		{PaymentStatusAuthorized, PaymentStatusPending, false},
+		{PaymentStatusSucceeded, PaymentStatusAuthorized, false},
+		{PaymentStatusSucceeded, PaymentStatusPending, false},
+		{PaymentStatusSucceeded, PaymentStatusFailed, false},
+		{PaymentStatusSucceeded, PaymentStatusPartialRefund, true},
+		{PaymentStatusSucceeded, PaymentStatusRefunded, true},
+		{PaymentStatusPartialRefund, PaymentStatusPartialRefund, true},
+		{PaymentStatusPartialRefund, PaymentStatusRefunded, true},
+		{PaymentStatusPartialRefund, PaymentStatusSucceeded, false},
+		{PaymentStatusRefunded, PaymentStatusPartialRefund, false},
+		{PaymentStatusFailed, PaymentStatusSucceeded, false},
+		{PaymentStatusCancelled, PaymentStatusPending, false},
+		{PaymentStatusExpired, PaymentStatusSucceeded, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.from)+"->"+string(tt.to), func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.from.CanTransitionTo(tt.to))
+		})
+	}
+}
+
+func TestPayment_TransitionTo_Rejected(t *testing.T) {
+	payment := &Payment{Status: PaymentStatusSucceeded}
+
+	err := payment.TransitionTo(PaymentStatusPending)
+
+	var transitionErr *InvalidTransitionError
+	assert.True(t, errors.As(err, &transitionErr))
+	assert.True(t, errors.Is(err, ErrInvalidTransition))
+	assert.Equal(t, PaymentStatusSucceeded, transitionErr.From)
+	assert.Equal(t, PaymentStatusPending, transitionErr.To)
+	assert.Equal(t, PaymentStatusSucceeded, payment.Status)
+}
+
+func TestPayment_TransitionTo_Allowed(t *testing.T) {
+	payment := &Payment{Status: PaymentStatusPending}
+
+	err := payment.TransitionTo(PaymentStatusSucceeded)
+
+	assert.NoError(t, err)
+	assert.Equal(t, PaymentStatusSucceeded, payment.Status)
+}
+
+func TestStatusesTransitioningTo(t *testing.T) {
+	assert.Equal(t,
+		[]PaymentStatus{PaymentStatusPending, PaymentStatusProcessing, PaymentStatusAuthorized, PaymentStatusSucceeded},
+		StatusesTransitioningTo(PaymentStatusSucceeded),
+	)
+	assert.Equal(t,
+		[]PaymentStatus{PaymentStatusSucceeded, PaymentStatusPartialRefund, PaymentStatusRefunded},
+		StatusesTransitioningTo(PaymentStatusRefunded),
+	)
+	assert.True(t, PaymentStatusFailed.IsTerminal())
+	assert.True(t, PaymentStatusExpired.IsTerminal())
+	assert.False(t, PaymentStatusSucceeded.IsTerminal())
+}
@@ -1,12 +1,15 @@
 package entity
 
-import "time"
+import (
+	"time"
+
+	"github.com/omerbeden/paymentgateway/internal/domain/money"
+)
 
 type Transaction struct {
 	ID        string            `json:"id"`
 	PaymentID string            `json:"payment_id"`
-	Amount    float64           `json:"amount"`
-	Currency  string            `json:"currency"`
+	Amount    money.Money       `json:"amount"`
 	Type      TransactionType   `json:"type"`
 	Status    TransactionStatus `json:"status"`
 
@@ -23,10 +26,15 @@ type Transaction struct {
 type TransactionType string
 
 const (
-	TransactionTypeCharge  TransactionType = "charge"
-	TransactionTypeRefund  TransactionType = "refund"
-	TransactionTypeCapture TransactionType = "capture"
-	TransactionTypeVoid    TransactionType = "void"
+	TransactionTypeCharge    TransactionType = "charge"
+	TransactionTypeAuthorize TransactionType = "authorize"
+	TransactionTypeRefund    TransactionType = "refund"
+	TransactionTypeCapture   TransactionType = "capture"
+	TransactionTypeVoid      TransactionType = "void"
+	TransactionTypeCancel    TransactionType = "cancel"
+	// TransactionTypeWebhookVerification is a call asking the provider whether
+	// a webhook it sent is genuine.
+	TransactionTypeWebhookVerification TransactionType = "webhook_verification"
 )
 
 type TransactionStatus string
@@ -36,3 +44,19 @@ const (
 	TransactionStatusSucceeded TransactionStatus = "succeeded"
 	TransactionStatusFailed    TransactionStatus = "failed"
 )
+
+// NewTransaction starts a transaction record for a provider call made on behalf
+// of payment. Callers fill in the outcome before saving it.
+func NewTransaction(id string, payment *Payment, txnType TransactionType) *Transaction {
+	now := time.Now()
+	return &Transaction{
+		ID:         id,
+		PaymentID:  payment.ID,
+		Amount:     payment.Amount,
+		Type:       txnType,
+		Status:     TransactionStatusPending,
+		ProviderID: payment.ProviderID,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+}
@@ -0,0 +1,79 @@
+package entity
+
+import "time"
+
+// WebhookEndpoint is a merchant URL that receives payment events.
+type WebhookEndpoint struct {
+	ID          string                `json:"id"`
+	URL         string                `json:"url"`
+	Description string                `json:"description,omitempty"`
+	EventTypes  []string              `json:"event_types"`
+	Secret      string                `json:"-"`
+	Status      WebhookEndpointStatus `json:"status"`
+	// ConsecutiveFailures counts failed delivery attempts since the last
+	// successful one; the endpoint is disabled when it reaches the configured
+	// limit.
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	DisabledAt          time.Time `json:"disabled_at,omitempty"`
+	CreatedAt           time.Time `json:"created_at"`
+	UpdatedAt           time.Time `json:"updated_at"`
+}
+
+type WebhookEndpointStatus string
+
+const (
+	WebhookEndpointStatusEnabled  WebhookEndpointStatus = "enabled"
+	WebhookEndpointStatusDisabled WebhookEndpointStatus = "disabled"
+)
+
+// Subscribes reports whether the endpoint wants events of eventType.
+func (e *WebhookEndpoint) Subscribes(eventType string) bool {
+	for _, t := range e.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery is one event queued for one endpoint. Payload is the exact
+// body sent on every attempt.
+type WebhookDelivery struct {
+	ID            string                `json:"id"`
+	EndpointID    string                `json:"endpoint_id"`
+	EventID       string                `json:"event_id"`
+	EventType     string                `json:"event_type"`
+	Payload       string                `json:"payload"`
+	Status        WebhookDeliveryStatus `json:"status"`
+	AttemptCount  int                   `json:"attempt_count"`
+	NextAttemptAt time.Time             `json:"next_attempt_at,omitempty"`
+	LastError     string                `json:"last_error,omitempty"`
+	CreatedAt     time.Time             `json:"created_at"`
+	UpdatedAt     time.Time             `json:"updated_at"`
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryStatusPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliveryStatusSucceeded WebhookDeliveryStatus = "succeeded"
+	// WebhookDeliveryStatusFailed deliveries ran out of retries.
+	WebhookDeliveryStatusFailed WebhookDeliveryStatus = "failed"
+)
+
+// WebhookDeliveryAttempt logs a single HTTP call made for a delivery.
+type WebhookDeliveryAttempt struct {
+	ID             string        `json:"id"`
+	DeliveryID     string        `json:"delivery_id"`
+	AttemptNumber  int           `json:"attempt_number"`
+	ResponseStatus int           `json:"response_status,omitempty"`
+	ResponseBody   string        `json:"response_body,omitempty"`
+	Error          string        `json:"error,omitempty"`
+	Duration       time.Duration `json:"duration"`
+	AttemptedAt    time.Time     `json:"attempted_at"`
+}
+
+// Succeeded reports whether the endpoint accepted the delivery with a 2xx.
+func (a *WebhookDeliveryAttempt) Succeeded() bool {
+	return a.Error == "" && a.ResponseStatus >= 200 && a.ResponseStatus < 300
+}
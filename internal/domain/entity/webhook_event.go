@@ -5,6 +5,7 @@ import "time"
 type WebhookEvent struct {
 	ID                string    `json:"id"`
 	ProviderID        string    `json:"provider_id,omitempty"`
+	ProviderEventID   string    `json:"provider_event_id,omitempty"`
 	ProviderPaymentID string    `json:"provider_payment_id,omitempty"`
 	EventType         string    `json:"event_type,omitempty"`
 	Signature         string    `json:"signature,omitempty"`
@@ -1,13 +1,39 @@
 package event
 
-import "time"
+import (
+	"time"
+
+	"github.com/omerbeden/paymentgateway/internal/domain/money"
+)
 
 type EventType string
 
 const (
-	PaymentCompleted EventType = "payment.completed"
+	PaymentCreated    EventType = "payment.created"
+	PaymentAuthorized EventType = "payment.authorized"
+	PaymentCaptured   EventType = "payment.captured"
+	PaymentCompleted  EventType = "payment.completed"
+	PaymentFailed     EventType = "payment.failed"
+	PaymentCancelled  EventType = "payment.cancelled"
+	PaymentRefunded   EventType = "payment.refunded"
+	PaymentExpired    EventType = "payment.expired"
+	WebhookReceived   EventType = "webhook.received"
 )
 
+// EventTypes lists every event type. A new type belongs here, and needs a
+// topic in TopicFor and a decoder in each event store.
+var EventTypes = []EventType{
+	PaymentCreated,
+	PaymentAuthorized,
+	PaymentCaptured,
+	PaymentCompleted,
+	PaymentFailed,
+	PaymentCancelled,
+	PaymentRefunded,
+	PaymentExpired,
+	WebhookReceived,
+}
+
 type DomainEvent interface {
 	EventType() EventType
 	AggregateID() string
@@ -24,24 +50,196 @@ func (b BaseEvent) EventType() EventType  { return b.Type }
 func (b BaseEvent) AggregateID() string   { return b.AggregateId }
 func (b BaseEvent) OccurredAt() time.Time { return b.OccurredOn }
 
+// PaymentCreatedEvent announces a payment the provider accepted. Status is
+// pending until the buyer approves, or succeeded when the provider charged
+// right away.
+type PaymentCreatedEvent struct {
+	BaseEvent
+	PaymentID string      `json:"payment_id"`
+	Amount    money.Money `json:"amount"`
+	Provider  string      `json:"provider"`
+	Intent    string      `json:"intent"`
+	Status    string      `json:"status"`
+}
+
+func NewPaymentCreatedEvent(paymentID, provider, intent, status string, amount money.Money) PaymentCreatedEvent {
+	return PaymentCreatedEvent{
+		BaseEvent: BaseEvent{Type: PaymentCreated, AggregateId: paymentID, OccurredOn: time.Now().UTC()},
+		PaymentID: paymentID,
+		Amount:    amount,
+		Provider:  provider,
+		Intent:    intent,
+		Status:    status,
+	}
+}
+
+// PaymentAuthorizedEvent announces funds held for a payment created with the
+// authorize intent. They are released at ExpiresAt unless captured, when the
+// provider says so.
+type PaymentAuthorizedEvent struct {
+	BaseEvent
+	PaymentID string      `json:"payment_id"`
+	Amount    money.Money `json:"amount"`
+	Provider  string      `json:"provider"`
+	ExpiresAt time.Time   `json:"expires_at,omitempty"`
+}
+
+func NewPaymentAuthorizedEvent(paymentID, provider string, amount money.Money, expiresAt time.Time) PaymentAuthorizedEvent {
+	return PaymentAuthorizedEvent{
+		BaseEvent: BaseEvent{Type: PaymentAuthorized, AggregateId: paymentID, OccurredOn: time.Now().UTC()},
+		PaymentID: paymentID,
+		Amount:    amount,
+		Provider:  provider,
+		ExpiresAt: expiresAt,
+	}
+}
+
+// PaymentCapturedEvent announces captured funds. Amount is what was captured,
+// which is less than the payment amount after a partial capture.
+type PaymentCapturedEvent struct {
+	BaseEvent
+	PaymentID string      `json:"payment_id"`
+	Amount    money.Money `json:"amount"`
+	Provider  string      `json:"provider"`
+}
+
+func NewPaymentCapturedEvent(paymentID, provider string, amount money.Money) PaymentCapturedEvent {
+	return PaymentCapturedEvent{
+		BaseEvent: BaseEvent{Type: PaymentCaptured, AggregateId: paymentID, OccurredOn: time.Now().UTC()},
+		PaymentID: paymentID,
+		Amount:    amount,
+		Provider:  provider,
+	}
+}
+
+// PaymentCompletedEvent drives the buyer notification for a captured payment.
+// It is emitted together with PaymentCapturedEvent.
 type PaymentCompletedEvent struct {
 	BaseEvent
 	PaymentID string `json:"payment_id"`
 	//CustomerID  string  `json:"customer_id"`
-	Amount      float64 `json:"amount"`
-	Currency    string  `json:"currency"`
-	Provider    string  `json:"provider"`
-	Description string  `json:"description"`
+	Amount      money.Money `json:"amount"`
+	Provider    string      `json:"provider"`
+	Description string      `json:"description"`
 }
 
-func NewPaymentCompletedEvent(paymentID, currency, provider, description string, amount float64) PaymentCompletedEvent {
+func NewPaymentCompletedEvent(paymentID, provider, description string, amount money.Money) PaymentCompletedEvent {
 	return PaymentCompletedEvent{
 		BaseEvent: BaseEvent{Type: PaymentCompleted, AggregateId: paymentID, OccurredOn: time.Now().UTC()},
 		PaymentID: paymentID,
 		//CustomerID:  customerID,
 		Amount:      amount,
-		Currency:    currency,
 		Provider:    provider,
 		Description: description,
 	}
 }
+
+// PaymentFailedEvent announces a payment the provider declined or reported
+// as failed. Nothing was captured.
+type PaymentFailedEvent struct {
+	BaseEvent
+	PaymentID string      `json:"payment_id"`
+	Amount    money.Money `json:"amount"`
+	Provider  string      `json:"provider"`
+	Reason    string      `json:"reason,omitempty"`
+}
+
+func NewPaymentFailedEvent(paymentID, provider, reason string, amount money.Money) PaymentFailedEvent {
+	return PaymentFailedEvent{
+		BaseEvent: BaseEvent{Type: PaymentFailed, AggregateId: paymentID, OccurredOn: time.Now().UTC()},
+		PaymentID: paymentID,
+		Amount:    amount,
+		Provider:  provider,
+		Reason:    reason,
+	}
+}
+
+// PaymentCancelledEvent announces a payment that was cancelled before the buyer
+// approved it, or whose authorization was voided. Nothing was captured.
+type PaymentCancelledEvent struct {
+	BaseEvent
+	PaymentID string      `json:"payment_id"`
+	Amount    money.Money `json:"amount"`
+	Provider  string      `json:"provider"`
+	Reason    string      `json:"reason,omitempty"`
+}
+
+func NewPaymentCancelledEvent(paymentID, provider, reason string, amount money.Money) PaymentCancelledEvent {
+	return PaymentCancelledEvent{
+		BaseEvent: BaseEvent{Type: PaymentCancelled, AggregateId: paymentID, OccurredOn: time.Now().UTC()},
+		PaymentID: paymentID,
+		Amount:    amount,
+		Provider:  provider,
+		Reason:    reason,
+	}
+}
+
+// PaymentRefundedEvent announces a refund. Amount is this refund and
+// RefundedAmount the total refunded so far; Status is partial_refund or
+// refunded.
+type PaymentRefundedEvent struct {
+	BaseEvent
+	PaymentID      string      `json:"payment_id"`
+	RefundID       string      `json:"refund_id"`
+	Amount         money.Money `json:"amount"`
+	RefundedAmount money.Money `json:"refunded_amount"`
+	Provider       string      `json:"provider"`
+	Status         string      `json:"status"`
+	Reason         string      `json:"reason,omitempty"`
+}
+
+func NewPaymentRefundedEvent(paymentID, provider, refundID, status, reason string, amount, refundedAmount money.Money) PaymentRefundedEvent {
+	return PaymentRefundedEvent{
+		BaseEvent:      BaseEvent{Type: PaymentRefunded, AggregateId: paymentID, OccurredOn: time.Now().UTC()},
+		PaymentID:      paymentID,
+		RefundID:       refundID,
+		Amount:         amount,
+		RefundedAmount: refundedAmount,
+		Provider:       provider,
+		Status:         status,
+		Reason:         reason,
+	}
+}
+
+// PaymentExpiredEvent announces a payment the buyer did not approve before it
+// expired. Nothing was captured.
+type PaymentExpiredEvent struct {
+	BaseEvent
+	PaymentID string      `json:"payment_id"`
+	Amount    money.Money `json:"amount"`
+	Provider  string      `json:"provider"`
+	ExpiresAt time.Time   `json:"expires_at"`
+}
+
+func NewPaymentExpiredEvent(paymentID, provider string, amount money.Money, expiresAt time.Time) PaymentExpiredEvent {
+	return PaymentExpiredEvent{
+		BaseEvent: BaseEvent{Type: PaymentExpired, AggregateId: paymentID, OccurredOn: time.Now().UTC()},
+		PaymentID: paymentID,
+		Amount:    amount,
+		Provider:  provider,
+		ExpiresAt: expiresAt,
+	}
+}
+
+// WebhookReceivedEvent announces a verified provider webhook that has been
+// stored and is waiting to be processed. It refers to the stored event instead
+// of carrying the payload.
+type WebhookReceivedEvent struct {
+	BaseEvent
+	WebhookEventID    string `json:"webhook_event_id"`
+	ProviderID        string `json:"provider_id"`
+	ProviderPaymentID string `json:"provider_payment_id"`
+	ProviderEventType string `json:"provider_event_type"`
+}
+
+// NewWebhookReceivedEvent keys the event by provider payment, so webhooks for
+// the same payment are processed in the order they arrived.
+func NewWebhookReceivedEvent(webhookEventID, providerID, providerPaymentID, providerEventType string) WebhookReceivedEvent {
+	return WebhookReceivedEvent{
+		BaseEvent:         BaseEvent{Type: WebhookReceived, AggregateId: providerID + ":" + providerPaymentID, OccurredOn: time.Now().UTC()},
+		WebhookEventID:    webhookEventID,
+		ProviderID:        providerID,
+		ProviderPaymentID: providerPaymentID,
+		ProviderEventType: providerEventType,
+	}
+}
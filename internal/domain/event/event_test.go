@@ -0,0 +1,46 @@
+package event
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTopicFor(t *testing.T) {
+	tests := []struct {
+		eventType EventType
+		want      string
+	}{
+		{PaymentCreated, TopicPaymentCreated},
+		{PaymentAuthorized, TopicPaymentAuthorized},
+		{PaymentCaptured, TopicPaymentCaptured},
+		{PaymentCompleted, TopicNotificationPaymentCompleted},
+		{PaymentFailed, TopicPaymentFailed},
+		{PaymentCancelled, TopicPaymentCancelled},
+		{PaymentRefunded, TopicPaymentRefunded},
+		{PaymentExpired, TopicPaymentExpired},
+		{WebhookReceived, TopicWebhookReceived},
+		{EventType("payment.unknown"), "events.unknown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.eventType), func(t *testing.T) {
+			assert.Equal(t, tt.want, TopicFor(tt.eventType))
+		})
+	}
+}
+
+func TestTopicFor_EveryEventTypeHasATopic(t *testing.T) {
+	for _, eventType := range EventTypes {
+		assert.NotEqual(t, "events.unknown", TopicFor(eventType), "event type %s has no topic", eventType)
+	}
+}
+
+func TestPaymentEventTopics_CoverEveryPaymentEvent(t *testing.T) {
+	for _, eventType := range EventTypes {
+		if eventType == WebhookReceived {
+			continue
+		}
+		assert.Contains(t, PaymentEventTopics, TopicFor(eventType), "event type %s is not published from the event store", eventType)
+	}
+}
@@ -23,5 +23,52 @@ type Message struct {
 }
 
 const (
+	TopicPaymentCreated               = "payment.created"
+	TopicPaymentAuthorized            = "payment.authorized"
+	TopicPaymentCaptured              = "payment.processed"
+	TopicPaymentFailed                = "payment.failed"
+	TopicPaymentCancelled             = "payment.cancelled"
+	TopicPaymentRefunded              = "payment.refunded"
+	TopicPaymentExpired               = "payment.expired"
 	TopicNotificationPaymentCompleted = "notification.payment_completed"
+	TopicWebhookReceived              = "webhook.received"
 )
+
+// PaymentEventTopics are the topics payment events from the event store are
+// published to.
+var PaymentEventTopics = []string{
+	TopicPaymentCreated,
+	TopicPaymentAuthorized,
+	TopicPaymentCaptured,
+	TopicNotificationPaymentCompleted,
+	TopicPaymentFailed,
+	TopicPaymentCancelled,
+	TopicPaymentRefunded,
+	TopicPaymentExpired,
+}
+
+// TopicFor returns the topic events of type t are published to.
+func TopicFor(t EventType) string {
+	switch t {
+	case PaymentCreated:
+		return TopicPaymentCreated
+	case PaymentAuthorized:
+		return TopicPaymentAuthorized
+	case PaymentCaptured:
+		return TopicPaymentCaptured
+	case PaymentCompleted:
+		return TopicNotificationPaymentCompleted
+	case PaymentFailed:
+		return TopicPaymentFailed
+	case PaymentCancelled:
+		return TopicPaymentCancelled
+	case PaymentRefunded:
+		return TopicPaymentRefunded
+	case PaymentExpired:
+		return TopicPaymentExpired
+	case WebhookReceived:
+		return TopicWebhookReceived
+	default:
+		return "events.unknown"
+	}
+}
@@ -2,8 +2,53 @@ package event
 
 import (
 	"context"
+	"errors"
+	"fmt"
 )
 
+// AnyVersion makes Append skip the version check.
+const AnyVersion = -1
+
+// ErrConcurrencyConflict is matched by a *ConcurrencyError.
+var ErrConcurrencyConflict = errors.New("event store: concurrency conflict")
+
+// ConcurrencyError is returned by Append when the aggregate is not at the
+// expected version, because another append got there first.
+type ConcurrencyError struct {
+	AggregateID string
+	Expected    int
+	Actual      int
+}
+
+func (e *ConcurrencyError) Error() string {
+	return fmt.Sprintf("event store: %s is at version %d, expected %d", e.AggregateID, e.Actual, e.Expected)
+}
+
+func (e *ConcurrencyError) Is(target error) bool {
+	return target == ErrConcurrencyConflict
+}
+
+// RecordedEvent is a stored event and where it was stored. Version numbers the
+// events of an aggregate 1, 2, ...; Position orders the events of all
+// aggregates.
+type RecordedEvent struct {
+	Event    DomainEvent
+	Version  int
+	Position int64
+}
+
 type Store interface {
-	Append(ctx context.Context, event DomainEvent) error
+	// Append stores events of aggregateID as the versions after
+	// expectedVersion, 0 for a new aggregate, or after whatever the aggregate
+	// is at with AnyVersion. It fails with a *ConcurrencyError when the
+	// aggregate is at another version. Either all events are stored or none.
+	Append(ctx context.Context, aggregateID string, expectedVersion int, events ...DomainEvent) error
+	// Load returns the events of aggregateID, oldest first.
+	Load(ctx context.Context, aggregateID string) ([]RecordedEvent, error)
+	// LoadSince returns the events of aggregateID after version, oldest first.
+	LoadSince(ctx context.Context, aggregateID string, version int) ([]RecordedEvent, error)
+	// ReadAll returns up to limit events of any aggregate at or after
+	// fromPosition, in position order; zero means no limit. Positions start
+	// at 1.
+	ReadAll(ctx context.Context, fromPosition int64, limit int) ([]RecordedEvent, error)
 }
@@ -0,0 +1,85 @@
+package money
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		value    string
+		currency string
+		want     int64
+	}{
+		{"10.50", "USD", 1050},
+		{"10.5", "USD", 1050},
+		{"10", "USD", 1000},
+		{"0.01", "EUR", 1},
+		{"1050", "JPY", 1050},
+		{"1.050", "KWD", 1050},
+		{"-3.25", "GBP", -325},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value+" "+tt.currency, func(t *testing.T) {
+			m, err := Parse(tt.value, tt.currency)
+
+			require.NoError(t, err)
+			assert.Equal(t, New(tt.want, tt.currency), m)
+		})
+	}
+}
+
+func TestParse_Rejects(t *testing.T) {
+	tests := []struct {
+		value    string
+		currency string
+		wantErr  error
+	}{
+		{"10.505", "USD", ErrInvalidAmount},
+		{"10.5", "JPY", ErrInvalidAmount},
+		{".5", "USD", ErrInvalidAmount},
+		{"1e3", "USD", ErrInvalidAmount},
+		{"+5", "USD", ErrInvalidAmount},
+		{"", "USD", ErrInvalidAmount},
+		{"10.00", "XXX", ErrUnknownCurrency},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value+" "+tt.currency, func(t *testing.T) {
+			_, err := Parse(tt.value, tt.currency)
+
+			assert.True(t, errors.Is(err, tt.wantErr), "got %v", err)
+		})
+	}
+}
+
+func TestDecimal(t *testing.T) {
+	assert.Equal(t, "10.50", New(1050, "USD").Decimal())
+	assert.Equal(t, "0.05", New(5, "USD").Decimal())
+	assert.Equal(t, "-0.05", New(-5, "USD").Decimal())
+	assert.Equal(t, "1050", New(1050, "JPY").Decimal())
+	assert.Equal(t, "1.050", New(1050, "KWD").Decimal())
+	assert.Equal(t, "0.001", New(1, "KWD").Decimal())
+	assert.Equal(t, "10.50 USD", New(1050, "USD").String())
+}
+
+func TestArithmetic(t *testing.T) {
+	sum, err := New(1050, "USD").Add(New(25, "USD"))
+	require.NoError(t, err)
+	assert.Equal(t, New(1075, "USD"), sum)
+
+	diff, err := New(1050, "USD").Sub(New(1050, "USD"))
+	require.NoError(t, err)
+	assert.True(t, diff.IsZero())
+
+	cmp, err := New(1, "USD").Cmp(New(2, "USD"))
+	require.NoError(t, err)
+	assert.Equal(t, -1, cmp)
+
+	_, err = New(1050, "USD").Add(New(1050, "JPY"))
+	assert.True(t, errors.Is(err, ErrCurrencyMismatch))
+}
@@ -3,6 +3,8 @@ package notification
 import (
 	"context"
 	"time"
+
+	"github.com/omerbeden/paymentgateway/internal/domain/money"
 )
 
 type Channel string
@@ -26,8 +28,7 @@ type PaymentCompletedNotification struct {
 	CustomerPhone  string
 	PaymentID      string
 	TransactionID  string
-	Amount         float64
-	Currency       string
+	Amount         money.Money
 	Provider       string
 	CompletedAt    time.Time
 	Channel        Channel
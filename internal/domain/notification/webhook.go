@@ -0,0 +1,14 @@
+package notification
+
+import (
+	"context"
+
+	"github.com/omerbeden/paymentgateway/internal/domain/entity"
+)
+
+// WebhookSender delivers a signed merchant webhook. The HTTP outcome is
+// reported in the returned attempt rather than as an error: a failed delivery
+// is an expected result that is logged and retried.
+type WebhookSender interface {
+	Send(ctx context.Context, endpoint *entity.WebhookEndpoint, delivery *entity.WebhookDelivery) *entity.WebhookDeliveryAttempt
+}
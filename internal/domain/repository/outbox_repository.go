@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/omerbeden/paymentgateway/internal/domain/entity"
+)
+
+// OutboxRepository reads the outbox filled by PaymentRepository.UpdatePayment.
+type OutboxRepository interface {
+	// ListUnpublished returns up to limit unpublished messages in the order
+	// they were written.
+	ListUnpublished(ctx context.Context, limit int) ([]*entity.OutboxMessage, error)
+	MarkPublished(ctx context.Context, id int64, at time.Time) error
+	// MarkFailed counts a failed attempt to publish the message.
+	MarkFailed(ctx context.Context, id int64, reason string) error
+	// DeletePublished removes messages published before the given time and
+	// returns how many it removed.
+	DeletePublished(ctx context.Context, before time.Time) (int64, error)
+}
@@ -2,12 +2,66 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/omerbeden/paymentgateway/internal/domain/entity"
+	"github.com/omerbeden/paymentgateway/internal/domain/event"
 )
 
 type PaymentRepository interface {
 	CreatePayment(ctx context.Context, payment *entity.Payment) error
+	GetByID(ctx context.Context, id string) (*entity.Payment, error)
 	GetByProviderPaymentID(ctx context.Context, providerPaymentID, providerID string) (*entity.Payment, error)
-	UpdatePayment(ctx context.Context, payment *entity.Payment) error
+	List(ctx context.Context, filter PaymentFilter) ([]*entity.Payment, error)
+	// UpdatePayment saves payment and writes events to the outbox in the same
+	// transaction, so they are published if and only if the change is stored.
+	UpdatePayment(ctx context.Context, payment *entity.Payment, events ...event.DomainEvent) error
+	// UpdatePaymentLocked locks the payment while update changes it, then
+	// saves it with what update returns, so concurrent read-modify-write
+	// changes of one payment run one after the other. Nothing is saved when
+	// update returns an error.
+	UpdatePaymentLocked(ctx context.Context, id string, update func(payment *entity.Payment) (PaymentUpdate, error)) (*entity.Payment, error)
+	// ListExpired returns up to limit pending payments whose expires_at is at
+	// or before the given time and that were not tried to be expired after
+	// attemptedBefore, the longest waiting first.
+	ListExpired(ctx context.Context, before, attemptedBefore time.Time, limit int) ([]*entity.Payment, error)
+	// MarkExpireAttempted records a failed try to expire the payment, which
+	// moves it behind the other expired payments.
+	MarkExpireAttempted(ctx context.Context, id string, at time.Time) error
+	// ListStale returns up to limit payments in one of the statuses that have
+	// neither been updated nor reconciled since the given time, the longest
+	// untouched first. Payments not yet known to the provider are left out.
+	ListStale(ctx context.Context, statuses []entity.PaymentStatus, before time.Time, limit int) ([]*entity.Payment, error)
+	// MarkReconciled records that the payment was checked with its provider.
+	MarkReconciled(ctx context.Context, id string, at time.Time) error
 }
+
+// PaymentUpdate is saved by UpdatePaymentLocked in the same database
+// transaction as the payment. Transactions must be written there rather than
+// through a TransactionRepository, which would wait for the payment lock.
+type PaymentUpdate struct {
+	Events       []event.DomainEvent
+	Transactions []*entity.Transaction
+}
+
+// PaymentFilter narrows a List query. Zero values are ignored. Results are
+// ordered newest first by (created_at, id); After, when set, returns only the
+// payments that come after that position.
+type PaymentFilter struct {
+	Status        entity.PaymentStatus
+	ProviderID    string
+	Currency      string
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	Metadata      map[string]string
+	After         *PaymentCursor
+	Limit         int
+}
+
+type PaymentCursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+var ErrPaymentNotFound = errors.New("payment not found")
@@ -0,0 +1,12 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/omerbeden/paymentgateway/internal/domain/entity"
+)
+
+type TransactionRepository interface {
+	Save(ctx context.Context, txn *entity.Transaction) error
+	ListByPaymentID(ctx context.Context, paymentID string) ([]*entity.Transaction, error)
+}
@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/omerbeden/paymentgateway/internal/domain/entity"
+)
+
+var (
+	ErrWebhookEndpointNotFound = errors.New("webhook endpoint not found")
+	ErrWebhookDeliveryNotFound = errors.New("webhook delivery not found")
+)
+
+type WebhookEndpointRepository interface {
+	Create(ctx context.Context, endpoint *entity.WebhookEndpoint) error
+	GetByID(ctx context.Context, id string) (*entity.WebhookEndpoint, error)
+	List(ctx context.Context) ([]*entity.WebhookEndpoint, error)
+	// ListSubscribed returns the enabled endpoints subscribed to eventType.
+	ListSubscribed(ctx context.Context, eventType string) ([]*entity.WebhookEndpoint, error)
+	Update(ctx context.Context, endpoint *entity.WebhookEndpoint) error
+	// RecordDeliveryResult resets the failure count after a successful
+	// delivery attempt, or increments it after a failed one and disables the
+	// endpoint once it reaches disableAfter. It returns the updated endpoint.
+	RecordDeliveryResult(ctx context.Context, id string, succeeded bool, disableAfter int) (*entity.WebhookEndpoint, error)
+}
+
+type WebhookDeliveryRepository interface {
+	// CreateMany queues deliveries, skipping events an endpoint already has.
+	CreateMany(ctx context.Context, deliveries []*entity.WebhookDelivery) error
+	GetByID(ctx context.Context, id string) (*entity.WebhookDelivery, error)
+	ListByEndpoint(ctx context.Context, endpointID string, limit int) ([]*entity.WebhookDelivery, error)
+	// ClaimDue returns up to limit pending deliveries of enabled endpoints
+	// that are due at now, and pushes their next attempt back by lease so
+	// concurrent workers do not send them twice.
+	ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*entity.WebhookDelivery, error)
+	Update(ctx context.Context, delivery *entity.WebhookDelivery) error
+	SaveAttempt(ctx context.Context, attempt *entity.WebhookDeliveryAttempt) error
+	ListAttempts(ctx context.Context, deliveryID string) ([]*entity.WebhookDeliveryAttempt, error)
+}
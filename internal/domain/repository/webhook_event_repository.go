@@ -2,10 +2,58 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/omerbeden/paymentgateway/internal/domain/entity"
 )
 
+var (
+	// ErrDuplicateWebhookEvent is returned by Save when the provider event was
+	// already stored.
+	ErrDuplicateWebhookEvent = errors.New("duplicate webhook event")
+	ErrWebhookEventNotFound  = errors.New("webhook event not found")
+)
+
 type WebhookEventRepository interface {
 	Save(ctx context.Context, event *entity.WebhookEvent) error
+	GetByID(ctx context.Context, id string) (*entity.WebhookEvent, error)
+	// MarkProcessed records the outcome of processing and counts the attempt.
+	// Failed events are not processed so they can be picked up again; events
+	// that were deliberately not applied, e.g. stale ones, are processed with
+	// the reason as processingError.
+	MarkProcessed(ctx context.Context, id string, processed bool, processingError string, processedAt time.Time) error
+	List(ctx context.Context, filter WebhookEventFilter) ([]*entity.WebhookEvent, error)
+}
+
+type WebhookEventState string
+
+const (
+	// WebhookEventStatePending events have not been processed yet.
+	WebhookEventStatePending WebhookEventState = "pending"
+	// WebhookEventStateProcessed events were applied successfully or skipped.
+	WebhookEventStateProcessed WebhookEventState = "processed"
+	// WebhookEventStateFailed events were verified but failed to process.
+	WebhookEventStateFailed WebhookEventState = "failed"
+	// WebhookEventStateUnverified events failed signature verification on
+	// receipt and are only kept for investigation.
+	WebhookEventStateUnverified WebhookEventState = "unverified"
+	// WebhookEventStateUnprocessed events were verified but not processed,
+	// whether or not processing failed, i.e. both pending and failed ones.
+	WebhookEventStateUnprocessed WebhookEventState = "unprocessed"
+)
+
+// WebhookEventFilter narrows a List query. Zero values are ignored. Results
+// are ordered newest first by received_at.
+type WebhookEventFilter struct {
+	ProviderID        string
+	ProviderPaymentID string
+	State             WebhookEventState
+	// ReceivedBefore keeps events received before it.
+	ReceivedBefore time.Time
+	// AttemptedBefore keeps events not tried to be processed since it.
+	AttemptedBefore time.Time
+	// AttemptsBelow keeps events tried to be processed fewer times than it.
+	AttemptsBelow int
+	Limit         int
 }
@@ -0,0 +1,115 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/omerbeden/paymentgateway/internal/infrastructure/logger"
+)
+
+// Advisory lock keys of the jobs that must run on a single replica.
+const (
+	LockKeyPaymentExpiration     int64 = 1001
+	LockKeyPaymentReconciliation int64 = 1002
+	LockKeyOutboxRelay           int64 = 1003
+	LockKeyChangeStreamPublisher int64 = 1004
+	LockKeyWebhookRequeue        int64 = 1005
+)
+
+// Leader runs a job on one replica at a time. The replica holding a Postgres
+// session advisory lock runs it; the others retry every interval. The lock is
+// held on a dedicated connection and Postgres releases it when that connection
+// dies, so a crashed leader is replaced on the next retry.
+type Leader struct {
+	db       *sql.DB
+	key      int64
+	interval time.Duration
+	log      logger.Logger
+}
+
+func NewLeader(db *sql.DB, key int64, interval time.Duration, log logger.Logger) *Leader {
+	return &Leader{
+		db:       db,
+		key:      key,
+		interval: interval,
+		log:      log.With("lock_key", key),
+	}
+}
+
+// Run calls job whenever this replica becomes leader, with a context that is
+// cancelled when leadership is lost. It returns when ctx is cancelled.
+func (l *Leader) Run(ctx context.Context, job func(ctx context.Context)) {
+	for {
+		led, err := l.lead(ctx, job)
+		if err != nil {
+			l.log.Error("Leader election failed", "error", err)
+		} else if led {
+			l.log.Info("Gave up leadership")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(l.interval):
+		}
+	}
+}
+
+// lead runs job for as long as this replica holds the lock. It reports whether
+// the lock was acquired.
+func (l *Leader) lead(ctx context.Context, job func(ctx context.Context)) (bool, error) {
+	conn, err := l.db.Conn(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, l.key).Scan(&acquired); err != nil {
+		return false, fmt.Errorf("failed to acquire advisory lock: %w", err)
+	}
+	if !acquired {
+		return false, nil
+	}
+	l.log.Info("Acquired leadership")
+
+	jobCtx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer cancel()
+		job(jobCtx)
+	}()
+
+	// A lost connection means the lock is gone and another replica may take
+	// over, so the job must stop.
+	ticker := time.NewTicker(l.interval)
+	defer ticker.Stop()
+	for err == nil {
+		select {
+		case <-jobCtx.Done():
+			err = jobCtx.Err()
+		case <-ticker.C:
+			if pingErr := conn.PingContext(jobCtx); pingErr != nil {
+				l.log.Error("Lost leader connection", "error", pingErr)
+				err = pingErr
+			}
+		}
+	}
+	cancel()
+	wg.Wait()
+
+	unlockCtx, unlockCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer unlockCancel()
+	if _, err := conn.ExecContext(unlockCtx, `SELECT pg_advisory_unlock($1)`, l.key); err != nil {
+		// Discard the connection rather than return it to the pool still
+		// holding the lock; closing the session releases it.
+		_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+	}
+	return true, nil
+}
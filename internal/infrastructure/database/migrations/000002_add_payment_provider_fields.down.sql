@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_payments_provider_payment_id;
+
+ALTER TABLE payments
+    DROP COLUMN IF EXISTS payment_url,
+    DROP COLUMN IF EXISTS provider_payment_id;
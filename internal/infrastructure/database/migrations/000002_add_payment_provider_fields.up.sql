@@ -0,0 +1,5 @@
+ALTER TABLE payments
+    ADD COLUMN IF NOT EXISTS provider_payment_id VARCHAR(255),
+    ADD COLUMN IF NOT EXISTS payment_url TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_payments_provider_payment_id ON payments(provider_id, provider_payment_id);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_payments_metadata;
+DROP INDEX IF EXISTS idx_payments_created_at_id;
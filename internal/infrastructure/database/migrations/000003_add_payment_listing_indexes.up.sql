@@ -0,0 +1,2 @@
+CREATE INDEX IF NOT EXISTS idx_payments_created_at_id ON payments(created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_payments_metadata ON payments USING GIN (metadata jsonb_path_ops);
@@ -0,0 +1,3 @@
+ALTER TABLE payments
+    DROP CONSTRAINT IF EXISTS chk_payments_refunded_amount,
+    DROP COLUMN IF EXISTS refunded_amount;
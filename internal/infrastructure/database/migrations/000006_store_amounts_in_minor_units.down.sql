@@ -0,0 +1,18 @@
+ALTER TABLE payments DROP CONSTRAINT IF EXISTS chk_payments_refunded_amount;
+
+ALTER TABLE payments
+    ALTER COLUMN amount TYPE DECIMAL(10, 2) USING amount::NUMERIC / CASE
+        WHEN currency IN ('JPY', 'KRW') THEN 1
+        WHEN currency IN ('KWD', 'BHD', 'JOD', 'OMR', 'TND') THEN 1000
+        ELSE 100 END,
+    ALTER COLUMN refunded_amount TYPE DECIMAL(10, 2) USING refunded_amount::NUMERIC / CASE
+        WHEN currency IN ('JPY', 'KRW') THEN 1
+        WHEN currency IN ('KWD', 'BHD', 'JOD', 'OMR', 'TND') THEN 1000
+        ELSE 100 END,
+    ADD CONSTRAINT chk_payments_refunded_amount CHECK (refunded_amount >= 0 AND refunded_amount <= amount);
+
+ALTER TABLE transactions
+    ALTER COLUMN amount TYPE DECIMAL(10, 2) USING amount::NUMERIC / CASE
+        WHEN currency IN ('JPY', 'KRW') THEN 1
+        WHEN currency IN ('KWD', 'BHD', 'JOD', 'OMR', 'TND') THEN 1000
+        ELSE 100 END;
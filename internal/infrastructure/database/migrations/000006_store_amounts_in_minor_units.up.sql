@@ -0,0 +1,20 @@
+-- Amounts become integers in the currency's minor unit (cents for USD, yen for
+-- JPY, fils for KWD) so no value is ever rounded.
+ALTER TABLE payments DROP CONSTRAINT IF EXISTS chk_payments_refunded_amount;
+
+ALTER TABLE payments
+    ALTER COLUMN amount TYPE BIGINT USING ROUND(amount * CASE
+        WHEN currency IN ('JPY', 'KRW') THEN 1
+        WHEN currency IN ('KWD', 'BHD', 'JOD', 'OMR', 'TND') THEN 1000
+        ELSE 100 END),
+    ALTER COLUMN refunded_amount TYPE BIGINT USING ROUND(refunded_amount * CASE
+        WHEN currency IN ('JPY', 'KRW') THEN 1
+        WHEN currency IN ('KWD', 'BHD', 'JOD', 'OMR', 'TND') THEN 1000
+        ELSE 100 END),
+    ADD CONSTRAINT chk_payments_refunded_amount CHECK (refunded_amount >= 0 AND refunded_amount <= amount);
+
+ALTER TABLE transactions
+    ALTER COLUMN amount TYPE BIGINT USING ROUND(amount * CASE
+        WHEN currency IN ('JPY', 'KRW') THEN 1
+        WHEN currency IN ('KWD', 'BHD', 'JOD', 'OMR', 'TND') THEN 1000
+        ELSE 100 END);
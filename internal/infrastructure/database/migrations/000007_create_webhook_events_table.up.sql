@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS webhook_events (
+    id VARCHAR(255) PRIMARY KEY,
+    provider_id VARCHAR(255) NOT NULL,
+    provider_event_id VARCHAR(255),
+    provider_payment_id VARCHAR(255),
+    event_type VARCHAR(255),
+    signature TEXT,
+    payload TEXT NOT NULL,
+    is_verified BOOLEAN NOT NULL DEFAULT FALSE,
+    is_processed BOOLEAN NOT NULL DEFAULT FALSE,
+    processing_error TEXT,
+    received_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    processed_at TIMESTAMP,
+    -- providers retry deliveries with the same event ID; events without one are NULL and never collide
+    CONSTRAINT uq_webhook_events_provider_event UNIQUE (provider_id, provider_event_id)
+);
+
+CREATE INDEX idx_webhook_events_provider_payment_id ON webhook_events(provider_id, provider_payment_id);
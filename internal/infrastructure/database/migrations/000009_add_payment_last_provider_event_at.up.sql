@@ -0,0 +1,2 @@
+-- creation time of the last provider event applied to the payment; webhooks created before it are stale
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS last_provider_event_at TIMESTAMP;
@@ -0,0 +1,8 @@
+ALTER TABLE payments
+    DROP CONSTRAINT IF EXISTS chk_payments_refunded_amount,
+    DROP CONSTRAINT IF EXISTS chk_payments_captured_amount,
+    ADD CONSTRAINT chk_payments_refunded_amount CHECK (refunded_amount >= 0 AND refunded_amount <= amount);
+
+ALTER TABLE payments
+    DROP COLUMN IF EXISTS captured_amount,
+    DROP COLUMN IF EXISTS intent;
@@ -0,0 +1,11 @@
+-- authorize payments hold the funds until they are captured, possibly for less than the authorized amount
+ALTER TABLE payments
+    ADD COLUMN IF NOT EXISTS intent VARCHAR(20) NOT NULL DEFAULT 'capture',
+    ADD COLUMN IF NOT EXISTS captured_amount BIGINT NOT NULL DEFAULT 0;
+
+UPDATE payments SET captured_amount = amount WHERE status IN ('succeeded', 'partial_refund', 'refunded');
+
+ALTER TABLE payments
+    DROP CONSTRAINT IF EXISTS chk_payments_refunded_amount,
+    ADD CONSTRAINT chk_payments_captured_amount CHECK (captured_amount >= 0 AND captured_amount <= amount),
+    ADD CONSTRAINT chk_payments_refunded_amount CHECK (refunded_amount >= 0 AND refunded_amount <= captured_amount);
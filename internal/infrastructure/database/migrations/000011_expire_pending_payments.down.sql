@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_payments_pending_expires_at;
@@ -0,0 +1,4 @@
+-- payments used to be stored with a zero expires_at instead of NULL; those never expire
+UPDATE payments SET expires_at = NULL WHERE expires_at = '0001-01-01 00:00:00';
+
+CREATE INDEX IF NOT EXISTS idx_payments_pending_expires_at ON payments(expires_at) WHERE status = 'pending';
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_payments_open_updated_at;
+ALTER TABLE payments DROP COLUMN IF EXISTS reconciled_at;
@@ -0,0 +1,4 @@
+-- when the payment was last checked with its provider because no webhook updated it
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS reconciled_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_payments_open_updated_at ON payments(updated_at) WHERE status IN ('pending', 'processing');
@@ -0,0 +1,2 @@
+-- when expiring the payment last failed because its provider could not cancel it
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS expire_attempted_at TIMESTAMP;
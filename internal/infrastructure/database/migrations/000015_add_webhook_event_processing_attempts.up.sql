@@ -0,0 +1,2 @@
+-- how often processing the webhook was tried, so failures are retried a bounded number of times
+ALTER TABLE webhook_events ADD COLUMN IF NOT EXISTS processing_attempts INT NOT NULL DEFAULT 0;
@@ -6,17 +6,23 @@ import (
 )
 
 type Metrics struct {
-	HTTPRequestTotal          *prometheus.CounterVec
-	HTTPRequestDuration       *prometheus.HistogramVec
-	PaymentsTotal             *prometheus.CounterVec
-	PaymentAmount             *prometheus.HistogramVec
-	PaymentDuration           *prometheus.HistogramVec
-	ProviderRequestsTotal     *prometheus.CounterVec
-	ProviderRequestDuration   *prometheus.HistogramVec
-	ProviderErrors            *prometheus.CounterVec
-	DBQueryDuration           *prometheus.HistogramVec
-	WebhooksReceived          *prometheus.CounterVec
-	WebhookProcessingDuration *prometheus.HistogramVec
+	HTTPRequestTotal           *prometheus.CounterVec
+	HTTPRequestDuration        *prometheus.HistogramVec
+	PaymentsTotal              *prometheus.CounterVec
+	PaymentAmount              *prometheus.HistogramVec
+	PaymentDuration            *prometheus.HistogramVec
+	ProviderRequestsTotal      *prometheus.CounterVec
+	ProviderRequestDuration    *prometheus.HistogramVec
+	ProviderErrors             *prometheus.CounterVec
+	DBQueryDuration            *prometheus.HistogramVec
+	WebhooksReceived           *prometheus.CounterVec
+	WebhookProcessingDuration  *prometheus.HistogramVec
+	WebhooksProcessed          *prometheus.CounterVec
+	MerchantWebhookDeliveries  *prometheus.CounterVec
+	PaymentTransitionsRejected *prometheus.CounterVec
+	WebhooksOutOfOrder         *prometheus.CounterVec
+	PaymentsReconciled         *prometheus.CounterVec
+	OutboxMessagesPublished    *prometheus.CounterVec
 }
 
 func New() *Metrics {
@@ -105,5 +111,47 @@ func New() *Metrics {
 			},
 			[]string{"provider"},
 		),
+		WebhooksProcessed: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "webhooks_processed_total",
+				Help: "Total stored webhooks processed by the webhook worker",
+			},
+			[]string{"provider", "event_type", "status"},
+		),
+		MerchantWebhookDeliveries: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "merchant_webhook_delivery_attempts_total",
+				Help: "Total delivery attempts to merchant webhook endpoints",
+			},
+			[]string{"event_type", "status"},
+		),
+		PaymentTransitionsRejected: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "payment_transitions_rejected_total",
+				Help: "Total payment status changes rejected by the state machine",
+			},
+			[]string{"from", "to"},
+		),
+		WebhooksOutOfOrder: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "webhooks_out_of_order_total",
+				Help: "Total webhooks ignored because a newer provider event was already applied",
+			},
+			[]string{"provider", "event_type"},
+		),
+		PaymentsReconciled: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "payments_reconciled_total",
+				Help: "Total stale payments checked with their provider, by outcome",
+			},
+			[]string{"provider", "outcome"},
+		),
+		OutboxMessagesPublished: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "outbox_messages_published_total",
+				Help: "Total attempts to publish outbox messages, by topic and outcome",
+			},
+			[]string{"topic", "outcome"},
+		),
 	}
 }
@@ -71,9 +71,12 @@ func DefaultTopics() []TopicConfig {
 	)
 	return []TopicConfig{
 		{Name: "payment.created", NumPartitions: partitions, ReplicationFactor: replicas, RetentionMs: sevenDaysMs},
+		{Name: "payment.authorized", NumPartitions: partitions, ReplicationFactor: replicas, RetentionMs: sevenDaysMs},
 		{Name: "payment.processed", NumPartitions: partitions, ReplicationFactor: replicas, RetentionMs: sevenDaysMs},
 		{Name: "payment.failed", NumPartitions: partitions, ReplicationFactor: replicas, RetentionMs: sevenDaysMs},
 		{Name: "payment.refunded", NumPartitions: partitions, ReplicationFactor: replicas, RetentionMs: sevenDaysMs},
+		{Name: "payment.cancelled", NumPartitions: partitions, ReplicationFactor: replicas, RetentionMs: sevenDaysMs},
+		{Name: "payment.expired", NumPartitions: partitions, ReplicationFactor: replicas, RetentionMs: sevenDaysMs},
 		{Name: "webhook.received", NumPartitions: partitions, ReplicationFactor: replicas, RetentionMs: sevenDaysMs},
 		{Name: "notification.payment_completed", NumPartitions: partitions, ReplicationFactor: replicas, RetentionMs: sevenDaysMs},
 		{Name: "payment.dlq", NumPartitions: 1, ReplicationFactor: replicas, RetentionMs: -1},
@@ -15,7 +15,9 @@ type Consumer struct {
 	cfg config.Kafka
 }
 
-func NewConsumer(cfg config.Kafka) (*Consumer, error) {
+// NewConsumer creates a consumer in the given consumer group. Every service
+// reading a topic needs its own group so each gets a full copy of the stream.
+func NewConsumer(cfg config.Kafka, groupID string) (*Consumer, error) {
 	autoOffset := cfg.AutoOffsetReset
 	if autoOffset == "" {
 		autoOffset = "earliest"
@@ -23,6 +25,7 @@ func NewConsumer(cfg config.Kafka) (*Consumer, error) {
 
 	cm := kafka.ConfigMap{
 		"bootstrap.servers":    cfg.Brokers,
+		"group.id":             groupID,
 		"auto.offset.reset":    autoOffset,
 		"enable.auto.commit":   false, // manual commit for at-least-once guarantee
 		"session.timeout.ms":   30000,
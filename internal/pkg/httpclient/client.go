@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 )
 
 type RequestParam[B any] struct {
@@ -20,15 +21,33 @@ type RequestParam[B any] struct {
 	ClientSecret string
 }
 
+// StatusError is returned when the server answers with a non-2xx status code.
+type StatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status code %d: %s", e.StatusCode, e.Body)
+}
+
 func MakeRequest[B, T any](p RequestParam[B], result T) error {
 
 	var body io.Reader
 
-	bodyBytes, err := json.Marshal(p.Body)
-	if err != nil {
-		return fmt.Errorf("failed to marshal request body: %w", err)
+	switch b := any(p.Body).(type) {
+	case string:
+		// pre-encoded bodies such as application/x-www-form-urlencoded are sent as is
+		body = strings.NewReader(b)
+	default:
+		if p.Method != http.MethodGet {
+			bodyBytes, err := json.Marshal(p.Body)
+			if err != nil {
+				return fmt.Errorf("failed to marshal request body: %w", err)
+			}
+			body = bytes.NewReader(bodyBytes)
+		}
 	}
-	body = bytes.NewReader(bodyBytes)
 
 	req, err := http.NewRequestWithContext(p.Ctx, p.Method, p.URL, body)
 	if err != nil {
@@ -53,6 +72,11 @@ func MakeRequest[B, T any](p RequestParam[B], result T) error {
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return &StatusError{StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
 		return fmt.Errorf("failed to parse response: %w", err)
 	}
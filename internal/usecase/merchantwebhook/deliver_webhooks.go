@@ -0,0 +1,180 @@
+package merchantwebhook
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/omerbeden/paymentgateway/internal/domain/entity"
+	"github.com/omerbeden/paymentgateway/internal/domain/notification"
+	"github.com/omerbeden/paymentgateway/internal/domain/repository"
+	"github.com/omerbeden/paymentgateway/internal/infrastructure/config"
+	"github.com/omerbeden/paymentgateway/internal/infrastructure/logger"
+	"github.com/omerbeden/paymentgateway/internal/infrastructure/metrics"
+)
+
+const (
+	initialBackoff = 30 * time.Second
+	maxBackoff     = 6 * time.Hour
+)
+
+// Backoff returns the delay before the next attempt after the given number of
+// failed attempts: 30s doubling up to 6h, so a delivery is tried about 20
+// times over a 3 day retry window.
+func Backoff(failedAttempts int) time.Duration {
+	delay := initialBackoff
+	for i := 1; i < failedAttempts; i++ {
+		delay *= 2
+		if delay >= maxBackoff {
+			return maxBackoff
+		}
+	}
+	return delay
+}
+
+type DeliverWebhooksUseCase struct {
+	endpointRepo repository.WebhookEndpointRepository
+	deliveryRepo repository.WebhookDeliveryRepository
+	sender       notification.WebhookSender
+	cfg          config.MerchantWebhooks
+	log          logger.Logger
+	metrics      *metrics.Metrics
+}
+
+func NewDeliverWebhooksUseCase(endpointRepo repository.WebhookEndpointRepository,
+	deliveryRepo repository.WebhookDeliveryRepository,
+	sender notification.WebhookSender,
+	cfg config.MerchantWebhooks,
+	log logger.Logger,
+	metrics *metrics.Metrics) *DeliverWebhooksUseCase {
+	return &DeliverWebhooksUseCase{
+		endpointRepo: endpointRepo,
+		deliveryRepo: deliveryRepo,
+		sender:       sender,
+		cfg:          cfg,
+		log:          log,
+		metrics:      metrics,
+	}
+}
+
+// Execute sends one batch of due deliveries and returns how many it sent.
+func (uc *DeliverWebhooksUseCase) Execute(ctx context.Context) (int, error) {
+	// The lease outlives a send, so a delivery is only picked up again if this
+	// worker died before recording the attempt.
+	lease := uc.cfg.Timeout + time.Minute
+	deliveries, err := uc.deliveryRepo.ClaimDue(ctx, time.Now(), lease, uc.cfg.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	var wg sync.WaitGroup
+	for _, delivery := range deliveries {
+		wg.Add(1)
+		go func(delivery *entity.WebhookDelivery) {
+			defer wg.Done()
+			uc.deliver(ctx, delivery)
+		}(delivery)
+	}
+	wg.Wait()
+
+	return len(deliveries), nil
+}
+
+func (uc *DeliverWebhooksUseCase) deliver(ctx context.Context, delivery *entity.WebhookDelivery) {
+	log := uc.log.With("delivery_id", delivery.ID, "endpoint_id", delivery.EndpointID, "event_id", delivery.EventID)
+
+	endpoint, err := uc.endpointRepo.GetByID(ctx, delivery.EndpointID)
+	if err != nil {
+		log.Error("Failed to load webhook endpoint", "error", err)
+		return
+	}
+
+	attempt := uc.sender.Send(ctx, endpoint, delivery)
+	attempt.ID = uuid.New().String()
+	attempt.DeliveryID = delivery.ID
+	attempt.AttemptNumber = delivery.AttemptCount + 1
+	if err := uc.deliveryRepo.SaveAttempt(ctx, attempt); err != nil {
+		log.Error("Failed to log webhook delivery attempt", "error", err)
+	}
+
+	now := time.Now()
+	delivery.AttemptCount = attempt.AttemptNumber
+	delivery.UpdatedAt = now
+
+	status := "succeeded"
+	switch {
+	case attempt.Succeeded():
+		delivery.Status = entity.WebhookDeliveryStatusSucceeded
+		delivery.NextAttemptAt = time.Time{}
+		delivery.LastError = ""
+	case now.Add(Backoff(delivery.AttemptCount)).Before(delivery.CreatedAt.Add(uc.cfg.RetryWindow)):
+		status = "retrying"
+		delivery.NextAttemptAt = now.Add(Backoff(delivery.AttemptCount))
+		delivery.LastError = attempt.Error
+	default:
+		status = "failed"
+		delivery.Status = entity.WebhookDeliveryStatusFailed
+		delivery.NextAttemptAt = time.Time{}
+		delivery.LastError = fmt.Sprintf("gave up after %d attempts: %s", delivery.AttemptCount, attempt.Error)
+	}
+	uc.recordAttempt(delivery.EventType, status)
+
+	if err := uc.deliveryRepo.Update(ctx, delivery); err != nil {
+		log.Error("Failed to update webhook delivery", "error", err)
+	}
+
+	if delivery.Status == entity.WebhookDeliveryStatusPending {
+		log.Warn("Merchant webhook delivery failed, will retry",
+			"attempt", delivery.AttemptCount,
+			"next_attempt_at", delivery.NextAttemptAt,
+			"error", attempt.Error,
+		)
+	}
+
+	// Every attempt counts, so an endpoint that is down is disabled within
+	// the retry window rather than after it.
+	updated, err := uc.endpointRepo.RecordDeliveryResult(ctx, endpoint.ID, attempt.Succeeded(), uc.cfg.DisableAfter)
+	if err != nil {
+		log.Error("Failed to record webhook endpoint result", "error", err)
+		return
+	}
+	if endpoint.Status == entity.WebhookEndpointStatusEnabled && updated.Status == entity.WebhookEndpointStatusDisabled {
+		log.Warn("Disabled webhook endpoint after repeated failed deliveries",
+			"consecutive_failures", updated.ConsecutiveFailures,
+		)
+	}
+}
+
+func (uc *DeliverWebhooksUseCase) recordAttempt(eventType, status string) {
+	if uc.metrics == nil {
+		return
+	}
+	uc.metrics.MerchantWebhookDeliveries.WithLabelValues(eventType, status).Inc()
+}
+
+// Run delivers due webhooks every poll interval until ctx is cancelled.
+func (uc *DeliverWebhooksUseCase) Run(ctx context.Context) {
+	ticker := time.NewTicker(uc.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		// keep draining while full batches come back
+		for ctx.Err() == nil {
+			n, err := uc.Execute(ctx)
+			if err != nil {
+				uc.log.Error("Failed to deliver merchant webhooks", "error", err)
+			}
+			if err != nil || n < uc.cfg.BatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package merchantwebhook
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/omerbeden/paymentgateway/internal/domain/entity"
+	"github.com/omerbeden/paymentgateway/internal/domain/repository"
+	"github.com/omerbeden/paymentgateway/internal/infrastructure/logger"
+)
+
+// DispatchEventUseCase queues a payment event for every endpoint subscribed
+// to it. Sending happens in DeliverWebhooksUseCase.
+type DispatchEventUseCase struct {
+	endpointRepo repository.WebhookEndpointRepository
+	deliveryRepo repository.WebhookDeliveryRepository
+	log          logger.Logger
+}
+
+func NewDispatchEventUseCase(endpointRepo repository.WebhookEndpointRepository,
+	deliveryRepo repository.WebhookDeliveryRepository,
+	log logger.Logger) *DispatchEventUseCase {
+	return &DispatchEventUseCase{
+		endpointRepo: endpointRepo,
+		deliveryRepo: deliveryRepo,
+		log:          log,
+	}
+}
+
+// DispatchEventInput is a domain event as published to Kafka; Data is its JSON
+// encoding and becomes the data field of the webhook body.
+type DispatchEventInput struct {
+	EventType  string
+	OccurredAt time.Time
+	Data       json.RawMessage
+}
+
+// envelope is the body merchants receive.
+type envelope struct {
+	ID        string          `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+func (uc *DispatchEventUseCase) Execute(ctx context.Context, input DispatchEventInput) error {
+	if !isSupported(input.EventType) {
+		return nil
+	}
+
+	endpoints, err := uc.endpointRepo.ListSubscribed(ctx, input.EventType)
+	if err != nil {
+		return err
+	}
+	if len(endpoints) == 0 {
+		return nil
+	}
+
+	// The ID is derived from the event so a redelivered Kafka message maps to
+	// the same deliveries and merchants can deduplicate on it.
+	sum := sha256.Sum256(input.Data)
+	eventID := "evt_" + hex.EncodeToString(sum[:16])
+
+	payload, err := json.Marshal(envelope{
+		ID:        eventID,
+		Type:      input.EventType,
+		CreatedAt: input.OccurredAt,
+		Data:      input.Data,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook payload: %w", err)
+	}
+
+	now := time.Now()
+	deliveries := make([]*entity.WebhookDelivery, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		deliveries = append(deliveries, &entity.WebhookDelivery{
+			ID:            uuid.New().String(),
+			EndpointID:    endpoint.ID,
+			EventID:       eventID,
+			EventType:     input.EventType,
+			Payload:       string(payload),
+			Status:        entity.WebhookDeliveryStatusPending,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		})
+	}
+
+	if err := uc.deliveryRepo.CreateMany(ctx, deliveries); err != nil {
+		return err
+	}
+
+	uc.log.Info("Queued merchant webhooks",
+		"event_id", eventID,
+		"event_type", input.EventType,
+		"endpoints", len(deliveries),
+	)
+	return nil
+}
@@ -0,0 +1,55 @@
+package merchantwebhook
+
+import (
+	"context"
+
+	"github.com/omerbeden/paymentgateway/internal/domain/entity"
+	"github.com/omerbeden/paymentgateway/internal/domain/repository"
+)
+
+const (
+	defaultDeliveryLimit = 20
+	maxDeliveryLimit     = 100
+)
+
+// ListEndpointsUseCase serves the read side of the endpoint admin API:
+// endpoints, their recent deliveries and the attempts made for a delivery.
+type ListEndpointsUseCase struct {
+	endpointRepo repository.WebhookEndpointRepository
+	deliveryRepo repository.WebhookDeliveryRepository
+}
+
+func NewListEndpointsUseCase(endpointRepo repository.WebhookEndpointRepository, deliveryRepo repository.WebhookDeliveryRepository) *ListEndpointsUseCase {
+	return &ListEndpointsUseCase{
+		endpointRepo: endpointRepo,
+		deliveryRepo: deliveryRepo,
+	}
+}
+
+func (uc *ListEndpointsUseCase) List(ctx context.Context) ([]*entity.WebhookEndpoint, error) {
+	return uc.endpointRepo.List(ctx)
+}
+
+func (uc *ListEndpointsUseCase) Get(ctx context.Context, endpointID string) (*entity.WebhookEndpoint, error) {
+	return uc.endpointRepo.GetByID(ctx, endpointID)
+}
+
+func (uc *ListEndpointsUseCase) ListDeliveries(ctx context.Context, endpointID string, limit int) ([]*entity.WebhookDelivery, error) {
+	if _, err := uc.endpointRepo.GetByID(ctx, endpointID); err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = defaultDeliveryLimit
+	}
+	if limit > maxDeliveryLimit {
+		limit = maxDeliveryLimit
+	}
+	return uc.deliveryRepo.ListByEndpoint(ctx, endpointID, limit)
+}
+
+func (uc *ListEndpointsUseCase) ListAttempts(ctx context.Context, deliveryID string) ([]*entity.WebhookDeliveryAttempt, error) {
+	if _, err := uc.deliveryRepo.GetByID(ctx, deliveryID); err != nil {
+		return nil, err
+	}
+	return uc.deliveryRepo.ListAttempts(ctx, deliveryID)
+}
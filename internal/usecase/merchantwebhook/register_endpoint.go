@@ -0,0 +1,122 @@
+package merchantwebhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/omerbeden/paymentgateway/internal/domain/entity"
+	"github.com/omerbeden/paymentgateway/internal/domain/event"
+	"github.com/omerbeden/paymentgateway/internal/domain/repository"
+	"github.com/omerbeden/paymentgateway/internal/infrastructure/logger"
+)
+
+// SupportedEventTypes are the payment events merchants can subscribe to.
+var SupportedEventTypes = []event.EventType{
+	event.PaymentCreated,
+	event.PaymentAuthorized,
+	event.PaymentCaptured,
+	event.PaymentCompleted,
+	event.PaymentFailed,
+	event.PaymentCancelled,
+	event.PaymentRefunded,
+	event.PaymentExpired,
+}
+
+var (
+	ErrInvalidEndpointURL   = errors.New("endpoint URL must be an absolute http or https URL")
+	ErrNoEventTypes         = errors.New("at least one event type is required")
+	ErrUnsupportedEventType = errors.New("unsupported event type")
+)
+
+type RegisterEndpointUseCase struct {
+	endpointRepo repository.WebhookEndpointRepository
+	log          logger.Logger
+}
+
+func NewRegisterEndpointUseCase(endpointRepo repository.WebhookEndpointRepository, log logger.Logger) *RegisterEndpointUseCase {
+	return &RegisterEndpointUseCase{
+		endpointRepo: endpointRepo,
+		log:          log,
+	}
+}
+
+type RegisterEndpointInput struct {
+	URL         string
+	Description string
+	EventTypes  []string
+}
+
+// Execute registers the endpoint with a new signing secret. The secret is only
+// returned here; merchants use it to verify the signature header.
+func (uc *RegisterEndpointUseCase) Execute(ctx context.Context, input RegisterEndpointInput) (*entity.WebhookEndpoint, error) {
+	if err := validateEndpoint(input.URL, input.EventTypes); err != nil {
+		return nil, err
+	}
+
+	secret, err := newSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	endpoint := &entity.WebhookEndpoint{
+		ID:          uuid.New().String(),
+		URL:         input.URL,
+		Description: input.Description,
+		EventTypes:  input.EventTypes,
+		Secret:      secret,
+		Status:      entity.WebhookEndpointStatusEnabled,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := uc.endpointRepo.Create(ctx, endpoint); err != nil {
+		uc.log.Error("Failed to register webhook endpoint", "error", err)
+		return nil, err
+	}
+
+	uc.log.Info("Webhook endpoint registered",
+		"endpoint_id", endpoint.ID,
+		"event_types", endpoint.EventTypes,
+	)
+	return endpoint, nil
+}
+
+func validateEndpoint(rawURL string, eventTypes []string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrInvalidEndpointURL
+	}
+
+	if len(eventTypes) == 0 {
+		return ErrNoEventTypes
+	}
+	for _, t := range eventTypes {
+		if !isSupported(t) {
+			return fmt.Errorf("%w: %s", ErrUnsupportedEventType, t)
+		}
+	}
+	return nil
+}
+
+func isSupported(eventType string) bool {
+	for _, t := range SupportedEventTypes {
+		if string(t) == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+func newSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
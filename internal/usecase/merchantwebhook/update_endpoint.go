@@ -0,0 +1,76 @@
+package merchantwebhook
+
+import (
+	"context"
+	"time"
+
+	"github.com/omerbeden/paymentgateway/internal/domain/entity"
+	"github.com/omerbeden/paymentgateway/internal/domain/repository"
+	"github.com/omerbeden/paymentgateway/internal/infrastructure/logger"
+)
+
+type UpdateEndpointUseCase struct {
+	endpointRepo repository.WebhookEndpointRepository
+	log          logger.Logger
+}
+
+func NewUpdateEndpointUseCase(endpointRepo repository.WebhookEndpointRepository, log logger.Logger) *UpdateEndpointUseCase {
+	return &UpdateEndpointUseCase{
+		endpointRepo: endpointRepo,
+		log:          log,
+	}
+}
+
+// UpdateEndpointInput changes the fields that are set.
+type UpdateEndpointInput struct {
+	EndpointID  string
+	URL         *string
+	Description *string
+	EventTypes  []string
+	Enabled     *bool
+}
+
+// Execute applies the changes. Re-enabling an endpoint clears its failure
+// count, and its pending deliveries are picked up again.
+func (uc *UpdateEndpointUseCase) Execute(ctx context.Context, input UpdateEndpointInput) (*entity.WebhookEndpoint, error) {
+	endpoint, err := uc.endpointRepo.GetByID(ctx, input.EndpointID)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.URL != nil {
+		endpoint.URL = *input.URL
+	}
+	if input.Description != nil {
+		endpoint.Description = *input.Description
+	}
+	if input.EventTypes != nil {
+		endpoint.EventTypes = input.EventTypes
+	}
+	if err := validateEndpoint(endpoint.URL, endpoint.EventTypes); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if input.Enabled != nil {
+		switch {
+		case *input.Enabled && endpoint.Status != entity.WebhookEndpointStatusEnabled:
+			endpoint.Status = entity.WebhookEndpointStatusEnabled
+			endpoint.ConsecutiveFailures = 0
+			endpoint.DisabledAt = time.Time{}
+		case !*input.Enabled && endpoint.Status != entity.WebhookEndpointStatusDisabled:
+			endpoint.Status = entity.WebhookEndpointStatusDisabled
+			endpoint.DisabledAt = now
+		}
+	}
+	endpoint.UpdatedAt = now
+
+	if err := uc.endpointRepo.Update(ctx, endpoint); err != nil {
+		uc.log.Error("Failed to update webhook endpoint",
+			"error", err,
+			"endpoint_id", endpoint.ID,
+		)
+		return nil, err
+	}
+	return endpoint, nil
+}
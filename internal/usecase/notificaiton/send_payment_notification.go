@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/omerbeden/paymentgateway/internal/domain/money"
+
 	dnotification "github.com/omerbeden/paymentgateway/internal/domain/notification"
 )
 
@@ -13,8 +15,7 @@ type SendPaymentNotificationInput struct {
 	CustomerID    string
 	CustomerEmail string
 	CustomerPhone string
-	Amount        float64
-	Currency      string
+	Amount        money.Money
 	Provider      string
 	CompletedAt   time.Time
 }
@@ -37,7 +38,6 @@ func (uc *SendPaymentNotificationUseCase) Execute(ctx context.Context, input Sen
 		CustomerPhone:  input.CustomerPhone,
 		PaymentID:      input.PaymentID,
 		Amount:         input.Amount,
-		Currency:       input.Currency,
 		Provider:       input.Provider,
 		CompletedAt:    input.CompletedAt,
 		Channel:        dnotification.ChannelEmail, // for now, we only support email notifications. In the future, we can add more channels and determine the channel based on user preferences or other factors.
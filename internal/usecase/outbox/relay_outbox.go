@@ -0,0 +1,168 @@
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"github.com/omerbeden/paymentgateway/internal/domain/entity"
+	"github.com/omerbeden/paymentgateway/internal/domain/event"
+	"github.com/omerbeden/paymentgateway/internal/domain/repository"
+	"github.com/omerbeden/paymentgateway/internal/infrastructure/config"
+	"github.com/omerbeden/paymentgateway/internal/infrastructure/logger"
+	"github.com/omerbeden/paymentgateway/internal/infrastructure/metrics"
+)
+
+// purgeInterval is how often published messages past their retention are
+// deleted.
+const purgeInterval = time.Hour
+
+// RelayOutboxUseCase publishes outbox messages in the order they were written.
+// A message is marked published only after the publisher accepted it, so it
+// may be published more than once but is never lost. When a message fails,
+// the later messages of the same aggregate wait for it, which keeps each
+// aggregate's events in order. It must run on a single replica; see
+// database.Leader.
+type RelayOutboxUseCase struct {
+	outboxRepo repository.OutboxRepository
+	publisher  event.Publisher
+	// projection, when set, also receives every published event. It is a
+	// read model only: failing to project a message does not hold it back.
+	projection event.Store
+	cfg        config.Outbox
+	log        logger.Logger
+	metrics    *metrics.Metrics
+	nextPurge  time.Time
+}
+
+func NewRelayOutboxUseCase(
+	outboxRepo repository.OutboxRepository,
+	publisher event.Publisher,
+	projection event.Store,
+	cfg config.Outbox,
+	log logger.Logger,
+	metrics *metrics.Metrics,
+) *RelayOutboxUseCase {
+	return &RelayOutboxUseCase{
+		outboxRepo: outboxRepo,
+		publisher:  publisher,
+		projection: projection,
+		cfg:        cfg,
+		log:        log,
+		metrics:    metrics,
+	}
+}
+
+// Execute publishes one batch of messages and returns how many it published.
+func (uc *RelayOutboxUseCase) Execute(ctx context.Context) (int, error) {
+	messages, err := uc.outboxRepo.ListUnpublished(ctx, uc.cfg.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	published := 0
+	blocked := make(map[string]bool)
+	for _, msg := range messages {
+		if blocked[msg.AggregateID] {
+			continue
+		}
+		if !uc.relay(ctx, msg) {
+			blocked[msg.AggregateID] = true
+			continue
+		}
+		published++
+	}
+	return published, nil
+}
+
+// relay publishes msg and reports whether it is done with it.
+func (uc *RelayOutboxUseCase) relay(ctx context.Context, msg *entity.OutboxMessage) bool {
+	log := uc.log.With("outbox_id", msg.ID, "aggregate_id", msg.AggregateID, "event_type", msg.EventType)
+	evt := outboxEvent{msg: msg}
+
+	if err := uc.publisher.Publish(ctx, msg.Topic, evt); err != nil {
+		uc.recordPublish(msg.Topic, "failed")
+		log.Error("Failed to publish outbox message", "error", err, "attempts", msg.Attempts+1)
+		if err := uc.outboxRepo.MarkFailed(ctx, msg.ID, err.Error()); err != nil {
+			log.Error("Failed to record outbox publish failure", "error", err)
+		}
+		return false
+	}
+	uc.recordPublish(msg.Topic, "published")
+
+	if uc.projection != nil {
+		if err := uc.projection.Append(ctx, msg.AggregateID, event.AnyVersion, evt); err != nil {
+			log.Error("Failed to project outbox message to the event store", "error", err)
+		}
+	}
+
+	// Left unmarked, the message is published again with the rest of its
+	// aggregate on the next run.
+	if err := uc.outboxRepo.MarkPublished(ctx, msg.ID, time.Now()); err != nil {
+		log.Error("Failed to mark outbox message published", "error", err)
+		return false
+	}
+	return true
+}
+
+func (uc *RelayOutboxUseCase) recordPublish(topic, outcome string) {
+	if uc.metrics == nil {
+		return
+	}
+	uc.metrics.OutboxMessagesPublished.WithLabelValues(topic, outcome).Inc()
+}
+
+// purge deletes published messages older than the retention, at most once per
+// purgeInterval.
+func (uc *RelayOutboxUseCase) purge(ctx context.Context) {
+	now := time.Now()
+	if uc.cfg.Retention <= 0 || now.Before(uc.nextPurge) {
+		return
+	}
+	uc.nextPurge = now.Add(purgeInterval)
+
+	deleted, err := uc.outboxRepo.DeletePublished(ctx, now.Add(-uc.cfg.Retention))
+	if err != nil {
+		uc.log.Error("Failed to delete published outbox messages", "error", err)
+		return
+	}
+	if deleted > 0 {
+		uc.log.Info("Deleted published outbox messages", "count", deleted)
+	}
+}
+
+// Run relays outbox messages every poll interval until ctx is cancelled.
+func (uc *RelayOutboxUseCase) Run(ctx context.Context) {
+	ticker := time.NewTicker(uc.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		// keep draining while full batches come back
+		for ctx.Err() == nil {
+			n, err := uc.Execute(ctx)
+			if err != nil {
+				uc.log.Error("Failed to relay outbox messages", "error", err)
+			}
+			if err != nil || n < uc.cfg.BatchSize {
+				break
+			}
+		}
+		uc.purge(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// outboxEvent publishes a stored message exactly as it was written, without
+// decoding it back into its event type.
+type outboxEvent struct {
+	msg *entity.OutboxMessage
+}
+
+func (e outboxEvent) EventType() event.EventType   { return event.EventType(e.msg.EventType) }
+func (e outboxEvent) AggregateID() string          { return e.msg.AggregateID }
+func (e outboxEvent) OccurredAt() time.Time        { return e.msg.OccurredAt }
+func (e outboxEvent) MarshalJSON() ([]byte, error) { return []byte(e.msg.Payload), nil }
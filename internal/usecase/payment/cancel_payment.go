@@ -0,0 +1,129 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/omerbeden/paymentgateway/internal/adapter/provider"
+	"github.com/omerbeden/paymentgateway/internal/domain/entity"
+	"github.com/omerbeden/paymentgateway/internal/domain/event"
+	"github.com/omerbeden/paymentgateway/internal/domain/repository"
+	"github.com/omerbeden/paymentgateway/internal/infrastructure/logger"
+	"github.com/omerbeden/paymentgateway/internal/infrastructure/metrics"
+)
+
+var (
+	ErrPaymentAlreadyCaptured = errors.New("payment is already captured")
+	ErrPaymentNotCancellable  = errors.New("payment cannot be cancelled")
+)
+
+// CancelPaymentUseCase cancels a payment the buyer has not approved yet. The
+// provider is asked to cancel it too when it supports that; otherwise the
+// provider side is left to expire.
+type CancelPaymentUseCase struct {
+	paymentRepo     repository.PaymentRepository
+	transactionRepo repository.TransactionRepository
+	providerFactory *provider.Factory
+	log             logger.Logger
+	metrics         *metrics.Metrics
+}
+
+func NewCancelPaymentUseCase(
+	paymentRepo repository.PaymentRepository,
+	transactionRepo repository.TransactionRepository,
+	providerFactory *provider.Factory,
+	log logger.Logger,
+	metrics *metrics.Metrics,
+) *CancelPaymentUseCase {
+	return &CancelPaymentUseCase{
+		paymentRepo:     paymentRepo,
+		transactionRepo: transactionRepo,
+		providerFactory: providerFactory,
+		log:             log,
+		metrics:         metrics,
+	}
+}
+
+type CancelPaymentInput struct {
+	PaymentID string
+	Reason    string
+}
+
+func (uc *CancelPaymentUseCase) Execute(ctx context.Context, input CancelPaymentInput) (*entity.Payment, error) {
+	log := uc.log.With("request_id", getRequestID(ctx))
+
+	payment, err := uc.paymentRepo.GetByID(ctx, input.PaymentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payment: %w", err)
+	}
+
+	// A processing payment was already approved by the buyer, e.g. an approved
+	// PayPal order, and may still be captured at the provider.
+	switch payment.Status {
+	case entity.PaymentStatusPending:
+	case entity.PaymentStatusSucceeded, entity.PaymentStatusPartialRefund, entity.PaymentStatusRefunded:
+		return nil, ErrPaymentAlreadyCaptured
+	default:
+		// Authorized payments are released with a void.
+		return nil, ErrPaymentNotCancellable
+	}
+
+	providerAdapter, err := uc.providerFactory.GetProvider(payment.ProviderID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid provider: %w", err)
+	}
+
+	log.Info("Cancelling payment",
+		"payment_id", payment.ID,
+		"provider", payment.ProviderID,
+	)
+
+	if canceller, ok := providerAdapter.(provider.Canceller); ok && payment.ProviderPaymentID != "" {
+		result, err := canceller.Cancel(ctx, provider.CancelRequest{
+			ProviderPaymentID: payment.ProviderPaymentID,
+			IdempotencyKey:    "cancel-" + payment.ID,
+		})
+		if err != nil {
+			saveTransaction(ctx, uc.transactionRepo, log, newTransaction(payment,
+				entity.TransactionTypeCancel, entity.TransactionStatusFailed, "", provider.ExchangeFromError(err)))
+			log.Error("Failed to cancel payment, provider error",
+				"error", err,
+				"payment_id", payment.ID,
+				"provider", payment.ProviderID,
+			)
+			return nil, fmt.Errorf("provider failed to cancel payment: %w", err)
+		}
+
+		saveTransaction(ctx, uc.transactionRepo, log, newTransaction(payment,
+			entity.TransactionTypeCancel, entity.TransactionStatusSucceeded, result.ProviderCancelID, result.Exchange))
+	}
+
+	if err := payment.TransitionTo(entity.PaymentStatusCancelled); err != nil {
+		recordRejectedTransition(uc.metrics, err)
+		return nil, err
+	}
+	payment.UpdatedAt = time.Now()
+
+	cancelled := event.NewPaymentCancelledEvent(payment.ID, payment.ProviderID, input.Reason, payment.Amount)
+	if err := uc.paymentRepo.UpdatePayment(ctx, payment, cancelled); err != nil {
+		log.Error("Failed to update payment after cancel",
+			"error", err,
+			"payment_id", payment.ID,
+		)
+		return nil, fmt.Errorf("failed to update payment: %w", err)
+	}
+
+	uc.metrics.PaymentsTotal.WithLabelValues(
+		string(payment.Status),
+		payment.Amount.Currency,
+		payment.ProviderID,
+	).Inc()
+
+	log.Info("Payment cancelled",
+		"payment_id", payment.ID,
+	)
+
+	return payment, nil
+}
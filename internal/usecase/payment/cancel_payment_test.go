@@ -0,0 +1,87 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/omerbeden/paymentgateway/internal/domain/entity"
+	"github.com/omerbeden/paymentgateway/internal/domain/event"
+	"github.com/omerbeden/paymentgateway/internal/infrastructure/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newCancelUseCase(p *fakeProvider, payment *entity.Payment) (*CancelPaymentUseCase, *fakePaymentRepository, *fakeTransactionRepository) {
+	payments := &fakePaymentRepository{payment: payment}
+	transactions := &fakeTransactionRepository{}
+	uc := NewCancelPaymentUseCase(payments, transactions, newFakeProviderFactory(p), logger.NewNoOp(), testMetrics)
+	return uc, payments, transactions
+}
+
+func TestCancelPayment_CancelsWithThePaymentKey(t *testing.T) {
+	// Arrange
+	p := &fakeProvider{}
+	uc, payments, transactions := newCancelUseCase(p, newTestPayment(entity.PaymentStatusPending, entity.PaymentIntentCapture))
+
+	// Act
+	out, err := uc.Execute(context.Background(), CancelPaymentInput{PaymentID: "pay_1", Reason: "requested_by_customer"})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, entity.PaymentStatusCancelled, out.Status)
+	require.Len(t, p.cancels, 1)
+	assert.Equal(t, "cancel-pay_1", p.cancels[0].IdempotencyKey)
+	assert.Equal(t, "pi_1", p.cancels[0].ProviderPaymentID)
+	require.Equal(t, []event.EventType{event.PaymentCancelled}, payments.eventTypes())
+	assert.Equal(t, "requested_by_customer", payments.events[0].(event.PaymentCancelledEvent).Reason)
+	require.Len(t, transactions.txns, 1)
+	assert.Equal(t, entity.TransactionTypeCancel, transactions.txns[0].Type)
+	assert.Equal(t, "cancel_pi_1", transactions.txns[0].ProviderTxnID)
+}
+
+func TestCancelPayment_RejectsPaymentsThatAreNotPending(t *testing.T) {
+	tests := []struct {
+		status  entity.PaymentStatus
+		wantErr error
+	}{
+		{entity.PaymentStatusAuthorized, ErrPaymentNotCancellable},
+		{entity.PaymentStatusProcessing, ErrPaymentNotCancellable},
+		{entity.PaymentStatusCancelled, ErrPaymentNotCancellable},
+		{entity.PaymentStatusSucceeded, ErrPaymentAlreadyCaptured},
+		{entity.PaymentStatusPartialRefund, ErrPaymentAlreadyCaptured},
+		{entity.PaymentStatusRefunded, ErrPaymentAlreadyCaptured},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.status), func(t *testing.T) {
+			// Arrange
+			p := &fakeProvider{}
+			uc, payments, _ := newCancelUseCase(p, newTestPayment(tt.status, entity.PaymentIntentAuthorize))
+
+			// Act
+			_, err := uc.Execute(context.Background(), CancelPaymentInput{PaymentID: "pay_1"})
+
+			// Assert
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Empty(t, p.cancels)
+			assert.Empty(t, payments.events)
+		})
+	}
+}
+
+func TestCancelPayment_ProviderFailureKeepsThePaymentPending(t *testing.T) {
+	// Arrange
+	p := &fakeProvider{err: errors.New("intent is processing")}
+	uc, payments, transactions := newCancelUseCase(p, newTestPayment(entity.PaymentStatusPending, entity.PaymentIntentCapture))
+
+	// Act
+	_, err := uc.Execute(context.Background(), CancelPaymentInput{PaymentID: "pay_1"})
+
+	// Assert
+	assert.Error(t, err)
+	assert.Equal(t, entity.PaymentStatusPending, payments.payment.Status)
+	assert.Empty(t, payments.events)
+	require.Len(t, transactions.txns, 1)
+	assert.Equal(t, entity.TransactionStatusFailed, transactions.txns[0].Status)
+}
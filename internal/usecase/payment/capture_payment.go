@@ -0,0 +1,154 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/omerbeden/paymentgateway/internal/adapter/provider"
+	"github.com/omerbeden/paymentgateway/internal/domain/entity"
+	"github.com/omerbeden/paymentgateway/internal/domain/event"
+	"github.com/omerbeden/paymentgateway/internal/domain/money"
+	"github.com/omerbeden/paymentgateway/internal/domain/repository"
+	"github.com/omerbeden/paymentgateway/internal/infrastructure/logger"
+	"github.com/omerbeden/paymentgateway/internal/infrastructure/metrics"
+)
+
+var (
+	ErrPaymentNotCapturable        = errors.New("payment is not authorized")
+	ErrCaptureExceedsAuthorization = errors.New("capture amount exceeds the authorized amount")
+	ErrInvalidCaptureAmount        = errors.New("capture amount must be positive")
+)
+
+// CapturePaymentUseCase captures an authorized payment. A payment is captured
+// once; capturing less than was authorized releases the rest.
+type CapturePaymentUseCase struct {
+	paymentRepo     repository.PaymentRepository
+	transactionRepo repository.TransactionRepository
+	providerFactory *provider.Factory
+	log             logger.Logger
+	metrics         *metrics.Metrics
+}
+
+func NewCapturePaymentUseCase(
+	paymentRepo repository.PaymentRepository,
+	transactionRepo repository.TransactionRepository,
+	providerFactory *provider.Factory,
+	log logger.Logger,
+	metrics *metrics.Metrics,
+) *CapturePaymentUseCase {
+	return &CapturePaymentUseCase{
+		paymentRepo:     paymentRepo,
+		transactionRepo: transactionRepo,
+		providerFactory: providerFactory,
+		log:             log,
+		metrics:         metrics,
+	}
+}
+
+type CapturePaymentInput struct {
+	PaymentID string
+	// Amount to capture in minor units of the payment currency; zero captures
+	// the full authorized amount.
+	Amount int64
+}
+
+func (uc *CapturePaymentUseCase) Execute(ctx context.Context, input CapturePaymentInput) (*entity.Payment, error) {
+	log := uc.log.With("request_id", getRequestID(ctx))
+
+	if input.Amount < 0 {
+		return nil, ErrInvalidCaptureAmount
+	}
+
+	payment, err := uc.paymentRepo.GetByID(ctx, input.PaymentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payment: %w", err)
+	}
+
+	if payment.Status != entity.PaymentStatusAuthorized {
+		return nil, ErrPaymentNotCapturable
+	}
+
+	amount := payment.Amount
+	if input.Amount != 0 {
+		amount = money.New(input.Amount, payment.Amount.Currency)
+	}
+	if amount.Amount > payment.Amount.Amount {
+		return nil, ErrCaptureExceedsAuthorization
+	}
+
+	providerAdapter, err := uc.providerFactory.GetProvider(payment.ProviderID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid provider: %w", err)
+	}
+
+	log.Info("Capturing payment",
+		"payment_id", payment.ID,
+		"amount", amount.Decimal(),
+		"currency", amount.Currency,
+		"provider", payment.ProviderID,
+	)
+
+	req := provider.CaptureRequest{
+		ProviderPaymentID: payment.ProviderPaymentID,
+		// A payment is captured once, so retries of the capture share a key.
+		IdempotencyKey: "capture-" + payment.ID,
+	}
+	if amount.Amount < payment.Amount.Amount {
+		req.Amount = amount
+	}
+
+	result, err := providerAdapter.Capture(ctx, req)
+	if err != nil {
+		txn := newTransaction(payment, entity.TransactionTypeCapture, entity.TransactionStatusFailed, "", provider.ExchangeFromError(err))
+		txn.Amount = amount
+		saveTransaction(ctx, uc.transactionRepo, log, txn)
+		log.Error("Failed to capture payment, provider error",
+			"error", err,
+			"payment_id", payment.ID,
+			"provider", payment.ProviderID,
+		)
+		return nil, fmt.Errorf("provider failed to capture payment: %w", err)
+	}
+
+	if result.Amount.IsPositive() {
+		amount = result.Amount
+	}
+	txn := newTransaction(payment, entity.TransactionTypeCapture, entity.TransactionStatusSucceeded, result.ProviderCaptureID, result.Exchange)
+	txn.Amount = amount
+	saveTransaction(ctx, uc.transactionRepo, log, txn)
+
+	if err := payment.TransitionTo(result.Status); err != nil {
+		recordRejectedTransition(uc.metrics, err)
+		return nil, err
+	}
+	payment.CapturedAmount = amount
+	payment.CompletedAt = time.Now()
+	payment.UpdatedAt = time.Now()
+
+	if err := uc.paymentRepo.UpdatePayment(ctx, payment,
+		event.NewPaymentCapturedEvent(payment.ID, payment.ProviderID, amount),
+		event.NewPaymentCompletedEvent(payment.ID, payment.ProviderID, "", amount),
+	); err != nil {
+		log.Error("Failed to update payment after provider capture",
+			"error", err,
+			"payment_id", payment.ID,
+			"provider_capture_id", result.ProviderCaptureID,
+		)
+		return nil, fmt.Errorf("failed to update payment: %w", err)
+	}
+
+	uc.metrics.PaymentsTotal.WithLabelValues(
+		string(payment.Status),
+		payment.Amount.Currency,
+		payment.ProviderID,
+	).Inc()
+
+	log.Info("Payment captured",
+		"payment_id", payment.ID,
+		"captured_amount", payment.CapturedAmount.Decimal(),
+	)
+
+	return payment, nil
+}
@@ -0,0 +1,101 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/omerbeden/paymentgateway/internal/domain/entity"
+	"github.com/omerbeden/paymentgateway/internal/domain/event"
+	"github.com/omerbeden/paymentgateway/internal/domain/money"
+	"github.com/omerbeden/paymentgateway/internal/infrastructure/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newCaptureUseCase(p *fakeProvider, payment *entity.Payment) (*CapturePaymentUseCase, *fakePaymentRepository, *fakeTransactionRepository) {
+	payments := &fakePaymentRepository{payment: payment}
+	transactions := &fakeTransactionRepository{}
+	uc := NewCapturePaymentUseCase(payments, transactions, newFakeProviderFactory(p), logger.NewNoOp(), testMetrics)
+	return uc, payments, transactions
+}
+
+func TestCapturePayment_CapturesWithThePaymentKey(t *testing.T) {
+	// Arrange
+	p := &fakeProvider{}
+	uc, payments, transactions := newCaptureUseCase(p, newTestPayment(entity.PaymentStatusAuthorized, entity.PaymentIntentAuthorize))
+
+	// Act
+	out, err := uc.Execute(context.Background(), CapturePaymentInput{PaymentID: "pay_1"})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, entity.PaymentStatusSucceeded, out.Status)
+	assert.Equal(t, money.New(5000, "USD"), out.CapturedAmount)
+	require.Len(t, p.captures, 1)
+	assert.Equal(t, "capture-pay_1", p.captures[0].IdempotencyKey)
+	assert.Equal(t, "pi_1", p.captures[0].ProviderPaymentID)
+	assert.False(t, p.captures[0].Amount.IsPositive(), "a full capture sends no amount")
+	assert.Equal(t, []event.EventType{event.PaymentCaptured, event.PaymentCompleted}, payments.eventTypes())
+	require.Len(t, transactions.txns, 1)
+	assert.Equal(t, entity.TransactionTypeCapture, transactions.txns[0].Type)
+	assert.Equal(t, entity.TransactionStatusSucceeded, transactions.txns[0].Status)
+}
+
+func TestCapturePayment_PartialCaptureSendsTheAmount(t *testing.T) {
+	// Arrange
+	p := &fakeProvider{}
+	uc, payments, _ := newCaptureUseCase(p, newTestPayment(entity.PaymentStatusAuthorized, entity.PaymentIntentAuthorize))
+
+	// Act
+	out, err := uc.Execute(context.Background(), CapturePaymentInput{PaymentID: "pay_1", Amount: 3000})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, money.New(3000, "USD"), out.CapturedAmount)
+	require.Len(t, p.captures, 1)
+	assert.Equal(t, money.New(3000, "USD"), p.captures[0].Amount)
+	require.Len(t, payments.events, 2)
+	assert.Equal(t, money.New(3000, "USD"), payments.events[0].(event.PaymentCapturedEvent).Amount)
+}
+
+func TestCapturePayment_RejectsPaymentsThatAreNotAuthorized(t *testing.T) {
+	statuses := []entity.PaymentStatus{
+		entity.PaymentStatusPending,
+		entity.PaymentStatusProcessing,
+		entity.PaymentStatusSucceeded,
+		entity.PaymentStatusCancelled,
+	}
+
+	for _, status := range statuses {
+		t.Run(string(status), func(t *testing.T) {
+			// Arrange
+			p := &fakeProvider{}
+			uc, payments, _ := newCaptureUseCase(p, newTestPayment(status, entity.PaymentIntentAuthorize))
+
+			// Act
+			_, err := uc.Execute(context.Background(), CapturePaymentInput{PaymentID: "pay_1"})
+
+			// Assert
+			assert.ErrorIs(t, err, ErrPaymentNotCapturable)
+			assert.Empty(t, p.captures)
+			assert.Empty(t, payments.events)
+		})
+	}
+}
+
+func TestCapturePayment_ProviderFailureIsRecordedWithoutEvents(t *testing.T) {
+	// Arrange
+	p := &fakeProvider{err: errors.New("authorization expired")}
+	uc, payments, transactions := newCaptureUseCase(p, newTestPayment(entity.PaymentStatusAuthorized, entity.PaymentIntentAuthorize))
+
+	// Act
+	_, err := uc.Execute(context.Background(), CapturePaymentInput{PaymentID: "pay_1"})
+
+	// Assert
+	assert.Error(t, err)
+	assert.Empty(t, payments.events)
+	assert.Equal(t, entity.PaymentStatusAuthorized, payments.payment.Status)
+	require.Len(t, transactions.txns, 1)
+	assert.Equal(t, entity.TransactionStatusFailed, transactions.txns[0].Status)
+}
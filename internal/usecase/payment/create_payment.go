@@ -5,8 +5,12 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/omerbeden/paymentgateway/internal/adapter/provider"
+	"github.com/omerbeden/paymentgateway/internal/domain/currency"
 	"github.com/omerbeden/paymentgateway/internal/domain/entity"
+	"github.com/omerbeden/paymentgateway/internal/domain/event"
+	"github.com/omerbeden/paymentgateway/internal/domain/money"
 	"github.com/omerbeden/paymentgateway/internal/domain/repository"
 	"github.com/omerbeden/paymentgateway/internal/infrastructure/logger"
 	"github.com/omerbeden/paymentgateway/internal/infrastructure/metrics"
@@ -14,20 +18,30 @@ import (
 
 type CreatePaymentUseCase struct {
 	paymentRepo     repository.PaymentRepository
+	transactionRepo repository.TransactionRepository
 	providerFactory *provider.Factory
-	log             logger.Logger
-	metrics         *metrics.Metrics
+	currencies      *currency.Registry
+	// ttl is how long a payment waits for buyer approval; zero never expires.
+	ttl     time.Duration
+	log     logger.Logger
+	metrics *metrics.Metrics
 }
 
 func NewCreatePaymentUseCase(
 	paymentRepo repository.PaymentRepository,
+	transactionRepo repository.TransactionRepository,
 	providerFactory *provider.Factory,
+	currencies *currency.Registry,
+	ttl time.Duration,
 	log logger.Logger,
 	metrics *metrics.Metrics,
 ) *CreatePaymentUseCase {
 	return &CreatePaymentUseCase{
 		paymentRepo:     paymentRepo,
+		transactionRepo: transactionRepo,
 		providerFactory: providerFactory,
+		currencies:      currencies,
+		ttl:             ttl,
 		log:             log,
 		metrics:         metrics,
 	}
@@ -35,33 +49,57 @@ func NewCreatePaymentUseCase(
 
 type CreatePaymentInput struct {
 	IdempotencyKey string
-	Amount         float64
-	Currency       string
+	Amount         money.Money
 	ProviderID     string
-	Metadata       map[string]string
+	// Intent defaults to capture.
+	Intent   entity.PaymentIntent
+	Metadata map[string]string
 }
 
 func (uc *CreatePaymentUseCase) Execute(ctx context.Context, input CreatePaymentInput) (*entity.Payment, error) {
 	start := time.Now()
-	provider, err := uc.providerFactory.GetProvider(input.ProviderID)
-	if provider == nil {
+	providerAdapter, err := uc.providerFactory.GetProvider(input.ProviderID)
+	if providerAdapter == nil {
 		return nil, fmt.Errorf("invalid provider: %w", err)
 	}
 
 	requestID := getRequestID(ctx)
 	log := uc.log.With("request_id", requestID)
 
+	if err := uc.currencies.Validate(input.ProviderID, input.Amount); err != nil {
+		log.Warn("Rejected payment with unsupported amount",
+			"error", err,
+			"provider", input.ProviderID,
+		)
+		return nil, err
+	}
+
 	log.Info("Creating payment",
-		"amount", input.Amount,
-		"currency", input.Currency,
+		"amount", input.Amount.Decimal(),
+		"currency", input.Amount.Currency,
 		"provider", input.ProviderID,
 	)
+	intent := input.Intent
+	if intent == "" {
+		intent = entity.PaymentIntentCapture
+	}
+
+	now := time.Now()
 	payment := &entity.Payment{
-		Amount:     input.Amount,
-		Currency:   input.Currency,
-		Metadata:   input.Metadata,
-		Status:     entity.PaymentStatusPending,
-		ProviderID: input.ProviderID,
+		ID:             uuid.New().String(),
+		Amount:         input.Amount,
+		CapturedAmount: money.New(0, input.Amount.Currency),
+		RefundedAmount: money.New(0, input.Amount.Currency),
+		Intent:         intent,
+		IdempotencyKey: input.IdempotencyKey,
+		Metadata:       input.Metadata,
+		Status:         entity.PaymentStatusPending,
+		ProviderID:     input.ProviderID,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	if uc.ttl > 0 {
+		payment.ExpiresAt = now.Add(uc.ttl)
 	}
 	if err := uc.paymentRepo.CreatePayment(ctx, payment); err != nil {
 		log.Error("Failed to create payment while saving to database",
@@ -72,10 +110,18 @@ func (uc *CreatePaymentUseCase) Execute(ctx context.Context, input CreatePayment
 		return nil, fmt.Errorf("failed to create payment: %w", err)
 	}
 
-	result, err := provider.CreatePayment(ctx, payment)
+	result, err := providerAdapter.CreatePayment(ctx, payment)
 	if err != nil {
-		payment.Status = entity.PaymentStatusFailed
-		if err := uc.paymentRepo.UpdatePayment(ctx, payment); err != nil {
+		saveTransaction(ctx, uc.transactionRepo, log, newTransaction(payment,
+			entity.TransactionTypeCharge, entity.TransactionStatusFailed, "", provider.ExchangeFromError(err)))
+
+		if err := payment.TransitionTo(entity.PaymentStatusFailed); err != nil {
+			recordRejectedTransition(uc.metrics, err)
+			return nil, err
+		}
+		payment.UpdatedAt = time.Now()
+		failed := event.NewPaymentFailedEvent(payment.ID, payment.ProviderID, err.Error(), payment.Amount)
+		if err := uc.paymentRepo.UpdatePayment(ctx, payment, failed); err != nil {
 			log.Error("Failed to create payment while updating database",
 				"error", err,
 				"payment_id", payment.ID,
@@ -93,10 +139,39 @@ func (uc *CreatePaymentUseCase) Execute(ctx context.Context, input CreatePayment
 		return nil, fmt.Errorf("provider failed to create payment: %w", err)
 	}
 
-	payment.Status = result.Status
-	payment.Metadata = result.Metadata
+	saveTransaction(ctx, uc.transactionRepo, log, newTransaction(payment,
+		entity.TransactionTypeCharge, entity.TransactionStatusSucceeded, result.ProviderPaymentID, result.Exchange))
 
-	if err := uc.paymentRepo.UpdatePayment(ctx, payment); err != nil {
+	if err := payment.TransitionTo(result.Status); err != nil {
+		recordRejectedTransition(uc.metrics, err)
+		log.Error("Provider returned an unexpected payment status",
+			"error", err,
+			"payment_id", payment.ID,
+			"provider", input.ProviderID,
+		)
+		return nil, err
+	}
+	if payment.Metadata == nil {
+		payment.Metadata = make(map[string]string, len(result.Metadata))
+	}
+	for k, v := range result.Metadata {
+		payment.Metadata[k] = v
+	}
+	if payment.Status == entity.PaymentStatusSucceeded {
+		payment.CapturedAmount = result.Amount
+	}
+	payment.ProviderPaymentID = result.ProviderPaymentID
+	payment.PaymentURL = result.PaymentURL
+	payment.UpdatedAt = time.Now()
+
+	events := []event.DomainEvent{event.NewPaymentCreatedEvent(payment.ID, payment.ProviderID,
+		string(payment.Intent), string(payment.Status), payment.Amount)}
+	if payment.Status == entity.PaymentStatusSucceeded {
+		events = append(events,
+			event.NewPaymentCapturedEvent(payment.ID, payment.ProviderID, payment.CapturedAmount),
+			event.NewPaymentCompletedEvent(payment.ID, payment.ProviderID, "", payment.CapturedAmount))
+	}
+	if err := uc.paymentRepo.UpdatePayment(ctx, payment, events...); err != nil {
 		log.Error("Failed to create payment while updating database after provider call",
 			"error", err,
 			"payment_id", payment.ID,
@@ -127,14 +202,14 @@ func getRequestID(ctx context.Context) string {
 func (uc *CreatePaymentUseCase) recordPaymentMetrics(payment *entity.Payment, duration time.Duration) {
 	uc.metrics.PaymentsTotal.WithLabelValues(
 		string(payment.Status),
-		payment.Currency,
+		payment.Amount.Currency,
 		payment.ProviderID,
 	).Inc()
 
 	uc.metrics.PaymentAmount.WithLabelValues(
-		payment.Currency,
+		payment.Amount.Currency,
 		payment.ProviderID,
-	).Observe(payment.Amount)
+	).Observe(payment.Amount.Float64())
 
 	uc.metrics.PaymentDuration.WithLabelValues(
 		payment.ProviderID,
@@ -0,0 +1,151 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/omerbeden/paymentgateway/internal/adapter/provider"
+	"github.com/omerbeden/paymentgateway/internal/domain/entity"
+	"github.com/omerbeden/paymentgateway/internal/domain/event"
+	"github.com/omerbeden/paymentgateway/internal/domain/repository"
+	"github.com/omerbeden/paymentgateway/internal/infrastructure/config"
+	"github.com/omerbeden/paymentgateway/internal/infrastructure/logger"
+	"github.com/omerbeden/paymentgateway/internal/infrastructure/metrics"
+)
+
+// ExpirePaymentsUseCase expires pending payments the buyer did not approve
+// before their expires_at. It must run on a single replica; see
+// database.Leader.
+type ExpirePaymentsUseCase struct {
+	paymentRepo     repository.PaymentRepository
+	transactionRepo repository.TransactionRepository
+	providerFactory *provider.Factory
+	cfg             config.PaymentExpiration
+	log             logger.Logger
+	metrics         *metrics.Metrics
+}
+
+func NewExpirePaymentsUseCase(
+	paymentRepo repository.PaymentRepository,
+	transactionRepo repository.TransactionRepository,
+	providerFactory *provider.Factory,
+	cfg config.PaymentExpiration,
+	log logger.Logger,
+	metrics *metrics.Metrics,
+) *ExpirePaymentsUseCase {
+	return &ExpirePaymentsUseCase{
+		paymentRepo:     paymentRepo,
+		transactionRepo: transactionRepo,
+		providerFactory: providerFactory,
+		cfg:             cfg,
+		log:             log,
+		metrics:         metrics,
+	}
+}
+
+// Execute expires one batch of payments and returns how many it expired.
+func (uc *ExpirePaymentsUseCase) Execute(ctx context.Context) (int, error) {
+	now := time.Now()
+	payments, err := uc.paymentRepo.ListExpired(ctx, now, now.Add(-uc.cfg.RetryAfter), uc.cfg.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, payment := range payments {
+		if uc.expire(ctx, payment) {
+			expired++
+		}
+	}
+	return expired, nil
+}
+
+func (uc *ExpirePaymentsUseCase) expire(ctx context.Context, payment *entity.Payment) bool {
+	log := uc.log.With("payment_id", payment.ID, "provider", payment.ProviderID)
+
+	// Payments at providers without a cancel call are expired here and left
+	// to expire at the provider. A failed cancel may mean the buyer paid after
+	// all, e.g. a Stripe intent that is already processing or succeeded, so
+	// the payment is not expired; it is tried again after cfg.RetryAfter and
+	// the reconciler picks up whatever status the provider reports.
+	providerAdapter, err := uc.providerFactory.GetProvider(payment.ProviderID)
+	if err != nil {
+		log.Warn("Expiring payment of an unregistered provider", "error", err)
+	} else if canceller, ok := providerAdapter.(provider.Canceller); ok && payment.ProviderPaymentID != "" {
+		result, err := canceller.Cancel(ctx, provider.CancelRequest{
+			ProviderPaymentID: payment.ProviderPaymentID,
+			IdempotencyKey:    "expire-" + payment.ID,
+		})
+		if err != nil {
+			saveTransaction(ctx, uc.transactionRepo, log, newTransaction(payment,
+				entity.TransactionTypeCancel, entity.TransactionStatusFailed, "", provider.ExchangeFromError(err)))
+			log.Warn("Failed to cancel expired payment at the provider, not expiring it", "error", err)
+			if err := uc.paymentRepo.MarkExpireAttempted(ctx, payment.ID, time.Now()); err != nil {
+				log.Error("Failed to mark payment expire attempted", "error", err)
+			}
+			return false
+		}
+		saveTransaction(ctx, uc.transactionRepo, log, newTransaction(payment,
+			entity.TransactionTypeCancel, entity.TransactionStatusSucceeded, result.ProviderCancelID, result.Exchange))
+	}
+
+	if err := payment.TransitionTo(entity.PaymentStatusExpired); err != nil {
+		recordRejectedTransition(uc.metrics, err)
+		log.Error("Failed to expire payment", "error", err)
+		return false
+	}
+	payment.UpdatedAt = time.Now()
+
+	expired := event.NewPaymentExpiredEvent(payment.ID, payment.ProviderID, payment.Amount, payment.ExpiresAt)
+	if err := uc.paymentRepo.UpdatePayment(ctx, payment, expired); err != nil {
+		// A webhook may have moved the payment on since it was listed.
+		if errors.Is(err, entity.ErrInvalidTransition) {
+			log.Info("Payment changed while expiring it, skipped", "error", err)
+			return false
+		}
+		log.Error("Failed to update expired payment", "error", err)
+		return false
+	}
+
+	uc.recordExpired(payment)
+
+	log.Info("Payment expired", "expires_at", payment.ExpiresAt)
+	return true
+}
+
+func (uc *ExpirePaymentsUseCase) recordExpired(payment *entity.Payment) {
+	if uc.metrics == nil {
+		return
+	}
+	uc.metrics.PaymentsTotal.WithLabelValues(
+		string(payment.Status),
+		payment.Amount.Currency,
+		payment.ProviderID,
+	).Inc()
+}
+
+// Run expires due payments every poll interval until ctx is cancelled.
+func (uc *ExpirePaymentsUseCase) Run(ctx context.Context) {
+	ticker := time.NewTicker(uc.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		// keep draining while full batches come back
+		for ctx.Err() == nil {
+			n, err := uc.Execute(ctx)
+			if err != nil {
+				uc.log.Error("Failed to expire payments", "error", err)
+			}
+			if err != nil || n < uc.cfg.BatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package payment
+
+import (
+	"context"
+
+	"github.com/omerbeden/paymentgateway/internal/adapter/provider"
+	"github.com/omerbeden/paymentgateway/internal/domain/entity"
+	"github.com/omerbeden/paymentgateway/internal/infrastructure/metrics"
+)
+
+// metrics.New registers collectors globally, so the package shares one set.
+var testMetrics = metrics.New()
+
+// fakeProvider records the requests made to it. Every call fails with err when
+// it is set.
+type fakeProvider struct {
+	err error
+
+	captures []provider.CaptureRequest
+	voids    []provider.VoidRequest
+	cancels  []provider.CancelRequest
+	refunds  []provider.RefundRequest
+}
+
+func newFakeProviderFactory(p *fakeProvider) *provider.Factory {
+	factory := provider.NewProviderFactory()
+	factory.RegisterProvider("fake", p)
+	return factory
+}
+
+func (p *fakeProvider) CreatePayment(ctx context.Context, payment *entity.Payment) (*provider.CreatePaymentResult, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &provider.CreatePaymentResult{ProviderPaymentID: "fake_" + payment.ID, Status: entity.PaymentStatusPending}, nil
+}
+
+func (p *fakeProvider) Authorize(ctx context.Context, id string) (*provider.AuthorizeResult, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &provider.AuthorizeResult{ProviderAuthorizationID: "auth_" + id, Status: entity.PaymentStatusAuthorized}, nil
+}
+
+func (p *fakeProvider) Capture(ctx context.Context, req provider.CaptureRequest) (*provider.CaptureResult, error) {
+	p.captures = append(p.captures, req)
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &provider.CaptureResult{
+		ProviderCaptureID: "cap_" + req.ProviderPaymentID,
+		Status:            entity.PaymentStatusSucceeded,
+		Amount:            req.Amount,
+		Exchange:          provider.Exchange{Request: "capture", Response: "captured"},
+	}, nil
+}
+
+func (p *fakeProvider) Void(ctx context.Context, req provider.VoidRequest) (*provider.VoidResult, error) {
+	p.voids = append(p.voids, req)
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &provider.VoidResult{ProviderVoidID: "void_" + req.ProviderPaymentID}, nil
+}
+
+func (p *fakeProvider) Cancel(ctx context.Context, req provider.CancelRequest) (*provider.CancelResult, error) {
+	p.cancels = append(p.cancels, req)
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &provider.CancelResult{ProviderCancelID: "cancel_" + req.ProviderPaymentID}, nil
+}
+
+func (p *fakeProvider) VerifyWebhook(ctx context.Context, webhookCtx *provider.WebhookContext) error {
+	return p.err
+}
+
+func (p *fakeProvider) ParseWebhook(payload []byte) (*provider.WebhookEvent, error) {
+	return &provider.WebhookEvent{Ignored: true}, p.err
+}
+
+func (p *fakeProvider) Refund(ctx context.Context, req provider.RefundRequest) (*provider.RefundResult, error) {
+	p.refunds = append(p.refunds, req)
+	if p.err != nil {
+		return nil, &provider.Error{Operation: "refund", Exchange: provider.Exchange{Request: "refund", Response: "declined"}, Err: p.err}
+	}
+	return &provider.RefundResult{
+		ProviderRefundID: "re_" + req.ProviderPaymentID,
+		Status:           entity.TransactionStatusSucceeded,
+		Amount:           req.Amount,
+		Exchange:         provider.Exchange{Request: "refund", Response: "refunded"},
+	}, nil
+}
+
+func (p *fakeProvider) GetPaymentStatus(ctx context.Context, providerPaymentID string) (*provider.PaymentStatusResult, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &provider.PaymentStatusResult{ProviderPaymentID: providerPaymentID}, nil
+}
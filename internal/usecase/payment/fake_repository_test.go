@@ -0,0 +1,72 @@
+package payment
+
+import (
+	"context"
+
+	"github.com/omerbeden/paymentgateway/internal/domain/entity"
+	"github.com/omerbeden/paymentgateway/internal/domain/event"
+	"github.com/omerbeden/paymentgateway/internal/domain/money"
+	"github.com/omerbeden/paymentgateway/internal/domain/repository"
+)
+
+// fakePaymentRepository keeps one payment and the events saved with it.
+// Methods the use cases under test do not call are left to the embedded nil
+// interface and panic.
+type fakePaymentRepository struct {
+	repository.PaymentRepository
+
+	payment *entity.Payment
+	events  []event.DomainEvent
+}
+
+func (r *fakePaymentRepository) GetByID(ctx context.Context, id string) (*entity.Payment, error) {
+	if r.payment == nil || r.payment.ID != id {
+		return nil, repository.ErrPaymentNotFound
+	}
+	return r.payment, nil
+}
+
+func (r *fakePaymentRepository) UpdatePayment(ctx context.Context, payment *entity.Payment, events ...event.DomainEvent) error {
+	r.payment = payment
+	r.events = append(r.events, events...)
+	return nil
+}
+
+func (r *fakePaymentRepository) eventTypes() []event.EventType {
+	types := make([]event.EventType, len(r.events))
+	for i, e := range r.events {
+		types[i] = e.EventType()
+	}
+	return types
+}
+
+type fakeTransactionRepository struct {
+	txns []*entity.Transaction
+}
+
+func (r *fakeTransactionRepository) Save(ctx context.Context, txn *entity.Transaction) error {
+	r.txns = append(r.txns, txn)
+	return nil
+}
+
+func (r *fakeTransactionRepository) ListByPaymentID(ctx context.Context, paymentID string) ([]*entity.Transaction, error) {
+	var txns []*entity.Transaction
+	for _, txn := range r.txns {
+		if txn.PaymentID == paymentID {
+			txns = append(txns, txn)
+		}
+	}
+	return txns, nil
+}
+
+// newTestPayment returns a payment of 50.00 USD at the fake provider.
+func newTestPayment(status entity.PaymentStatus, intent entity.PaymentIntent) *entity.Payment {
+	return &entity.Payment{
+		ID:                "pay_1",
+		Amount:            money.New(5000, "USD"),
+		ProviderID:        "fake",
+		ProviderPaymentID: "pi_1",
+		Intent:            intent,
+		Status:            status,
+	}
+}
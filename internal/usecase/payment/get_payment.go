@@ -0,0 +1,39 @@
+package payment
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/omerbeden/paymentgateway/internal/domain/entity"
+	"github.com/omerbeden/paymentgateway/internal/domain/repository"
+	"github.com/omerbeden/paymentgateway/internal/infrastructure/logger"
+)
+
+type GetPaymentUseCase struct {
+	paymentRepo repository.PaymentRepository
+	log         logger.Logger
+}
+
+func NewGetPaymentUseCase(paymentRepo repository.PaymentRepository, log logger.Logger) *GetPaymentUseCase {
+	return &GetPaymentUseCase{
+		paymentRepo: paymentRepo,
+		log:         log,
+	}
+}
+
+type GetPaymentInput struct {
+	PaymentID string
+}
+
+func (uc *GetPaymentUseCase) Execute(ctx context.Context, input GetPaymentInput) (*entity.Payment, error) {
+	payment, err := uc.paymentRepo.GetByID(ctx, input.PaymentID)
+	if err != nil {
+		uc.log.With("request_id", getRequestID(ctx)).Error("Failed to get payment",
+			"error", err,
+			"payment_id", input.PaymentID,
+		)
+		return nil, fmt.Errorf("failed to get payment: %w", err)
+	}
+
+	return payment, nil
+}
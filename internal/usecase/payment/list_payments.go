@@ -0,0 +1,119 @@
+package payment
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/omerbeden/paymentgateway/internal/domain/entity"
+	"github.com/omerbeden/paymentgateway/internal/domain/repository"
+	"github.com/omerbeden/paymentgateway/internal/infrastructure/logger"
+)
+
+const (
+	defaultListLimit = 20
+	maxListLimit     = 100
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+type ListPaymentsUseCase struct {
+	paymentRepo repository.PaymentRepository
+	log         logger.Logger
+}
+
+func NewListPaymentsUseCase(paymentRepo repository.PaymentRepository, log logger.Logger) *ListPaymentsUseCase {
+	return &ListPaymentsUseCase{
+		paymentRepo: paymentRepo,
+		log:         log,
+	}
+}
+
+type ListPaymentsInput struct {
+	Status        entity.PaymentStatus
+	ProviderID    string
+	Currency      string
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	Metadata      map[string]string
+	Cursor        string
+	Limit         int
+}
+
+type ListPaymentsOutput struct {
+	Payments   []*entity.Payment
+	NextCursor string
+}
+
+func (uc *ListPaymentsUseCase) Execute(ctx context.Context, input ListPaymentsInput) (*ListPaymentsOutput, error) {
+	limit := input.Limit
+	if limit <= 0 {
+		limit = defaultListLimit
+	}
+	if limit > maxListLimit {
+		limit = maxListLimit
+	}
+
+	filter := repository.PaymentFilter{
+		Status:        input.Status,
+		ProviderID:    input.ProviderID,
+		Currency:      input.Currency,
+		CreatedAfter:  input.CreatedAfter,
+		CreatedBefore: input.CreatedBefore,
+		Metadata:      input.Metadata,
+		// fetch one extra row to find out whether another page exists
+		Limit: limit + 1,
+	}
+
+	if input.Cursor != "" {
+		cursor, err := decodeCursor(input.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		filter.After = cursor
+	}
+
+	payments, err := uc.paymentRepo.List(ctx, filter)
+	if err != nil {
+		uc.log.With("request_id", getRequestID(ctx)).Error("Failed to list payments",
+			"error", err,
+		)
+		return nil, fmt.Errorf("failed to list payments: %w", err)
+	}
+
+	output := &ListPaymentsOutput{Payments: payments}
+	if len(payments) > limit {
+		output.Payments = payments[:limit]
+		last := output.Payments[limit-1]
+		output.NextCursor = encodeCursor(repository.PaymentCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+
+	return output, nil
+}
+
+func encodeCursor(c repository.PaymentCursor) string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + c.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(s string) (*repository.PaymentCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	createdAt, id, found := strings.Cut(string(raw), "|")
+	if !found || id == "" {
+		return nil, ErrInvalidCursor
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &repository.PaymentCursor{CreatedAt: t, ID: id}, nil
+}
@@ -0,0 +1,201 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/omerbeden/paymentgateway/internal/adapter/provider"
+	"github.com/omerbeden/paymentgateway/internal/domain/entity"
+	"github.com/omerbeden/paymentgateway/internal/domain/event"
+	"github.com/omerbeden/paymentgateway/internal/domain/money"
+	"github.com/omerbeden/paymentgateway/internal/domain/repository"
+	"github.com/omerbeden/paymentgateway/internal/infrastructure/logger"
+	"github.com/omerbeden/paymentgateway/internal/infrastructure/metrics"
+)
+
+var (
+	ErrPaymentNotRefundable = errors.New("payment is not in a refundable state")
+	ErrRefundExceedsCapture = errors.New("refund amount exceeds the remaining captured amount")
+	ErrInvalidRefundAmount  = errors.New("refund amount must be positive")
+)
+
+type RefundPaymentUseCase struct {
+	paymentRepo     repository.PaymentRepository
+	transactionRepo repository.TransactionRepository
+	providerFactory *provider.Factory
+	log             logger.Logger
+	metrics         *metrics.Metrics
+}
+
+func NewRefundPaymentUseCase(
+	paymentRepo repository.PaymentRepository,
+	transactionRepo repository.TransactionRepository,
+	providerFactory *provider.Factory,
+	log logger.Logger,
+	metrics *metrics.Metrics,
+) *RefundPaymentUseCase {
+	return &RefundPaymentUseCase{
+		paymentRepo:     paymentRepo,
+		transactionRepo: transactionRepo,
+		providerFactory: providerFactory,
+		log:             log,
+		metrics:         metrics,
+	}
+}
+
+type RefundPaymentInput struct {
+	PaymentID string
+	// Amount to refund in minor units of the payment currency; zero refunds
+	// whatever has not been refunded yet.
+	Amount int64
+	Reason string
+}
+
+type RefundPaymentOutput struct {
+	Payment *entity.Payment
+	Refund  *provider.RefundResult
+}
+
+func (uc *RefundPaymentUseCase) Execute(ctx context.Context, input RefundPaymentInput) (*RefundPaymentOutput, error) {
+	log := uc.log.With("request_id", getRequestID(ctx))
+
+	if input.Amount < 0 {
+		return nil, ErrInvalidRefundAmount
+	}
+
+	// The payment stays locked until the refund is recorded, so concurrent
+	// refunds see each other's refunded amount and cannot together refund
+	// more than was captured.
+	var outcome refundOutcome
+	payment, err := uc.paymentRepo.UpdatePaymentLocked(ctx, input.PaymentID, func(payment *entity.Payment) (repository.PaymentUpdate, error) {
+		var err error
+		outcome, err = uc.refund(ctx, log, payment, input)
+		if err != nil {
+			return repository.PaymentUpdate{}, err
+		}
+		return repository.PaymentUpdate{
+			Events: []event.DomainEvent{event.NewPaymentRefundedEvent(payment.ID, payment.ProviderID, outcome.result.ProviderRefundID,
+				string(payment.Status), input.Reason, outcome.amount, payment.RefundedAmount)},
+			Transactions: []*entity.Transaction{outcome.txn},
+		}, nil
+	})
+	if err != nil {
+		// Nothing was saved, but the provider was called; record that now
+		// that the payment is unlocked.
+		if outcome.txn != nil {
+			saveTransaction(ctx, uc.transactionRepo, log, outcome.txn)
+		}
+		if outcome.result != nil {
+			log.Error("Failed to update payment after provider refund",
+				"error", err,
+				"payment_id", input.PaymentID,
+				"provider_refund_id", outcome.result.ProviderRefundID,
+			)
+			return nil, fmt.Errorf("failed to update payment: %w", err)
+		}
+		return nil, err
+	}
+
+	uc.metrics.PaymentsTotal.WithLabelValues(
+		string(payment.Status),
+		payment.Amount.Currency,
+		payment.ProviderID,
+	).Inc()
+
+	log.Info("Payment refunded",
+		"payment_id", payment.ID,
+		"status", payment.Status,
+		"refunded_amount", payment.RefundedAmount.Decimal(),
+	)
+
+	return &RefundPaymentOutput{Payment: payment, Refund: outcome.result}, nil
+}
+
+// refundOutcome is what refund did at the provider.
+type refundOutcome struct {
+	// result is set once the provider has refunded.
+	result *provider.RefundResult
+	amount money.Money
+	// txn records the provider call; it is nil when the provider was not
+	// called.
+	txn *entity.Transaction
+}
+
+// refund refunds the locked payment at its provider and moves it to the
+// refunded status. The provider call is returned to be saved by the caller:
+// the payment lock would block saving it here.
+func (uc *RefundPaymentUseCase) refund(ctx context.Context, log logger.Logger, payment *entity.Payment, input RefundPaymentInput) (refundOutcome, error) {
+	if payment.Status != entity.PaymentStatusSucceeded && payment.Status != entity.PaymentStatusPartialRefund {
+		return refundOutcome{}, ErrPaymentNotRefundable
+	}
+
+	remaining, err := payment.CapturedAmount.Sub(payment.RefundedAmount)
+	if err != nil {
+		return refundOutcome{}, fmt.Errorf("failed to compute refundable amount: %w", err)
+	}
+	amount := remaining
+	if input.Amount != 0 {
+		amount = money.New(input.Amount, payment.Amount.Currency)
+	}
+	if amount.Amount > remaining.Amount {
+		return refundOutcome{}, ErrRefundExceedsCapture
+	}
+
+	providerAdapter, err := uc.providerFactory.GetProvider(payment.ProviderID)
+	if err != nil {
+		return refundOutcome{}, fmt.Errorf("invalid provider: %w", err)
+	}
+
+	log.Info("Refunding payment",
+		"payment_id", payment.ID,
+		"amount", amount.Decimal(),
+		"currency", amount.Currency,
+		"provider", payment.ProviderID,
+	)
+
+	result, err := providerAdapter.Refund(ctx, provider.RefundRequest{
+		ProviderPaymentID: payment.ProviderPaymentID,
+		Amount:            amount,
+		Reason:            input.Reason,
+		// Refunds run one at a time under the payment lock, so the amount
+		// already refunded tells this refund apart from the earlier ones
+		// while a retry of it, before it was recorded, reuses the key.
+		IdempotencyKey: fmt.Sprintf("refund-%s-%d-%d", payment.ID, payment.RefundedAmount.Amount, amount.Amount),
+	})
+	if err != nil {
+		txn := newTransaction(payment, entity.TransactionTypeRefund, entity.TransactionStatusFailed, "", provider.ExchangeFromError(err))
+		txn.Amount = amount
+		log.Error("Failed to refund payment, provider error",
+			"error", err,
+			"payment_id", payment.ID,
+			"provider", payment.ProviderID,
+		)
+		return refundOutcome{txn: txn}, fmt.Errorf("provider failed to refund payment: %w", err)
+	}
+
+	txn := newTransaction(payment, entity.TransactionTypeRefund, result.Status, result.ProviderRefundID, result.Exchange)
+	txn.Amount = amount
+
+	if result.Status == entity.TransactionStatusFailed {
+		return refundOutcome{txn: txn}, fmt.Errorf("provider rejected refund %s", result.ProviderRefundID)
+	}
+
+	refunded, err := payment.RefundedAmount.Add(amount)
+	if err != nil {
+		return refundOutcome{result: result, txn: txn}, fmt.Errorf("failed to compute refunded amount: %w", err)
+	}
+	next := entity.PaymentStatusPartialRefund
+	if refunded.Amount >= payment.CapturedAmount.Amount {
+		next = entity.PaymentStatusRefunded
+	}
+	if err := payment.TransitionTo(next); err != nil {
+		recordRejectedTransition(uc.metrics, err)
+		return refundOutcome{result: result, txn: txn}, err
+	}
+	payment.RefundedAmount = refunded
+	payment.UpdatedAt = time.Now()
+
+	return refundOutcome{result: result, amount: amount, txn: txn}, nil
+}
@@ -0,0 +1,113 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/omerbeden/paymentgateway/internal/adapter/repository/postgres"
+	"github.com/omerbeden/paymentgateway/internal/domain/entity"
+	"github.com/omerbeden/paymentgateway/internal/domain/money"
+	"github.com/omerbeden/paymentgateway/internal/infrastructure/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var paymentColumns = []string{"id", "amount", "refunded_amount", "currency", "idempotency_key", "provider_id", "provider_payment_id",
+	"payment_url", "status", "created_at", "updated_at", "completed_at", "expires_at", "metadata", "last_provider_event_at", "intent",
+	"captured_amount"}
+
+// newLockedRefundUseCase runs refunds against the Postgres repositories, so the
+// statements the locked refund issues can be checked in order.
+func newLockedRefundUseCase(t *testing.T, p *fakeProvider) (*RefundPaymentUseCase, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	uc := NewRefundPaymentUseCase(postgres.NewPaymentRepository(db, nil), postgres.NewTransactionRepository(db, nil),
+		newFakeProviderFactory(p), logger.NewNoOp(), testMetrics)
+	return uc, mock
+}
+
+func expectLockedPayment(mock sqlmock.Sqlmock) {
+	now := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT (.+) FROM payments WHERE id=\$1 FOR UPDATE`).
+		WithArgs("pay_1").
+		WillReturnRows(sqlmock.NewRows(paymentColumns).
+			AddRow("pay_1", 5000, 1000, "USD", "idem_1", "fake", "pi_1", "", entity.PaymentStatusPartialRefund,
+				now, now, now, nil, `{}`, nil, entity.PaymentIntentCapture, 5000))
+}
+
+func TestRefundPayment_RecordsRefundInTheLockingTransaction(t *testing.T) {
+	// Arrange
+	p := &fakeProvider{}
+	uc, mock := newLockedRefundUseCase(t, p)
+
+	// The transaction row is written on the transaction holding the payment
+	// lock, before it commits; from another connection its foreign key check
+	// would wait for that lock.
+	expectLockedPayment(mock)
+	mock.ExpectExec(`UPDATE payments SET`).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO transactions`).
+		WithArgs(sqlmock.AnyArg(), "pay_1", int64(1500), "USD", entity.TransactionTypeRefund, entity.TransactionStatusSucceeded,
+			"fake", "re_pi_1", "refund", "refunded", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO outbox`).
+		WithArgs("pay_1", "payment.refunded", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	// Act
+	out, err := uc.Execute(context.Background(), RefundPaymentInput{PaymentID: "pay_1", Amount: 1500})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, entity.PaymentStatusPartialRefund, out.Payment.Status)
+	assert.Equal(t, money.New(2500, "USD"), out.Payment.RefundedAmount)
+	require.Len(t, p.refunds, 1)
+	assert.Equal(t, "refund-pay_1-1000-1500", p.refunds[0].IdempotencyKey)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRefundPayment_FailedRefundIsRecordedAfterTheLockIsReleased(t *testing.T) {
+	// Arrange
+	p := &fakeProvider{err: errors.New("card declined")}
+	uc, mock := newLockedRefundUseCase(t, p)
+
+	expectLockedPayment(mock)
+	mock.ExpectRollback()
+	mock.ExpectExec(`INSERT INTO transactions`).
+		WithArgs(sqlmock.AnyArg(), "pay_1", int64(4000), "USD", entity.TransactionTypeRefund, entity.TransactionStatusFailed,
+			"fake", "", "refund", "declined", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	// Act
+	out, err := uc.Execute(context.Background(), RefundPaymentInput{PaymentID: "pay_1"})
+
+	// Assert
+	assert.Error(t, err)
+	assert.Nil(t, out)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRefundPayment_RejectsRefundAboveTheRemainingCapture(t *testing.T) {
+	// Arrange
+	p := &fakeProvider{}
+	uc, mock := newLockedRefundUseCase(t, p)
+
+	expectLockedPayment(mock)
+	mock.ExpectRollback()
+
+	// Act
+	_, err := uc.Execute(context.Background(), RefundPaymentInput{PaymentID: "pay_1", Amount: 4001})
+
+	// Assert
+	assert.ErrorIs(t, err, ErrRefundExceedsCapture)
+	assert.Empty(t, p.refunds)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
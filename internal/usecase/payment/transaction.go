@@ -0,0 +1,54 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/omerbeden/paymentgateway/internal/adapter/provider"
+	"github.com/omerbeden/paymentgateway/internal/domain/entity"
+	"github.com/omerbeden/paymentgateway/internal/domain/repository"
+	"github.com/omerbeden/paymentgateway/internal/infrastructure/logger"
+	"github.com/omerbeden/paymentgateway/internal/infrastructure/metrics"
+)
+
+// newTransaction describes a single provider call made for payment.
+func newTransaction(
+	payment *entity.Payment,
+	txnType entity.TransactionType,
+	status entity.TransactionStatus,
+	providerTxnID string,
+	exchange provider.Exchange,
+) *entity.Transaction {
+	txn := entity.NewTransaction(uuid.New().String(), payment, txnType)
+	txn.Status = status
+	txn.ProviderTxnID = providerTxnID
+	txn.RequestPayload = exchange.Request
+	txn.ResponsePayload = exchange.Response
+	txn.ProcessedAt = time.Now()
+	return txn
+}
+
+// saveTransaction stores txn. A failure to store it is logged but does not fail
+// the payment operation itself, since the provider call has already happened.
+func saveTransaction(ctx context.Context, repo repository.TransactionRepository, log logger.Logger, txn *entity.Transaction) {
+	if err := repo.Save(ctx, txn); err != nil {
+		log.Error("Failed to save transaction",
+			"error", err,
+			"payment_id", txn.PaymentID,
+			"type", txn.Type,
+			"status", txn.Status,
+		)
+	}
+}
+
+// recordRejectedTransition counts a status change refused by the payment state
+// machine. Errors of any other kind are ignored.
+func recordRejectedTransition(m *metrics.Metrics, err error) {
+	var transitionErr *entity.InvalidTransitionError
+	if m == nil || !errors.As(err, &transitionErr) {
+		return
+	}
+	m.PaymentTransitionsRejected.WithLabelValues(string(transitionErr.From), string(transitionErr.To)).Inc()
+}
@@ -0,0 +1,116 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/omerbeden/paymentgateway/internal/adapter/provider"
+	"github.com/omerbeden/paymentgateway/internal/domain/entity"
+	"github.com/omerbeden/paymentgateway/internal/domain/event"
+	"github.com/omerbeden/paymentgateway/internal/domain/repository"
+	"github.com/omerbeden/paymentgateway/internal/infrastructure/logger"
+	"github.com/omerbeden/paymentgateway/internal/infrastructure/metrics"
+)
+
+var ErrPaymentNotVoidable = errors.New("payment is not authorized")
+
+// VoidPaymentUseCase releases the funds held for an authorized payment that
+// will not be captured. The payment ends up cancelled.
+type VoidPaymentUseCase struct {
+	paymentRepo     repository.PaymentRepository
+	transactionRepo repository.TransactionRepository
+	providerFactory *provider.Factory
+	log             logger.Logger
+	metrics         *metrics.Metrics
+}
+
+func NewVoidPaymentUseCase(
+	paymentRepo repository.PaymentRepository,
+	transactionRepo repository.TransactionRepository,
+	providerFactory *provider.Factory,
+	log logger.Logger,
+	metrics *metrics.Metrics,
+) *VoidPaymentUseCase {
+	return &VoidPaymentUseCase{
+		paymentRepo:     paymentRepo,
+		transactionRepo: transactionRepo,
+		providerFactory: providerFactory,
+		log:             log,
+		metrics:         metrics,
+	}
+}
+
+type VoidPaymentInput struct {
+	PaymentID string
+}
+
+func (uc *VoidPaymentUseCase) Execute(ctx context.Context, input VoidPaymentInput) (*entity.Payment, error) {
+	log := uc.log.With("request_id", getRequestID(ctx))
+
+	payment, err := uc.paymentRepo.GetByID(ctx, input.PaymentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payment: %w", err)
+	}
+
+	if payment.Status != entity.PaymentStatusAuthorized {
+		return nil, ErrPaymentNotVoidable
+	}
+
+	providerAdapter, err := uc.providerFactory.GetProvider(payment.ProviderID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid provider: %w", err)
+	}
+
+	log.Info("Voiding payment",
+		"payment_id", payment.ID,
+		"provider", payment.ProviderID,
+	)
+
+	result, err := providerAdapter.Void(ctx, provider.VoidRequest{
+		ProviderPaymentID: payment.ProviderPaymentID,
+		IdempotencyKey:    "void-" + payment.ID,
+	})
+	if err != nil {
+		saveTransaction(ctx, uc.transactionRepo, log, newTransaction(payment,
+			entity.TransactionTypeVoid, entity.TransactionStatusFailed, "", provider.ExchangeFromError(err)))
+		log.Error("Failed to void payment, provider error",
+			"error", err,
+			"payment_id", payment.ID,
+			"provider", payment.ProviderID,
+		)
+		return nil, fmt.Errorf("provider failed to void payment: %w", err)
+	}
+
+	saveTransaction(ctx, uc.transactionRepo, log, newTransaction(payment,
+		entity.TransactionTypeVoid, entity.TransactionStatusSucceeded, result.ProviderVoidID, result.Exchange))
+
+	if err := payment.TransitionTo(entity.PaymentStatusCancelled); err != nil {
+		recordRejectedTransition(uc.metrics, err)
+		return nil, err
+	}
+	payment.UpdatedAt = time.Now()
+
+	voided := event.NewPaymentCancelledEvent(payment.ID, payment.ProviderID, "voided", payment.Amount)
+	if err := uc.paymentRepo.UpdatePayment(ctx, payment, voided); err != nil {
+		log.Error("Failed to update payment after provider void",
+			"error", err,
+			"payment_id", payment.ID,
+			"provider_void_id", result.ProviderVoidID,
+		)
+		return nil, fmt.Errorf("failed to update payment: %w", err)
+	}
+
+	uc.metrics.PaymentsTotal.WithLabelValues(
+		string(payment.Status),
+		payment.Amount.Currency,
+		payment.ProviderID,
+	).Inc()
+
+	log.Info("Payment voided",
+		"payment_id", payment.ID,
+	)
+
+	return payment, nil
+}
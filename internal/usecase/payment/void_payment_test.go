@@ -0,0 +1,65 @@
+package payment
+
+import (
+	"context"
+	"testing"
+
+	"github.com/omerbeden/paymentgateway/internal/domain/entity"
+	"github.com/omerbeden/paymentgateway/internal/domain/event"
+	"github.com/omerbeden/paymentgateway/internal/infrastructure/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newVoidUseCase(p *fakeProvider, payment *entity.Payment) (*VoidPaymentUseCase, *fakePaymentRepository, *fakeTransactionRepository) {
+	payments := &fakePaymentRepository{payment: payment}
+	transactions := &fakeTransactionRepository{}
+	uc := NewVoidPaymentUseCase(payments, transactions, newFakeProviderFactory(p), logger.NewNoOp(), testMetrics)
+	return uc, payments, transactions
+}
+
+func TestVoidPayment_VoidsWithThePaymentKey(t *testing.T) {
+	// Arrange
+	p := &fakeProvider{}
+	uc, payments, transactions := newVoidUseCase(p, newTestPayment(entity.PaymentStatusAuthorized, entity.PaymentIntentAuthorize))
+
+	// Act
+	out, err := uc.Execute(context.Background(), VoidPaymentInput{PaymentID: "pay_1"})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, entity.PaymentStatusCancelled, out.Status)
+	require.Len(t, p.voids, 1)
+	assert.Equal(t, "void-pay_1", p.voids[0].IdempotencyKey)
+	assert.Equal(t, "pi_1", p.voids[0].ProviderPaymentID)
+	require.Equal(t, []event.EventType{event.PaymentCancelled}, payments.eventTypes())
+	assert.Equal(t, "voided", payments.events[0].(event.PaymentCancelledEvent).Reason)
+	require.Len(t, transactions.txns, 1)
+	assert.Equal(t, entity.TransactionTypeVoid, transactions.txns[0].Type)
+	assert.Equal(t, "void_pi_1", transactions.txns[0].ProviderTxnID)
+}
+
+func TestVoidPayment_RejectsPaymentsThatAreNotAuthorized(t *testing.T) {
+	statuses := []entity.PaymentStatus{
+		entity.PaymentStatusPending,
+		entity.PaymentStatusProcessing,
+		entity.PaymentStatusSucceeded,
+		entity.PaymentStatusCancelled,
+	}
+
+	for _, status := range statuses {
+		t.Run(string(status), func(t *testing.T) {
+			// Arrange
+			p := &fakeProvider{}
+			uc, payments, _ := newVoidUseCase(p, newTestPayment(status, entity.PaymentIntentAuthorize))
+
+			// Act
+			_, err := uc.Execute(context.Background(), VoidPaymentInput{PaymentID: "pay_1"})
+
+			// Assert
+			assert.ErrorIs(t, err, ErrPaymentNotVoidable)
+			assert.Empty(t, p.voids)
+			assert.Empty(t, payments.events)
+		})
+	}
+}
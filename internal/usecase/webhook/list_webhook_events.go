@@ -0,0 +1,61 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/omerbeden/paymentgateway/internal/domain/entity"
+	"github.com/omerbeden/paymentgateway/internal/domain/repository"
+	"github.com/omerbeden/paymentgateway/internal/infrastructure/logger"
+)
+
+const (
+	defaultListLimit = 20
+	maxListLimit     = 100
+)
+
+type ListWebhookEventsUseCase struct {
+	webhookEventRepo repository.WebhookEventRepository
+	log              logger.Logger
+}
+
+func NewListWebhookEventsUseCase(webhookEventRepo repository.WebhookEventRepository, log logger.Logger) *ListWebhookEventsUseCase {
+	return &ListWebhookEventsUseCase{
+		webhookEventRepo: webhookEventRepo,
+		log:              log,
+	}
+}
+
+type ListWebhookEventsInput struct {
+	ProviderID        string
+	ProviderPaymentID string
+	State             repository.WebhookEventState
+	Limit             int
+}
+
+func (uc *ListWebhookEventsUseCase) Execute(ctx context.Context, input ListWebhookEventsInput) ([]*entity.WebhookEvent, error) {
+	events, err := uc.webhookEventRepo.List(ctx, input.filter())
+	if err != nil {
+		uc.log.With("request_id", getRequestID(ctx)).Error("Failed to list webhook events",
+			"error", err,
+		)
+		return nil, fmt.Errorf("failed to list webhook events: %w", err)
+	}
+	return events, nil
+}
+
+func (input ListWebhookEventsInput) filter() repository.WebhookEventFilter {
+	limit := input.Limit
+	if limit <= 0 {
+		limit = defaultListLimit
+	}
+	if limit > maxListLimit {
+		limit = maxListLimit
+	}
+	return repository.WebhookEventFilter{
+		ProviderID:        input.ProviderID,
+		ProviderPaymentID: input.ProviderPaymentID,
+		State:             input.State,
+		Limit:             limit,
+	}
+}
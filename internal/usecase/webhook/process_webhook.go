@@ -2,6 +2,8 @@ package webhook
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
@@ -9,94 +11,324 @@ import (
 	"github.com/omerbeden/paymentgateway/internal/domain/entity"
 	"github.com/omerbeden/paymentgateway/internal/domain/event"
 	"github.com/omerbeden/paymentgateway/internal/domain/repository"
+	"github.com/omerbeden/paymentgateway/internal/infrastructure/logger"
+	"github.com/omerbeden/paymentgateway/internal/infrastructure/metrics"
 )
 
+// ErrStaleWebhookEvent is returned when a webhook was created before the last
+// provider event already applied to the payment.
+var ErrStaleWebhookEvent = errors.New("stale webhook event")
+
+// ErrIgnoredWebhookEvent is returned for a webhook whose event type does not
+// change the payment.
+var ErrIgnoredWebhookEvent = errors.New("webhook event type is not applied")
+
 type ProcessWebHookUseCase struct {
 	paymentRepo      repository.PaymentRepository
 	webhookEventRepo repository.WebhookEventRepository
+	transactionRepo  repository.TransactionRepository
 	providerFactory  *provider.Factory
-	eventStore       event.Store
+	verifier         *webhookVerifier
+	log              logger.Logger
+	metrics          *metrics.Metrics
 }
 
 func NewProcessWebHookUseCase(paymentRepo repository.PaymentRepository,
+	webhookEventRepo repository.WebhookEventRepository,
+	transactionRepo repository.TransactionRepository,
 	providerFactory *provider.Factory,
-	eventStore event.Store) *ProcessWebHookUseCase {
+	log logger.Logger,
+	metrics *metrics.Metrics) *ProcessWebHookUseCase {
 	return &ProcessWebHookUseCase{
-		paymentRepo:     paymentRepo,
-		providerFactory: providerFactory,
-		eventStore:      eventStore,
+		paymentRepo:      paymentRepo,
+		webhookEventRepo: webhookEventRepo,
+		transactionRepo:  transactionRepo,
+		providerFactory:  providerFactory,
+		verifier:         &webhookVerifier{paymentRepo: paymentRepo, transactionRepo: transactionRepo, log: log},
+		log:              log,
+		metrics:          metrics,
 	}
 }
 
 type ProcessWebHookInput struct {
-	ProviderId     string
-	WebhookContext *provider.WebhookContext
+	WebhookEventID string
+	// SkipVerification skips checking the stored signature again, e.g. for
+	// events verified on receipt. Signatures that expire (Stripe) or need
+	// headers that are not stored (PayPal) only replay with this set.
+	SkipVerification bool
+	// Reprocess applies the event even if it was already processed.
+	Reprocess bool
 }
 
+// Execute applies a webhook stored by ReceiveWebHookUseCase. The outcome is
+// written back to the stored event, so a failed event can be found and
+// replayed; events that were already processed are skipped unless Reprocess
+// is set. Stale events and event types that change nothing are recorded as
+// processed with the reason, not applied.
 func (uc *ProcessWebHookUseCase) Execute(ctx context.Context, input ProcessWebHookInput) error {
+	stored, err := uc.webhookEventRepo.GetByID(ctx, input.WebhookEventID)
+	if err != nil {
+		return err
+	}
+	if stored.IsProcessed && !input.Reprocess {
+		uc.log.Info("Skipping already processed webhook event",
+			"webhook_event_id", stored.ID,
+			"provider", stored.ProviderID,
+		)
+		return nil
+	}
+
+	start := time.Now()
+	err = uc.process(ctx, stored, !input.SkipVerification)
+	if uc.metrics != nil {
+		uc.metrics.WebhookProcessingDuration.WithLabelValues(stored.ProviderID).Observe(time.Since(start).Seconds())
+	}
 
-	providerAdapter, err := uc.providerFactory.GetProvider(input.ProviderId)
+	processed := true
+	processingError := ""
+	status := "success"
+	switch {
+	case errors.Is(err, ErrStaleWebhookEvent), errors.Is(err, ErrIgnoredWebhookEvent):
+		processingError = err.Error()
+		status = "ignored"
+		err = nil
+	case err != nil:
+		processed = false
+		processingError = err.Error()
+		status = "error"
+	}
+	uc.recordWebhook(stored.ProviderID, stored.EventType, status)
+
+	if markErr := uc.webhookEventRepo.MarkProcessed(ctx, stored.ID, processed, processingError, time.Now()); markErr != nil {
+		uc.log.Error("Failed to record webhook event outcome",
+			"error", markErr,
+			"webhook_event_id", stored.ID,
+		)
+	}
+	return err
+}
+
+func (uc *ProcessWebHookUseCase) process(ctx context.Context, stored *entity.WebhookEvent, verify bool) error {
+	providerAdapter, err := uc.providerFactory.GetProvider(stored.ProviderID)
 	if err != nil {
 		return err
 	}
 
-	err = providerAdapter.VerifyWebhook(ctx, input.WebhookContext)
+	if verify {
+		err = uc.verifier.verify(ctx, providerAdapter, stored.ProviderID, &provider.WebhookContext{
+			Payload:   []byte(stored.Payload),
+			Signature: stored.Signature,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	webhookEvent, err := providerAdapter.ParseWebhook([]byte(stored.Payload))
 	if err != nil {
 		return err
 	}
+	if webhookEvent.Ignored {
+		return fmt.Errorf("%w: %s", ErrIgnoredWebhookEvent, webhookEvent.EventType)
+	}
+
+	return uc.apply(ctx, providerAdapter, stored.ProviderID, webhookEvent)
+}
 
-	webhookEvent, err := providerAdapter.ParseWebhook(input.WebhookContext.Payload)
+// apply moves the payment to the status reported by the webhook.
+func (uc *ProcessWebHookUseCase) apply(ctx context.Context, providerAdapter provider.PaymentProvider, providerID string, webhookEvent *provider.WebhookEvent) error {
+	payment, err := uc.paymentRepo.GetByProviderPaymentID(ctx, webhookEvent.ProviderPaymentID, providerID)
 	if err != nil {
 		return err
 	}
 
-	uc.webhookEventRepo.Save(ctx, &entity.WebhookEvent{
-		ID:                uuid.New().String(),
-		ProviderID:        input.ProviderId,
-		ProviderPaymentID: webhookEvent.ProviderPaymentID,
-		EventType:         webhookEvent.EventType,
-		Signature:         input.WebhookContext.Signature,
-		Payload:           string(input.WebhookContext.Payload),
-		IsVerified:        true,
-		IsProcessed:       false,
-		ReceivedAt:        time.Now(),
-	})
-
-	//Ready to Capture
-	if webhookEvent.Status == entity.PaymentStatusPending {
-		err := providerAdapter.Capture(ctx, webhookEvent.ProviderPaymentID)
+	// Providers do not guarantee delivery order, so an event created before
+	// the last applied one must not move the payment back. Events without a
+	// provider timestamp, e.g. from reconciliation, are not ordered.
+	if !webhookEvent.CreateTime.IsZero() && webhookEvent.CreateTime.Before(payment.LastProviderEventAt) {
+		return uc.rejectStale(payment, providerID, webhookEvent)
+	}
+
+	// A pending webhook means the buyer approved the order and it is ready to
+	// capture, or to authorize for payments with the authorize intent; anything
+	// else is a final status reported by the provider.
+	if !payment.Status.CanTransitionTo(webhookEvent.Status) {
+		return uc.rejectTransition(payment, webhookEvent.Status)
+	}
+
+	next := webhookEvent.Status
+	if webhookEvent.Status == entity.PaymentStatusPending && payment.Intent == entity.PaymentIntentAuthorize {
+		authorizeResult, err := providerAdapter.Authorize(ctx, webhookEvent.ProviderPaymentID)
+		uc.recordAuthorize(ctx, payment, authorizeResult, err)
 		if err != nil {
 			return err
 		}
-		notifyEvent := event.NewPaymentCompletedEvent(
-			webhookEvent.ProviderPaymentID,
-			webhookEvent.Currency,
-			input.ProviderId,
-			"",
-			webhookEvent.Amount,
-		)
-
-		if err := uc.eventStore.Append(ctx, notifyEvent); err != nil {
+		next = authorizeResult.Status
+		if !authorizeResult.ExpiresAt.IsZero() {
+			payment.ExpiresAt = authorizeResult.ExpiresAt
+		}
+	} else if webhookEvent.Status == entity.PaymentStatusPending {
+		captureResult, err := providerAdapter.Capture(ctx, provider.CaptureRequest{ProviderPaymentID: webhookEvent.ProviderPaymentID})
+		uc.recordCapture(ctx, payment, captureResult, err)
+		if err != nil {
 			return err
 		}
-
+		next = captureResult.Status
+		// Not every provider repeats the amount in its webhook, so prefer what
+		// was captured and fall back to the payment.
+		amount := payment.Amount
+		if captureResult.Amount.IsPositive() {
+			amount = captureResult.Amount
+		}
+		payment.CapturedAmount = amount
 	}
 
-	payment, err := uc.paymentRepo.GetByProviderPaymentID(ctx, webhookEvent.ProviderPaymentID, input.ProviderId)
-	if err != nil {
-		return err
+	if !payment.Status.CanTransitionTo(next) {
+		return uc.rejectTransition(payment, next)
 	}
-
-	payment.Status = webhookEvent.Status
+	previous := payment.Status
+	payment.Status = next
 	payment.UpdatedAt = time.Now()
+	if webhookEvent.CreateTime.After(payment.LastProviderEventAt) {
+		payment.LastProviderEventAt = webhookEvent.CreateTime
+	}
 
-	if webhookEvent.Status == entity.PaymentStatusSucceeded {
+	if next == entity.PaymentStatusSucceeded {
 		payment.CompletedAt = time.Now()
+		// The provider captured without us, e.g. an order completed at PayPal.
+		if !payment.CapturedAmount.IsPositive() {
+			payment.CapturedAmount = payment.Amount
+			if webhookEvent.Amount.IsPositive() {
+				payment.CapturedAmount = webhookEvent.Amount
+			}
+		}
 	}
 
-	if err = uc.paymentRepo.UpdatePayment(ctx, payment); err != nil {
-		return err
+	var events []event.DomainEvent
+	if next != previous {
+		events = statusEvents(payment, webhookEvent.EventType)
+	}
+	return uc.paymentRepo.UpdatePayment(ctx, payment, events...)
+}
+
+// statusEvents are the events announcing the status payment was just moved to
+// by a provider event of type reason.
+func statusEvents(payment *entity.Payment, reason string) []event.DomainEvent {
+	switch payment.Status {
+	case entity.PaymentStatusAuthorized:
+		return []event.DomainEvent{
+			event.NewPaymentAuthorizedEvent(payment.ID, payment.ProviderID, payment.Amount, payment.ExpiresAt),
+		}
+	case entity.PaymentStatusSucceeded:
+		return []event.DomainEvent{
+			event.NewPaymentCapturedEvent(payment.ID, payment.ProviderID, payment.CapturedAmount),
+			event.NewPaymentCompletedEvent(payment.ID, payment.ProviderID, "", payment.CapturedAmount),
+		}
+	case entity.PaymentStatusFailed:
+		return []event.DomainEvent{
+			event.NewPaymentFailedEvent(payment.ID, payment.ProviderID, reason, payment.Amount),
+		}
+	case entity.PaymentStatusCancelled:
+		return []event.DomainEvent{
+			event.NewPaymentCancelledEvent(payment.ID, payment.ProviderID, reason, payment.Amount),
+		}
+	default:
+		return nil
+	}
+}
+
+func (uc *ProcessWebHookUseCase) recordWebhook(providerID, eventType, status string) {
+	if uc.metrics == nil {
+		return
 	}
+	uc.metrics.WebhooksProcessed.WithLabelValues(providerID, eventType, status).Inc()
+}
+
+// rejectTransition counts and logs a webhook whose status the payment cannot
+// move to, e.g. a late approval for a payment that already succeeded.
+func (uc *ProcessWebHookUseCase) rejectTransition(payment *entity.Payment, to entity.PaymentStatus) error {
+	if uc.metrics != nil {
+		uc.metrics.PaymentTransitionsRejected.WithLabelValues(string(payment.Status), string(to)).Inc()
+	}
+	uc.log.Warn("Ignoring webhook with illegal status transition",
+		"payment_id", payment.ID,
+		"from", payment.Status,
+		"to", to,
+	)
+	return &entity.InvalidTransitionError{From: payment.Status, To: to}
+}
 
-	return nil
+// rejectStale counts and logs a webhook created before the last provider event
+// applied to the payment.
+func (uc *ProcessWebHookUseCase) rejectStale(payment *entity.Payment, providerID string, webhookEvent *provider.WebhookEvent) error {
+	if uc.metrics != nil {
+		uc.metrics.WebhooksOutOfOrder.WithLabelValues(providerID, webhookEvent.EventType).Inc()
+	}
+	uc.log.Warn("Ignoring webhook older than the last applied provider event",
+		"payment_id", payment.ID,
+		"event_id", webhookEvent.EventID,
+		"event_type", webhookEvent.EventType,
+		"event_time", webhookEvent.CreateTime,
+		"last_event_time", payment.LastProviderEventAt,
+	)
+	return fmt.Errorf("%w: created at %s, last applied event at %s", ErrStaleWebhookEvent,
+		webhookEvent.CreateTime.Format(time.RFC3339), payment.LastProviderEventAt.Format(time.RFC3339))
+}
+
+// recordAuthorize stores the authorize call as a transaction whether it
+// succeeded or not.
+func (uc *ProcessWebHookUseCase) recordAuthorize(ctx context.Context, payment *entity.Payment, result *provider.AuthorizeResult, authorizeErr error) {
+	txn := entity.NewTransaction(uuid.New().String(), payment, entity.TransactionTypeAuthorize)
+	txn.ProcessedAt = time.Now()
+
+	exchange := provider.ExchangeFromError(authorizeErr)
+	if authorizeErr == nil {
+		txn.Status = entity.TransactionStatusSucceeded
+		txn.ProviderTxnID = result.ProviderAuthorizationID
+		if result.Amount.IsPositive() {
+			txn.Amount = result.Amount
+		}
+		exchange = result.Exchange
+	} else {
+		txn.Status = entity.TransactionStatusFailed
+	}
+	txn.RequestPayload = exchange.Request
+	txn.ResponsePayload = exchange.Response
+
+	if err := uc.transactionRepo.Save(ctx, txn); err != nil {
+		uc.log.Error("Failed to save authorize transaction",
+			"error", err,
+			"payment_id", payment.ID,
+			"status", txn.Status,
+		)
+	}
+}
+
+// recordCapture stores the capture call as a transaction whether it succeeded
+// or not, so failed captures can be investigated afterwards.
+func (uc *ProcessWebHookUseCase) recordCapture(ctx context.Context, payment *entity.Payment, result *provider.CaptureResult, captureErr error) {
+	txn := entity.NewTransaction(uuid.New().String(), payment, entity.TransactionTypeCapture)
+	txn.ProcessedAt = time.Now()
+
+	exchange := provider.ExchangeFromError(captureErr)
+	if captureErr == nil {
+		txn.Status = entity.TransactionStatusSucceeded
+		txn.ProviderTxnID = result.ProviderCaptureID
+		if result.Amount.IsPositive() {
+			txn.Amount = result.Amount
+		}
+		exchange = result.Exchange
+	} else {
+		txn.Status = entity.TransactionStatusFailed
+	}
+	txn.RequestPayload = exchange.Request
+	txn.ResponsePayload = exchange.Response
+
+	if err := uc.transactionRepo.Save(ctx, txn); err != nil {
+		uc.log.Error("Failed to save capture transaction",
+			"error", err,
+			"payment_id", payment.ID,
+			"status", txn.Status,
+		)
+	}
 }
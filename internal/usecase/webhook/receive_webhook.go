@@ -0,0 +1,150 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/omerbeden/paymentgateway/internal/adapter/provider"
+	"github.com/omerbeden/paymentgateway/internal/domain/entity"
+	"github.com/omerbeden/paymentgateway/internal/domain/event"
+	"github.com/omerbeden/paymentgateway/internal/domain/repository"
+	"github.com/omerbeden/paymentgateway/internal/infrastructure/logger"
+	"github.com/omerbeden/paymentgateway/internal/infrastructure/metrics"
+)
+
+// ReceiveWebHookUseCase is the synchronous half of webhook handling: it stores
+// the webhook and queues it for ProcessWebHookUseCase. Verification stays in
+// the request so that forged payloads are rejected to the sender and never
+// claim a provider event ID.
+type ReceiveWebHookUseCase struct {
+	webhookEventRepo repository.WebhookEventRepository
+	providerFactory  *provider.Factory
+	verifier         *webhookVerifier
+	publisher        event.Publisher
+	log              logger.Logger
+	metrics          *metrics.Metrics
+}
+
+func NewReceiveWebHookUseCase(webhookEventRepo repository.WebhookEventRepository,
+	paymentRepo repository.PaymentRepository,
+	transactionRepo repository.TransactionRepository,
+	providerFactory *provider.Factory,
+	publisher event.Publisher,
+	log logger.Logger,
+	metrics *metrics.Metrics) *ReceiveWebHookUseCase {
+	return &ReceiveWebHookUseCase{
+		webhookEventRepo: webhookEventRepo,
+		providerFactory:  providerFactory,
+		verifier:         &webhookVerifier{paymentRepo: paymentRepo, transactionRepo: transactionRepo, log: log},
+		publisher:        publisher,
+		log:              log,
+		metrics:          metrics,
+	}
+}
+
+type ReceiveWebHookInput struct {
+	ProviderId     string
+	WebhookContext *provider.WebhookContext
+}
+
+func (uc *ReceiveWebHookUseCase) Execute(ctx context.Context, input ReceiveWebHookInput) error {
+	log := uc.log.With("request_id", getRequestID(ctx))
+
+	providerAdapter, err := uc.providerFactory.GetProvider(input.ProviderId)
+	if err != nil {
+		return err
+	}
+
+	stored := &entity.WebhookEvent{
+		ID:         uuid.New().String(),
+		ProviderID: input.ProviderId,
+		Signature:  input.WebhookContext.Signature,
+		Payload:    string(input.WebhookContext.Payload),
+		ReceivedAt: time.Now(),
+	}
+
+	// Unverified webhooks are kept for investigation, without a provider event
+	// ID so they cannot shadow the genuine event.
+	if err := uc.verifier.verify(ctx, providerAdapter, input.ProviderId, input.WebhookContext); err != nil {
+		stored.ProcessingError = err.Error()
+		if saveErr := uc.webhookEventRepo.Save(ctx, stored); saveErr != nil {
+			log.Error("Failed to save unverified webhook event",
+				"error", saveErr,
+				"provider", input.ProviderId,
+			)
+		}
+		uc.recordWebhook(input.ProviderId, "unknown", "unverified")
+		return err
+	}
+
+	// The provider has proven the webhook is genuine, so it is acknowledged
+	// even if it cannot be parsed; it is stored as failed and retried or
+	// replayed from there.
+	webhookEvent, err := providerAdapter.ParseWebhook(input.WebhookContext.Payload)
+	if err != nil {
+		stored.IsVerified = true
+		stored.ProcessingError = err.Error()
+		if saveErr := uc.webhookEventRepo.Save(ctx, stored); saveErr != nil {
+			return fmt.Errorf("failed to store webhook event: %w", saveErr)
+		}
+		log.Warn("Stored webhook event that could not be parsed",
+			"error", err,
+			"provider", input.ProviderId,
+			"webhook_event_id", stored.ID,
+		)
+		uc.recordWebhook(input.ProviderId, "unknown", "unparseable")
+		return nil
+	}
+
+	stored.ProviderEventID = webhookEvent.EventID
+	stored.ProviderPaymentID = webhookEvent.ProviderPaymentID
+	stored.EventType = webhookEvent.EventType
+	stored.IsVerified = true
+
+	// Saving first lets the unique provider event ID catch retried deliveries,
+	// including ones that arrive while the first is still being processed.
+	err = uc.webhookEventRepo.Save(ctx, stored)
+	if errors.Is(err, repository.ErrDuplicateWebhookEvent) {
+		uc.recordWebhook(input.ProviderId, webhookEvent.EventType, "duplicate")
+		log.Info("Ignoring duplicate webhook event",
+			"provider", input.ProviderId,
+			"event_id", webhookEvent.EventID,
+			"event_type", webhookEvent.EventType,
+		)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to store webhook event: %w", err)
+	}
+
+	// The event is stored, so it is acknowledged even if queueing fails; it
+	// stays pending and RequeueWebhookEventsUseCase queues it again.
+	received := event.NewWebhookReceivedEvent(stored.ID, input.ProviderId, webhookEvent.ProviderPaymentID, webhookEvent.EventType)
+	if err := uc.publisher.Publish(ctx, event.TopicWebhookReceived, received); err != nil {
+		log.Error("Failed to queue webhook event",
+			"error", err,
+			"provider", input.ProviderId,
+			"webhook_event_id", stored.ID,
+		)
+	}
+
+	uc.recordWebhook(input.ProviderId, webhookEvent.EventType, "accepted")
+	return nil
+}
+
+func (uc *ReceiveWebHookUseCase) recordWebhook(providerID, eventType, status string) {
+	if uc.metrics == nil {
+		return
+	}
+	uc.metrics.WebhooksReceived.WithLabelValues(providerID, eventType, status).Inc()
+}
+
+func getRequestID(ctx context.Context) string {
+	if requestID, ok := ctx.Value("request_id").(string); ok {
+		return requestID
+	}
+	return "unknown"
+}
@@ -0,0 +1,155 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/omerbeden/paymentgateway/internal/adapter/provider"
+	"github.com/omerbeden/paymentgateway/internal/domain/entity"
+	"github.com/omerbeden/paymentgateway/internal/domain/repository"
+	"github.com/omerbeden/paymentgateway/internal/infrastructure/config"
+	"github.com/omerbeden/paymentgateway/internal/infrastructure/logger"
+	"github.com/omerbeden/paymentgateway/internal/infrastructure/metrics"
+)
+
+// reconciliationEventType is the event type of the webhook events built from
+// a provider status poll.
+const reconciliationEventType = "RECONCILIATION"
+
+// reconciledStatuses are the statuses a payment waits in for a webhook.
+var reconciledStatuses = []entity.PaymentStatus{entity.PaymentStatusPending, entity.PaymentStatusProcessing}
+
+// ReconcilePaymentsUseCase asks providers for the status of payments that no
+// webhook has updated for a while, in case it was lost, and applies what they
+// report the way ProcessWebHookUseCase applies a webhook. It must run on a
+// single replica; see database.Leader.
+type ReconcilePaymentsUseCase struct {
+	paymentRepo      repository.PaymentRepository
+	processWebhookUC *ProcessWebHookUseCase
+	providerFactory  *provider.Factory
+	cfg              config.Reconciliation
+	log              logger.Logger
+	metrics          *metrics.Metrics
+}
+
+func NewReconcilePaymentsUseCase(paymentRepo repository.PaymentRepository,
+	processWebhookUC *ProcessWebHookUseCase,
+	providerFactory *provider.Factory,
+	cfg config.Reconciliation,
+	log logger.Logger,
+	metrics *metrics.Metrics) *ReconcilePaymentsUseCase {
+	return &ReconcilePaymentsUseCase{
+		paymentRepo:      paymentRepo,
+		processWebhookUC: processWebhookUC,
+		providerFactory:  providerFactory,
+		cfg:              cfg,
+		log:              log,
+		metrics:          metrics,
+	}
+}
+
+// Execute reconciles one batch of stale payments and returns how many it
+// checked.
+func (uc *ReconcilePaymentsUseCase) Execute(ctx context.Context) (int, error) {
+	payments, err := uc.paymentRepo.ListStale(ctx, reconciledStatuses, time.Now().Add(-uc.cfg.StaleAfter), uc.cfg.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, payment := range payments {
+		outcome := uc.reconcile(ctx, payment)
+		uc.recordReconciliation(payment.ProviderID, outcome)
+
+		// Checked payments wait another StaleAfter whatever the outcome, so a
+		// provider that keeps failing does not hold up the rest.
+		if err := uc.paymentRepo.MarkReconciled(ctx, payment.ID, time.Now()); err != nil {
+			uc.log.Error("Failed to mark payment reconciled",
+				"error", err,
+				"payment_id", payment.ID,
+			)
+		}
+	}
+	return len(payments), nil
+}
+
+func (uc *ReconcilePaymentsUseCase) reconcile(ctx context.Context, payment *entity.Payment) string {
+	log := uc.log.With("payment_id", payment.ID, "provider", payment.ProviderID)
+
+	providerAdapter, err := uc.providerFactory.GetProvider(payment.ProviderID)
+	if err != nil {
+		log.Warn("Cannot reconcile payment of an unregistered provider", "error", err)
+		return "error"
+	}
+
+	result, err := providerAdapter.GetPaymentStatus(ctx, payment.ProviderPaymentID)
+	if err != nil {
+		log.Error("Failed to get payment status from provider", "error", err)
+		return "error"
+	}
+
+	// Only pending is worth applying again: it means approved and ready to
+	// capture, while the payment's own pending means waiting for the buyer.
+	if result.Status == "" || (result.Status == payment.Status && result.Status != entity.PaymentStatusPending) {
+		return "unchanged"
+	}
+
+	// CreateTime is left zero: the poll has no provider timestamp, and the
+	// gateway's clock must not order the provider's own events.
+	now := time.Now().UTC()
+	webhookEvent := &provider.WebhookEvent{
+		ProviderID:        payment.ProviderID,
+		EventID:           reconciliationEventType + ":" + payment.ProviderPaymentID + ":" + now.Format(time.RFC3339Nano),
+		EventType:         reconciliationEventType,
+		ProviderPaymentID: payment.ProviderPaymentID,
+		Status:            result.Status,
+		Amount:            result.Amount,
+	}
+	if err := uc.processWebhookUC.apply(ctx, providerAdapter, payment.ProviderID, webhookEvent); err != nil {
+		// A webhook may have arrived since the payment was listed.
+		if errors.Is(err, entity.ErrInvalidTransition) || errors.Is(err, ErrStaleWebhookEvent) {
+			log.Info("Payment changed while reconciling it, skipped", "error", err)
+			return "unchanged"
+		}
+		log.Error("Failed to apply reconciled payment status", "error", err, "status", result.Status)
+		return "error"
+	}
+
+	log.Info("Reconciled payment with provider status",
+		"from", payment.Status,
+		"provider_status", result.Status,
+	)
+	return "applied"
+}
+
+func (uc *ReconcilePaymentsUseCase) recordReconciliation(providerID, outcome string) {
+	if uc.metrics == nil {
+		return
+	}
+	uc.metrics.PaymentsReconciled.WithLabelValues(providerID, outcome).Inc()
+}
+
+// Run reconciles stale payments every poll interval until ctx is cancelled.
+func (uc *ReconcilePaymentsUseCase) Run(ctx context.Context) {
+	ticker := time.NewTicker(uc.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		// keep draining while full batches come back
+		for ctx.Err() == nil {
+			n, err := uc.Execute(ctx)
+			if err != nil {
+				uc.log.Error("Failed to reconcile payments", "error", err)
+			}
+			if err != nil || n < uc.cfg.BatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/omerbeden/paymentgateway/internal/domain/entity"
+	"github.com/omerbeden/paymentgateway/internal/domain/repository"
+	"github.com/omerbeden/paymentgateway/internal/infrastructure/logger"
+)
+
+// ErrUnverifiedWebhookEvent is returned for an event that failed signature
+// verification on receipt and is replayed without verifying it again.
+var ErrUnverifiedWebhookEvent = errors.New("webhook event was not verified on receipt")
+
+// ReplayWebhookEventsUseCase re-drives stored webhooks through
+// ProcessWebHookUseCase, either a single event or a filtered batch.
+type ReplayWebhookEventsUseCase struct {
+	webhookEventRepo repository.WebhookEventRepository
+	processWebhookUC *ProcessWebHookUseCase
+	log              logger.Logger
+}
+
+func NewReplayWebhookEventsUseCase(webhookEventRepo repository.WebhookEventRepository,
+	processWebhookUC *ProcessWebHookUseCase,
+	log logger.Logger) *ReplayWebhookEventsUseCase {
+	return &ReplayWebhookEventsUseCase{
+		webhookEventRepo: webhookEventRepo,
+		processWebhookUC: processWebhookUC,
+		log:              log,
+	}
+}
+
+// ReplayWebhookEventsInput selects the events to replay. WebhookEventID
+// replays that event alone; otherwise Filter selects the batch.
+type ReplayWebhookEventsInput struct {
+	WebhookEventID   string
+	Filter           ListWebhookEventsInput
+	SkipVerification bool
+}
+
+type ReplayResult struct {
+	WebhookEventID string
+	Error          string
+}
+
+func (uc *ReplayWebhookEventsUseCase) Execute(ctx context.Context, input ReplayWebhookEventsInput) ([]ReplayResult, error) {
+	log := uc.log.With("request_id", getRequestID(ctx))
+
+	var events []*entity.WebhookEvent
+	if input.WebhookEventID != "" {
+		stored, err := uc.webhookEventRepo.GetByID(ctx, input.WebhookEventID)
+		if err != nil {
+			return nil, err
+		}
+		events = []*entity.WebhookEvent{stored}
+	} else {
+		var err error
+		events, err = uc.webhookEventRepo.List(ctx, input.Filter.filter())
+		if err != nil {
+			return nil, fmt.Errorf("failed to list webhook events: %w", err)
+		}
+	}
+
+	results := make([]ReplayResult, 0, len(events))
+	for _, stored := range events {
+		result := ReplayResult{WebhookEventID: stored.ID}
+		// Skipping verification trusts the stored payload, which is only
+		// safe for events that were verified when they arrived.
+		var err error
+		if input.SkipVerification && !stored.IsVerified {
+			err = ErrUnverifiedWebhookEvent
+		} else {
+			err = uc.processWebhookUC.Execute(ctx, ProcessWebHookInput{
+				WebhookEventID:   stored.ID,
+				SkipVerification: input.SkipVerification,
+				Reprocess:        true,
+			})
+		}
+		if err != nil {
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+
+		log.Info("Replayed webhook event",
+			"webhook_event_id", stored.ID,
+			"provider", stored.ProviderID,
+			"error", result.Error,
+		)
+	}
+	return results, nil
+}
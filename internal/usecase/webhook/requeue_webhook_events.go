@@ -0,0 +1,85 @@
+package webhook
+
+import (
+	"context"
+	"time"
+
+	"github.com/omerbeden/paymentgateway/internal/domain/event"
+	"github.com/omerbeden/paymentgateway/internal/domain/repository"
+	"github.com/omerbeden/paymentgateway/internal/infrastructure/config"
+	"github.com/omerbeden/paymentgateway/internal/infrastructure/logger"
+)
+
+// RequeueWebhookEventsUseCase queues verified webhooks for
+// ProcessWebHookUseCase again when they were stored but never processed, e.g.
+// because queueing them failed after the provider had been answered, or when
+// processing them failed, up to cfg.MaxAttempts tries. It must run on a
+// single replica; see database.Leader.
+type RequeueWebhookEventsUseCase struct {
+	webhookEventRepo repository.WebhookEventRepository
+	publisher        event.Publisher
+	cfg              config.WebhookRequeue
+	log              logger.Logger
+}
+
+func NewRequeueWebhookEventsUseCase(webhookEventRepo repository.WebhookEventRepository,
+	publisher event.Publisher,
+	cfg config.WebhookRequeue,
+	log logger.Logger) *RequeueWebhookEventsUseCase {
+	return &RequeueWebhookEventsUseCase{
+		webhookEventRepo: webhookEventRepo,
+		publisher:        publisher,
+		cfg:              cfg,
+		log:              log,
+	}
+}
+
+// Execute queues one batch of unprocessed webhooks received and last tried
+// more than cfg.After ago and returns how many it queued.
+func (uc *RequeueWebhookEventsUseCase) Execute(ctx context.Context) (int, error) {
+	cutoff := time.Now().Add(-uc.cfg.After)
+	events, err := uc.webhookEventRepo.List(ctx, repository.WebhookEventFilter{
+		State:           repository.WebhookEventStateUnprocessed,
+		ReceivedBefore:  cutoff,
+		AttemptedBefore: cutoff,
+		AttemptsBelow:   uc.cfg.MaxAttempts,
+		Limit:           uc.cfg.BatchSize,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for i, stored := range events {
+		received := event.NewWebhookReceivedEvent(stored.ID, stored.ProviderID, stored.ProviderPaymentID, stored.EventType)
+		if err := uc.publisher.Publish(ctx, event.TopicWebhookReceived, received); err != nil {
+			return i, err
+		}
+		uc.log.Info("Queued unprocessed webhook event again",
+			"webhook_event_id", stored.ID,
+			"provider", stored.ProviderID,
+			"received_at", stored.ReceivedAt,
+			"processing_error", stored.ProcessingError,
+		)
+	}
+	return len(events), nil
+}
+
+// Run queues one batch every cfg.PollInterval until ctx is cancelled. Unlike
+// the other jobs it does not drain full batches: queued events stay pending
+// until they are processed, so the same batch would come back.
+func (uc *RequeueWebhookEventsUseCase) Run(ctx context.Context) {
+	ticker := time.NewTicker(uc.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := uc.Execute(ctx); err != nil {
+			uc.log.Error("Failed to requeue webhook events", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package webhook
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/omerbeden/paymentgateway/internal/adapter/provider"
+	"github.com/omerbeden/paymentgateway/internal/domain/entity"
+	"github.com/omerbeden/paymentgateway/internal/domain/repository"
+	"github.com/omerbeden/paymentgateway/internal/infrastructure/logger"
+)
+
+// webhookVerifier verifies webhooks for ReceiveWebHookUseCase and
+// ProcessWebHookUseCase. Verifications that call the provider are stored as a
+// transaction of the payment the webhook is about, like every other provider
+// call.
+type webhookVerifier struct {
+	paymentRepo     repository.PaymentRepository
+	transactionRepo repository.TransactionRepository
+	log             logger.Logger
+}
+
+func (v *webhookVerifier) verify(ctx context.Context, providerAdapter provider.PaymentProvider, providerID string, webhookCtx *provider.WebhookContext) error {
+	remote, ok := providerAdapter.(provider.RemoteWebhookVerifier)
+	if !ok {
+		return providerAdapter.VerifyWebhook(ctx, webhookCtx)
+	}
+
+	exchange, err := remote.VerifyWebhookExchange(ctx, webhookCtx)
+	v.record(ctx, providerAdapter, providerID, webhookCtx.Payload, exchange, err)
+	return err
+}
+
+// record stores the verification call. It is skipped when no call was made,
+// e.g. without an access token, or when the payload names no known payment,
+// since a transaction always belongs to a payment.
+func (v *webhookVerifier) record(ctx context.Context, providerAdapter provider.PaymentProvider, providerID string, payload []byte, exchange provider.Exchange, verifyErr error) {
+	if exchange.Request == "" && exchange.Response == "" {
+		return
+	}
+
+	webhookEvent, err := providerAdapter.ParseWebhook(payload)
+	if err != nil || webhookEvent.ProviderPaymentID == "" {
+		v.log.Warn("Not storing webhook verification without a payment",
+			"provider", providerID,
+		)
+		return
+	}
+	payment, err := v.paymentRepo.GetByProviderPaymentID(ctx, webhookEvent.ProviderPaymentID, providerID)
+	if err != nil {
+		v.log.Warn("Not storing webhook verification without a payment",
+			"error", err,
+			"provider", providerID,
+			"provider_payment_id", webhookEvent.ProviderPaymentID,
+		)
+		return
+	}
+
+	txn := entity.NewTransaction(uuid.New().String(), payment, entity.TransactionTypeWebhookVerification)
+	txn.ProcessedAt = time.Now()
+	txn.ProviderTxnID = webhookEvent.EventID
+	txn.Status = entity.TransactionStatusSucceeded
+	if verifyErr != nil {
+		txn.Status = entity.TransactionStatusFailed
+	}
+	txn.RequestPayload = exchange.Request
+	txn.ResponsePayload = exchange.Response
+
+	if err := v.transactionRepo.Save(ctx, txn); err != nil {
+		v.log.Error("Failed to save webhook verification transaction",
+			"error", err,
+			"payment_id", payment.ID,
+			"status", txn.Status,
+		)
+	}
+}
@@ -2,14 +2,18 @@ package integration
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"testing"
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"github.com/omerbeden/paymentgateway/internal/adapter/eventstore/mongodb"
 	"github.com/omerbeden/paymentgateway/internal/domain/event"
+	"github.com/omerbeden/paymentgateway/internal/domain/money"
 )
 
 // Run with: go test ./tests/integration/... -v -count=1
@@ -52,9 +56,242 @@ func TestMongoEventStore_Append(t *testing.T) {
 
 	paymentID := "pay_test_001"
 
-	evt1 := event.NewPaymentCompletedEvent(paymentID, "USD", "stripe", "test payment", 100.00)
-	if err := store.Append(ctx, evt1); err != nil {
+	evt1 := event.NewPaymentCompletedEvent(paymentID, "stripe", "test payment", money.New(10000, "USD"))
+	if err := store.Append(ctx, paymentID, 0, evt1); err != nil {
 		t.Fatalf("append event 1: %v", err)
 	}
 
+	events, err := store.Load(ctx, paymentID)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(events) != 1 || events[0].Version != 1 {
+		t.Fatalf("expected one event at version 1, got %+v", events)
+	}
+	loaded, ok := events[0].Event.(event.PaymentCompletedEvent)
+	if !ok {
+		t.Fatalf("expected a PaymentCompletedEvent, got %T", events[0].Event)
+	}
+	if loaded.PaymentID != paymentID || loaded.Amount != evt1.Amount {
+		t.Errorf("unexpected loaded event: %+v", loaded)
+	}
+}
+
+func TestMongoEventStore_AppendStoresOneDocumentPerEvent(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	store := mongodb.NewMongoEventStore(db)
+	ctx := context.Background()
+
+	paymentID := "pay_test_002"
+	amount := money.New(10000, "USD")
+
+	if err := store.Append(ctx, paymentID, 0, event.NewPaymentCreatedEvent(paymentID, "stripe", "capture", "pending", amount)); err != nil {
+		t.Fatalf("append created event: %v", err)
+	}
+	if err := store.Append(ctx, paymentID, event.AnyVersion, event.NewPaymentCapturedEvent(paymentID, "stripe", amount)); err != nil {
+		t.Fatalf("append captured event: %v", err)
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "version", Value: 1}})
+	cursor, err := db.Collection("domain_events").Find(ctx, bson.M{"aggregate_id": paymentID}, opts)
+	if err != nil {
+		t.Fatalf("find events: %v", err)
+	}
+	var docs []struct {
+		Version int    `bson:"version"`
+		Type    string `bson:"type"`
+	}
+	if err := cursor.All(ctx, &docs); err != nil {
+		t.Fatalf("decode events: %v", err)
+	}
+
+	if len(docs) != 2 {
+		t.Fatalf("expected 2 event documents, got %d", len(docs))
+	}
+	if docs[0].Version != 1 || docs[0].Type != string(event.PaymentCreated) {
+		t.Errorf("unexpected first event: %+v", docs[0])
+	}
+	if docs[1].Version != 2 || docs[1].Type != string(event.PaymentCaptured) {
+		t.Errorf("unexpected second event: %+v", docs[1])
+	}
+}
+
+func TestMongoEventStore_AppendRejectsUnexpectedVersion(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	store := mongodb.NewMongoEventStore(db)
+	ctx := context.Background()
+
+	paymentID := "pay_test_003"
+	amount := money.New(5000, "EUR")
+
+	if err := store.Append(ctx, paymentID, 0, event.NewPaymentCreatedEvent(paymentID, "paypal", "capture", "pending", amount)); err != nil {
+		t.Fatalf("append created event: %v", err)
+	}
+
+	err := store.Append(ctx, paymentID, 0, event.NewPaymentFailedEvent(paymentID, "paypal", "DECLINED", amount))
+
+	var conflict *event.ConcurrencyError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("expected a concurrency error, got %v", err)
+	}
+	if conflict.Expected != 0 || conflict.Actual != 1 {
+		t.Errorf("unexpected conflict: %+v", conflict)
+	}
+
+	events, err := store.Load(ctx, paymentID)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(events) != 1 {
+		t.Errorf("expected the rejected event not to be stored, got %d events", len(events))
+	}
+}
+
+func TestMongoEventStore_LoadSinceAndReadAll(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	store := mongodb.NewMongoEventStore(db)
+	ctx := context.Background()
+	amount := money.New(2500, "USD")
+
+	if err := store.Append(ctx, "pay_a", 0,
+		event.NewPaymentCreatedEvent("pay_a", "stripe", "authorize", "pending", amount),
+		event.NewPaymentAuthorizedEvent("pay_a", "stripe", amount, time.Now().Add(7*24*time.Hour)),
+	); err != nil {
+		t.Fatalf("append pay_a: %v", err)
+	}
+	if err := store.Append(ctx, "pay_b", 0, event.NewPaymentCreatedEvent("pay_b", "stripe", "capture", "pending", amount)); err != nil {
+		t.Fatalf("append pay_b: %v", err)
+	}
+	if err := store.Append(ctx, "pay_a", 2, event.NewPaymentCapturedEvent("pay_a", "stripe", amount)); err != nil {
+		t.Fatalf("append pay_a capture: %v", err)
+	}
+
+	since, err := store.LoadSince(ctx, "pay_a", 1)
+	if err != nil {
+		t.Fatalf("load since: %v", err)
+	}
+	if len(since) != 2 || since[0].Version != 2 || since[1].Version != 3 {
+		t.Fatalf("expected versions 2 and 3, got %+v", since)
+	}
+	if since[1].Event.EventType() != event.PaymentCaptured {
+		t.Errorf("expected the capture last, got %s", since[1].Event.EventType())
+	}
+
+	all, err := store.ReadAll(ctx, 1, 0)
+	if err != nil {
+		t.Fatalf("read all: %v", err)
+	}
+	if len(all) != 4 {
+		t.Fatalf("expected 4 events, got %d", len(all))
+	}
+	for i := 1; i < len(all); i++ {
+		if all[i].Position <= all[i-1].Position {
+			t.Errorf("positions out of order: %d after %d", all[i].Position, all[i-1].Position)
+		}
+	}
+	if all[2].Event.AggregateID() != "pay_b" {
+		t.Errorf("expected pay_b third in position order, got %s", all[2].Event.AggregateID())
+	}
+
+	page, err := store.ReadAll(ctx, all[2].Position, 10)
+	if err != nil {
+		t.Fatalf("read all from position: %v", err)
+	}
+	if len(page) != 2 {
+		t.Errorf("expected 2 events from position %d, got %d", all[2].Position, len(page))
+	}
+}
+
+func TestMongoEventStore_MigrateLegacyEvents(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	store := mongodb.NewMongoEventStore(db)
+	ctx := context.Background()
+
+	paymentID := "pay_legacy_001"
+	amount := money.New(4200, "USD")
+	legacyEvents := []event.DomainEvent{
+		event.NewPaymentCreatedEvent(paymentID, "stripe", "capture", "pending", amount),
+		event.NewPaymentCapturedEvent(paymentID, "stripe", amount),
+	}
+
+	var records bson.A
+	for _, evt := range legacyEvents {
+		data, err := json.Marshal(evt)
+		if err != nil {
+			t.Fatalf("marshal legacy event: %v", err)
+		}
+		var dataMap map[string]interface{}
+		if err := json.Unmarshal(data, &dataMap); err != nil {
+			t.Fatalf("unmarshal legacy event: %v", err)
+		}
+		records = append(records, bson.M{"type": string(evt.EventType()), "data": dataMap, "occurred_at": evt.OccurredAt()})
+	}
+	_, err := db.Collection("events").InsertOne(ctx, bson.M{
+		"_id":          paymentID,
+		"aggregate_id": paymentID,
+		"version":      len(records),
+		"events":       records,
+	})
+	if err != nil {
+		t.Fatalf("insert legacy document: %v", err)
+	}
+
+	// appended after the switch, before the migration ran
+	if err := store.Append(ctx, paymentID, 0, event.NewPaymentRefundedEvent(paymentID, "stripe", "re_1", "refunded", "", amount, amount)); err != nil {
+		t.Fatalf("append new event: %v", err)
+	}
+
+	migrated, err := store.MigrateLegacyEvents(ctx)
+	if err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if migrated != 1 {
+		t.Errorf("expected 1 migrated aggregate, got %d", migrated)
+	}
+
+	events, err := store.Load(ctx, paymentID)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	wantTypes := []event.EventType{event.PaymentCreated, event.PaymentCaptured, event.PaymentRefunded}
+	if len(events) != len(wantTypes) {
+		t.Fatalf("expected %d events, got %d", len(wantTypes), len(events))
+	}
+	for i, recorded := range events {
+		if recorded.Version != i+1 || recorded.Event.EventType() != wantTypes[i] {
+			t.Errorf("event %d: got %s at version %d", i, recorded.Event.EventType(), recorded.Version)
+		}
+	}
+
+	again, err := store.MigrateLegacyEvents(ctx)
+	if err != nil {
+		t.Fatalf("migrate again: %v", err)
+	}
+	if again != 0 {
+		t.Errorf("expected nothing left to migrate, got %d", again)
+	}
 }